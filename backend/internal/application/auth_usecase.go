@@ -1,106 +1,501 @@
 package application
 
 import (
+	"errors"
 	"fmt"
-	"net/http"
 	"time"
 
-	"app/internal/application/ports"
+	"app/internal/domain/federation"
+	"app/internal/domain/lockout"
+	"app/internal/domain/password"
+	"app/internal/domain/provider"
 	"app/internal/domain/role"
+	"app/internal/domain/security"
+	"app/internal/domain/session"
 	"app/internal/domain/user"
+	"app/internal/domain/user_identity"
 	"app/internal/infrastructure/token/paseto"
 	"app/internal/infrastructure/token/refresh"
 	"app/pkg/errorsLib"
+	"app/pkg/logger"
 )
 
 type AuthUseCase struct {
-	userRepo    user.Repository
-	verSvc      ports.VerificacionesService
-	roleRepo    role.RoleRepository
-	userService *user.UserService
+	userRepo     user.Repository
+	roleRepo     role.RoleRepository
+	sessionRepo  session.Repository
+	identityRepo user_identity.Repository
+	userService  *user.UserService
+	securitySvc  *SecurityEventUseCase
+	mfaUC        *MFAUseCase
+	lockoutUC    *LockoutUseCase
+	passwordUC   *PasswordUseCase
+
+	// Email sent after a password change ({username} and {date} are replaced)
+	passwordChangedSubject string
+	passwordChangedBody    string
 }
 
-func NewAuthUseCase(userRepo user.Repository, verSvc ports.VerificacionesService, roleRepo role.RoleRepository) *AuthUseCase {
+func NewAuthUseCase(userRepo user.Repository, roleRepo role.RoleRepository, sessionRepo session.Repository, securityRepo security.Repository, identityRepo user_identity.Repository, mfaUC *MFAUseCase, lockoutUC *LockoutUseCase, passwordUC *PasswordUseCase) *AuthUseCase {
 	userService := user.NewUserService(userRepo, roleRepo)
 	return &AuthUseCase{
-		userRepo:    userRepo,
-		verSvc:      verSvc,
-		roleRepo:    roleRepo,
-		userService: userService,
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		sessionRepo:  sessionRepo,
+		identityRepo: identityRepo,
+		userService:  userService,
+		securitySvc:  NewSecurityEventUseCase(securityRepo),
+		mfaUC:        mfaUC,
+		lockoutUC:    lockoutUC,
+		passwordUC:   passwordUC,
+	}
+}
+
+// SetPasswordChangedEmail sets the email that notifies the users of a password change
+// ({username} and {date} are replaced in the body). Nothing is sent if the subject or the body are empty.
+func (uc *AuthUseCase) SetPasswordChangedEmail(subject, body string) {
+	uc.passwordChangedSubject = subject
+	uc.passwordChangedBody = body
+}
+
+// Login authenticates the user and opens a new session for the client device.
+// Sessions of other devices are not affected.
+// When the login needs a second factor no session is opened: the user is returned with
+// a short-lived MFA token (user.MFAToken) to complete the login with CompleteMFALogin.
+// When the password is expired no session is opened either: the user is returned with
+// a short-lived token (user.PasswordChangeToken) that only allows ChangeExpiredPassword.
+func (uc *AuthUseCase) Login(login, password string, client session.ClientInfo) (*user.User, error) {
+	usr, ownerUsername, err := uc.BeginLogin(login, password, client.IP)
+	if err != nil {
+		return nil, err
+	}
+	if usr.MFAToken != "" {
+		return usr, nil
+	}
+	expired, err := uc.passwordUC.checkExpiry(usr)
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return usr, nil
+	}
+
+	if err := uc.StartSession(usr, ownerUsername, client, "", ""); err != nil {
+		return nil, err
 	}
+	return usr, nil
 }
 
-func (uc *AuthUseCase) Login(login, password string) (*user.User, error) {
+// BeginLogin authenticates the user (first factor) without opening a session.
+// If the login needs a second factor, the MFA token of the login is set on the user
+// (and MFAEnrollmentRequired if the user has to set up its authenticator first).
+func (uc *AuthUseCase) BeginLogin(login, password, ip string) (*user.User, string, error) {
+	usr, ownerUsername, err := uc.Authenticate(login, password, ip)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := uc.checkSecondFactor(usr); err != nil {
+		return nil, "", err
+	}
+	return usr, ownerUsername, nil
+}
+
+// checkSecondFactor sets the MFA token of the login on the user if it needs a second factor
+// (and MFAEnrollmentRequired if the user has to set up its authenticator first)
+func (uc *AuthUseCase) checkSecondFactor(usr *user.User) error {
+	required, enrolled, err := uc.mfaUC.loginState(usr)
+	if err != nil || !required {
+		return err
+	}
+	token, _, err := paseto.Paseto().GenerateMFAPendingToken(paseto.PasetoClaims{Username: usr.Login}, uc.mfaUC.pendingTokenTTL)
+	if err != nil {
+		return fmt.Errorf("mfa token generation error: %w", err)
+	}
+	usr.MFAToken = token
+	usr.MFAEnrollmentRequired = !enrolled
+	return nil
+}
+
+// LoginFederated opens a session for the user of an identity proven by a federated provider
+// (e.g. an external OpenID Connect provider); its first login creates the user. A login that belongs
+// to a user of another provider is rejected. The second factor is required like in Login.
+func (uc *AuthUseCase) LoginFederated(identity *provider.Identity, idp provider.IdentityProvider, providerID uint, client session.ClientInfo) (*user.User, error) {
+	usr, err := uc.SaveFederatedUser(identity, idp, providerID)
+	if err != nil {
+		return nil, err
+	}
+	return uc.LoginFederatedUser(usr, client)
+}
+
+// SaveFederatedUser creates (first login) or updates the user of an identity proven by a federated provider.
+// A login that belongs to a user of another provider, or of another company, is rejected.
+func (uc *AuthUseCase) SaveFederatedUser(identity *provider.Identity, idp provider.IdentityProvider, providerID uint) (*user.User, error) {
+	usr, err := uc.userRepo.GetByLogin(identity.Login)
+	if err != nil {
+		if !uc.userRepo.IsNotFoundError(err) {
+			return nil, fmt.Errorf("repo error: %w", err)
+		}
+		usr = nil
+	}
+	if usr != nil && (usr.ProviderID != providerID || (identity.CompanyID != 0 && usr.CompanyID != identity.CompanyID)) {
+		logger.GetLogger().ServiceWarn("Federated login of a user of another provider", map[string]interface{}{
+			"username":   identity.Login,
+			"providerId": providerID,
+			"companyId":  identity.CompanyID,
+		})
+		return nil, federation.ErrIdentityInUse
+	}
+
+	return uc.saveIdentityUser(usr, identity, idp, providerID)
+}
+
+// LoginFederatedUser opens a session for a user saved by SaveFederatedUser. The user (and the owner
+// of a subuser) must be active and the second factor is required like in Login.
+func (uc *AuthUseCase) LoginFederatedUser(usr *user.User, client session.ClientInfo) (*user.User, error) {
+	if !usr.Active {
+		return nil, errorsLib.ErrForbidden
+	}
+
+	var ownerUsername string
+	if usr.OwnerID != nil {
+		owner, err := uc.userRepo.GetByID(*usr.OwnerID)
+		if err == nil {
+			if !owner.Active {
+				return nil, errorsLib.ErrForbidden
+			}
+			ownerUsername = owner.Login
+		}
+	}
+
+	if err := uc.checkSecondFactor(usr); err != nil {
+		return nil, err
+	}
+	if usr.MFAToken != "" {
+		return usr, nil
+	}
+
+	if err := uc.StartSession(usr, ownerUsername, client, "", ""); err != nil {
+		return nil, err
+	}
+	return usr, nil
+}
+
+// CompleteMFALogin verifies the second factor (code of the authenticator app or recovery code)
+// of a login started with an MFA token and opens the session (unless the password is expired, see Login)
+func (uc *AuthUseCase) CompleteMFALogin(mfaToken, code string, client session.ClientInfo) (*user.User, error) {
+	usr, ownerUsername, err := uc.VerifyMFALogin(mfaToken, code)
+	if err != nil {
+		return nil, err
+	}
+	expired, err := uc.passwordUC.checkExpiry(usr)
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return usr, nil
+	}
+
+	if err := uc.StartSession(usr, ownerUsername, client, "", ""); err != nil {
+		return nil, err
+	}
+	return usr, nil
+}
+
+// VerifyMFALogin verifies the second factor of a login started with an MFA token without opening a session.
+// The MFA token can only be used once.
+func (uc *AuthUseCase) VerifyMFALogin(mfaToken, code string) (*user.User, string, error) {
+	claims, usr, ownerUsername, err := uc.mfaTokenUser(mfaToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := uc.mfaUC.verifyLogin(usr, code); err != nil {
+		return nil, "", err
+	}
+
+	if err := paseto.Paseto().RevokeToken(claims); err != nil {
+		return nil, "", fmt.Errorf("error revoking mfa token: %w", err)
+	}
+	return usr, ownerUsername, nil
+}
+
+// BeginMFAEnrollment starts the enrollment of a user that can't log in without two-factor
+// authentication (required by the company owner) and has not set it up yet
+func (uc *AuthUseCase) BeginMFAEnrollment(mfaToken string) (*TOTPSetup, error) {
+	_, usr, _, err := uc.mfaTokenUser(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	return uc.mfaUC.beginEnrollment(usr)
+}
+
+// CompleteMFAEnrollment confirms the enrollment started with BeginMFAEnrollment and opens the session
+// (unless the password is expired, see Login). Returns the recovery codes of the new enrollment (shown only once).
+func (uc *AuthUseCase) CompleteMFAEnrollment(mfaToken, code string, client session.ClientInfo) (*user.User, []string, error) {
+	claims, usr, ownerUsername, err := uc.mfaTokenUser(mfaToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recoveryCodes, err := uc.mfaUC.confirmEnrollment(usr, code)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := paseto.Paseto().RevokeToken(claims); err != nil {
+		return nil, nil, fmt.Errorf("error revoking mfa token: %w", err)
+	}
+	expired, err := uc.passwordUC.checkExpiry(usr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if expired {
+		return usr, recoveryCodes, nil
+	}
+	if err := uc.StartSession(usr, ownerUsername, client, "", ""); err != nil {
+		return nil, nil, err
+	}
+	return usr, recoveryCodes, nil
+}
+
+// mfaTokenUser validates an MFA token and returns its claims, its user and the username of the owner.
+// The user (and the owner of a subuser) must still be active.
+func (uc *AuthUseCase) mfaTokenUser(mfaToken string) (*paseto.PasetoClaims, *user.User, string, error) {
+	claims, err := paseto.Paseto().ValidateToken(mfaToken)
+	if err != nil || !claims.HasRole(role.RoleMFAPending) {
+		return nil, nil, "", errorsLib.ErrAccessDenied
+	}
+
+	usr, err := uc.userRepo.GetByLogin(claims.Username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, nil, "", errorsLib.ErrAccessDenied
+		}
+		return nil, nil, "", fmt.Errorf("error retrieving user: %w", err)
+	}
+	if !usr.Active {
+		return nil, nil, "", errorsLib.ErrForbidden
+	}
+
+	var ownerUsername string
+	if usr.OwnerID != nil {
+		owner, err := uc.userRepo.GetByID(*usr.OwnerID)
+		if err == nil {
+			if !owner.Active {
+				return nil, nil, "", errorsLib.ErrForbidden
+			}
+			ownerUsername = owner.Login
+		}
+	}
+	return claims, usr, ownerUsername, nil
+}
+
+// Authenticate checks the credentials of the user with its identity provider (users that don't exist yet
+// are created on their first login by the provider that knows them) without opening a session. Returns the user and the username of its owner (for subusers).
+// A login that is not the login of a user may be an identity linked to a user (see authenticateLinked).
+// The attempts are limited per username and per IP address (ip of the client, may be empty):
+// a rejected attempt returns a *lockout.ThrottledError without checking the password.
+func (uc *AuthUseCase) Authenticate(login, password, ip string) (*user.User, string, error) {
+	// 0. Brute-force protection: locked username or IP, or delay after the last failure
+	if err := uc.lockoutUC.Check(login, ip); err != nil {
+		return nil, "", err
+	}
+
 	// 1. Try to find user by login
 	usr, err := uc.userRepo.GetByLogin(login)
 	if err != nil {
 		if !uc.userRepo.IsNotFoundError(err) {
 			// another error
-			return nil, fmt.Errorf("repo error: %w", err)
+			return nil, "", fmt.Errorf("repo error: %w", err)
 		}
 		usr = nil
 	}
 
-	// 2. If user NOT found OR user.ProviderID=2 => check external service
-	if usr == nil || usr.ProviderID == 2 {
+	// 2. A login without user may be an identity linked to a user (login of another provider)
+	linked := false
+	if usr == nil {
+		usr, err = uc.authenticateLinked(login, password)
+		switch {
+		case err == nil:
+			linked = true
+		case errors.Is(err, user_identity.ErrIdentityNotFound):
+			usr = nil
+		default:
+			if errors.Is(err, provider.ErrUnknownUser) || errors.Is(err, provider.ErrInvalidCredentials) {
+				uc.lockoutUC.RecordFailure(login, ip)
+			}
+			return nil, "", err
+		}
+	}
 
-		if usr == nil {
-			// Check if user already exists in verificaciones
-			exists, err := uc.verSvc.CheckIfUserExists(login)
+	if !linked {
+		// 3. Check the credentials with the identity provider of the user
+		// (or with the providers that may know the login of a user that doesn't exist yet)
+		identity, idp, providerID, err := uc.authenticateIdentity(login, password, usr)
+		switch {
+		case err == nil:
+			// 4. Create the user on its first login, or update it with the identity
+			usr, err = uc.saveIdentityUser(usr, identity, idp, providerID)
 			if err != nil {
-				return nil, fmt.Errorf("error checking if user exists in verificaciones: %w", err)
+				return nil, "", err
 			}
-			if !exists {
-				return nil, fmt.Errorf("user does not exist")
+		case usr != nil && errors.Is(err, provider.ErrInvalidCredentials) && uc.authenticateOwnLogin(usr, login, password):
+			// The login of the user is also its login at another provider (linked identity with the same login)
+		default:
+			if errors.Is(err, provider.ErrUnknownUser) || errors.Is(err, provider.ErrInvalidCredentials) {
+				uc.lockoutUC.RecordFailure(login, ip)
 			}
+			return nil, "", err
 		}
+	}
 
-		resp, status, err := uc.verSvc.Login(ports.LoginReq{
-			Username: login,
-			Password: password,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("external login error: %w", err)
-		}
-		if status != 200 {
-			return nil, fmt.Errorf("login failed with status %d", status)
-		}
-
-		if usr == nil {
-			// Create new user
-			newUser := &user.User{
-				Login:        login,
-				ProviderID:   2,
-				ProviderName: "Verificaciones",
-				CompanyID:    uint(resp.IdEmpresa),
-				CompanyName:  resp.Empresa,
-				Active:       true,
-				IsLogged:     true,
-				Password:     nil, // do not store password
-			}
-			newUser.LastAccess = time.Now().Format("2006-01-02 15:04:05")
+	// The password is correct: the failed attempts of the username are forgotten
+	uc.lockoutUC.RecordSuccess(login, usr)
 
-			if err := uc.userRepo.Create(newUser); err != nil {
-				return nil, fmt.Errorf("create user error: %w", err)
-			}
-			// After creation, get user from DB again
-			usr, err = uc.userRepo.GetByLogin(login)
-			if err != nil {
-				return nil, fmt.Errorf("get user error: %w", err)
+	// Check if user is active
+	if !usr.Active {
+		return nil, "", errorsLib.ErrForbidden
+	}
+
+	// 5. Check if `OwnerID` exists, if yes, get owner
+	var ownerUsername string
+	if usr.OwnerID != nil {
+		ownerUser, err := uc.userRepo.GetByID(*usr.OwnerID)
+		if err == nil {
+			ownerUsername = ownerUser.Login
+			// Check if owner is active
+			if !ownerUser.Active {
+				return nil, "", errorsLib.ErrForbidden
 			}
-		} else {
-			// user already exists
-			usr.LastAccess = time.Now().Format("2006-01-02 15:04:05")
-			usr.IsLogged = true
-			if err := uc.userRepo.Update(usr); err != nil {
-				return nil, fmt.Errorf("update user error: %w", err)
+		}
+	}
+
+	// At this point, usr is definitely not nil and is authorized
+	return usr, ownerUsername, nil
+}
+
+// authenticateIdentity checks the credentials with the identity provider of the user. For a login without
+// user the registered providers are tried in order until one knows it. Returns the identity, its provider
+// and the ID of the provider.
+func (uc *AuthUseCase) authenticateIdentity(login, password string, usr *user.User) (*provider.Identity, provider.IdentityProvider, uint, error) {
+	if usr != nil {
+		idp, err := provider.Lookup(usr.ProviderID)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		identity, err := idp.Authenticate(login, password, usr)
+		return identity, idp, usr.ProviderID, err
+	}
+
+	for _, r := range provider.Registered() {
+		identity, err := r.Provider.Authenticate(login, password, nil)
+		if errors.Is(err, provider.ErrUnknownUser) {
+			continue
+		}
+		return identity, r.Provider, r.ID, err
+	}
+	return nil, nil, 0, provider.ErrUnknownUser
+}
+
+// authenticateLinked checks the credentials with the providers of the identities linked with the login
+// (ErrIdentityNotFound if no user has linked it). The profile of the user is not updated by the identity:
+// it belongs to the provider of the user. A user locked after failed logins can't sign in with its identities.
+func (uc *AuthUseCase) authenticateLinked(login, password string) (*user.User, error) {
+	links, err := uc.identityRepo.GetBySubject(login)
+	if err != nil {
+		return nil, fmt.Errorf("get linked identities error: %w", err)
+	}
+
+	for _, link := range links {
+		idp, err := provider.Lookup(link.ProviderID)
+		if err != nil {
+			// provider no longer registered: its identities can't sign in
+			continue
+		}
+		if _, err := idp.Authenticate(login, password, nil); err != nil {
+			if errors.Is(err, provider.ErrUnknownUser) {
+				continue
 			}
+			return nil, err
 		}
-	} else {
-		// 3. If user found + ProviderID=1 => local password check
-		if !usr.CheckPassword(password) {
-			return nil, fmt.Errorf("invalid password")
+
+		usr, err := uc.userRepo.GetByID(link.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("get user error: %w", err)
+		}
+		now := time.Now()
+		if usr.IsLocked(now) {
+			return nil, &lockout.ThrottledError{Err: lockout.ErrAccountLocked, RetryAfter: usr.LockedUntil.Sub(now)}
+		}
+
+		if err := uc.identityRepo.UpdateLastUsed(link.ID, now); err != nil {
+			logger.GetLogger().ServiceWarn("Error updating last use of linked identity", map[string]interface{}{
+				"identityId": link.ID,
+				"error":      err.Error(),
+			})
+		}
+		usr.LastAccess = now.Format("2006-01-02 15:04:05")
+		usr.IsLogged = true
+		if err := uc.userRepo.Update(usr); err != nil {
+			return nil, fmt.Errorf("update user error: %w", err)
+		}
+		return usr, nil
+	}
+	return nil, user_identity.ErrIdentityNotFound
+}
+
+// authenticateOwnLogin checks the credentials with the providers of the identities linked with the login
+// of the user itself (e.g. a subuser that is also a technician of Verificaciones with the same login)
+func (uc *AuthUseCase) authenticateOwnLogin(usr *user.User, login, password string) bool {
+	linkedUsr, err := uc.authenticateLinked(login, password)
+	return err == nil && linkedUsr.ID == usr.ID
+}
+
+// Reauthenticate checks the password of the user with its identity provider before a sensitive change
+// of its account (e.g. linking an identity). The attempts are limited like the logins (ip of the client,
+// may be empty): a wrong password returns password.ErrWrongPassword.
+func (uc *AuthUseCase) Reauthenticate(usr *user.User, currentPassword, ip string) error {
+	if err := uc.lockoutUC.Check(usr.Login, ip); err != nil {
+		return err
+	}
+	idp, err := provider.Lookup(usr.ProviderID)
+	if err != nil {
+		return err
+	}
+	if _, err := idp.Authenticate(usr.Login, currentPassword, usr); err != nil {
+		if errors.Is(err, provider.ErrUnknownUser) || errors.Is(err, provider.ErrInvalidCredentials) {
+			uc.lockoutUC.RecordFailure(usr.Login, ip)
+			return password.ErrWrongPassword
+		}
+		return err
+	}
+	uc.lockoutUC.RecordSuccess(usr.Login, usr)
+	return nil
+}
+
+// saveIdentityUser creates the user of an identity on its first login (usr is nil) or updates the user
+// with it, and applies the profile and the roles of the identity
+func (uc *AuthUseCase) saveIdentityUser(usr *user.User, identity *provider.Identity, idp provider.IdentityProvider, providerID uint) (*user.User, error) {
+	if usr == nil {
+		newUser, err := idp.ProvisionUser(identity)
+		if err != nil {
+			return nil, fmt.Errorf("provision user error: %w", err)
+		}
+		newUser.ProviderID = providerID
+		newUser.Active = true
+		newUser.IsLogged = true
+		newUser.LastAccess = time.Now().Format("2006-01-02 15:04:05")
+
+		if err := uc.userRepo.Create(newUser); err != nil {
+			return nil, fmt.Errorf("create user error: %w", err)
 		}
+		// After creation, get user from DB again
+		usr, err = uc.userRepo.GetByLogin(identity.Login)
+		if err != nil {
+			return nil, fmt.Errorf("get user error: %w", err)
+		}
+	} else {
+		idp.SyncProfile(usr, identity)
 		usr.LastAccess = time.Now().Format("2006-01-02 15:04:05")
 		usr.IsLogged = true
 		if err := uc.userRepo.Update(usr); err != nil {
@@ -108,53 +503,285 @@ func (uc *AuthUseCase) Login(login, password string) (*user.User, error) {
 		}
 	}
 
-	// Check if user is active
+	// Profile and roles of the identity (e.g. attributes and groups of a directory)
+	if err := uc.applyIdentity(usr, identity); err != nil {
+		return nil, err
+	}
+	return usr, nil
+}
+
+// applyIdentity updates the profile of the user with the identity and grants or removes
+// the roles managed by its provider
+func (uc *AuthUseCase) applyIdentity(usr *user.User, identity *provider.Identity) error {
+	profile := usr.Profile
+	if profile == nil {
+		profile = &user.Profile{}
+	}
+	if identity.ApplyProfile(profile) {
+		if err := uc.userRepo.UploadProfileTransaction(usr.ID, profile); err != nil {
+			return fmt.Errorf("update profile error: %w", err)
+		}
+		usr.Profile = profile
+	}
+
+	if len(identity.ManagedRoles) == 0 {
+		return nil
+	}
+	current, err := uc.roleRepo.GetUserRoles(usr.ID)
+	if err != nil {
+		return fmt.Errorf("get user roles error: %w", err)
+	}
+	has := make(map[string]uint, len(current))
+	for _, r := range current {
+		has[r.Role] = r.ID
+	}
+	granted := make(map[string]bool, len(identity.Roles))
+	for _, name := range identity.Roles {
+		granted[name] = true
+	}
+
+	for _, name := range identity.ManagedRoles {
+		roleID, assigned := has[name]
+		switch {
+		case granted[name] && !assigned:
+			r, err := uc.roleRepo.GetRoleByName(name)
+			if err != nil {
+				if uc.roleRepo.IsNotFoundError(err) {
+					logger.GetLogger().ServiceWarn("Role of the identity provider not found", map[string]interface{}{
+						"role":     name,
+						"username": usr.Login,
+					})
+					continue
+				}
+				return fmt.Errorf("get role error: %w", err)
+			}
+			if err := uc.roleRepo.AssignRoleToUser(usr.ID, r.ID); err != nil {
+				return fmt.Errorf("assign role error: %w", err)
+			}
+		case !granted[name] && assigned:
+			if err := uc.roleRepo.RemoveRoleFromUser(usr.ID, roleID); err != nil {
+				return fmt.Errorf("remove role error: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// LoginAuthenticated opens a session for a local user already authenticated without
+// password (e.g. with a passkey). The user (and the owner of a subuser) must be active.
+func (uc *AuthUseCase) LoginAuthenticated(usr *user.User, client session.ClientInfo) (*user.User, error) {
 	if !usr.Active {
 		return nil, errorsLib.ErrForbidden
 	}
 
-	// 4. Check if `OwnerID` exists, if yes, get owner
 	var ownerUsername string
 	if usr.OwnerID != nil {
-		ownerUser, err := uc.userRepo.GetByID(*usr.OwnerID)
+		owner, err := uc.userRepo.GetByID(*usr.OwnerID)
 		if err == nil {
-			ownerUsername = ownerUser.Login
-			// Check if owner is active
-			if !ownerUser.Active {
+			if !owner.Active {
 				return nil, errorsLib.ErrForbidden
 			}
+			ownerUsername = owner.Login
 		}
 	}
 
-	// At this point, usr is definitely not nil and is authorized
-	// 5. Generate refresh-token + expDate
-	token, expDate, err := refresh.GenerateRefreshToken()
-	if err != nil {
-		return nil, fmt.Errorf("refresh token generation error: %w", err)
+	usr.LastAccess = time.Now().Format("2006-01-02 15:04:05")
+	usr.IsLogged = true
+	if err := uc.userRepo.Update(usr); err != nil {
+		return nil, fmt.Errorf("update user error: %w", err)
 	}
-	usr.Refresh = &token
-	usr.RefreshExp = expDate
 
-	// 6. Save refresh-token in DB
-	if err := uc.userRepo.Update(usr); err != nil {
-		return nil, fmt.Errorf("update user (refresh) error: %w", err)
+	if err := uc.StartSession(usr, ownerUsername, client, "", ""); err != nil {
+		return nil, err
 	}
+	return usr, nil
+}
 
-	// 7. Check and assign roles to user
+// StartSession opens a new session of the authenticated user for the client device
+// (refresh-token + expDate), ensures its roles and generates its access-token.
+// clientID and scope are set when the session is opened for an OAuth client.
+func (uc *AuthUseCase) StartSession(usr *user.User, ownerUsername string, client session.ClientInfo, clientID, scope string) error {
+	// 1. Open a new session for this device
+	if err := uc.openSession(usr, client, clientID, scope); err != nil {
+		return err
+	}
+
+	// 2. Check and assign roles to user
 	if err := uc.userService.EnsureUserRoles(usr); err != nil {
-		return nil, fmt.Errorf("ensure user roles error: %w", err)
+		return fmt.Errorf("ensure user roles error: %w", err)
 	}
 
-	// 8. Get user roles directly from database
-	userRoles, err := uc.userService.GetUserRoles(usr.ID)
+	// 3. Generate access-token
+	return uc.issueAccessToken(usr, ownerUsername, clientID, scope)
+}
+
+// RefreshPairTokens exchanges a refresh token for a new access/refresh pair (rotation).
+// Presenting an already rotated refresh token is treated as a theft: the whole family
+// (session) is revoked and marked as compromised, and a security event is emitted.
+// clientID is the OAuth client presenting the token (empty for direct logins): a session
+// can only be refreshed by the client that opened it.
+// The session of a deactivated or locked user (or of a subuser of a deactivated owner) is revoked.
+func (uc *AuthUseCase) RefreshPairTokens(refreshTokenReq string, client session.ClientInfo, clientID string) (string, string, error) {
+	if refreshTokenReq == "" {
+		return "", "", errorsLib.ErrAccessDenied
+	}
+
+	// 1. Find the refresh token (only its hash is stored)
+	oldToken, err := uc.sessionRepo.GetRefreshTokenByHash(refresh.HashRefreshToken(refreshTokenReq))
 	if err != nil {
-		return nil, fmt.Errorf("get user roles error: %w", err)
+		if !uc.sessionRepo.IsNotFoundError(err) {
+			return "", "", fmt.Errorf("get refresh token error: %w", err)
+		}
+		return "", "", errorsLib.ErrAccessDenied
+	}
+
+	sess, err := uc.sessionRepo.GetByID(oldToken.SessionID)
+	if err != nil {
+		if !uc.sessionRepo.IsNotFoundError(err) {
+			return "", "", fmt.Errorf("get session error: %w", err)
+		}
+		return "", "", errorsLib.ErrAccessDenied
+	}
+
+	// 2. Reuse detection: the token was already exchanged for a new one
+	if oldToken.IsRotated() {
+		uc.compromiseSession(sess, client, "rotated refresh token presented again")
+		return "", "", errorsLib.ErrAccessDenied
+	}
+
+	// 3. Check that the session and the token are not revoked or expired
+	now := time.Now()
+	if !sess.IsActive(now) || oldToken.RevokedAt != nil || !now.Before(oldToken.ExpiresAt) {
+		return "", "", errorsLib.ErrAccessDenied
+	}
+	if sess.ClientID != clientID {
+		return "", "", errorsLib.ErrAccessDenied
+	}
+
+	user, err := uc.userRepo.GetByID(sess.UserID)
+	if err != nil {
+		if !uc.userRepo.IsNotFoundError(err) {
+			return "", "", fmt.Errorf("get user error: %w", err)
+		}
+		return "", "", errorsLib.ErrAccessDenied
+	}
+
+	// The user (and the owner of a subuser) must still be allowed to sign in
+	var ownerUsername string
+	allowed := user.Active && !user.IsLocked(now)
+	if allowed && user.OwnerID != nil {
+		ownerUser, err := uc.userRepo.GetByID(*user.OwnerID)
+		if err == nil {
+			ownerUsername = ownerUser.Login
+			allowed = ownerUser.Active
+		}
+	}
+	if !allowed {
+		if err := uc.RevokeSessionByID(sess.ID); err != nil {
+			return "", "", err
+		}
+		return "", "", errorsLib.ErrAccessDenied
+	}
+
+	// 4. Rotate: the new token is a child of the presented one
+	token, expDate, err := refresh.NewRefreshToken()
+	if err != nil {
+		return "", "", fmt.Errorf("refresh token generation error: %w", err)
+	}
+
+	newToken := &session.RefreshToken{
+		SessionID: sess.ID,
+		TokenHash: refresh.HashRefreshToken(token),
+		CreatedAt: now,
+		ExpiresAt: expDate,
+	}
+	if err := uc.sessionRepo.RotateRefreshToken(oldToken.ID, newToken); err != nil {
+		if errors.Is(err, session.ErrRefreshTokenAlreadyRotated) {
+			// Another request rotated the same token at the same time
+			uc.compromiseSession(sess, client, "refresh token rotated concurrently")
+			return "", "", errorsLib.ErrAccessDenied
+		}
+		return "", "", fmt.Errorf("rotate refresh token error: %w", err)
+	}
+
+	if err := uc.sessionRepo.Touch(sess.ID, now, expDate); err != nil {
+		return "", "", fmt.Errorf("update session (refresh) error: %w", err)
+	}
+	user.RefreshToken = token
+	user.SessionID = sess.ID
+
+	// 5. Generate access-token
+	if err := uc.issueAccessToken(user, ownerUsername, sess.ClientID, sess.Scope); err != nil {
+		return "", "", err
+	}
+
+	return user.AccessToken, user.RefreshToken, nil
+}
+
+// compromiseSession revokes the whole refresh token family of the session and emits a security event
+func (uc *AuthUseCase) compromiseSession(sess *session.Session, client session.ClientInfo, details string) {
+	if err := uc.sessionRepo.RevokeFamily(sess.ID); err != nil {
+		logger.GetLogger().ServiceError("Error revoking compromised session", map[string]interface{}{
+			"sessionId": sess.ID,
+			"error":     err.Error(),
+		})
+	}
+
+	userID := sess.UserID
+	uc.securitySvc.Emit(security.Event{
+		Type:      security.EventRefreshTokenReuse,
+		UserID:    &userID,
+		SessionID: sess.ID,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Details:   details,
+	})
+}
+
+// openSession creates a new session (refresh token family) for the user and sets its refresh token on the user
+func (uc *AuthUseCase) openSession(usr *user.User, client session.ClientInfo, clientID, scope string) error {
+	token, expDate, err := refresh.NewRefreshToken()
+	if err != nil {
+		return fmt.Errorf("refresh token generation error: %w", err)
+	}
+
+	now := time.Now()
+	sess := &session.Session{
+		UserID:     usr.ID,
+		Device:     client.Device,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  expDate,
+		ClientID:   clientID,
+		Scope:      scope,
+	}
+	firstToken := &session.RefreshToken{
+		TokenHash: refresh.HashRefreshToken(token),
+		CreatedAt: now,
+		ExpiresAt: expDate,
+	}
+	if err := uc.sessionRepo.Create(sess, firstToken); err != nil {
+		return fmt.Errorf("create session error: %w", err)
+	}
+
+	usr.RefreshToken = token
+	usr.SessionID = sess.ID
+	return nil
+}
+
+// issueAccessToken loads the roles of the user (including inherited roles) and generates its access-token
+func (uc *AuthUseCase) issueAccessToken(usr *user.User, ownerUsername, clientID, scope string) error {
+	// Get user roles directly from database (including inherited roles)
+	userRoles, err := uc.userService.GetUserRolesExpanded(usr.ID)
+	if err != nil {
+		return fmt.Errorf("get user roles error: %w", err)
 	}
 
 	// Assign roles to user object
 	usr.Roles = userRoles
 
-	// 9. Generate access-token
 	// Convert roles to string for token
 	roleNames := uc.userService.GetRoleNamesString(userRoles)
 
@@ -164,91 +791,187 @@ func (uc *AuthUseCase) Login(login, password string) (*user.User, error) {
 		CompanyName:   usr.CompanyName,
 		Roles:         roleNames,
 		OwnerUsername: ownerUsername,
+		SessionID:     usr.SessionID,
+		ClientID:      clientID,
+		Scope:         scope,
 		// IsPrimary:     usr.Profile != nil && usr.Profile.IsPrimary,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("access token generation error: %w", err)
+		return fmt.Errorf("access token generation error: %w", err)
 	}
 	usr.AccessToken = accessToken
-	return usr, nil
+	return nil
 }
 
-func (uc *AuthUseCase) RefreshPairTokens(refreshTokenReq string) (string, string, error) {
-
-	user, err := uc.userRepo.GetByRefreshToken(refreshTokenReq)
+// ListSessions returns the active sessions of the user, marking the current one
+func (uc *AuthUseCase) ListSessions(username, currentSessionID string) ([]*session.Session, error) {
+	usr, err := uc.userRepo.GetByLogin(username)
 	if err != nil {
-		if !uc.userRepo.IsNotFoundError(err) {
-			return "", "", fmt.Errorf("get user by refresh token error: %w", err)
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, errorsLib.ErrNotFound
 		}
-		return "", "", errorsLib.ErrAccessDenied
+		return nil, fmt.Errorf("error retrieving user: %w", err)
 	}
 
-	if refreshTokenReq != *user.Refresh {
-		return "", "", errorsLib.ErrAccessDenied
+	sessions, err := uc.sessionRepo.GetActiveByUserID(usr.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving sessions: %w", err)
 	}
 
-	status, err := refresh.ValidateRefreshToken(refreshTokenReq, user.RefreshExp)
-	if err != nil && status != http.StatusUnauthorized {
-		return "", "", fmt.Errorf("refresh token validation error: %w", err)
+	for _, s := range sessions {
+		s.Current = s.ID == currentSessionID
 	}
+	return sessions, nil
+}
 
-	if status != http.StatusOK {
-		return "", "", errorsLib.ErrAccessDenied
+// RevokeSession revokes one session of the user
+func (uc *AuthUseCase) RevokeSession(username, sessionID string) error {
+	usr, err := uc.userRepo.GetByLogin(username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return errorsLib.ErrNotFound
+		}
+		return fmt.Errorf("error retrieving user: %w", err)
 	}
 
-	token, expDate, err := refresh.GenerateRefreshToken()
+	sess, err := uc.sessionRepo.GetByID(sessionID)
 	if err != nil {
-		return "", "", fmt.Errorf("refresh token generation error: %w", err)
+		if uc.sessionRepo.IsNotFoundError(err) {
+			return errorsLib.ErrNotFound
+		}
+		return fmt.Errorf("error retrieving session: %w", err)
 	}
 
-	user.Refresh = &token
-	user.RefreshExp = expDate
+	// A user can only revoke its own sessions
+	if sess.UserID != usr.ID {
+		return errorsLib.ErrNotFound
+	}
 
-	if err := uc.userRepo.Update(user); err != nil {
-		return "", "", fmt.Errorf("update user (refresh) error: %w", err)
+	if err := uc.sessionRepo.Revoke(sess.ID); err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
 	}
+	return uc.revokeSessionAccessTokens(sess.ID)
+}
+
+// RevokeSessionByID revokes a session and its access tokens (e.g. opened with a reused authorization code)
+func (uc *AuthUseCase) RevokeSessionByID(sessionID string) error {
+	if err := uc.sessionRepo.Revoke(sessionID); err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	return uc.revokeSessionAccessTokens(sessionID)
+}
 
-	// 8. Get user roles directly from database
-	userRoles, err := uc.userService.GetUserRoles(user.ID)
+// RevokeAllSessions revokes all the sessions of the user except exceptSessionID (if not empty)
+func (uc *AuthUseCase) RevokeAllSessions(username, exceptSessionID string) error {
+	usr, err := uc.userRepo.GetByLogin(username)
 	if err != nil {
-		return "", "", fmt.Errorf("get user roles error: %w", err)
+		if uc.userRepo.IsNotFoundError(err) {
+			return errorsLib.ErrNotFound
+		}
+		return fmt.Errorf("error retrieving user: %w", err)
 	}
 
-	// Assign roles to user object
-	user.Roles = userRoles
+	return uc.revokeAllSessions(usr.ID, exceptSessionID)
+}
 
-	// 9. Generate access-token
-	// Convert roles to string for token
-	roleNames := uc.userService.GetRoleNamesString(userRoles)
+// Logout closes the session of the token: its refresh tokens and access tokens stop working.
+// The user is marked as not logged when it has no other active session.
+func (uc *AuthUseCase) Logout(claims *paseto.PasetoClaims) error {
+	usr, err := uc.userRepo.GetByLogin(claims.Username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return errorsLib.ErrNotFound
+		}
+		return fmt.Errorf("error retrieving user: %w", err)
+	}
 
-	var ownerUsername string
-	if user.OwnerID != nil {
-		ownerUser, err := uc.userRepo.GetByID(*user.OwnerID)
-		if err == nil {
-			ownerUsername = ownerUser.Login
+	if claims.SessionID != "" {
+		if err := uc.sessionRepo.Revoke(claims.SessionID); err != nil {
+			return fmt.Errorf("error revoking session: %w", err)
+		}
+		if err := uc.revokeSessionAccessTokens(claims.SessionID); err != nil {
+			return err
 		}
 	}
 
-	accessToken, _, err := paseto.Paseto().GenerateToken(paseto.PasetoClaims{
-		Username:      user.Login,
-		CompanyID:     int(user.CompanyID),
-		CompanyName:   user.CompanyName,
-		Roles:         roleNames,
-		OwnerUsername: ownerUsername,
-		// IsPrimary:     usr.Profile != nil && usr.Profile.IsPrimary,
-	})
+	// The token itself is revoked even if it was issued without a session
+	if err := paseto.Paseto().RevokeToken(claims); err != nil {
+		return fmt.Errorf("error revoking access token: %w", err)
+	}
+
+	sessions, err := uc.sessionRepo.GetActiveByUserID(usr.ID)
+	if err != nil {
+		return fmt.Errorf("error retrieving sessions: %w", err)
+	}
+	if len(sessions) == 0 {
+		if err := uc.userRepo.UpdateLoggedStatus(usr.ID, false); err != nil {
+			return fmt.Errorf("error updating user: %w", err)
+		}
+	}
+	return nil
+}
+
+// LogoutEverywhere closes all the sessions of the token user on every device
+func (uc *AuthUseCase) LogoutEverywhere(claims *paseto.PasetoClaims) error {
+	usr, err := uc.userRepo.GetByLogin(claims.Username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return errorsLib.ErrNotFound
+		}
+		return fmt.Errorf("error retrieving user: %w", err)
+	}
+
+	if err := uc.revokeAllSessions(usr.ID, ""); err != nil {
+		return err
+	}
+	if err := paseto.Paseto().RevokeToken(claims); err != nil {
+		return fmt.Errorf("error revoking access token: %w", err)
+	}
+
+	if err := uc.userRepo.UpdateLoggedStatus(usr.ID, false); err != nil {
+		return fmt.Errorf("error updating user: %w", err)
+	}
+	return nil
+}
+
+// revokeAllSessions revokes all the sessions of the user (except exceptSessionID, if not empty)
+// together with the access tokens issued for them
+func (uc *AuthUseCase) revokeAllSessions(userID uint, exceptSessionID string) error {
+	// The active sessions are read first: they are the ones that can still have valid access tokens
+	sessions, err := uc.sessionRepo.GetActiveByUserID(userID)
 	if err != nil {
-		return "", "", fmt.Errorf("access token generation error: %w", err)
+		return fmt.Errorf("error retrieving sessions: %w", err)
+	}
+
+	if err := uc.sessionRepo.RevokeAllByUserID(userID, exceptSessionID); err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
 	}
-	user.AccessToken = accessToken
 
-	return user.AccessToken, *user.Refresh, nil
+	for _, s := range sessions {
+		if s.ID == exceptSessionID {
+			continue
+		}
+		if err := uc.revokeSessionAccessTokens(s.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// revokeSessionAccessTokens puts the session into the revocation list,
+// so the access tokens already issued for it are rejected before they expire
+func (uc *AuthUseCase) revokeSessionAccessTokens(sessionID string) error {
+	if err := paseto.Paseto().RevokeSession(sessionID); err != nil {
+		return fmt.Errorf("error revoking access tokens: %w", err)
+	}
+	return nil
 }
 
 // ForgotPassword sends a forgot password email to the user
 // link is the link to the reset password page (link to frontend)
 // subject is the subject of the email
 // body is the body of the email
+// The recovery token of the link can be used once; requesting a new one invalidates it.
 func (uc *AuthUseCase) ForgotPassword(username, link, subject, body string) (string, error) {
 
 	user, err := uc.userRepo.GetByLogin(username)
@@ -259,13 +982,14 @@ func (uc *AuthUseCase) ForgotPassword(username, link, subject, body string) (str
 		return "", fmt.Errorf("error retrieving user: %w", err)
 	}
 
-	if user.ProviderID != 1 && user.ProviderID != 3 {
+	if !isLocalProvider(user.ProviderID) {
 		return "", fmt.Errorf("forbidden")
 	}
 
-	recoverToken, _, err := paseto.Paseto().GenerateRecoverToken(paseto.PasetoClaims{
-		Username: user.Login,
-	})
+	recoverToken, err := uc.passwordUC.issueRecoveryToken(user)
+	if err != nil {
+		return "", err
+	}
 
 	link = fmt.Sprintf("%s?token=%s", link, recoverToken)
 	err = NewMailUseCase().SendEmailForgotPassword(user.Login, subject, body, link)
@@ -276,26 +1000,114 @@ func (uc *AuthUseCase) ForgotPassword(username, link, subject, body string) (str
 	return link, nil
 }
 
-// ResetPassword resets the password for a user
-func (uc *AuthUseCase) ResetPassword(username, password string) error {
+// RecoveryTokenInfo — state of a valid password recovery token (shown by the reset password form)
+type RecoveryTokenInfo struct {
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
 
-	user, err := uc.userRepo.GetByLogin(username)
+// ValidateRecoveryToken checks a password recovery token without using it
+func (uc *AuthUseCase) ValidateRecoveryToken(recoverToken string) (*RecoveryTokenInfo, error) {
+	rt, usr, err := uc.passwordUC.recoveryTokenUser(recoverToken)
 	if err != nil {
-		return fmt.Errorf("error retrieving user: %w", err)
+		return nil, err
+	}
+	return &RecoveryTokenInfo{Username: usr.Login, ExpiresAt: rt.ExpiresAt}, nil
+}
+
+// ResetPassword resets the password of the user of a recovery token and closes all its sessions.
+// The token is used (and can't be used again) only once the new password is accepted.
+func (uc *AuthUseCase) ResetPassword(recoverToken, newPassword string) error {
+	rt, usr, err := uc.passwordUC.recoveryTokenUser(recoverToken)
+	if err != nil {
+		return err
 	}
 
-	if user.ProviderID != 1 && user.ProviderID != 3 {
-		return fmt.Errorf("forbidden")
+	usr.IsLogged = false
+	if err := uc.passwordUC.resetPassword(rt, usr, newPassword); err != nil {
+		return err
 	}
 
-	user.Password = &password
-	user.IsLogged = false
-	user.Refresh = nil
-	user.RefreshExp = time.Now().Format("2006-01-02 15:04:05")
+	// Close all the sessions of the user
+	return uc.revokeAllSessions(usr.ID, "")
+}
 
-	if err := uc.userRepo.Update(user); err != nil {
-		return fmt.Errorf("error updating user: %w", err)
+// ChangePassword changes the password of the token user (local users only): the current password is
+// required again and the new one must meet the password policy and history. The other sessions of the user
+// are closed and the user is notified by email. The attempts with a wrong current password count as failed
+// logins (ip of the client, may be empty).
+// With the password change token of a login (expired password, see Login) the token can only be used once
+// and no session is kept: the user logs in with the new password (second factor included).
+func (uc *AuthUseCase) ChangePassword(claims *paseto.PasetoClaims, currentPassword, newPassword, ip string) error {
+	if err := uc.lockoutUC.Check(claims.Username, ip); err != nil {
+		return err
 	}
 
+	usr, err := uc.userRepo.GetByLogin(claims.Username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return errorsLib.ErrAccessDenied
+		}
+		return fmt.Errorf("error retrieving user: %w", err)
+	}
+	if !usr.Active {
+		return errorsLib.ErrForbidden
+	}
+	if !isLocalProvider(usr.ProviderID) {
+		return password.ErrNotLocalUser
+	}
+	if !usr.CheckPassword(currentPassword) {
+		uc.lockoutUC.RecordFailure(claims.Username, ip)
+		return password.ErrWrongPassword
+	}
+	uc.lockoutUC.RecordSuccess(claims.Username, usr)
+
+	expiredLogin := claims.HasRole(role.RolePasswordExpired)
+	if expiredLogin {
+		usr.IsLogged = false
+	}
+	if err := uc.passwordUC.changePassword(usr, newPassword); err != nil {
+		return err
+	}
+
+	// Close the other sessions (all of them after an expired password)
+	keepSessionID := claims.SessionID
+	if expiredLogin {
+		if err := paseto.Paseto().RevokeToken(claims); err != nil {
+			return fmt.Errorf("error revoking password change token: %w", err)
+		}
+		keepSessionID = ""
+	}
+	if err := uc.revokeAllSessions(usr.ID, keepSessionID); err != nil {
+		return err
+	}
+
+	uc.notifyPasswordChanged(usr)
 	return nil
 }
+
+// notifyPasswordChanged emails the user that its password was changed (in the background: the password is already changed)
+func (uc *AuthUseCase) notifyPasswordChanged(usr *user.User) {
+	if uc.passwordChangedSubject == "" || uc.passwordChangedBody == "" {
+		return
+	}
+
+	to := usr.Login
+	if usr.Profile != nil && usr.Profile.Email != nil && *usr.Profile.Email != "" {
+		to = *usr.Profile.Email
+	}
+	changedAt := time.Now()
+	if usr.PasswordChangedAt != nil {
+		changedAt = *usr.PasswordChangedAt
+	}
+
+	subject, body := uc.passwordChangedSubject, uc.passwordChangedBody
+	go func() {
+		if err := NewMailUseCase().SendEmailPasswordChanged(to, usr.Login, subject, body, changedAt); err != nil {
+			logger.GetLogger().ServiceError("Error sending password changed email", map[string]interface{}{
+				"userId": usr.ID,
+				"error":  err.Error(),
+			})
+		}
+	}()
+}
@@ -0,0 +1,130 @@
+package application
+
+import (
+	"fmt"
+	"strings"
+
+	"app/internal/domain/client"
+	"app/internal/domain/internal_company"
+	"app/internal/domain/user"
+	"app/pkg/errorsLib"
+)
+
+type ClientUseCase struct {
+	clientRepo  client.Repository
+	userRepo    user.Repository
+	companyRepo internal_company.Repository
+}
+
+func NewClientUseCase(clientRepo client.Repository, userRepo user.Repository, companyRepo internal_company.Repository) *ClientUseCase {
+	return &ClientUseCase{clientRepo: clientRepo, userRepo: userRepo, companyRepo: companyRepo}
+}
+
+// CreateClient registers a new client of the company of the owner and returns it with its plain secret
+// (the secret can't be retrieved later). Public clients have no secret.
+func (uc *ClientUseCase) CreateClient(ownerUsername string, name string, redirectURIs, scopes []string, public bool) (*client.Client, string, error) {
+	owner, err := uc.companyOwner(ownerUsername)
+	if err != nil {
+		return nil, "", err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("client name is required")
+	}
+	for _, uri := range redirectURIs {
+		if err := client.ValidateRedirectURI(uri); err != nil {
+			return nil, "", fmt.Errorf("%w: %s", err, uri)
+		}
+	}
+	if err := client.ValidateScopes(scopes); err != nil {
+		return nil, "", err
+	}
+
+	c := &client.Client{
+		Name:         name,
+		CompanyID:    owner.CompanyID,
+		Public:       public,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		Active:       true,
+	}
+
+	var secret string
+	if !public {
+		var err error
+		if secret, err = c.GenerateSecret(); err != nil {
+			return nil, "", fmt.Errorf("client secret generation error: %w", err)
+		}
+	}
+
+	if err := uc.clientRepo.Create(c); err != nil {
+		return nil, "", fmt.Errorf("create client error: %w", err)
+	}
+	return c, secret, nil
+}
+
+// GetClientsByCompany returns the clients of the company of the owner
+func (uc *ClientUseCase) GetClientsByCompany(ownerUsername string) ([]*client.Client, error) {
+	owner, err := uc.companyOwner(ownerUsername)
+	if err != nil {
+		return nil, err
+	}
+
+	clients, err := uc.clientRepo.GetByCompanyID(owner.CompanyID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving clients: %w", err)
+	}
+	return clients, nil
+}
+
+// DeleteClient deletes a client of the company of the owner
+func (uc *ClientUseCase) DeleteClient(ownerUsername string, clientID string) error {
+	owner, err := uc.companyOwner(ownerUsername)
+	if err != nil {
+		return err
+	}
+
+	c, err := uc.clientRepo.GetByClientID(clientID)
+	if err != nil {
+		if uc.clientRepo.IsNotFoundError(err) {
+			return errorsLib.ErrNotFound
+		}
+		return fmt.Errorf("error retrieving client: %w", err)
+	}
+
+	// A company can only delete its own clients
+	if c.CompanyID != owner.CompanyID {
+		return errorsLib.ErrNotFound
+	}
+
+	if err := uc.clientRepo.Delete(c.ClientID); err != nil {
+		return fmt.Errorf("error deleting client: %w", err)
+	}
+	return nil
+}
+
+// AuthenticateClient checks the credentials of a confidential client
+func (uc *ClientUseCase) AuthenticateClient(clientID, secret string) (*client.Client, error) {
+	if clientID == "" || secret == "" {
+		return nil, client.ErrInvalidClient
+	}
+
+	c, err := uc.clientRepo.GetByClientID(clientID)
+	if err != nil {
+		if uc.clientRepo.IsNotFoundError(err) {
+			return nil, client.ErrInvalidClient
+		}
+		return nil, fmt.Errorf("error retrieving client: %w", err)
+	}
+
+	if !c.Active || !c.CheckSecret(secret) {
+		return nil, client.ErrInvalidClient
+	}
+	return c, nil
+}
+
+// companyOwner returns the owner of an internal company (the OAuth clients are only managed by them)
+func (uc *ClientUseCase) companyOwner(ownerUsername string) (*user.User, error) {
+	return internalCompanyOwner(uc.userRepo, uc.companyRepo, ownerUsername, client.ErrNotInternalCompany)
+}
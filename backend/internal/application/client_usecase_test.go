@@ -0,0 +1,114 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"app/internal/domain/client"
+	"app/internal/domain/internal_company"
+	"app/internal/domain/user"
+	"app/pkg/errorsLib"
+)
+
+const testVerificacionesProviderID = 2
+
+// newTestClientUseCase returns the use case with the users of newTestCompanies (their owners are local
+// accounts of the internal companies 20001 and 20002) and "verif", a login of Verificaciones without
+// owner that also belongs to the company 20001
+func newTestClientUseCase() (*ClientUseCase, *memoryClientRepository) {
+	users := newTestCompanies()
+	for _, u := range users.users {
+		u.ProviderID = testLocalProviderID
+	}
+	users.add(&user.User{Login: "verif", CompanyID: 20001, ProviderID: testVerificacionesProviderID, Active: true})
+	users.add(&user.User{Login: "outsider", CompanyID: 30001, ProviderID: testLocalProviderID, Active: true})
+
+	companies := &memoryInternalCompanyRepository{companies: []*internal_company.InternalCompany{
+		{ID: 20001, Name: "Acme"},
+		{ID: 20002, Name: "Globex"},
+	}}
+	clients := &memoryClientRepository{}
+	return NewClientUseCase(clients, users, companies), clients
+}
+
+func TestCreateClient(t *testing.T) {
+	tests := []struct {
+		name  string
+		owner string
+		want  error
+	}{
+		{name: "owner of an internal company", owner: "acme"},
+		{name: "subuser", owner: "tech1", want: errorsLib.ErrForbidden},
+		{name: "login of an external provider", owner: "verif", want: client.ErrNotInternalCompany},
+		{name: "company not registered", owner: "outsider", want: client.ErrNotInternalCompany},
+		{name: "unknown user", owner: "nobody", want: errorsLib.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, clients := newTestClientUseCase()
+
+			c, secret, err := uc.CreateClient(tt.owner, "Portal", []string{"https://portal.example.com/callback"}, nil, false)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				if len(clients.clients) != 0 {
+					t.Error("client created")
+				}
+				return
+			}
+			if c.CompanyID != 20001 || secret == "" {
+				t.Errorf("got client of company %d with secret %q", c.CompanyID, secret)
+			}
+		})
+	}
+}
+
+func TestGetClientsByCompany(t *testing.T) {
+	uc, _ := newTestClientUseCase()
+	for _, owner := range []string{"acme", "globex"} {
+		if _, _, err := uc.CreateClient(owner, owner, nil, nil, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clients, err := uc.GetClientsByCompany("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 1 || clients[0].CompanyID != 20001 {
+		t.Errorf("got %d clients, want only the client of the company", len(clients))
+	}
+
+	if _, err := uc.GetClientsByCompany("verif"); !errors.Is(err, client.ErrNotInternalCompany) {
+		t.Errorf("login of an external provider: got %v, want %v", err, client.ErrNotInternalCompany)
+	}
+}
+
+func TestDeleteClient(t *testing.T) {
+	tests := []struct {
+		name  string
+		owner string
+		want  error
+	}{
+		{name: "owner of the company", owner: "globex"},
+		{name: "owner of another company", owner: "acme", want: errorsLib.ErrNotFound},
+		{name: "subuser of the company", owner: "tech2", want: errorsLib.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, clients := newTestClientUseCase()
+			c, _, err := uc.CreateClient("globex", "Portal", nil, nil, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := uc.DeleteClient(tt.owner, c.ClientID); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if deleted := len(clients.clients) == 0; deleted != (tt.want == nil) {
+				t.Errorf("client deleted: %v", deleted)
+			}
+		})
+	}
+}
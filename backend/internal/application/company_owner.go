@@ -0,0 +1,42 @@
+package application
+
+import (
+	"fmt"
+
+	"app/internal/domain/internal_company"
+	"app/internal/domain/provider"
+	"app/internal/domain/user"
+	"app/pkg/errorsLib"
+)
+
+// internalCompanyOwner returns the owner of an internal company: the local account registered with the
+// company. The settings shared by the whole company (SAML, OAuth clients) are only for these owners:
+// the logins of an external provider without owner (e.g. Verificaciones) also get the company role,
+// but they are not the owners of an internal company and get notInternal.
+func internalCompanyOwner(userRepo user.Repository, companyRepo internal_company.Repository, ownerUsername string, notInternal error) (*user.User, error) {
+	owner, err := userRepo.GetByLogin(ownerUsername)
+	if err != nil {
+		if userRepo.IsNotFoundError(err) {
+			return nil, errorsLib.ErrNotFound
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if owner.OwnerID != nil {
+		return nil, errorsLib.ErrForbidden
+	}
+
+	localID, err := provider.IDByName(provider.NameLiftel)
+	if err != nil {
+		return nil, err
+	}
+	if owner.ProviderID != localID {
+		return nil, notInternal
+	}
+	if _, err := companyRepo.GetByID(owner.CompanyID); err != nil {
+		if companyRepo.IsNotFoundError(err) {
+			return nil, notInternal
+		}
+		return nil, fmt.Errorf("error retrieving company: %w", err)
+	}
+	return owner, nil
+}
@@ -0,0 +1,607 @@
+package application
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"app/internal/domain/client"
+	"app/internal/domain/internal_company"
+	"app/internal/domain/lockout"
+	"app/internal/domain/oauth"
+	"app/internal/domain/passkey"
+	"app/internal/domain/role"
+	"app/internal/domain/security"
+	"app/internal/domain/session"
+	"app/internal/domain/user"
+	"app/internal/infrastructure/token/paseto"
+
+	"github.com/google/uuid"
+)
+
+// In-memory repositories that stand in for the database in the tests of the use cases.
+// They embed the domain interface: a method that is not implemented panics if it is called.
+
+var errRecordNotFound = errors.New("record not found")
+
+// memoryKeyStore — signing keys of the access tokens
+type memoryKeyStore struct {
+	mu   sync.Mutex
+	keys []paseto.StoredSigningKey
+}
+
+func (s *memoryKeyStore) GetAll(now time.Time) ([]paseto.StoredSigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]paseto.StoredSigningKey(nil), s.keys...), nil
+}
+
+func (s *memoryKeyStore) Create(key paseto.StoredSigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *memoryKeyStore) Retire(id string, retiredAt, expiresAt time.Time) error { return nil }
+
+func (s *memoryKeyStore) DeleteExpired(now time.Time) (int64, error) { return 0, nil }
+
+// memoryRevocationList — revoked access tokens and sessions
+type memoryRevocationList struct {
+	mu      sync.Mutex
+	revoked map[string]bool
+}
+
+func (l *memoryRevocationList) Revoke(identifier string, expiresAt time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.revoked == nil {
+		l.revoked = make(map[string]bool)
+	}
+	l.revoked[identifier] = true
+	return nil
+}
+
+func (l *memoryRevocationList) IsRevoked(identifiers ...string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range identifiers {
+		if l.revoked[id] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (l *memoryRevocationList) DeleteExpired(now time.Time) (int64, error) { return 0, nil }
+
+// memoryUserRepository — users by ID, login and UUID
+type memoryUserRepository struct {
+	user.Repository
+	mu    sync.Mutex
+	users []*user.User
+}
+
+func (r *memoryUserRepository) add(u *user.User) *user.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u.ID = uint(len(r.users) + 1)
+	if u.UUID == "" {
+		u.UUID = uuid.New().String()
+	}
+	r.users = append(r.users, u)
+	return u
+}
+
+func (r *memoryUserRepository) find(match func(u *user.User) bool) (*user.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if match(u) {
+			found := *u
+			return &found, nil
+		}
+	}
+	return nil, errRecordNotFound
+}
+
+func (r *memoryUserRepository) GetByID(id uint) (*user.User, error) {
+	return r.find(func(u *user.User) bool { return u.ID == id })
+}
+
+func (r *memoryUserRepository) GetByLogin(login string) (*user.User, error) {
+	return r.find(func(u *user.User) bool { return u.Login == login })
+}
+
+func (r *memoryUserRepository) GetByUUID(uuid string) (*user.User, error) {
+	return r.find(func(u *user.User) bool { return u.UUID == uuid })
+}
+
+func (r *memoryUserRepository) Update(u *user.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.users {
+		if r.users[i].ID == u.ID {
+			updated := *u
+			r.users[i] = &updated
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+func (r *memoryUserRepository) UpdateActiveStatus(userID uint, active bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.ID == userID {
+			u.Active = active
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+func (r *memoryUserRepository) UpdateLockedUntil(userID uint, lockedUntil *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if u.ID == userID {
+			u.LockedUntil = lockedUntil
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+func (r *memoryUserRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, errRecordNotFound)
+}
+
+// memoryLockoutRepository — failed login attempts by subject and key
+type memoryLockoutRepository struct {
+	lockout.Repository
+	mu       sync.Mutex
+	counters map[string]*lockout.Counter
+}
+
+func (r *memoryLockoutRepository) Get(subject, key string) (*lockout.Counter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counter, ok := r.counters[subject+":"+key]
+	if !ok {
+		return nil, errRecordNotFound
+	}
+	found := *counter
+	return &found, nil
+}
+
+func (r *memoryLockoutRepository) RecordFailure(subject, key string, now, windowStart time.Time) (*lockout.Counter, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.counters == nil {
+		r.counters = make(map[string]*lockout.Counter)
+	}
+	counter, ok := r.counters[subject+":"+key]
+	if !ok || counter.FirstFailedAt.Before(windowStart) || (counter.LockedUntil != nil && !now.Before(*counter.LockedUntil)) {
+		counter = &lockout.Counter{Subject: subject, Key: key, FirstFailedAt: now}
+		r.counters[subject+":"+key] = counter
+	}
+	counter.Failures++
+	counter.LastFailedAt = now
+	found := *counter
+	return &found, nil
+}
+
+func (r *memoryLockoutRepository) Lock(subject, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if counter, ok := r.counters[subject+":"+key]; ok {
+		counter.LockedUntil = &until
+	}
+	return nil
+}
+
+func (r *memoryLockoutRepository) Reset(subject, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.counters, subject+":"+key)
+	return nil
+}
+
+func (r *memoryLockoutRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, errRecordNotFound)
+}
+
+// memorySecurityRepository — security events
+type memorySecurityRepository struct {
+	security.Repository
+	mu     sync.Mutex
+	events []security.Event
+}
+
+func (r *memorySecurityRepository) Create(e *security.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, *e)
+	return nil
+}
+
+// memoryRoleRepository — roles and their grants (without hierarchy)
+type memoryRoleRepository struct {
+	role.RoleRepository
+	mu     sync.Mutex
+	roles  []role.Role
+	grants map[uint][]uint // user => roles
+}
+
+func (r *memoryRoleRepository) GetAllRoles() ([]role.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]role.Role(nil), r.roles...), nil
+}
+
+func (r *memoryRoleRepository) CreateRole(rl *role.Role) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rl.ID = uint(len(r.roles) + 1)
+	r.roles = append(r.roles, *rl)
+	return rl.ID, nil
+}
+
+func (r *memoryRoleRepository) AssignRoleToUser(userID, roleID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.grants == nil {
+		r.grants = make(map[uint][]uint)
+	}
+	r.grants[userID] = append(r.grants[userID], roleID)
+	return nil
+}
+
+func (r *memoryRoleRepository) GetUserRoles(userID uint) ([]role.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var roles []role.Role
+	for _, id := range r.grants[userID] {
+		roles = append(roles, r.roles[id-1])
+	}
+	return roles, nil
+}
+
+func (r *memoryRoleRepository) GetUserRolesExpanded(userID uint) ([]role.Role, error) {
+	return r.GetUserRoles(userID)
+}
+
+func (r *memoryRoleRepository) GetRoleByID(id uint) (*role.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.roles) {
+		return nil, errRecordNotFound
+	}
+	found := r.roles[id-1]
+	return &found, nil
+}
+
+func (r *memoryRoleRepository) GetRoleByName(name string) (*role.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rl := range r.roles {
+		if rl.Role == name {
+			found := rl
+			return &found, nil
+		}
+	}
+	return nil, errRecordNotFound
+}
+
+func (r *memoryRoleRepository) AssignRoleToUserWithValidity(userID, roleID uint, validFrom, validUntil *time.Time) error {
+	for _, id := range r.grants[userID] {
+		if id == roleID {
+			return nil
+		}
+	}
+	return r.AssignRoleToUser(userID, roleID)
+}
+
+func (r *memoryRoleRepository) RemoveRoleFromUser(userID, roleID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, id := range r.grants[userID] {
+		if id == roleID {
+			r.grants[userID] = append(r.grants[userID][:i], r.grants[userID][i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *memoryRoleRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, errRecordNotFound)
+}
+
+// memoryClientRepository — OAuth clients by client ID
+type memoryClientRepository struct {
+	mu      sync.Mutex
+	clients []*client.Client
+}
+
+func (r *memoryClientRepository) Create(c *client.Client) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.ID = uint(len(r.clients) + 1)
+	if c.ClientID == "" {
+		c.ClientID = uuid.New().String()
+	}
+	stored := *c
+	r.clients = append(r.clients, &stored)
+	return nil
+}
+
+func (r *memoryClientRepository) GetByClientID(clientID string) (*client.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.clients {
+		if c.ClientID == clientID {
+			found := *c
+			return &found, nil
+		}
+	}
+	return nil, errRecordNotFound
+}
+
+func (r *memoryClientRepository) GetByCompanyID(companyID uint) ([]*client.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var clients []*client.Client
+	for _, c := range r.clients {
+		if c.CompanyID == companyID {
+			found := *c
+			clients = append(clients, &found)
+		}
+	}
+	return clients, nil
+}
+
+func (r *memoryClientRepository) Delete(clientID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, c := range r.clients {
+		if c.ClientID == clientID {
+			r.clients = append(r.clients[:i], r.clients[i+1:]...)
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+func (r *memoryClientRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, errRecordNotFound)
+}
+
+// memoryInternalCompanyRepository — companies registered in the service
+type memoryInternalCompanyRepository struct {
+	internal_company.Repository
+	companies []*internal_company.InternalCompany
+}
+
+func (r *memoryInternalCompanyRepository) GetByID(id uint) (*internal_company.InternalCompany, error) {
+	for _, c := range r.companies {
+		if c.ID == id {
+			found := *c
+			return &found, nil
+		}
+	}
+	return nil, errRecordNotFound
+}
+
+func (r *memoryInternalCompanyRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, errRecordNotFound)
+}
+
+// memorySessionRepository — sessions with their first refresh token
+type memorySessionRepository struct {
+	session.Repository
+	mu       sync.Mutex
+	sessions map[string]*session.Session
+}
+
+func (r *memorySessionRepository) Create(s *session.Session, token *session.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions == nil {
+		r.sessions = make(map[string]*session.Session)
+	}
+	s.ID = uuid.New().String()
+	token.SessionID = s.ID
+	stored := *s
+	r.sessions[s.ID] = &stored
+	return nil
+}
+
+func (r *memorySessionRepository) GetByID(id string) (*session.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if !ok {
+		return nil, errRecordNotFound
+	}
+	found := *s
+	return &found, nil
+}
+
+func (r *memorySessionRepository) Revoke(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[id]; ok && s.RevokedAt == nil {
+		now := time.Now()
+		s.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r *memorySessionRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, errRecordNotFound)
+}
+
+// memoryAuthorizationCodeRepository — authorization codes by hash
+type memoryAuthorizationCodeRepository struct {
+	mu    sync.Mutex
+	codes map[string]*oauth.AuthorizationCode
+}
+
+func (r *memoryAuthorizationCodeRepository) Create(code *oauth.AuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.codes == nil {
+		r.codes = make(map[string]*oauth.AuthorizationCode)
+	}
+	stored := *code
+	r.codes[code.CodeHash] = &stored
+	return nil
+}
+
+func (r *memoryAuthorizationCodeRepository) GetByHash(codeHash string) (*oauth.AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.codes[codeHash]
+	if !ok {
+		return nil, errRecordNotFound
+	}
+	found := *c
+	return &found, nil
+}
+
+func (r *memoryAuthorizationCodeRepository) MarkUsed(codeHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.codes[codeHash]
+	if !ok || c.UsedAt != nil {
+		return oauth.ErrCodeAlreadyUsed
+	}
+	now := time.Now()
+	c.UsedAt = &now
+	return nil
+}
+
+func (r *memoryAuthorizationCodeRepository) SetSessionID(codeHash, sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.codes[codeHash]; ok {
+		c.SessionID = sessionID
+	}
+	return nil
+}
+
+func (r *memoryAuthorizationCodeRepository) DeleteExpired(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, c := range r.codes {
+		if !now.Before(c.ExpiresAt) {
+			delete(r.codes, hash)
+		}
+	}
+	return nil
+}
+
+func (r *memoryAuthorizationCodeRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, errRecordNotFound)
+}
+
+// memoryPasskeyRepository — passkeys and ceremonies
+type memoryPasskeyRepository struct {
+	mu          sync.Mutex
+	credentials []*passkey.Credential
+	ceremonies  map[string]*passkey.Ceremony
+}
+
+func (r *memoryPasskeyRepository) Create(c *passkey.Credential) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.ID = uint(len(r.credentials) + 1)
+	stored := *c
+	r.credentials = append(r.credentials, &stored)
+	return nil
+}
+
+func (r *memoryPasskeyRepository) GetByUserID(userID uint) ([]*passkey.Credential, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var credentials []*passkey.Credential
+	for _, c := range r.credentials {
+		if c.UserID == userID {
+			found := *c
+			credentials = append(credentials, &found)
+		}
+	}
+	return credentials, nil
+}
+
+func (r *memoryPasskeyRepository) UpdateUsage(id uint, signCount uint32, flags uint8, backupState bool, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.credentials {
+		if c.ID == id {
+			c.SignCount, c.Flags, c.BackupState, c.LastUsedAt = signCount, flags, backupState, &usedAt
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+func (r *memoryPasskeyRepository) Delete(userID, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, c := range r.credentials {
+		if c.ID == id && c.UserID == userID {
+			r.credentials = append(r.credentials[:i], r.credentials[i+1:]...)
+			return nil
+		}
+	}
+	return errRecordNotFound
+}
+
+func (r *memoryPasskeyRepository) CreateCeremony(c *passkey.Ceremony) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ceremonies == nil {
+		r.ceremonies = make(map[string]*passkey.Ceremony)
+	}
+	stored := *c
+	r.ceremonies[c.ID] = &stored
+	return nil
+}
+
+func (r *memoryPasskeyRepository) ConsumeCeremony(id, ceremonyType string, now time.Time) (*passkey.Ceremony, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.ceremonies[id]
+	if !ok || c.Type != ceremonyType || !now.Before(c.ExpiresAt) {
+		return nil, passkey.ErrCeremonyNotFound
+	}
+	delete(r.ceremonies, id)
+	return c, nil
+}
+
+func (r *memoryPasskeyRepository) DeleteExpiredCeremonies(now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, c := range r.ceremonies {
+		if !now.Before(c.ExpiresAt) {
+			delete(r.ceremonies, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryPasskeyRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, errRecordNotFound)
+}
+
+// newTestAuthUseCase returns the authentication use case over in-memory repositories,
+// enough to open sessions and issue tokens
+func newTestAuthUseCase(userRepo *memoryUserRepository) (*AuthUseCase, *memorySessionRepository) {
+	sessionRepo := &memorySessionRepository{}
+	return NewAuthUseCase(userRepo, &memoryRoleRepository{}, sessionRepo, nil, nil, nil, nil, nil), sessionRepo
+}
@@ -0,0 +1,148 @@
+package application
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"app/internal/domain/federation"
+	"app/internal/domain/provider"
+	"app/internal/domain/session"
+	"app/internal/domain/user"
+	"app/pkg/logger"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+// FederatedLogin — start of a login with an external provider: the URL where the user signs in
+// and the ID to send back with the code and the state of the callback
+type FederatedLogin struct {
+	FlowID           string `json:"flowId"`
+	AuthorizationURL string `json:"authorizationUrl"`
+}
+
+type FederationUseCase struct {
+	flowRepo    federation.Repository
+	authUC      *AuthUseCase
+	flowTimeout time.Duration
+}
+
+func NewFederationUseCase(flowRepo federation.Repository, authUC *AuthUseCase) *FederationUseCase {
+	return &FederationUseCase{
+		flowRepo:    flowRepo,
+		authUC:      authUC,
+		flowTimeout: 10 * time.Minute,
+	}
+}
+
+// SetFlowTimeout sets the time to come back from the provider once a login is started
+func (uc *FederationUseCase) SetFlowTimeout(d time.Duration) {
+	if d > 0 {
+		uc.flowTimeout = d
+	}
+}
+
+// Start starts a login with the federated provider (authorization code flow with PKCE).
+// The flow ID is only known by the client that started the login: a callback can't be
+// finished by another client (login CSRF).
+func (uc *FederationUseCase) Start(providerName string) (*FederatedLogin, error) {
+	idp, providerID, err := uc.federatedProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	flow := &federation.Flow{
+		ID:           uuid.New().String(),
+		ProviderID:   providerID,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+	}
+
+	authURL, err := idp.AuthCodeURL(flow.State, flow.Nonce, flow.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	flow.ExpiresAt = now.Add(uc.flowTimeout)
+	if err := uc.flowRepo.CreateFlow(flow); err != nil {
+		return nil, fmt.Errorf("error saving federated login: %w", err)
+	}
+
+	// The abandoned logins are removed along the way
+	if err := uc.flowRepo.DeleteExpiredFlows(now); err != nil {
+		logger.GetLogger().ServiceWarn("Error deleting expired federated logins", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	return &FederatedLogin{FlowID: flow.ID, AuthorizationURL: authURL}, nil
+}
+
+// Finish redeems the code of the callback of the provider and opens the session like Login
+// (the user is returned with an MFA token if the login needs a second factor).
+// The flow can only be finished once.
+func (uc *FederationUseCase) Finish(providerName, flowID, state, code string, client session.ClientInfo) (*user.User, error) {
+	idp, providerID, err := uc.federatedProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	flow, err := uc.flowRepo.ConsumeFlow(flowID, providerID, time.Now())
+	if err != nil {
+		if errors.Is(err, federation.ErrFlowNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error retrieving federated login: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(flow.State), []byte(state)) != 1 {
+		return nil, federation.ErrFlowNotFound
+	}
+
+	identity, err := idp.Exchange(code, flow.CodeVerifier, flow.Nonce)
+	if err != nil {
+		if errors.Is(err, federation.ErrLoginRejected) {
+			logger.GetLogger().ServiceWarn("Federated login rejected", map[string]interface{}{
+				"provider": idp.Name(),
+				"error":    err.Error(),
+			})
+		}
+		return nil, err
+	}
+
+	return uc.authUC.LoginFederated(identity, idp, providerID, client)
+}
+
+// federatedProvider returns a registered federated provider by name and its ID
+func (uc *FederationUseCase) federatedProvider(name string) (provider.FederatedProvider, uint, error) {
+	idp, providerID, err := provider.LookupByName(name)
+	if err != nil {
+		return nil, 0, federation.ErrNotFederated
+	}
+	federated, ok := idp.(provider.FederatedProvider)
+	if !ok {
+		return nil, 0, federation.ErrNotFederated
+	}
+	return federated, providerID, nil
+}
+
+// randomToken returns 32 random bytes (base64url), for the state and the nonce of a login
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("random token generation error: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,86 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"app/internal/infrastructure/token/paseto"
+	"app/pkg/logger"
+)
+
+// SigningKeyRotator periodically rotates the signing key of the access tokens
+// and removes the retired keys that can't verify any token anymore
+type SigningKeyRotator struct {
+	keyRing          *paseto.KeyRing
+	rotationInterval time.Duration
+	checkInterval    time.Duration
+}
+
+func NewSigningKeyRotator(keyRing *paseto.KeyRing, rotationInterval, checkInterval time.Duration) *SigningKeyRotator {
+	if rotationInterval <= 0 {
+		rotationInterval = 30 * 24 * time.Hour
+	}
+	if checkInterval <= 0 {
+		checkInterval = time.Hour
+	}
+	return &SigningKeyRotator{
+		keyRing:          keyRing,
+		rotationInterval: rotationInterval,
+		checkInterval:    checkInterval,
+	}
+}
+
+// Start runs the rotator in a separate goroutine until ctx is cancelled
+func (r *SigningKeyRotator) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(r.checkInterval)
+		defer ticker.Stop()
+
+		r.Check()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.Check()
+			}
+		}
+	}()
+}
+
+// Check reloads the keys (another instance may have rotated them), rotates them if the
+// current key is older than the rotation interval and removes the expired keys
+func (r *SigningKeyRotator) Check() {
+	if err := r.keyRing.Reload(); err != nil {
+		logger.GetLogger().ServiceError("Error reloading signing keys", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	rotated, err := r.keyRing.RotateIfDue(r.rotationInterval)
+	if err != nil {
+		logger.GetLogger().ServiceError("Error rotating signing key", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if rotated {
+		logger.GetLogger().ServiceInfo("Signing key rotated", map[string]interface{}{
+			"kid": r.keyRing.Current().ID,
+		})
+	}
+
+	removed, err := r.keyRing.DeleteExpired()
+	if err != nil {
+		logger.GetLogger().ServiceError("Error removing expired signing keys", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if removed > 0 {
+		logger.GetLogger().ServiceInfo("Expired signing keys removed", map[string]interface{}{
+			"count": removed,
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package application
+
+import (
+	"fmt"
+	"time"
+
+	"app/internal/domain/lockout"
+	"app/internal/domain/security"
+	"app/internal/domain/user"
+	"app/pkg/errorsLib"
+	"app/pkg/logger"
+)
+
+// LockoutUseCase protects the password logins against brute force: the failed attempts are
+// counted per username and per IP address, the attempts of a username are progressively delayed
+// and a username (or an IP address) is temporarily locked after too many failures.
+// The lock only applies to the password: passkey logins are not affected.
+type LockoutUseCase struct {
+	lockoutRepo lockout.Repository
+	userRepo    user.Repository
+	securitySvc *SecurityEventUseCase
+	policy      lockout.Policy
+}
+
+func NewLockoutUseCase(lockoutRepo lockout.Repository, userRepo user.Repository, securityRepo security.Repository) *LockoutUseCase {
+	return &LockoutUseCase{
+		lockoutRepo: lockoutRepo,
+		userRepo:    userRepo,
+		securitySvc: NewSecurityEventUseCase(securityRepo),
+		policy:      lockout.DefaultPolicy(),
+	}
+}
+
+// SetPolicy sets the limits of the failed attempts (not positive values keep the defaults)
+func (uc *LockoutUseCase) SetPolicy(policy lockout.Policy) {
+	if policy.Window > 0 {
+		uc.policy.Window = policy.Window
+	}
+	if policy.MaxUsernameFailures > 0 {
+		uc.policy.MaxUsernameFailures = policy.MaxUsernameFailures
+	}
+	if policy.MaxIPFailures > 0 {
+		uc.policy.MaxIPFailures = policy.MaxIPFailures
+	}
+	if policy.LockoutDuration > 0 {
+		uc.policy.LockoutDuration = policy.LockoutDuration
+	}
+	if policy.DelayBase > 0 {
+		uc.policy.DelayBase = policy.DelayBase
+	}
+	if policy.DelayMax > 0 {
+		uc.policy.DelayMax = policy.DelayMax
+	}
+}
+
+// Check returns a *lockout.ThrottledError if the password of the username can't be checked now:
+// the username or the IP address is locked, or the delay after the last failure is not over
+func (uc *LockoutUseCase) Check(username, ip string) error {
+	now := time.Now()
+
+	counter, err := uc.getCounter(lockout.SubjectUsername, lockout.NormalizeUsername(username))
+	if err != nil {
+		return err
+	}
+	if counter != nil {
+		if counter.IsLocked(now) {
+			return &lockout.ThrottledError{Err: lockout.ErrAccountLocked, RetryAfter: counter.LockedUntil.Sub(now)}
+		}
+		if counter.LockedUntil == nil && counter.FirstFailedAt.After(now.Add(-uc.policy.Window)) {
+			if next := counter.LastFailedAt.Add(uc.policy.Delay(counter.Failures)); now.Before(next) {
+				return &lockout.ThrottledError{Err: lockout.ErrTooManyAttempts, RetryAfter: next.Sub(now)}
+			}
+		}
+	}
+
+	if ip == "" {
+		return nil
+	}
+	counter, err = uc.getCounter(lockout.SubjectIP, ip)
+	if err != nil {
+		return err
+	}
+	if counter != nil && counter.IsLocked(now) {
+		return &lockout.ThrottledError{Err: lockout.ErrTooManyAttempts, RetryAfter: counter.LockedUntil.Sub(now)}
+	}
+	return nil
+}
+
+// RecordFailure counts a wrong password of the username from the IP address and locks them
+// when they reach their limit (or are over it: concurrent failures, a lower limit in the config).
+// Errors are only logged: the login fails anyway.
+func (uc *LockoutUseCase) RecordFailure(username, ip string) {
+	now := time.Now()
+	windowStart := now.Add(-uc.policy.Window)
+	until := now.Add(uc.policy.LockoutDuration)
+
+	key := lockout.NormalizeUsername(username)
+	counter, err := uc.lockoutRepo.RecordFailure(lockout.SubjectUsername, key, now, windowStart)
+	if err != nil {
+		uc.logError("Error recording failed login attempt", err)
+	} else if counter.Failures >= uc.policy.MaxUsernameFailures {
+		uc.lockUsername(key, ip, counter.Failures, until)
+	}
+
+	if ip == "" {
+		return
+	}
+	counter, err = uc.lockoutRepo.RecordFailure(lockout.SubjectIP, ip, now, windowStart)
+	if err != nil {
+		uc.logError("Error recording failed login attempt", err)
+	} else if counter.Failures >= uc.policy.MaxIPFailures {
+		if err := uc.lockoutRepo.Lock(lockout.SubjectIP, ip, until); err != nil {
+			uc.logError("Error locking IP address", err)
+			return
+		}
+		uc.securitySvc.Emit(security.Event{
+			Type:    security.EventIPLocked,
+			IP:      ip,
+			Details: fmt.Sprintf("%d failed login attempts, locked until %s", counter.Failures, until.Format(time.RFC3339)),
+		})
+	}
+}
+
+// RecordSuccess forgets the failed attempts of the username after a correct password
+// and removes the expired lock of the user. The counter of the IP address is kept:
+// one valid account must not reset it.
+func (uc *LockoutUseCase) RecordSuccess(username string, usr *user.User) {
+	if err := uc.lockoutRepo.Reset(lockout.SubjectUsername, lockout.NormalizeUsername(username)); err != nil {
+		uc.logError("Error resetting failed login attempts", err)
+	}
+
+	if usr.LockedUntil != nil {
+		if err := uc.userRepo.UpdateLockedUntil(usr.ID, nil); err != nil {
+			uc.logError("Error unlocking user", err)
+		}
+		usr.Locked, usr.LockedUntil = false, nil
+	}
+}
+
+// Unlock removes the lock of a user before it expires. The company owner can unlock
+// itself and its subusers (ownerUsername is the main username of the caller).
+func (uc *LockoutUseCase) Unlock(ownerUsername, username string) error {
+	owner, err := uc.userRepo.GetByLogin(ownerUsername)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return errorsLib.ErrNotFound
+		}
+		return fmt.Errorf("error retrieving user: %w", err)
+	}
+
+	usr, err := uc.userRepo.GetByLogin(username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return errorsLib.ErrNotFound
+		}
+		return fmt.Errorf("error retrieving user: %w", err)
+	}
+	if !usr.IsManagedBy(owner) {
+		return errorsLib.ErrNotFound
+	}
+
+	if !usr.IsLocked(time.Now()) {
+		return lockout.ErrNotLocked
+	}
+
+	if err := uc.lockoutRepo.Reset(lockout.SubjectUsername, lockout.NormalizeUsername(usr.Login)); err != nil {
+		return fmt.Errorf("error resetting failed login attempts: %w", err)
+	}
+	if err := uc.userRepo.UpdateLockedUntil(usr.ID, nil); err != nil {
+		return fmt.Errorf("error unlocking user: %w", err)
+	}
+
+	uc.securitySvc.Emit(security.Event{
+		Type:     security.EventAccountUnlocked,
+		UserID:   &usr.ID,
+		Username: usr.Login,
+		Details:  "unlocked by " + owner.Login,
+	})
+	return nil
+}
+
+// lockUsername locks the username and, if it is a user, shows the lock on the user
+func (uc *LockoutUseCase) lockUsername(key, ip string, failures int, until time.Time) {
+	if err := uc.lockoutRepo.Lock(lockout.SubjectUsername, key, until); err != nil {
+		uc.logError("Error locking username", err)
+		return
+	}
+
+	event := security.Event{
+		Type:     security.EventAccountLocked,
+		Username: key,
+		IP:       ip,
+		Details:  fmt.Sprintf("%d failed login attempts, locked until %s", failures, until.Format(time.RFC3339)),
+	}
+	if usr, err := uc.userRepo.GetByLogin(key); err == nil {
+		if err := uc.userRepo.UpdateLockedUntil(usr.ID, &until); err != nil {
+			uc.logError("Error locking user", err)
+		}
+		event.UserID = &usr.ID
+		event.Username = usr.Login
+	}
+	uc.securitySvc.Emit(event)
+}
+
+func (uc *LockoutUseCase) getCounter(subject, key string) (*lockout.Counter, error) {
+	counter, err := uc.lockoutRepo.Get(subject, key)
+	if err != nil {
+		if uc.lockoutRepo.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error retrieving failed login attempts: %w", err)
+	}
+	return counter, nil
+}
+
+func (uc *LockoutUseCase) logError(message string, err error) {
+	logger.GetLogger().ServiceError(message, map[string]interface{}{
+		"error": err.Error(),
+	})
+}
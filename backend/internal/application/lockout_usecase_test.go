@@ -0,0 +1,145 @@
+package application
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"app/internal/domain/lockout"
+	"app/internal/domain/security"
+	"app/pkg/errorsLib"
+)
+
+func newTestLockoutUseCase(policy lockout.Policy) (*LockoutUseCase, *memoryLockoutRepository, *memoryUserRepository, *memorySecurityRepository) {
+	counters := &memoryLockoutRepository{}
+	users := newTestCompanies()
+	events := &memorySecurityRepository{}
+	uc := NewLockoutUseCase(counters, users, events)
+	uc.SetPolicy(policy)
+	return uc, counters, users, events
+}
+
+// checkThrottled checks that the attempt is rejected with the error and a wait of about retryAfter
+func checkThrottled(t *testing.T, err, want error, retryAfter time.Duration) {
+	t.Helper()
+	var throttled *lockout.ThrottledError
+	if !errors.As(err, &throttled) || !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+	if throttled.RetryAfter > retryAfter || throttled.RetryAfter < retryAfter-time.Minute {
+		t.Errorf("got retry after %s, want %s", throttled.RetryAfter, retryAfter)
+	}
+}
+
+func TestLockoutProgressiveDelay(t *testing.T) {
+	uc, counters, _, _ := newTestLockoutUseCase(lockout.Policy{DelayBase: time.Hour, DelayMax: 3 * time.Hour, MaxUsernameFailures: 10})
+
+	if err := uc.Check("tech1", "192.0.2.1"); err != nil {
+		t.Fatalf("first attempt: %v", err)
+	}
+
+	// The delay doubles after each failure up to the maximum, from any IP address
+	for failures, delay := range []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 3 * time.Hour} {
+		uc.RecordFailure("tech1", "192.0.2.1")
+		checkThrottled(t, uc.Check("TECH1", "192.0.2.2"), lockout.ErrTooManyAttempts, delay)
+
+		if failures == 3 {
+			// Once the delay is over the password can be checked again
+			counters.counters[lockout.SubjectUsername+":tech1"].LastFailedAt = time.Now().Add(-delay)
+			if err := uc.Check("tech1", "192.0.2.1"); err != nil {
+				t.Fatalf("delay over: %v", err)
+			}
+		}
+	}
+
+	if err := uc.Check("tech2", "192.0.2.1"); err != nil {
+		t.Errorf("another username: %v", err)
+	}
+}
+
+func TestLockoutUsernameLock(t *testing.T) {
+	uc, _, users, events := newTestLockoutUseCase(lockout.Policy{MaxUsernameFailures: 3, LockoutDuration: time.Hour, DelayBase: time.Nanosecond, DelayMax: time.Nanosecond})
+
+	// Each failure from another IP address: only the username reaches its limit
+	ips := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}
+	for i, ip := range ips {
+		if usr, _ := users.GetByLogin("tech1"); usr.LockedUntil != nil {
+			t.Fatalf("locked after %d failures", i)
+		}
+		uc.RecordFailure("tech1", ip)
+	}
+
+	checkThrottled(t, uc.Check("tech1", "192.0.2.4"), lockout.ErrAccountLocked, time.Hour)
+	if usr, _ := users.GetByLogin("tech1"); !usr.IsLocked(time.Now()) {
+		t.Error("user not locked")
+	}
+	if len(events.events) != 1 || events.events[0].Type != security.EventAccountLocked || events.events[0].Username != "tech1" {
+		t.Errorf("got events %+v, want account locked", events.events)
+	}
+
+	if err := uc.Check("tech2", "192.0.2.1"); err != nil {
+		t.Errorf("another username from the same IP address: %v", err)
+	}
+}
+
+func TestLockoutIPLock(t *testing.T) {
+	uc, _, users, events := newTestLockoutUseCase(lockout.Policy{MaxIPFailures: 3, LockoutDuration: time.Hour, DelayBase: time.Nanosecond, DelayMax: time.Nanosecond})
+
+	// Each failure with another username: only the IP address reaches its limit
+	for _, username := range []string{"acme", "tech1", "nobody"} {
+		uc.RecordFailure(username, "192.0.2.1")
+	}
+
+	checkThrottled(t, uc.Check("globex", "192.0.2.1"), lockout.ErrTooManyAttempts, time.Hour)
+	if err := uc.Check("globex", "192.0.2.2"); err != nil {
+		t.Errorf("another IP address: %v", err)
+	}
+	for _, u := range users.users {
+		if u.LockedUntil != nil {
+			t.Errorf("user %s locked", u.Login)
+		}
+	}
+	if len(events.events) != 1 || events.events[0].Type != security.EventIPLocked || events.events[0].IP != "192.0.2.1" {
+		t.Errorf("got events %+v, want IP address locked", events.events)
+	}
+}
+
+func TestLockoutUnlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		owner    string
+		username string
+		want     error
+	}{
+		{name: "subuser of the company", owner: "acme", username: "tech1"},
+		{name: "owner itself", owner: "acme", username: "acme"},
+		{name: "owner of another company", owner: "acme", username: "globex", want: errorsLib.ErrNotFound},
+		{name: "subuser of another company", owner: "acme", username: "tech2", want: errorsLib.ErrNotFound},
+		{name: "unknown user", owner: "acme", username: "nobody", want: errorsLib.ErrNotFound},
+		{name: "user not locked", owner: "globex", username: "globex", want: lockout.ErrNotLocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, counters, users, _ := newTestLockoutUseCase(lockout.Policy{MaxUsernameFailures: 1, LockoutDuration: time.Hour})
+			for _, username := range []string{"acme", "tech1", "tech2"} {
+				uc.RecordFailure(username, "")
+			}
+
+			if err := uc.Unlock(tt.owner, tt.username); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+
+			// Only the user unlocked by the caller can check its password again
+			for _, username := range []string{"acme", "tech1", "tech2"} {
+				unlocked := tt.want == nil && username == tt.username
+				usr, _ := users.GetByLogin(username)
+				if usr.IsLocked(time.Now()) == unlocked {
+					t.Errorf("user %s locked: %v", username, !unlocked)
+				}
+				if _, err := counters.Get(lockout.SubjectUsername, username); (err != nil) != unlocked {
+					t.Errorf("failed attempts of %s reset: %v", username, err != nil)
+				}
+			}
+		})
+	}
+}
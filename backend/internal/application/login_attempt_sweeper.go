@@ -0,0 +1,67 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"app/internal/domain/lockout"
+	"app/pkg/logger"
+)
+
+// LoginAttemptSweeper periodically removes the failed login attempt counters that are not needed anymore
+// (window and lock over)
+type LoginAttemptSweeper struct {
+	lockoutRepo lockout.Repository
+	window      time.Duration
+	interval    time.Duration
+}
+
+func NewLoginAttemptSweeper(lockoutRepo lockout.Repository, window, interval time.Duration) *LoginAttemptSweeper {
+	if window <= 0 {
+		window = lockout.DefaultPolicy().Window
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	return &LoginAttemptSweeper{
+		lockoutRepo: lockoutRepo,
+		window:      window,
+		interval:    interval,
+	}
+}
+
+// Start runs the sweeper in a separate goroutine until ctx is cancelled
+func (s *LoginAttemptSweeper) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.Sweep()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep()
+			}
+		}
+	}()
+}
+
+// Sweep removes the expired counters once
+func (s *LoginAttemptSweeper) Sweep() {
+	now := time.Now()
+	removed, err := s.lockoutRepo.DeleteExpired(now.Add(-s.window), now)
+	if err != nil {
+		logger.GetLogger().ServiceError("Error removing expired login attempts", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if removed > 0 {
+		logger.GetLogger().ServiceInfo("Expired login attempts removed", map[string]interface{}{
+			"count": removed,
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"app/internal/infrastructure/transport/email"
 	"errors"
 	"strings"
+	"time"
 )
 
 type MailUseCase struct {
@@ -29,3 +30,12 @@ func (uc *MailUseCase) SendEmailForgotPassword(to, subject, body, link string) e
 	// Send the email
 	return email.SendEmail(to, subject, body)
 }
+
+// SendEmailPasswordChanged notifies the user that its password was changed.
+// {username} and {date} are replaced in the body.
+func (uc *MailUseCase) SendEmailPasswordChanged(to, username, subject, body string, changedAt time.Time) error {
+	body = strings.ReplaceAll(body, "{username}", username)
+	body = strings.ReplaceAll(body, "{date}", changedAt.Format("2006-01-02 15:04:05"))
+
+	return email.SendEmail(to, subject, body)
+}
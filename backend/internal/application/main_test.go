@@ -0,0 +1,66 @@
+package application
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"app/internal/domain/provider"
+	"app/internal/infrastructure/identity"
+	"app/internal/infrastructure/token/paseto"
+)
+
+// ID of the local provider in the providers table of the tests
+const testLocalProviderID = 1
+
+// TestMain prepares what the use cases take from the running service: the environment
+// (config.ENV() loads the .env of the working directory), the key ring and the revocation
+// list of the access tokens (kept in memory) and the local identity provider.
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	dir, err := os.MkdirTemp("", "application-test")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	env := "DB_USER=test\nDB_PASSWORD=test\n" +
+		"VERIFICACIONES_USERNAME=test\nVERIFICACIONES_PASSWORD=test\n" +
+		"DEFAULT_USER_LOGIN=test\nDEFAULT_USER_PASSWORD=test\n" +
+		"PASETO_SK=test-secret\nPASETO_EXPIRATION_TIME=1h\nPASETO_RECOVER_EXPIRATION_TIME=5m\nREFRESH_EXPIRATION_TIME=24h\n" +
+		"MAIL_SMTP_HOST=localhost\nMAIL_SMTP_PORT=25\nMAIL_SMTP_USERNAME=test\nMAIL_SMTP_PASSWORD=test\nMAIL_SMTP_TLS=false\n" +
+		"MIDDLEWARE_PASSWORD=test\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(env), 0o600); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer os.Chdir(wd)
+
+	if err := paseto.Paseto().SetKeyStore(&memoryKeyStore{}); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	paseto.Paseto().SetRevocationList(&memoryRevocationList{})
+
+	provider.Register(identity.NewLocalProvider(provider.NameLiftel))
+	if err := provider.Bind([]provider.Provider{{ID: testLocalProviderID, Name: provider.NameLiftel}}); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	return m.Run()
+}
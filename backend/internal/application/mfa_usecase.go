@@ -0,0 +1,395 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"app/internal/domain/mfa"
+	"app/internal/domain/user"
+	"app/pkg/errorsLib"
+	"app/pkg/logger"
+)
+
+// MFAStatus — two-factor authentication state of a user
+type MFAStatus struct {
+	Enabled             bool  `json:"enabled"`
+	PendingConfirmation bool  `json:"pendingConfirmation"` // enrollment started but not confirmed with a first code
+	RequiredByOwner     bool  `json:"requiredByOwner"`     // the company owner requires it for its subusers
+	RecoveryCodesLeft   int64 `json:"recoveryCodesLeft"`
+}
+
+// TOTPSetup — secret of a new enrollment, to be added to an authenticator app
+type TOTPSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"` // payload of the QR code
+	Digits     int    `json:"digits"`
+	Period     int    `json:"period"`
+}
+
+type MFAUseCase struct {
+	mfaRepo         mfa.Repository
+	userRepo        user.Repository
+	issuer          string
+	pendingTokenTTL time.Duration
+}
+
+func NewMFAUseCase(mfaRepo mfa.Repository, userRepo user.Repository) *MFAUseCase {
+	return &MFAUseCase{
+		mfaRepo:         mfaRepo,
+		userRepo:        userRepo,
+		issuer:          "Liftel",
+		pendingTokenTTL: 5 * time.Minute,
+	}
+}
+
+// SetIssuer sets the issuer shown by the authenticator apps next to the account
+func (uc *MFAUseCase) SetIssuer(issuer string) {
+	if issuer != "" {
+		uc.issuer = issuer
+	}
+}
+
+// SetPendingTokenTTL sets the lifetime of the token of a login waiting for its second factor
+func (uc *MFAUseCase) SetPendingTokenTTL(ttl time.Duration) {
+	if ttl > 0 {
+		uc.pendingTokenTTL = ttl
+	}
+}
+
+// Status returns the two-factor authentication state of the user
+func (uc *MFAUseCase) Status(username string) (*MFAStatus, error) {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{}
+	enrollment, err := uc.getEnrollment(usr.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment != nil {
+		status.Enabled = enrollment.IsConfirmed()
+		status.PendingConfirmation = !enrollment.IsConfirmed()
+	}
+
+	if status.RequiredByOwner, err = uc.requiredByOwner(usr); err != nil {
+		return nil, err
+	}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = uc.mfaRepo.CountRecoveryCodes(usr.ID); err != nil {
+			return nil, fmt.Errorf("error counting recovery codes: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// BeginEnrollment generates a new TOTP secret for the user. The enrollment is only enabled
+// once it is confirmed with a first code (ConfirmEnrollment).
+func (uc *MFAUseCase) BeginEnrollment(username string) (*TOTPSetup, error) {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+	return uc.beginEnrollment(usr)
+}
+
+// ConfirmEnrollment enables the enrollment of the user with a first code of the authenticator app
+// and returns the recovery codes (shown only once)
+func (uc *MFAUseCase) ConfirmEnrollment(username, code string) ([]string, error) {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+	return uc.confirmEnrollment(usr, code)
+}
+
+// Disable removes the enrollment of the user (a code is required if it is enabled).
+// Subusers can't disable it when the company owner requires it.
+func (uc *MFAUseCase) Disable(username, code string) error {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return err
+	}
+
+	required, err := uc.requiredByOwner(usr)
+	if err != nil {
+		return err
+	}
+	if required {
+		return mfa.ErrRequiredByOwner
+	}
+
+	enrollment, err := uc.getEnrollment(usr.ID)
+	if err != nil {
+		return err
+	}
+	if enrollment == nil {
+		return mfa.ErrNotEnrolled
+	}
+	if enrollment.IsConfirmed() {
+		if err := uc.verify(enrollment, code, true); err != nil {
+			return err
+		}
+	}
+
+	if err := uc.mfaRepo.DeleteEnrollment(usr.ID); err != nil {
+		return fmt.Errorf("error removing enrollment: %w", err)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user (a code of the authenticator app is required)
+func (uc *MFAUseCase) RegenerateRecoveryCodes(username, code string) ([]string, error) {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := uc.getEnrollment(usr.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil || !enrollment.IsConfirmed() {
+		return nil, mfa.ErrNotEnrolled
+	}
+	if err := uc.verify(enrollment, code, false); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.mfaRepo.ReplaceRecoveryCodes(usr.ID, hashes); err != nil {
+		return nil, fmt.Errorf("error saving recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// GetPolicy returns the two-factor authentication policy of the company owner
+func (uc *MFAUseCase) GetPolicy(ownerUsername string) (*mfa.Policy, error) {
+	owner, err := uc.getUser(ownerUsername)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := uc.mfaRepo.GetPolicy(owner.ID)
+	if err != nil {
+		if !uc.mfaRepo.IsNotFoundError(err) {
+			return nil, fmt.Errorf("error retrieving policy: %w", err)
+		}
+		return &mfa.Policy{OwnerID: owner.ID}, nil
+	}
+	return policy, nil
+}
+
+// SetRequiredForSubusers requires (or not) two-factor authentication for all the subusers of the owner.
+// Subusers without an enrollment have to set it up on their next login.
+func (uc *MFAUseCase) SetRequiredForSubusers(ownerUsername string, required bool) (*mfa.Policy, error) {
+	owner, err := uc.getUser(ownerUsername)
+	if err != nil {
+		return nil, err
+	}
+	if owner.OwnerID != nil {
+		return nil, errorsLib.ErrForbidden
+	}
+
+	policy := &mfa.Policy{
+		OwnerID:            owner.ID,
+		RequireForSubusers: required,
+		UpdatedAt:          time.Now(),
+	}
+	if err := uc.mfaRepo.SavePolicy(policy); err != nil {
+		return nil, fmt.Errorf("error saving policy: %w", err)
+	}
+	return policy, nil
+}
+
+// loginState tells if the login of the user needs a second factor and if the user has an enabled enrollment
+func (uc *MFAUseCase) loginState(usr *user.User) (required bool, enrolled bool, err error) {
+	enrollment, err := uc.getEnrollment(usr.ID)
+	if err != nil {
+		return false, false, err
+	}
+	if enrollment != nil && enrollment.IsConfirmed() {
+		return true, true, nil
+	}
+
+	required, err = uc.requiredByOwner(usr)
+	return required, false, err
+}
+
+// verifyLogin checks the second factor of a login: a code of the authenticator app or a recovery code
+func (uc *MFAUseCase) verifyLogin(usr *user.User, code string) error {
+	enrollment, err := uc.getEnrollment(usr.ID)
+	if err != nil {
+		return err
+	}
+	if enrollment == nil || !enrollment.IsConfirmed() {
+		return mfa.ErrEnrollmentPending
+	}
+	return uc.verify(enrollment, code, true)
+}
+
+func (uc *MFAUseCase) beginEnrollment(usr *user.User) (*TOTPSetup, error) {
+	enrollment, err := uc.getEnrollment(usr.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment != nil && enrollment.IsConfirmed() {
+		return nil, mfa.ErrAlreadyEnrolled
+	}
+
+	secret, err := mfa.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("secret generation error: %w", err)
+	}
+
+	// A not confirmed enrollment is replaced: only the last secret can be confirmed
+	if err := uc.mfaRepo.SaveEnrollment(&mfa.Enrollment{
+		UserID:    usr.ID,
+		Secret:    secret,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return nil, fmt.Errorf("error saving enrollment: %w", err)
+	}
+
+	return &TOTPSetup{
+		Secret:     secret,
+		OTPAuthURI: mfa.OTPAuthURI(uc.issuer, usr.Login, secret),
+		Digits:     mfa.TOTPDigits,
+		Period:     int(mfa.TOTPPeriod / time.Second),
+	}, nil
+}
+
+func (uc *MFAUseCase) confirmEnrollment(usr *user.User, code string) ([]string, error) {
+	enrollment, err := uc.getEnrollment(usr.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, mfa.ErrNotEnrolled
+	}
+	if enrollment.IsConfirmed() {
+		return nil, mfa.ErrAlreadyEnrolled
+	}
+
+	now := time.Now()
+	if enrollment.IsThrottled(now) {
+		return nil, mfa.ErrTooManyAttempts
+	}
+	step, ok := mfa.VerifyTOTP(enrollment.Secret, code, now, enrollment.LastUsedStep)
+	if !ok {
+		uc.recordFailedAttempt(usr.ID, now)
+		return nil, mfa.ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.mfaRepo.ConfirmEnrollment(usr.ID, step, now, hashes); err != nil {
+		if errors.Is(err, mfa.ErrCodeAlreadyUsed) {
+			return nil, mfa.ErrInvalidCode
+		}
+		return nil, fmt.Errorf("error confirming enrollment: %w", err)
+	}
+	return codes, nil
+}
+
+// verify checks a code of the authenticator app (or a recovery code if allowed) of an enabled enrollment.
+// Each code is accepted only once and consecutive invalid codes block the verification for a while.
+func (uc *MFAUseCase) verify(enrollment *mfa.Enrollment, code string, allowRecoveryCode bool) error {
+	now := time.Now()
+	if enrollment.IsThrottled(now) {
+		return mfa.ErrTooManyAttempts
+	}
+
+	if allowRecoveryCode && mfa.IsRecoveryCode(code) {
+		if err := uc.mfaRepo.UseRecoveryCode(enrollment.UserID, mfa.HashRecoveryCode(code)); err != nil {
+			if errors.Is(err, mfa.ErrInvalidCode) {
+				uc.recordFailedAttempt(enrollment.UserID, now)
+				return mfa.ErrInvalidCode
+			}
+			return fmt.Errorf("error using recovery code: %w", err)
+		}
+		return nil
+	}
+
+	step, ok := mfa.VerifyTOTP(enrollment.Secret, code, now, enrollment.LastUsedStep)
+	if !ok {
+		uc.recordFailedAttempt(enrollment.UserID, now)
+		return mfa.ErrInvalidCode
+	}
+	if err := uc.mfaRepo.UseStep(enrollment.UserID, step); err != nil {
+		if errors.Is(err, mfa.ErrCodeAlreadyUsed) {
+			return mfa.ErrInvalidCode
+		}
+		return fmt.Errorf("error updating enrollment: %w", err)
+	}
+	return nil
+}
+
+func (uc *MFAUseCase) recordFailedAttempt(userID uint, at time.Time) {
+	if err := uc.mfaRepo.RecordFailedAttempt(userID, at); err != nil {
+		logger.GetLogger().ServiceError("Error recording invalid verification code", map[string]interface{}{
+			"userId": userID,
+			"error":  err.Error(),
+		})
+	}
+}
+
+// requiredByOwner checks if the user is a subuser whose owner requires two-factor authentication
+func (uc *MFAUseCase) requiredByOwner(usr *user.User) (bool, error) {
+	if usr.OwnerID == nil {
+		return false, nil
+	}
+
+	policy, err := uc.mfaRepo.GetPolicy(*usr.OwnerID)
+	if err != nil {
+		if uc.mfaRepo.IsNotFoundError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error retrieving policy: %w", err)
+	}
+	return policy.RequireForSubusers, nil
+}
+
+// getEnrollment returns the enrollment of the user or nil if it has none
+func (uc *MFAUseCase) getEnrollment(userID uint) (*mfa.Enrollment, error) {
+	enrollment, err := uc.mfaRepo.GetEnrollment(userID)
+	if err != nil {
+		if uc.mfaRepo.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error retrieving enrollment: %w", err)
+	}
+	return enrollment, nil
+}
+
+func (uc *MFAUseCase) getUser(username string) (*user.User, error) {
+	usr, err := uc.userRepo.GetByLogin(username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, errorsLib.ErrNotFound
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	return usr, nil
+}
+
+// newRecoveryCodes generates the recovery codes and their hashes
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := mfa.GenerateRecoveryCodes(mfa.RecoveryCodesCount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("recovery codes generation error: %w", err)
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = mfa.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
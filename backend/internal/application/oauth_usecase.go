@@ -0,0 +1,379 @@
+package application
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"app/internal/domain/client"
+	"app/internal/domain/mfa"
+	"app/internal/domain/oauth"
+	"app/internal/domain/password"
+	"app/internal/domain/session"
+	"app/internal/domain/user"
+	"app/internal/infrastructure/token/paseto"
+	"app/pkg/errorsLib"
+	"app/pkg/logger"
+)
+
+// OAuth 2.0 grant types supported by the token endpoint
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// AuthorizeRequest — parameters of the authorization endpoint (RFC 6749 section 4.1.1 + RFC 7636)
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string // OpenID Connect
+}
+
+// TokenResponse — successful response of the token endpoint (RFC 6749 section 5.1)
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"` // only with the openid scope
+}
+
+type OAuthUseCase struct {
+	clientRepo client.Repository
+	codeRepo   oauth.AuthorizationCodeRepository
+	userRepo   user.Repository
+	authUC     *AuthUseCase
+	oidcUC     *OIDCUseCase
+	codeTTL    time.Duration
+}
+
+func NewOAuthUseCase(clientRepo client.Repository, codeRepo oauth.AuthorizationCodeRepository, userRepo user.Repository, authUC *AuthUseCase) *OAuthUseCase {
+	return &OAuthUseCase{
+		clientRepo: clientRepo,
+		codeRepo:   codeRepo,
+		userRepo:   userRepo,
+		authUC:     authUC,
+		oidcUC:     NewOIDCUseCase(userRepo),
+		codeTTL:    time.Minute,
+	}
+}
+
+// SetCodeTTL sets the lifetime of the authorization codes
+func (uc *OAuthUseCase) SetCodeTTL(ttl time.Duration) {
+	if ttl > 0 {
+		uc.codeTTL = ttl
+	}
+}
+
+// ResolveClient checks the client and its redirect URI. These errors must be shown to the user
+// and never redirected: the redirect URI can't be trusted.
+func (uc *OAuthUseCase) ResolveClient(clientID, redirectURI string) (*client.Client, error) {
+	if clientID == "" {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidRequest, "client_id is required")
+	}
+
+	c, err := uc.clientRepo.GetByClientID(clientID)
+	if err != nil {
+		if uc.clientRepo.IsNotFoundError(err) {
+			return nil, oauth.NewError(oauth.ErrCodeInvalidClient, "unknown client")
+		}
+		return nil, fmt.Errorf("error retrieving client: %w", err)
+	}
+	if !c.Active {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidClient, "client is disabled")
+	}
+
+	if redirectURI == "" || !c.HasRedirectURI(redirectURI) {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidRequest, "redirect_uri is not registered for the client")
+	}
+	return c, nil
+}
+
+// ValidateAuthorizeRequest checks the rest of the authorization request and returns the requested scopes
+// (all the scopes of the client if none is requested). These errors are redirected to the client.
+func (uc *OAuthUseCase) ValidateAuthorizeRequest(c *client.Client, req AuthorizeRequest) ([]string, error) {
+	if req.ResponseType != "code" {
+		return nil, oauth.NewError(oauth.ErrCodeUnsupportedResponseType, "only response_type=code is supported")
+	}
+
+	// PKCE is required for all the clients, confidential ones included
+	if req.CodeChallengeMethod != oauth.CodeChallengeMethodS256 || !oauth.ValidCodeVerifier(req.CodeChallenge) {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidRequest, "code_challenge with code_challenge_method=S256 is required")
+	}
+
+	scopes := client.ParseScope(req.Scope)
+	if len(scopes) == 0 {
+		scopes = c.Scopes
+	}
+	if !c.AllowsScopes(scopes) {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidScope, "scope not allowed for the client")
+	}
+	return scopes, nil
+}
+
+// Authorize authenticates the user that approved the request and issues an authorization code.
+// If the login needs a second factor no code is issued: the MFA token of the login is returned
+// instead, to be sent back with the code of the user (AuthorizeMFA). A user with an expired
+// password gets password.ErrExpired: it has to change it with a direct login first.
+func (uc *OAuthUseCase) Authorize(c *client.Client, req AuthorizeRequest, scopes []string, login, password, ip string) (string, string, error) {
+	usr, _, err := uc.authUC.BeginLogin(login, password, ip)
+	if err != nil {
+		return "", "", err
+	}
+	if usr.MFAToken != "" {
+		// The authenticator can't be set up from the authorization page
+		if usr.MFAEnrollmentRequired {
+			return "", "", mfa.ErrEnrollmentPending
+		}
+		return "", usr.MFAToken, nil
+	}
+	// The expired password can't be changed from the authorization page
+	if err := uc.checkPasswordExpiry(usr); err != nil {
+		return "", "", err
+	}
+
+	code, err := uc.issueCode(c, req, scopes, usr)
+	return code, "", err
+}
+
+// AuthorizeMFA verifies the second factor of the user that approved the request and issues an authorization code
+func (uc *OAuthUseCase) AuthorizeMFA(c *client.Client, req AuthorizeRequest, scopes []string, mfaToken, otp string) (string, error) {
+	usr, _, err := uc.authUC.VerifyMFALogin(mfaToken, otp)
+	if err != nil {
+		return "", err
+	}
+	if err := uc.checkPasswordExpiry(usr); err != nil {
+		return "", err
+	}
+	return uc.issueCode(c, req, scopes, usr)
+}
+
+// checkPasswordExpiry returns password.ErrExpired if the password of the user is expired
+func (uc *OAuthUseCase) checkPasswordExpiry(usr *user.User) error {
+	expired, err := uc.authUC.passwordUC.isExpired(usr)
+	if err != nil {
+		return err
+	}
+	if expired {
+		return password.ErrExpired
+	}
+	return nil
+}
+
+// issueCode creates the authorization code of the authenticated user
+func (uc *OAuthUseCase) issueCode(c *client.Client, req AuthorizeRequest, scopes []string, usr *user.User) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("authorization code generation error: %w", err)
+	}
+	code := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	if err := uc.codeRepo.Create(&oauth.AuthorizationCode{
+		CodeHash:            oauth.HashCode(code),
+		ClientID:            c.ClientID,
+		UserID:              usr.ID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               client.FormatScope(scopes),
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Nonce:               req.Nonce,
+		CreatedAt:           now,
+		ExpiresAt:           now.Add(uc.codeTTL),
+	}); err != nil {
+		return "", fmt.Errorf("create authorization code error: %w", err)
+	}
+
+	// Codes live a few seconds, the expired ones are removed here
+	if err := uc.codeRepo.DeleteExpired(now); err != nil {
+		logger.GetLogger().ServiceWarn("Error removing expired authorization codes", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	return code, nil
+}
+
+// AuthenticateClient authenticates the client of the token endpoint.
+// Public clients only send their client_id; confidential clients must send their secret.
+func (uc *OAuthUseCase) AuthenticateClient(clientID, secret string) (*client.Client, error) {
+	invalidClient := oauth.NewError(oauth.ErrCodeInvalidClient, "client authentication failed")
+	if clientID == "" {
+		return nil, invalidClient
+	}
+
+	c, err := uc.clientRepo.GetByClientID(clientID)
+	if err != nil {
+		if uc.clientRepo.IsNotFoundError(err) {
+			return nil, invalidClient
+		}
+		return nil, fmt.Errorf("error retrieving client: %w", err)
+	}
+	if !c.Active {
+		return nil, invalidClient
+	}
+
+	if c.Public {
+		if secret != "" {
+			return nil, invalidClient
+		}
+		return c, nil
+	}
+	if !c.CheckSecret(secret) {
+		return nil, invalidClient
+	}
+	return c, nil
+}
+
+// ExchangeAuthorizationCode issues the tokens of the user for an authorization code (with the PKCE verifier).
+// Presenting a code twice revokes the session opened with it (RFC 6749 section 4.1.2).
+// With the openid scope an ID token for the issuer is also returned.
+func (uc *OAuthUseCase) ExchangeAuthorizationCode(c *client.Client, code, redirectURI, verifier, issuer string, info session.ClientInfo) (*TokenResponse, error) {
+	invalidGrant := oauth.NewError(oauth.ErrCodeInvalidGrant, "invalid authorization code")
+	if code == "" {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidRequest, "code is required")
+	}
+
+	authCode, err := uc.codeRepo.GetByHash(oauth.HashCode(code))
+	if err != nil {
+		if uc.codeRepo.IsNotFoundError(err) {
+			return nil, invalidGrant
+		}
+		return nil, fmt.Errorf("error retrieving authorization code: %w", err)
+	}
+
+	if authCode.IsUsed() {
+		uc.revokeCodeSession(authCode)
+		return nil, invalidGrant
+	}
+	if authCode.ClientID != c.ClientID || authCode.RedirectURI != redirectURI || !time.Now().Before(authCode.ExpiresAt) {
+		return nil, invalidGrant
+	}
+	if !authCode.VerifyPKCE(verifier) {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidGrant, "invalid code_verifier")
+	}
+
+	if err := uc.codeRepo.MarkUsed(authCode.CodeHash); err != nil {
+		if errors.Is(err, oauth.ErrCodeAlreadyUsed) {
+			return nil, invalidGrant
+		}
+		return nil, fmt.Errorf("error updating authorization code: %w", err)
+	}
+
+	// The user (and the owner of a subuser) must still be active
+	usr, err := uc.userRepo.GetByID(authCode.UserID)
+	if err != nil || !usr.Active {
+		return nil, invalidGrant
+	}
+	var ownerUsername string
+	if usr.OwnerID != nil {
+		owner, err := uc.userRepo.GetByID(*usr.OwnerID)
+		if err != nil || !owner.Active {
+			return nil, invalidGrant
+		}
+		ownerUsername = owner.Login
+	}
+
+	if info.Device == "" {
+		info.Device = c.Name
+	}
+	if err := uc.authUC.StartSession(usr, ownerUsername, info, c.ClientID, authCode.Scope); err != nil {
+		return nil, err
+	}
+	if err := uc.codeRepo.SetSessionID(authCode.CodeHash, usr.SessionID); err != nil {
+		return nil, fmt.Errorf("error updating authorization code: %w", err)
+	}
+
+	response := &TokenResponse{
+		AccessToken:  usr.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(paseto.Paseto().ExpirationTime().Seconds()),
+		RefreshToken: usr.RefreshToken,
+		Scope:        authCode.Scope,
+	}
+
+	if scopes := client.ParseScope(authCode.Scope); HasScope(scopes, ScopeOpenID) {
+		idToken, err := uc.oidcUC.IDToken(issuer, usr, ownerUsername, c.ClientID, authCode.Nonce, scopes, authCode.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		response.IDToken = idToken
+	}
+	return response, nil
+}
+
+// ClientCredentials issues a token for the client itself (machine-to-machine, no user and no refresh token)
+func (uc *OAuthUseCase) ClientCredentials(c *client.Client, scope string) (*TokenResponse, error) {
+	if c.Public {
+		return nil, oauth.NewError(oauth.ErrCodeUnauthorizedClient, "public clients can't use client_credentials")
+	}
+
+	scopes := client.ParseScope(scope)
+	if len(scopes) == 0 {
+		scopes = c.Scopes
+	}
+	if !c.AllowsScopes(scopes) {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidScope, "scope not allowed for the client")
+	}
+
+	accessToken, claims, err := paseto.Paseto().GenerateToken(paseto.PasetoClaims{
+		Username:    c.ClientID,
+		CompanyID:   int(c.CompanyID),
+		ClientID:    c.ClientID,
+		Scope:       client.FormatScope(scopes),
+		SubjectType: paseto.SubjectTypeClient,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("access token generation error: %w", err)
+	}
+
+	return &TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(claims.ExpiresAt).Seconds()),
+		Scope:       claims.Scope,
+	}, nil
+}
+
+// RefreshToken rotates a refresh token of a session opened by the client
+func (uc *OAuthUseCase) RefreshToken(c *client.Client, refreshToken string, info session.ClientInfo) (*TokenResponse, error) {
+	if refreshToken == "" {
+		return nil, oauth.NewError(oauth.ErrCodeInvalidRequest, "refresh_token is required")
+	}
+
+	accessToken, newRefreshToken, err := uc.authUC.RefreshPairTokens(refreshToken, info, c.ClientID)
+	if err != nil {
+		if errors.Is(err, errorsLib.ErrAccessDenied) {
+			return nil, oauth.NewError(oauth.ErrCodeInvalidGrant, "invalid refresh token")
+		}
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(paseto.Paseto().ExpirationTime().Seconds()),
+		RefreshToken: newRefreshToken,
+	}, nil
+}
+
+// revokeCodeSession revokes the session opened with an authorization code that was presented again
+func (uc *OAuthUseCase) revokeCodeSession(authCode *oauth.AuthorizationCode) {
+	if authCode.SessionID == "" {
+		return
+	}
+	if err := uc.authUC.RevokeSessionByID(authCode.SessionID); err != nil {
+		logger.GetLogger().ServiceError("Error revoking session of a reused authorization code", map[string]interface{}{
+			"sessionId": authCode.SessionID,
+			"error":     err.Error(),
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package application
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"app/internal/domain/client"
+	"app/internal/domain/oauth"
+	"app/internal/domain/session"
+	"app/internal/domain/user"
+	"app/internal/infrastructure/token/paseto"
+)
+
+const (
+	testIssuer      = "https://auth.example.com"
+	testRedirectURI = "https://app.example.com/callback"
+
+	// Example of RFC 7636 appendix B
+	testCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+type oauthTest struct {
+	uc          *OAuthUseCase
+	client      *client.Client
+	usr         *user.User
+	userRepo    *memoryUserRepository
+	codeRepo    *memoryAuthorizationCodeRepository
+	sessionRepo *memorySessionRepository
+}
+
+func newOAuthTest(t *testing.T) *oauthTest {
+	t.Helper()
+	userRepo := &memoryUserRepository{}
+	usr := userRepo.add(&user.User{Login: "tech1", CompanyID: 20001, CompanyName: "Acme", ProviderID: testLocalProviderID, Active: true})
+	authUC, sessionRepo := newTestAuthUseCase(userRepo)
+	codeRepo := &memoryAuthorizationCodeRepository{}
+
+	return &oauthTest{
+		uc: NewOAuthUseCase(nil, codeRepo, userRepo, authUC),
+		client: &client.Client{
+			ClientID:     "app",
+			Name:         "App",
+			CompanyID:    20001,
+			Public:       true,
+			RedirectURIs: []string{testRedirectURI},
+			Scopes:       []string{ScopeOpenID, ScopeProfile},
+			Active:       true,
+		},
+		usr:         usr,
+		userRepo:    userRepo,
+		codeRepo:    codeRepo,
+		sessionRepo: sessionRepo,
+	}
+}
+
+// authorize issues an authorization code for the user, as after its approval on the authorization page
+func (ot *oauthTest) authorize(t *testing.T) string {
+	t.Helper()
+	req := AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            ot.client.ClientID,
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid profile",
+		CodeChallenge:       testCodeChallenge,
+		CodeChallengeMethod: oauth.CodeChallengeMethodS256,
+		Nonce:               "n-0S6_WzA2Mj",
+	}
+	scopes, err := ot.uc.ValidateAuthorizeRequest(ot.client, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := ot.uc.issueCode(ot.client, req, scopes, ot.usr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func (ot *oauthTest) exchange(code, verifier string) (*TokenResponse, error) {
+	return ot.uc.ExchangeAuthorizationCode(ot.client, code, testRedirectURI, verifier, testIssuer, session.ClientInfo{IP: "127.0.0.1"})
+}
+
+func assertOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) || oauthErr.Code != code {
+		t.Fatalf("got error %v, want %s", err, code)
+	}
+}
+
+func TestExchangeAuthorizationCode(t *testing.T) {
+	ot := newOAuthTest(t)
+
+	res, err := ot.exchange(ot.authorize(t), testCodeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TokenType != "Bearer" || res.RefreshToken == "" || res.Scope != "openid profile" {
+		t.Errorf("unexpected response %+v", res)
+	}
+
+	claims, err := paseto.Paseto().ValidateToken(res.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Username != "tech1" || claims.ClientID != "app" || claims.Scope != "openid profile" || claims.SessionID == "" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	sess, err := ot.sessionRepo.GetByID(claims.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.ClientID != "app" || sess.Device != "App" {
+		t.Errorf("unexpected session %+v", sess)
+	}
+
+	// openid scope: ID token for the client with the nonce of the authorization request
+	parts := strings.Split(res.IDToken, ".")
+	if len(parts) != 3 {
+		t.Fatalf("ID token %q is not a JWS", res.IDToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idClaims map[string]interface{}
+	if err := json.Unmarshal(payload, &idClaims); err != nil {
+		t.Fatal(err)
+	}
+	if idClaims["iss"] != testIssuer || idClaims["aud"] != "app" || idClaims["nonce"] != "n-0S6_WzA2Mj" {
+		t.Errorf("unexpected ID token claims %v", idClaims)
+	}
+}
+
+func TestExchangeAuthorizationCodeReplay(t *testing.T) {
+	ot := newOAuthTest(t)
+	code := ot.authorize(t)
+
+	res, err := ot.exchange(code, testCodeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := paseto.Paseto().ValidateToken(res.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The code is presented again (e.g. intercepted): rejected, and the tokens issued with it are revoked
+	_, err = ot.exchange(code, testCodeVerifier)
+	assertOAuthError(t, err, oauth.ErrCodeInvalidGrant)
+
+	sess, err := ot.sessionRepo.GetByID(claims.SessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.RevokedAt == nil {
+		t.Error("session opened with the replayed code is still active")
+	}
+	if _, err := paseto.Paseto().ValidateToken(res.AccessToken); !errors.Is(err, paseto.ErrTokenRevoked) {
+		t.Errorf("access token of the replayed code: got %v, want %v", err, paseto.ErrTokenRevoked)
+	}
+}
+
+func TestExchangeAuthorizationCodeRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		prepare  func(ot *oauthTest, code string) string // returns the code to exchange
+		client   *client.Client
+		redirect string
+		verifier string
+		want     string
+	}{
+		{name: "PKCE verifier mismatch", verifier: strings.Repeat("a", 43), want: oauth.ErrCodeInvalidGrant},
+		{name: "challenge sent as the verifier", verifier: testCodeChallenge, want: oauth.ErrCodeInvalidGrant},
+		{name: "without PKCE verifier", verifier: "", want: oauth.ErrCodeInvalidGrant},
+		{name: "another client", client: &client.Client{ClientID: "other", Name: "Other", Active: true}, verifier: testCodeVerifier, want: oauth.ErrCodeInvalidGrant},
+		{name: "another redirect URI", redirect: "https://app.example.com/other", verifier: testCodeVerifier, want: oauth.ErrCodeInvalidGrant},
+		{name: "unknown code", prepare: func(ot *oauthTest, code string) string { return code + "x" }, verifier: testCodeVerifier, want: oauth.ErrCodeInvalidGrant},
+		{name: "empty code", prepare: func(ot *oauthTest, code string) string { return "" }, verifier: testCodeVerifier, want: oauth.ErrCodeInvalidRequest},
+		{name: "expired code", prepare: func(ot *oauthTest, code string) string {
+			ot.codeRepo.codes[oauth.HashCode(code)].ExpiresAt = time.Now().Add(-time.Second)
+			return code
+		}, verifier: testCodeVerifier, want: oauth.ErrCodeInvalidGrant},
+		{name: "deactivated user", prepare: func(ot *oauthTest, code string) string {
+			ot.userRepo.users[0].Active = false
+			return code
+		}, verifier: testCodeVerifier, want: oauth.ErrCodeInvalidGrant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ot := newOAuthTest(t)
+			code := ot.authorize(t)
+			if tt.prepare != nil {
+				code = tt.prepare(ot, code)
+			}
+			c := ot.client
+			if tt.client != nil {
+				c = tt.client
+			}
+			redirect := testRedirectURI
+			if tt.redirect != "" {
+				redirect = tt.redirect
+			}
+
+			_, err := ot.uc.ExchangeAuthorizationCode(c, code, redirect, tt.verifier, testIssuer, session.ClientInfo{})
+			assertOAuthError(t, err, tt.want)
+			if len(ot.sessionRepo.sessions) != 0 {
+				t.Error("session opened for a rejected code")
+			}
+		})
+	}
+}
+
+func TestValidateAuthorizeRequest(t *testing.T) {
+	ot := newOAuthTest(t)
+	valid := AuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            "app",
+		RedirectURI:         testRedirectURI,
+		CodeChallenge:       testCodeChallenge,
+		CodeChallengeMethod: oauth.CodeChallengeMethodS256,
+	}
+
+	// Without scope the client gets all its scopes
+	scopes, err := ot.uc.ValidateAuthorizeRequest(ot.client, valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.FormatScope(scopes) != "openid profile" {
+		t.Errorf("got scopes %v", scopes)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *AuthorizeRequest)
+		want   string
+	}{
+		{"token response type", func(r *AuthorizeRequest) { r.ResponseType = "token" }, oauth.ErrCodeUnsupportedResponseType},
+		{"without PKCE", func(r *AuthorizeRequest) { r.CodeChallenge, r.CodeChallengeMethod = "", "" }, oauth.ErrCodeInvalidRequest},
+		{"plain PKCE", func(r *AuthorizeRequest) { r.CodeChallengeMethod = "plain" }, oauth.ErrCodeInvalidRequest},
+		{"malformed challenge", func(r *AuthorizeRequest) { r.CodeChallenge = "short" }, oauth.ErrCodeInvalidRequest},
+		{"scope not allowed", func(r *AuthorizeRequest) { r.Scope = "openid email" }, oauth.ErrCodeInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			_, err := ot.uc.ValidateAuthorizeRequest(ot.client, req)
+			assertOAuthError(t, err, tt.want)
+		})
+	}
+}
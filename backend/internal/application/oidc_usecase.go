@@ -0,0 +1,172 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"app/internal/domain/client"
+	"app/internal/domain/user"
+	"app/internal/infrastructure/token/paseto"
+	"app/pkg/errorsLib"
+)
+
+// OpenID Connect scopes
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// DiscoveryDocument — OpenID Provider metadata (OpenID Connect Discovery 1.0, section 3)
+type DiscoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+type OIDCUseCase struct {
+	userRepo user.Repository
+}
+
+func NewOIDCUseCase(userRepo user.Repository) *OIDCUseCase {
+	return &OIDCUseCase{userRepo: userRepo}
+}
+
+// Discovery returns the metadata of the provider for the issuer (base URL of the service)
+func (uc *OIDCUseCase) Discovery(issuer string) *DiscoveryDocument {
+	issuer = strings.TrimSuffix(issuer, "/")
+	return &DiscoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/oauth/userinfo",
+		JwksURI:                           issuer + "/token/keys",
+		IntrospectionEndpoint:             issuer + "/token/introspect",
+		ScopesSupported:                   []string{ScopeOpenID, ScopeProfile, ScopeEmail},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode, GrantTypeClientCredentials, GrantTypeRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"EdDSA"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username",
+			"name", "given_name", "family_name", "phone_number", "email",
+			"companyId", "companyName", "ownerUsername",
+		},
+	}
+}
+
+// IDToken issues the ID token of the user for the client
+func (uc *OIDCUseCase) IDToken(issuer string, usr *user.User, ownerUsername, clientID, nonce string, scopes []string, authTime time.Time) (string, error) {
+	now := time.Now()
+	claims := userClaims(usr, ownerUsername, scopes)
+	claims["iss"] = strings.TrimSuffix(issuer, "/")
+	claims["aud"] = clientID
+	claims["azp"] = clientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(paseto.Paseto().ExpirationTime()).Unix()
+	claims["auth_time"] = authTime.Unix()
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	idToken, err := paseto.Paseto().SignJWT(claims)
+	if err != nil {
+		return "", fmt.Errorf("id token generation error: %w", err)
+	}
+	return idToken, nil
+}
+
+// UserInfo returns the claims of the token user allowed by the scope of the token.
+// The token must have been issued with the openid scope.
+func (uc *OIDCUseCase) UserInfo(tokenClaims *paseto.PasetoClaims) (map[string]interface{}, error) {
+	scopes := client.ParseScope(tokenClaims.Scope)
+	if !HasScope(scopes, ScopeOpenID) {
+		return nil, ErrInsufficientScope
+	}
+
+	usr, err := uc.userRepo.GetByLogin(tokenClaims.Username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, errorsLib.ErrNotFound
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if !usr.Active {
+		return nil, errorsLib.ErrAccessDenied
+	}
+
+	return userClaims(usr, tokenClaims.OwnerUsername, scopes), nil
+}
+
+// HasScope checks if the scope list contains the scope
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// userClaims returns the standard and company claims of the user allowed by the scopes
+func userClaims(usr *user.User, ownerUsername string, scopes []string) map[string]interface{} {
+	claims := map[string]interface{}{
+		"sub":         usr.Login,
+		"companyId":   usr.CompanyID,
+		"companyName": usr.CompanyName,
+	}
+	if ownerUsername != "" {
+		claims["ownerUsername"] = ownerUsername
+	}
+
+	profile := usr.Profile
+	if HasScope(scopes, ScopeProfile) {
+		claims["preferred_username"] = usr.Login
+		if profile != nil {
+			setClaim(claims, "given_name", profile.Name)
+			setClaim(claims, "family_name", profile.Surname)
+			setClaim(claims, "phone_number", profile.Phone)
+			if name := fullName(profile); name != "" {
+				claims["name"] = name
+			}
+		}
+	}
+	if HasScope(scopes, ScopeEmail) && profile != nil {
+		setClaim(claims, "email", profile.Email)
+	}
+	return claims
+}
+
+func setClaim(claims map[string]interface{}, name string, value *string) {
+	if value != nil && *value != "" {
+		claims[name] = *value
+	}
+}
+
+func fullName(profile *user.Profile) string {
+	var parts []string
+	if profile.Name != nil && *profile.Name != "" {
+		parts = append(parts, *profile.Name)
+	}
+	if profile.Surname != nil && *profile.Surname != "" {
+		parts = append(parts, *profile.Surname)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,401 @@
+package application
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"app/internal/domain/passkey"
+	"app/internal/domain/session"
+	"app/internal/domain/user"
+	"app/pkg/errorsLib"
+	"app/pkg/logger"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+// PasskeyConfig — relying party of the passkeys
+type PasskeyConfig struct {
+	RPID            string   // domain the passkeys are bound to
+	RPDisplayName   string   // name shown by the authenticators
+	RPOrigins       []string // origins allowed to run the ceremonies
+	CeremonyTimeout time.Duration
+}
+
+// PasskeyCeremony — first step of a registration or a login: the options for the browser
+// (navigator.credentials.create / get) and the ID to send back with its response
+type PasskeyCeremony struct {
+	CeremonyID string      `json:"ceremonyId"`
+	Options    interface{} `json:"options"`
+}
+
+type PasskeyUseCase struct {
+	passkeyRepo     passkey.Repository
+	userRepo        user.Repository
+	authUC          *AuthUseCase
+	webAuthn        *webauthn.WebAuthn
+	ceremonyTimeout time.Duration
+}
+
+func NewPasskeyUseCase(passkeyRepo passkey.Repository, userRepo user.Repository, authUC *AuthUseCase, cfg PasskeyConfig) (*PasskeyUseCase, error) {
+	if cfg.CeremonyTimeout <= 0 {
+		cfg.CeremonyTimeout = 5 * time.Minute
+	}
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.CeremonyTimeout, TimeoutUVD: cfg.CeremonyTimeout}
+
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          cfg.RPID,
+		RPDisplayName: cfg.RPDisplayName,
+		RPOrigins:     cfg.RPOrigins,
+		Timeouts:      webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webauthn config error: %w", err)
+	}
+
+	return &PasskeyUseCase{
+		passkeyRepo:     passkeyRepo,
+		userRepo:        userRepo,
+		authUC:          authUC,
+		webAuthn:        w,
+		ceremonyTimeout: cfg.CeremonyTimeout,
+	}, nil
+}
+
+// BeginRegistration starts the registration of a new passkey of the user.
+// Passkeys are discoverable credentials with user verification (they replace the password).
+func (uc *PasskeyUseCase) BeginRegistration(username string) (*PasskeyCeremony, error) {
+	pu, err := uc.getPasskeyUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	creation, sessionData, err := uc.webAuthn.BeginRegistration(pu,
+		webauthn.WithExclusions(webauthn.Credentials(pu.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			UserVerification:   protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("begin passkey registration error: %w", err)
+	}
+
+	ceremonyID, err := uc.saveCeremony(passkey.CeremonyRegistration, &pu.usr.ID, sessionData)
+	if err != nil {
+		return nil, err
+	}
+	return &PasskeyCeremony{CeremonyID: ceremonyID, Options: creation}, nil
+}
+
+// FinishRegistration verifies the response of the authenticator (attestation) and stores the new passkey
+func (uc *PasskeyUseCase) FinishRegistration(username, ceremonyID, name string, body io.Reader) (*passkey.Credential, error) {
+	pu, err := uc.getPasskeyUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionData, ceremony, err := uc.consumeCeremony(ceremonyID, passkey.CeremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if ceremony.UserID == nil || *ceremony.UserID != pu.usr.ID {
+		return nil, passkey.ErrCeremonyNotFound
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(body)
+	if err != nil {
+		return nil, passkey.ErrInvalidCredential
+	}
+	credential, err := uc.webAuthn.CreateCredential(pu, *sessionData, parsed)
+	if err != nil {
+		logger.GetLogger().ServiceWarn("Passkey registration rejected", map[string]interface{}{
+			"user":  pu.usr.Login,
+			"error": err.Error(),
+		})
+		return nil, passkey.ErrInvalidCredential
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, t := range credential.Transport {
+		transports[i] = string(t)
+	}
+
+	pk := &passkey.Credential{
+		UserID:          pu.usr.ID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Flags:           uint8(credential.Flags.ProtocolValue()),
+		Transports:      transports,
+		Attachment:      string(credential.Authenticator.Attachment),
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       time.Now(),
+	}
+	if err := uc.passkeyRepo.Create(pk); err != nil {
+		return nil, fmt.Errorf("error saving passkey: %w", err)
+	}
+	return pk, nil
+}
+
+// List returns the passkeys of the user
+func (uc *PasskeyUseCase) List(username string) ([]*passkey.Credential, error) {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := uc.passkeyRepo.GetByUserID(usr.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving passkeys: %w", err)
+	}
+	return credentials, nil
+}
+
+// Delete removes a passkey of the user
+func (uc *PasskeyUseCase) Delete(username string, id uint) error {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return err
+	}
+	if err := uc.passkeyRepo.Delete(usr.ID, id); err != nil {
+		if uc.passkeyRepo.IsNotFoundError(err) {
+			return errorsLib.ErrNotFound
+		}
+		return fmt.Errorf("error deleting passkey: %w", err)
+	}
+	return nil
+}
+
+// BeginLogin starts a login with a passkey. The user is not known yet:
+// the authenticator offers the passkeys it has for the relying party.
+func (uc *PasskeyUseCase) BeginLogin() (*PasskeyCeremony, error) {
+	assertion, sessionData, err := uc.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, fmt.Errorf("begin passkey login error: %w", err)
+	}
+
+	ceremonyID, err := uc.saveCeremony(passkey.CeremonyLogin, nil, sessionData)
+	if err != nil {
+		return nil, err
+	}
+	return &PasskeyCeremony{CeremonyID: ceremonyID, Options: assertion}, nil
+}
+
+// FinishLogin verifies the response of the authenticator (assertion) and opens the session,
+// like a password login. The user verification of the passkey (PIN or biometrics) is already
+// a second factor, so the TOTP of the user (or the policy of its owner) is not checked.
+func (uc *PasskeyUseCase) FinishLogin(ceremonyID string, body io.Reader, client session.ClientInfo) (*user.User, error) {
+	sessionData, _, err := uc.consumeCeremony(ceremonyID, passkey.CeremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(body)
+	if err != nil {
+		return nil, passkey.ErrInvalidCredential
+	}
+
+	webAuthnUser, credential, err := uc.webAuthn.ValidatePasskeyLogin(uc.discoverUser, *sessionData, parsed)
+	if err != nil {
+		// The errors of discoverUser are wrapped by the library
+		switch {
+		case errors.Is(err, passkey.ErrProviderNotAllowed):
+			return nil, passkey.ErrProviderNotAllowed
+		case errors.Is(err, errorsLib.ErrForbidden):
+			return nil, errorsLib.ErrForbidden
+		}
+		logger.GetLogger().ServiceWarn("Passkey login rejected", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, passkey.ErrInvalidCredential
+	}
+	pu := webAuthnUser.(*passkeyUser)
+
+	stored := pu.credential(credential.ID)
+	if stored == nil {
+		return nil, passkey.ErrInvalidCredential
+	}
+	if credential.Authenticator.CloneWarning {
+		logger.GetLogger().ServiceWarn("Passkey signature counter mismatch", map[string]interface{}{
+			"user":      pu.usr.Login,
+			"passkey":   stored.ID,
+			"signCount": credential.Authenticator.SignCount,
+		})
+		return nil, passkey.ErrClonedAuthenticator
+	}
+
+	if err := uc.passkeyRepo.UpdateUsage(stored.ID, credential.Authenticator.SignCount,
+		uint8(credential.Flags.ProtocolValue()), credential.Flags.BackupState, time.Now()); err != nil {
+		return nil, fmt.Errorf("error updating passkey: %w", err)
+	}
+
+	return uc.authUC.LoginAuthenticated(pu.usr, client)
+}
+
+// discoverUser returns the owner of a discoverable credential by its user handle (UUID of the user)
+func (uc *PasskeyUseCase) discoverUser(_, userHandle []byte) (webauthn.User, error) {
+	usr, err := uc.userRepo.GetByUUID(string(userHandle))
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, passkey.ErrInvalidCredential
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	return uc.newPasskeyUser(usr)
+}
+
+// getPasskeyUser returns the user with its passkeys
+func (uc *PasskeyUseCase) getPasskeyUser(username string) (*passkeyUser, error) {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+	return uc.newPasskeyUser(usr)
+}
+
+func (uc *PasskeyUseCase) newPasskeyUser(usr *user.User) (*passkeyUser, error) {
+	if !isLocalProvider(usr.ProviderID) {
+		return nil, passkey.ErrProviderNotAllowed
+	}
+	if !usr.Active {
+		return nil, errorsLib.ErrForbidden
+	}
+
+	credentials, err := uc.passkeyRepo.GetByUserID(usr.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving passkeys: %w", err)
+	}
+	return &passkeyUser{usr: usr, credentials: credentials}, nil
+}
+
+func (uc *PasskeyUseCase) getUser(username string) (*user.User, error) {
+	usr, err := uc.userRepo.GetByLogin(username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, errorsLib.ErrNotFound
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	return usr, nil
+}
+
+// saveCeremony stores the state of the ceremony until its second step and returns its ID
+func (uc *PasskeyUseCase) saveCeremony(ceremonyType string, userID *uint, sessionData *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(sessionData)
+	if err != nil {
+		return "", fmt.Errorf("passkey ceremony encoding error: %w", err)
+	}
+
+	now := time.Now()
+	ceremony := &passkey.Ceremony{
+		ID:        uuid.New().String(),
+		Type:      ceremonyType,
+		UserID:    userID,
+		Data:      data,
+		ExpiresAt: now.Add(uc.ceremonyTimeout),
+	}
+	if err := uc.passkeyRepo.CreateCeremony(ceremony); err != nil {
+		return "", fmt.Errorf("error saving passkey ceremony: %w", err)
+	}
+
+	// The abandoned ceremonies are removed along the way
+	if err := uc.passkeyRepo.DeleteExpiredCeremonies(now); err != nil {
+		logger.GetLogger().ServiceWarn("Error deleting expired passkey ceremonies", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	return ceremony.ID, nil
+}
+
+// consumeCeremony returns the state of the ceremony (it can only be finished once)
+func (uc *PasskeyUseCase) consumeCeremony(ceremonyID, ceremonyType string) (*webauthn.SessionData, *passkey.Ceremony, error) {
+	ceremony, err := uc.passkeyRepo.ConsumeCeremony(ceremonyID, ceremonyType, time.Now())
+	if err != nil {
+		if errors.Is(err, passkey.ErrCeremonyNotFound) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("error retrieving passkey ceremony: %w", err)
+	}
+
+	var sessionData webauthn.SessionData
+	if err := json.Unmarshal(ceremony.Data, &sessionData); err != nil {
+		return nil, nil, fmt.Errorf("passkey ceremony decoding error: %w", err)
+	}
+	return &sessionData, ceremony, nil
+}
+
+// passkeyUser — user with its passkeys as seen by the WebAuthn ceremonies
+type passkeyUser struct {
+	usr         *user.User
+	credentials []*passkey.Credential
+}
+
+// WebAuthnID — user handle stored in the passkeys: the UUID of the user (not its database ID)
+func (u *passkeyUser) WebAuthnID() []byte {
+	return []byte(u.usr.UUID)
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.usr.Login
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	if p := u.usr.Profile; p != nil && p.Name != nil && *p.Name != "" {
+		if p.Surname != nil && *p.Surname != "" {
+			return *p.Name + " " + *p.Surname
+		}
+		return *p.Name
+	}
+	return u.usr.Login
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, c := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, len(c.Transports))
+		for j, t := range c.Transports {
+			transports[j] = protocol.AuthenticatorTransport(t)
+		}
+		credentials[i] = webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(c.Flags)),
+			Authenticator: webauthn.Authenticator{
+				AAGUID:     c.AAGUID,
+				SignCount:  c.SignCount,
+				Attachment: protocol.AuthenticatorAttachment(c.Attachment),
+			},
+		}
+	}
+	return credentials
+}
+
+// credential returns the stored passkey by its credential ID
+func (u *passkeyUser) credential(credentialID []byte) *passkey.Credential {
+	for _, c := range u.credentials {
+		if bytes.Equal(c.CredentialID, credentialID) {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package application
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"app/internal/domain/passkey"
+	"app/internal/domain/session"
+	"app/internal/domain/user"
+	"app/internal/infrastructure/token/paseto"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+)
+
+const (
+	testRPID   = "auth.example.com"
+	testOrigin = "https://auth.example.com"
+)
+
+var b64url = base64.RawURLEncoding
+
+// softwareAuthenticator — platform authenticator (ES256, attestation "none") that holds one passkey
+type softwareAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftwareAuthenticator(t *testing.T) *softwareAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialID := make([]byte, 16)
+	if _, err := rand.Read(credentialID); err != nil {
+		t.Fatal(err)
+	}
+	return &softwareAuthenticator{key: key, credentialID: credentialID}
+}
+
+// ceremony — what the browser and the authenticator put in a response; the tests change it to forge one
+type ceremony struct {
+	Type       protocol.CeremonyType
+	Challenge  []byte
+	Origin     string
+	RPID       string
+	Flags      protocol.AuthenticatorFlags
+	SignCount  uint32
+	UserHandle []byte
+
+	TamperSignature bool
+}
+
+func (a *softwareAuthenticator) clientDataJSON(c *ceremony) []byte {
+	data, _ := json.Marshal(protocol.CollectedClientData{
+		Type:      c.Type,
+		Challenge: b64url.EncodeToString(c.Challenge),
+		Origin:    c.Origin,
+	})
+	return data
+}
+
+func (a *softwareAuthenticator) authenticatorData(c *ceremony, attestedCredential []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	data := append(rpIDHash[:], byte(c.Flags))
+	data = binary.BigEndian.AppendUint32(data, c.SignCount)
+	return append(data, attestedCredential...)
+}
+
+// register returns the response of navigator.credentials.create for the options of the registration
+func (a *softwareAuthenticator) register(t *testing.T, options interface{}, modify func(c *ceremony)) []byte {
+	t.Helper()
+	creation := options.(*protocol.CredentialCreation)
+	a.userHandle = creation.Response.User.ID.(protocol.URLEncodedBase64)
+	a.signCount = 1
+
+	c := &ceremony{
+		Type:      protocol.CreateCeremony,
+		Challenge: creation.Response.Challenge,
+		Origin:    testOrigin,
+		RPID:      creation.Response.RelyingParty.ID,
+		Flags:     protocol.FlagUserPresent | protocol.FlagUserVerified | protocol.FlagAttestedCredentialData,
+		SignCount: a.signCount,
+	}
+	if modify != nil {
+		modify(c)
+	}
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestationObject, err := webauthncbor.Marshal(struct {
+		Format   string         `cbor:"fmt"`
+		AttStmt  map[string]any `cbor:"attStmt"`
+		AuthData []byte         `cbor:"authData"`
+	}{"none", map[string]any{}, a.authenticatorData(c, attested)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a.response(t, map[string]interface{}{
+		"clientDataJSON":    b64url.EncodeToString(a.clientDataJSON(c)),
+		"attestationObject": b64url.EncodeToString(attestationObject),
+		"transports":        []string{"internal"},
+	})
+}
+
+// assert returns the response of navigator.credentials.get for the options of the login
+func (a *softwareAuthenticator) assert(t *testing.T, options interface{}, modify func(c *ceremony)) []byte {
+	t.Helper()
+	assertion := options.(*protocol.CredentialAssertion)
+	a.signCount++
+
+	c := &ceremony{
+		Type:       protocol.AssertCeremony,
+		Challenge:  assertion.Response.Challenge,
+		Origin:     testOrigin,
+		RPID:       assertion.Response.RelyingPartyID,
+		Flags:      protocol.FlagUserPresent | protocol.FlagUserVerified,
+		SignCount:  a.signCount,
+		UserHandle: a.userHandle,
+	}
+	if modify != nil {
+		modify(c)
+	}
+
+	clientData := a.clientDataJSON(c)
+	authData := a.authenticatorData(c, nil)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(bytes.Clone(authData), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.TamperSignature {
+		signature[len(signature)-1] ^= 0x01
+	}
+
+	return a.response(t, map[string]interface{}{
+		"clientDataJSON":    b64url.EncodeToString(clientData),
+		"authenticatorData": b64url.EncodeToString(authData),
+		"signature":         b64url.EncodeToString(signature),
+		"userHandle":        b64url.EncodeToString(c.UserHandle),
+	})
+}
+
+func (a *softwareAuthenticator) response(t *testing.T, response map[string]interface{}) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"id":       b64url.EncodeToString(a.credentialID),
+		"rawId":    b64url.EncodeToString(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+type passkeyTest struct {
+	uc          *PasskeyUseCase
+	usr         *user.User
+	userRepo    *memoryUserRepository
+	passkeyRepo *memoryPasskeyRepository
+	auth        *softwareAuthenticator
+}
+
+func newPasskeyTest(t *testing.T) *passkeyTest {
+	t.Helper()
+	userRepo := &memoryUserRepository{}
+	usr := userRepo.add(&user.User{Login: "tech1", CompanyID: 20001, CompanyName: "Acme", ProviderID: testLocalProviderID, Active: true})
+	authUC, _ := newTestAuthUseCase(userRepo)
+	passkeyRepo := &memoryPasskeyRepository{}
+
+	uc, err := NewPasskeyUseCase(passkeyRepo, userRepo, authUC, PasskeyConfig{
+		RPID:          testRPID,
+		RPDisplayName: "Auth",
+		RPOrigins:     []string{testOrigin},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &passkeyTest{uc: uc, usr: usr, userRepo: userRepo, passkeyRepo: passkeyRepo, auth: newSoftwareAuthenticator(t)}
+}
+
+// register registers the passkey of the software authenticator
+func (pt *passkeyTest) register(t *testing.T) *passkey.Credential {
+	t.Helper()
+	begin, err := pt.uc.BeginRegistration(pt.usr.Login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := pt.auth.register(t, begin.Options, nil)
+	credential, err := pt.uc.FinishRegistration(pt.usr.Login, begin.CeremonyID, "Laptop", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credential
+}
+
+// login runs a login ceremony with the software authenticator
+func (pt *passkeyTest) login(t *testing.T, modify func(c *ceremony)) (*user.User, error) {
+	t.Helper()
+	begin, err := pt.uc.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := pt.auth.assert(t, begin.Options, modify)
+	return pt.uc.FinishLogin(begin.CeremonyID, bytes.NewReader(body), session.ClientInfo{IP: "127.0.0.1"})
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	pt := newPasskeyTest(t)
+
+	credential := pt.register(t)
+	if credential.Name != "Laptop" || !bytes.Equal(credential.CredentialID, pt.auth.credentialID) ||
+		credential.AttestationType != "none" || credential.SignCount != 1 {
+		t.Errorf("unexpected passkey %+v", credential)
+	}
+	// The user handle is the UUID of the user, never its database ID
+	if string(pt.auth.userHandle) != pt.usr.UUID {
+		t.Errorf("user handle %q, want the UUID of the user", pt.auth.userHandle)
+	}
+
+	usr, err := pt.login(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := paseto.Paseto().ValidateToken(usr.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Username != "tech1" || claims.SessionID == "" || usr.RefreshToken == "" {
+		t.Errorf("unexpected login %+v", claims)
+	}
+
+	stored, _ := pt.passkeyRepo.GetByUserID(pt.usr.ID)
+	if len(stored) != 1 || stored[0].SignCount != pt.auth.signCount || stored[0].LastUsedAt == nil {
+		t.Errorf("usage of the passkey not recorded: %+v", stored)
+	}
+}
+
+func TestPasskeyLoginRejects(t *testing.T) {
+	otherChallenge := make([]byte, 32)
+	tests := []struct {
+		name   string
+		modify func(c *ceremony)
+		want   error
+	}{
+		{"tampered signature", func(c *ceremony) { c.TamperSignature = true }, passkey.ErrInvalidCredential},
+		{"challenge of another ceremony", func(c *ceremony) { c.Challenge = otherChallenge }, passkey.ErrInvalidCredential},
+		{"another origin", func(c *ceremony) { c.Origin = "https://evil.example.com" }, passkey.ErrInvalidCredential},
+		{"another relying party", func(c *ceremony) { c.RPID = "evil.example.com" }, passkey.ErrInvalidCredential},
+		{"registration response", func(c *ceremony) { c.Type = protocol.CreateCeremony }, passkey.ErrInvalidCredential},
+		{"without user verification", func(c *ceremony) { c.Flags = protocol.FlagUserPresent }, passkey.ErrInvalidCredential},
+		{"user handle of another user", func(c *ceremony) { c.UserHandle = []byte("00000000-0000-0000-0000-000000000000") }, passkey.ErrInvalidCredential},
+		{"signature counter gone back", func(c *ceremony) { c.SignCount = 1 }, passkey.ErrClonedAuthenticator},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := newPasskeyTest(t)
+			pt.register(t)
+			if _, err := pt.login(t, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			usr, err := pt.login(t, tt.modify)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if usr != nil {
+				t.Error("session opened")
+			}
+		})
+	}
+}
+
+func TestPasskeyLoginCeremonyReplay(t *testing.T) {
+	pt := newPasskeyTest(t)
+	pt.register(t)
+
+	begin, err := pt.uc.BeginLogin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := pt.auth.assert(t, begin.Options, nil)
+	if _, err := pt.uc.FinishLogin(begin.CeremonyID, bytes.NewReader(body), session.ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same response (or another one for the same challenge) can't open a second session
+	if _, err := pt.uc.FinishLogin(begin.CeremonyID, bytes.NewReader(body), session.ClientInfo{}); !errors.Is(err, passkey.ErrCeremonyNotFound) {
+		t.Errorf("replayed ceremony: got %v, want %v", err, passkey.ErrCeremonyNotFound)
+	}
+}
+
+func TestPasskeyLoginDeactivatedUser(t *testing.T) {
+	pt := newPasskeyTest(t)
+	pt.register(t)
+	pt.userRepo.users[0].Active = false
+
+	if _, err := pt.login(t, nil); err == nil {
+		t.Error("deactivated user signed in with its passkey")
+	}
+}
+
+func TestPasskeyRegistrationRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *ceremony)
+	}{
+		{"challenge of another ceremony", func(c *ceremony) { c.Challenge = make([]byte, 32) }},
+		{"another origin", func(c *ceremony) { c.Origin = "https://evil.example.com" }},
+		{"another relying party", func(c *ceremony) { c.RPID = "evil.example.com" }},
+		{"without user verification", func(c *ceremony) { c.Flags = protocol.FlagUserPresent | protocol.FlagAttestedCredentialData }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := newPasskeyTest(t)
+			begin, err := pt.uc.BeginRegistration(pt.usr.Login)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := pt.auth.register(t, begin.Options, tt.modify)
+			if _, err := pt.uc.FinishRegistration(pt.usr.Login, begin.CeremonyID, "", bytes.NewReader(body)); !errors.Is(err, passkey.ErrInvalidCredential) {
+				t.Fatalf("got %v, want %v", err, passkey.ErrInvalidCredential)
+			}
+			if stored, _ := pt.passkeyRepo.GetByUserID(pt.usr.ID); len(stored) != 0 {
+				t.Error("passkey stored")
+			}
+		})
+	}
+}
+
+func TestPasskeyRegistrationCeremonyOfAnotherUser(t *testing.T) {
+	pt := newPasskeyTest(t)
+	other := pt.userRepo.add(&user.User{Login: "tech2", CompanyID: 20001, ProviderID: testLocalProviderID, Active: true})
+
+	begin, err := pt.uc.BeginRegistration(pt.usr.Login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := pt.auth.register(t, begin.Options, nil)
+	if _, err := pt.uc.FinishRegistration(other.Login, begin.CeremonyID, "", bytes.NewReader(body)); !errors.Is(err, passkey.ErrCeremonyNotFound) {
+		t.Errorf("got %v, want %v", err, passkey.ErrCeremonyNotFound)
+	}
+}
+
+func TestPasskeyRegistrationFederatedUser(t *testing.T) {
+	pt := newPasskeyTest(t)
+	federated := pt.userRepo.add(&user.User{Login: "tech@acme.com", CompanyID: 20001, ProviderID: 99, Active: true})
+
+	if _, err := pt.uc.BeginRegistration(federated.Login); !errors.Is(err, passkey.ErrProviderNotAllowed) {
+		t.Errorf("got %v, want %v", err, passkey.ErrProviderNotAllowed)
+	}
+}
@@ -0,0 +1,327 @@
+package application
+
+import (
+	"fmt"
+	"time"
+
+	"app/internal/domain/password"
+	"app/internal/domain/provider"
+	"app/internal/domain/role"
+	"app/internal/domain/user"
+	"app/internal/infrastructure/token/paseto"
+	"app/pkg/errorsLib"
+	"app/pkg/logger"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type PasswordUseCase struct {
+	passwordRepo   password.Repository
+	userRepo       user.Repository
+	historySize    int
+	changeTokenTTL time.Duration
+}
+
+func NewPasswordUseCase(passwordRepo password.Repository, userRepo user.Repository) *PasswordUseCase {
+	return &PasswordUseCase{
+		passwordRepo:   passwordRepo,
+		userRepo:       userRepo,
+		historySize:    5,
+		changeTokenTTL: 10 * time.Minute,
+	}
+}
+
+// SetHistorySize sets how many of the last passwords of a user (the current one included)
+// can't be reused. 0 disables the password history.
+func (uc *PasswordUseCase) SetHistorySize(n int) {
+	if n >= 0 {
+		uc.historySize = n
+	}
+}
+
+// SetChangeTokenTTL sets the lifetime of the token that only allows to change an expired password
+func (uc *PasswordUseCase) SetChangeTokenTTL(ttl time.Duration) {
+	if ttl > 0 {
+		uc.changeTokenTTL = ttl
+	}
+}
+
+// GetPolicy returns the password expiry of the company of the owner (passwords don't expire by default)
+func (uc *PasswordUseCase) GetPolicy(ownerUsername string) (*password.Policy, error) {
+	owner, err := uc.getUser(ownerUsername)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := uc.getPolicy(owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return &password.Policy{OwnerID: owner.ID}, nil
+	}
+	return policy, nil
+}
+
+// SetMaxAge sets the max age (in days) of the passwords of the owner and all its subusers (0: they don't expire).
+// Passwords older than that have to be changed on the next login.
+func (uc *PasswordUseCase) SetMaxAge(ownerUsername string, days int) (*password.Policy, error) {
+	if days < 0 || days > password.MaxAgeDaysLimit {
+		return nil, password.ErrInvalidMaxAge
+	}
+
+	owner, err := uc.getUser(ownerUsername)
+	if err != nil {
+		return nil, err
+	}
+	if owner.OwnerID != nil {
+		return nil, errorsLib.ErrForbidden
+	}
+
+	policy := &password.Policy{
+		OwnerID:    owner.ID,
+		MaxAgeDays: days,
+		UpdatedAt:  time.Now(),
+	}
+	if err := uc.passwordRepo.SavePolicy(policy); err != nil {
+		return nil, fmt.Errorf("error saving policy: %w", err)
+	}
+	return policy, nil
+}
+
+// changePassword checks the new password of a local user (password policy and history), saves it
+// and keeps the replaced one in the history. Sessions are not affected.
+func (uc *PasswordUseCase) changePassword(usr *user.User, plain string) error {
+	if err := uc.validateNewPassword(usr, plain); err != nil {
+		return err
+	}
+	return uc.savePassword(usr, plain)
+}
+
+// validateNewPassword checks the new password of a local user against the password policy and history
+func (uc *PasswordUseCase) validateNewPassword(usr *user.User, plain string) error {
+	if !isLocalProvider(usr.ProviderID) {
+		return password.ErrNotLocalUser
+	}
+
+	// The policy is checked first: the history check is far more expensive (bcrypt)
+	if err := usr.ValidatePassword(plain); err != nil {
+		return err
+	}
+	return uc.checkReuse(usr, plain)
+}
+
+// savePassword saves the new password (already validated) and keeps the replaced one in the history.
+// The recovery tokens issued before stop working.
+func (uc *PasswordUseCase) savePassword(usr *user.User, plain string) error {
+	oldHash := usr.Password
+	if err := uc.updatePassword(usr, plain); err != nil {
+		return err
+	}
+	uc.passwordSaved(usr, oldHash)
+	return nil
+}
+
+// updatePassword sets the new password (already validated) of the user and saves it
+func (uc *PasswordUseCase) updatePassword(usr *user.User, plain string) error {
+	if err := usr.SetPassword(plain); err != nil {
+		return err
+	}
+	if err := uc.userRepo.Update(usr); err != nil {
+		return fmt.Errorf("error updating user: %w", err)
+	}
+	return nil
+}
+
+// passwordSaved keeps the replaced password in the history and removes the recovery tokens of the user.
+// The password is already changed: a missing history entry or a leftover recovery token
+// (rejected anyway, it is older than the password) is not worth failing the change.
+func (uc *PasswordUseCase) passwordSaved(usr *user.User, oldHash *string) {
+	if oldHash != nil && uc.historySize > 1 {
+		entry := &password.HistoryEntry{
+			UserID:    usr.ID,
+			Hash:      *oldHash,
+			CreatedAt: time.Now(),
+		}
+		if err := uc.passwordRepo.AddHistory(entry, uc.historySize-1); err != nil {
+			logger.GetLogger().ServiceError("Error saving password history", map[string]interface{}{
+				"userId": usr.ID,
+				"error":  err.Error(),
+			})
+		}
+	}
+	if err := uc.passwordRepo.DeleteRecoveryTokens(usr.ID); err != nil {
+		logger.GetLogger().ServiceError("Error removing recovery tokens", map[string]interface{}{
+			"userId": usr.ID,
+			"error":  err.Error(),
+		})
+	}
+}
+
+// checkReuse rejects the current password and the previous ones kept in the history
+func (uc *PasswordUseCase) checkReuse(usr *user.User, plain string) error {
+	if uc.historySize <= 0 {
+		return nil
+	}
+
+	hashes := make([]string, 0, uc.historySize)
+	if usr.Password != nil {
+		hashes = append(hashes, *usr.Password)
+	}
+	history, err := uc.passwordRepo.GetHistory(usr.ID, uc.historySize-1)
+	if err != nil {
+		return fmt.Errorf("error retrieving password history: %w", err)
+	}
+	for _, entry := range history {
+		hashes = append(hashes, entry.Hash)
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil {
+			return &user.PasswordPolicyError{Violations: []user.PasswordViolation{{
+				Code:    user.PasswordReused,
+				Message: fmt.Sprintf("password can't be one of the last %d passwords", uc.historySize),
+			}}}
+		}
+	}
+	return nil
+}
+
+// isExpired checks if the password of a local user is older than the max age of its company
+func (uc *PasswordUseCase) isExpired(usr *user.User) (bool, error) {
+	if !isLocalProvider(usr.ProviderID) || usr.Password == nil {
+		return false, nil
+	}
+
+	// The policy is set by the company owner for all its users
+	ownerID := usr.ID
+	if usr.OwnerID != nil {
+		ownerID = *usr.OwnerID
+	}
+	policy, err := uc.getPolicy(ownerID)
+	if err != nil || policy == nil {
+		return false, err
+	}
+
+	setAt, ok := usr.PasswordSetAt()
+	if !ok {
+		return false, nil
+	}
+	return policy.IsExpired(setAt, time.Now()), nil
+}
+
+// checkExpiry sets the password change token on a user whose password is expired (see isExpired)
+func (uc *PasswordUseCase) checkExpiry(usr *user.User) (bool, error) {
+	expired, err := uc.isExpired(usr)
+	if err != nil || !expired {
+		return false, err
+	}
+
+	token, _, err := paseto.Paseto().GeneratePasswordExpiredToken(paseto.PasetoClaims{Username: usr.Login}, uc.changeTokenTTL)
+	if err != nil {
+		return false, fmt.Errorf("password change token generation error: %w", err)
+	}
+	usr.PasswordChangeToken = token
+	return true, nil
+}
+
+// issueRecoveryToken generates the password recovery token of the user and records it:
+// the tokens issued before stop working
+func (uc *PasswordUseCase) issueRecoveryToken(usr *user.User) (string, error) {
+	now := time.Now()
+	if err := uc.passwordRepo.DeleteExpiredRecoveryTokens(now); err != nil {
+		logger.GetLogger().ServiceWarn("Error removing expired recovery tokens", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+
+	token, claims, err := paseto.Paseto().GenerateRecoverToken(paseto.PasetoClaims{Username: usr.Login})
+	if err != nil {
+		return "", fmt.Errorf("recover token generation error: %w", err)
+	}
+
+	if err := uc.passwordRepo.ReplaceRecoveryToken(&password.RecoveryToken{
+		Nonce:     claims.TokenID,
+		UserID:    usr.ID,
+		CreatedAt: claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+	}); err != nil {
+		return "", fmt.Errorf("error saving recovery token: %w", err)
+	}
+	return token, nil
+}
+
+// recoveryTokenUser validates a recovery token against its record and returns the record and its user.
+// The token must be the newest of the user, not used and issued after the last change of the password.
+func (uc *PasswordUseCase) recoveryTokenUser(token string) (*password.RecoveryToken, *user.User, error) {
+	claims, err := paseto.Paseto().ValidateToken(token)
+	if err != nil || !claims.HasRole(role.RoleRecover) || claims.TokenID == "" {
+		return nil, nil, password.ErrInvalidRecoveryToken
+	}
+
+	rt, err := uc.passwordRepo.GetRecoveryToken(claims.TokenID)
+	if err != nil {
+		if uc.passwordRepo.IsNotFoundError(err) {
+			return nil, nil, password.ErrInvalidRecoveryToken
+		}
+		return nil, nil, fmt.Errorf("error retrieving recovery token: %w", err)
+	}
+
+	usr, err := uc.userRepo.GetByID(rt.UserID)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, nil, password.ErrInvalidRecoveryToken
+		}
+		return nil, nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if usr.Login != claims.Username || !rt.IsValid(usr.PasswordChangedAt, time.Now()) {
+		return nil, nil, password.ErrInvalidRecoveryToken
+	}
+	return rt, usr, nil
+}
+
+// resetPassword changes the password of the user of a recovery token (see recoveryTokenUser).
+// The token is used only once the new password is saved, and only one request can use it.
+func (uc *PasswordUseCase) resetPassword(rt *password.RecoveryToken, usr *user.User, plain string) error {
+	if err := uc.validateNewPassword(usr, plain); err != nil {
+		return err
+	}
+
+	oldHash := usr.Password
+	if err := uc.passwordRepo.ConsumeRecoveryToken(rt.Nonce, func() error {
+		return uc.updatePassword(usr, plain)
+	}); err != nil {
+		return err
+	}
+	uc.passwordSaved(usr, oldHash)
+	return nil
+}
+
+// getPolicy returns the password expiry of the owner or nil if it has none
+func (uc *PasswordUseCase) getPolicy(ownerID uint) (*password.Policy, error) {
+	policy, err := uc.passwordRepo.GetPolicy(ownerID)
+	if err != nil {
+		if uc.passwordRepo.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error retrieving policy: %w", err)
+	}
+	return policy, nil
+}
+
+// isLocalProvider reports whether the users of the provider have local credentials
+// (passwords and passkeys managed by this service), according to its identity provider
+func isLocalProvider(providerID uint) bool {
+	return provider.SupportsPasswordReset(providerID)
+}
+
+func (uc *PasswordUseCase) getUser(username string) (*user.User, error) {
+	usr, err := uc.userRepo.GetByLogin(username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, errorsLib.ErrNotFound
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	return usr, nil
+}
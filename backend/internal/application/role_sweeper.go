@@ -0,0 +1,62 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"app/internal/domain/role"
+	"app/pkg/logger"
+)
+
+// RoleGrantSweeper periodically removes the expired time-bound role grants
+type RoleGrantSweeper struct {
+	roleRepo role.RoleRepository
+	interval time.Duration
+}
+
+func NewRoleGrantSweeper(roleRepo role.RoleRepository, interval time.Duration) *RoleGrantSweeper {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &RoleGrantSweeper{
+		roleRepo: roleRepo,
+		interval: interval,
+	}
+}
+
+// Start runs the sweeper in a separate goroutine until ctx is cancelled
+func (s *RoleGrantSweeper) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.Sweep()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep()
+			}
+		}
+	}()
+}
+
+// Sweep removes the expired grants once and logs what was removed
+func (s *RoleGrantSweeper) Sweep() {
+	removed, err := s.roleRepo.DeleteExpiredGrants(time.Now())
+	if err != nil {
+		logger.GetLogger().ServiceError("Error removing expired role grants", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	for _, grant := range removed {
+		logger.GetLogger().ServiceInfo("Expired role grant removed", map[string]interface{}{
+			"userId":     grant.UserID,
+			"roleId":     grant.RoleID,
+			"validUntil": grant.ValidUntil,
+		})
+	}
+}
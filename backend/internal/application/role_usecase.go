@@ -5,54 +5,103 @@ import (
 	"app/internal/domain/user"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // RoleUseCase - structure for processing business logic of roles
 type RoleUseCase struct {
-	roleRepo role.RoleRepository
-	userRepo user.Repository
+	roleRepo       role.RoleRepository
+	userRepo       user.Repository
+	permissionRepo role.PermissionRepository
+	authSvc        *role.AuthorizationService
+
+	// If true, assigning an unknown role fails instead of creating it
+	strictAssign bool
 }
 
 // NewRoleUseCase - constructor
-func NewRoleUseCase(roleRepo role.RoleRepository, userRepo user.Repository) *RoleUseCase {
+func NewRoleUseCase(roleRepo role.RoleRepository, userRepo user.Repository, permissionRepo role.PermissionRepository) *RoleUseCase {
 	return &RoleUseCase{
-		roleRepo: roleRepo,
-		userRepo: userRepo,
+		roleRepo:       roleRepo,
+		userRepo:       userRepo,
+		permissionRepo: permissionRepo,
+		authSvc:        role.NewAuthorizationService(roleRepo, permissionRepo),
 	}
 }
 
-// GetAllRoles - get all roles
-func (uc *RoleUseCase) GetAllRoles() ([]role.Role, error) {
-	return uc.roleRepo.GetAllRoles()
-}
+// SetStrictAssign - enable/disable the strict mode of AssignRolesToUser
+func (uc *RoleUseCase) SetStrictAssign(strict bool) { uc.strictAssign = strict }
 
-// GetRoleByID - get role by ID
-func (uc *RoleUseCase) GetRoleByID(id uint) (*role.Role, error) {
-	return uc.roleRepo.GetRoleByID(id)
+// GetAllRoles - get all roles visible to a company (the company_<id> roles of other companies are hidden)
+func (uc *RoleUseCase) GetAllRoles(companyID uint) ([]role.Role, error) {
+	allRoles, err := uc.roleRepo.GetAllRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make([]role.Role, 0, len(allRoles))
+	for _, r := range allRoles {
+		if isVisibleRole(r, companyID) {
+			visible = append(visible, r)
+		}
+	}
+	return visible, nil
 }
 
-// GetRolesByUsername - get roles by username
-func (uc *RoleUseCase) GetRolesByUsername(username string) ([]role.Role, error) {
-	usr, err := uc.userRepo.GetByLogin(username)
+// GetRoleByID - get role by ID (the company_<id> roles of other companies are not found)
+func (uc *RoleUseCase) GetRoleByID(companyID, id uint) (*role.Role, error) {
+	r, err := uc.roleRepo.GetRoleByID(id)
 	if err != nil {
-		if uc.userRepo.IsNotFoundError(err) {
-			return nil, fmt.Errorf("user not found")
+		if uc.roleRepo.IsNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %d", role.ErrRoleNotFound, id)
 		}
-		return nil, fmt.Errorf("error retrieving user: %w", err)
+		return nil, fmt.Errorf("error retrieving role: %w", err)
+	}
+	if !isVisibleRole(*r, companyID) {
+		return nil, fmt.Errorf("%w: %d", role.ErrRoleNotFound, id)
+	}
+	return r, nil
+}
+
+// GetRolesByUsername - get roles of the company owner or one of its subusers by username
+// (ownerUsername is the main username of the caller)
+func (uc *RoleUseCase) GetRolesByUsername(ownerUsername, username string) ([]role.Role, error) {
+	usr, err := uc.managedUser(ownerUsername, username)
+	if err != nil {
+		return nil, err
 	}
 
 	return uc.roleRepo.GetUserRoles(usr.ID)
 }
 
-// AssignRolesToUser - assign roles to user by username
-func (uc *RoleUseCase) AssignRolesToUser(username string, roleNames string) error {
-	// 1. Get user by username
-	usr, err := uc.userRepo.GetByLogin(username)
-	if err != nil {
-		if uc.userRepo.IsNotFoundError(err) {
-			return fmt.Errorf("user not found: %s", username)
+// AssignRolesToUser - assign roles to the company owner or one of its subusers by username
+// (ownerUsername is the main username of the caller)
+func (uc *RoleUseCase) AssignRolesToUser(ownerUsername, username string, roleNames string) error {
+	return uc.AssignRolesToUserWithValidity(ownerUsername, username, roleNames, nil, nil)
+}
+
+// AssignRolesToUserWithValidity - assign roles to the company owner or one of its subusers by username
+// for a time window. validFrom/validUntil may be nil (no lower/upper limit).
+func (uc *RoleUseCase) AssignRolesToUserWithValidity(ownerUsername, username string, roleNames string, validFrom, validUntil *time.Time) error {
+	timeBound := validFrom != nil || validUntil != nil
+	if validUntil != nil {
+		if !validUntil.After(time.Now()) {
+			return fmt.Errorf("invalid validity window: validUntil must be in the future")
 		}
-		return fmt.Errorf("error retrieving user: %w", err)
+		if validFrom != nil && !validUntil.After(*validFrom) {
+			return fmt.Errorf("invalid validity window: validUntil must be after validFrom")
+		}
+	}
+
+	// The roles managed by the service (company, company_<id>...) are never assigned through the API
+	if err := checkAssignableRoles(roleNames); err != nil {
+		return err
+	}
+
+	// 1. Get user by username (only users of the company of the caller)
+	usr, err := uc.managedUser(ownerUsername, username)
+	if err != nil {
+		return err
 	}
 
 	// 2. Get existing user roles
@@ -88,7 +137,7 @@ func (uc *RoleUseCase) AssignRolesToUser(username string, roleNames string) erro
 		}
 
 		// Check if user already has this role
-		if existingRoleMap[roleName] {
+		if existingRoleMap[roleName] && !timeBound {
 			// User already has this role, skip
 			continue
 		}
@@ -96,6 +145,11 @@ func (uc *RoleUseCase) AssignRolesToUser(username string, roleNames string) erro
 		// Check if role exists in the system
 		roleID, exists := systemRoleMap[roleName]
 		if !exists {
+			// Strict mode: unknown roles are not created
+			if uc.strictAssign {
+				return fmt.Errorf("%w: %s", role.ErrRoleNotFound, roleName)
+			}
+
 			// Role doesn't exist, create it
 			newRole := &role.Role{
 				Role: roleName,
@@ -108,8 +162,8 @@ func (uc *RoleUseCase) AssignRolesToUser(username string, roleNames string) erro
 			}
 		}
 
-		// Assign role to user
-		if err := uc.roleRepo.AssignRoleToUser(usr.ID, roleID); err != nil {
+		// Assign role to user (replaces the window of an existing grant)
+		if err := uc.roleRepo.AssignRoleToUserWithValidity(usr.ID, roleID, validFrom, validUntil); err != nil {
 			return fmt.Errorf("error assigning role %s to user: %w", roleName, err)
 		}
 	}
@@ -117,15 +171,18 @@ func (uc *RoleUseCase) AssignRolesToUser(username string, roleNames string) erro
 	return nil
 }
 
-// EliminateRolesOfUser - remove roles from user by username
-func (uc *RoleUseCase) EliminateRolesOfUser(username string, roleNames string) error {
-	// 1. Get user by username
-	usr, err := uc.userRepo.GetByLogin(username)
+// EliminateRolesOfUser - remove roles from the company owner or one of its subusers by username
+// (ownerUsername is the main username of the caller)
+func (uc *RoleUseCase) EliminateRolesOfUser(ownerUsername, username string, roleNames string) error {
+	// The roles managed by the service (company, company_<id>...) are never removed through the API
+	if err := checkAssignableRoles(roleNames); err != nil {
+		return err
+	}
+
+	// 1. Get user by username (only users of the company of the caller)
+	usr, err := uc.managedUser(ownerUsername, username)
 	if err != nil {
-		if uc.userRepo.IsNotFoundError(err) {
-			return fmt.Errorf("user not found: %s", username)
-		}
-		return fmt.Errorf("error retrieving user: %w", err)
+		return err
 	}
 
 	// 2. Get existing user roles
@@ -163,3 +220,217 @@ func (uc *RoleUseCase) EliminateRolesOfUser(username string, roleNames string) e
 
 	return nil
 }
+
+// GetAllPermissions - get all permissions
+func (uc *RoleUseCase) GetAllPermissions() ([]role.Permission, error) {
+	return uc.permissionRepo.GetAllPermissions()
+}
+
+// GetPermissionsByUsername - get effective permissions of user by username
+func (uc *RoleUseCase) GetPermissionsByUsername(username string) ([]role.Permission, error) {
+	usr, err := uc.userRepo.GetByLogin(username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+
+	return uc.authSvc.GetUserPermissions(usr.ID)
+}
+
+// SetRoleParent - set the parent role of a role (empty parentName removes the parent).
+// The hierarchy of the system roles is fixed: they can't inherit, or be inherited by, other roles.
+func (uc *RoleUseCase) SetRoleParent(roleName, parentName string) error {
+	r, err := uc.getRoleByName(roleName)
+	if err != nil {
+		return err
+	}
+	if r.System || role.IsReservedRoleName(r.Role) {
+		return fmt.Errorf("%w: %s", role.ErrSystemRole, roleName)
+	}
+
+	if parentName == "" {
+		return uc.roleRepo.SetRoleParent(r.ID, nil)
+	}
+
+	parent, err := uc.getParentRole(parentName)
+	if err != nil {
+		return err
+	}
+
+	allRoles, err := uc.roleRepo.GetAllRoles()
+	if err != nil {
+		return fmt.Errorf("error getting all roles: %w", err)
+	}
+
+	if err := role.ValidateParent(r.ID, parent.ID, allRoles); err != nil {
+		return err
+	}
+
+	return uc.roleRepo.SetRoleParent(r.ID, &parent.ID)
+}
+
+// CreateRole - create a new (non-system) role
+func (uc *RoleUseCase) CreateRole(name, desc, parentName string) (*role.Role, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.Contains(name, ",") {
+		return nil, fmt.Errorf("invalid role name")
+	}
+	if role.IsReservedRoleName(name) {
+		return nil, fmt.Errorf("%w: %s", role.ErrReservedRoleName, name)
+	}
+
+	if _, err := uc.roleRepo.GetRoleByName(name); err == nil {
+		return nil, fmt.Errorf("%w: %s", role.ErrRoleAlreadyExists, name)
+	} else if !uc.roleRepo.IsNotFoundError(err) {
+		return nil, fmt.Errorf("error retrieving role: %w", err)
+	}
+
+	newRole := &role.Role{
+		Role: name,
+		Desc: desc,
+	}
+
+	if parentName != "" {
+		parent, err := uc.getParentRole(parentName)
+		if err != nil {
+			return nil, err
+		}
+		newRole.ParentID = &parent.ID
+	}
+
+	roleID, err := uc.roleRepo.CreateRole(newRole)
+	if err != nil {
+		return nil, fmt.Errorf("error creating role %s: %w", name, err)
+	}
+
+	return uc.roleRepo.GetRoleByID(roleID)
+}
+
+// UpdateRole - update the description of a role (allowed for system roles too)
+func (uc *RoleUseCase) UpdateRole(name, desc string) (*role.Role, error) {
+	r, err := uc.getRoleByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Desc = desc
+	if err := uc.roleRepo.UpdateRole(r); err != nil {
+		return nil, fmt.Errorf("error updating role %s: %w", name, err)
+	}
+
+	return uc.roleRepo.GetRoleByID(r.ID)
+}
+
+// RenameRole - rename a non-system role
+func (uc *RoleUseCase) RenameRole(name, newName string) (*role.Role, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" || strings.Contains(newName, ",") {
+		return nil, fmt.Errorf("invalid role name")
+	}
+
+	r, err := uc.getRoleByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if r.System {
+		return nil, fmt.Errorf("%w: %s", role.ErrSystemRole, name)
+	}
+	if role.IsReservedRoleName(newName) {
+		return nil, fmt.Errorf("%w: %s", role.ErrReservedRoleName, newName)
+	}
+
+	if _, err := uc.roleRepo.GetRoleByName(newName); err == nil {
+		return nil, fmt.Errorf("%w: %s", role.ErrRoleAlreadyExists, newName)
+	} else if !uc.roleRepo.IsNotFoundError(err) {
+		return nil, fmt.Errorf("error retrieving role: %w", err)
+	}
+
+	r.Role = newName
+	if err := uc.roleRepo.UpdateRole(r); err != nil {
+		return nil, fmt.Errorf("error renaming role %s: %w", name, err)
+	}
+
+	return uc.roleRepo.GetRoleByID(r.ID)
+}
+
+// DeleteRole - delete a non-system role (it's removed from all users)
+func (uc *RoleUseCase) DeleteRole(name string) error {
+	r, err := uc.getRoleByName(name)
+	if err != nil {
+		return err
+	}
+	if r.System {
+		return fmt.Errorf("%w: %s", role.ErrSystemRole, name)
+	}
+
+	if err := uc.roleRepo.DeleteRole(r.ID); err != nil {
+		return fmt.Errorf("error deleting role %s: %w", name, err)
+	}
+	return nil
+}
+
+// managedUser - get the company owner (ownerUsername) or one of its subusers by username.
+// The users of other companies are not found.
+func (uc *RoleUseCase) managedUser(ownerUsername, username string) (*user.User, error) {
+	owner, err := uc.userRepo.GetByLogin(ownerUsername)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, fmt.Errorf("user not found: %s", ownerUsername)
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+
+	usr, err := uc.userRepo.GetByLogin(username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, fmt.Errorf("user not found: %s", username)
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if !usr.IsManagedBy(owner) {
+		return nil, fmt.Errorf("user not found: %s", username)
+	}
+	return usr, nil
+}
+
+// isVisibleRole - the company_<id> roles are only visible to their company
+func isVisibleRole(r role.Role, companyID uint) bool {
+	return !role.IsCompanyIDRole(r.Role) || r.Role == fmt.Sprintf("company_%d", companyID)
+}
+
+// checkAssignableRoles - reject the reserved role names of a comma-separated list of roles
+func checkAssignableRoles(roleNames string) error {
+	for _, roleName := range strings.Split(roleNames, ",") {
+		roleName = strings.TrimSpace(roleName)
+		if role.IsReservedRoleName(roleName) {
+			return fmt.Errorf("%w: %s", role.ErrReservedRoleName, roleName)
+		}
+	}
+	return nil
+}
+
+// getParentRole - get the role to inherit from by name: system roles (e.g. company) can't be inherited
+func (uc *RoleUseCase) getParentRole(name string) (*role.Role, error) {
+	parent, err := uc.getRoleByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if parent.System || role.IsReservedRoleName(parent.Role) {
+		return nil, fmt.Errorf("%w: %s", role.ErrSystemRole, name)
+	}
+	return parent, nil
+}
+
+// getRoleByName - get role by name wrapping the not found error
+func (uc *RoleUseCase) getRoleByName(name string) (*role.Role, error) {
+	r, err := uc.roleRepo.GetRoleByName(name)
+	if err != nil {
+		if uc.roleRepo.IsNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", role.ErrRoleNotFound, name)
+		}
+		return nil, fmt.Errorf("error retrieving role: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,159 @@
+package application
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"app/internal/domain/role"
+)
+
+// newTestRoles returns the roles of the two companies of newTestCompanies: the system roles,
+// the company_<id> role of each company and a role created by the companies
+func newTestRoles() *memoryRoleRepository {
+	repo := &memoryRoleRepository{}
+	for _, name := range []string{role.RoleCompany, "company_20001", "company_20002", "technician"} {
+		repo.CreateRole(&role.Role{Role: name})
+	}
+	return repo
+}
+
+func TestGetAllRoles(t *testing.T) {
+	uc := NewRoleUseCase(newTestRoles(), newTestCompanies(), nil)
+
+	roles, err := uc.GetAllRoles(20001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range roles {
+		names = append(names, r.Role)
+	}
+	if got, want := strings.Join(names, ","), "company,company_20001,technician"; got != want {
+		t.Errorf("got roles %s, want %s", got, want)
+	}
+}
+
+func TestGetRoleByID(t *testing.T) {
+	uc := NewRoleUseCase(newTestRoles(), newTestCompanies(), nil)
+
+	tests := []struct {
+		name string
+		id   uint
+		want error
+	}{
+		{name: "shared role", id: 1},
+		{name: "role of the company", id: 2},
+		{name: "role of another company", id: 3, want: role.ErrRoleNotFound},
+		{name: "unknown role", id: 99, want: role.ErrRoleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := uc.GetRoleByID(20001, tt.id)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if tt.want != nil && r != nil {
+				t.Error("role of another company returned")
+			}
+		})
+	}
+}
+
+func TestAssignRolesToUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		roles    string
+		strict   bool
+		want     error
+		notFound bool // "user not found" error
+	}{
+		{name: "subuser of the company", username: "tech1", roles: "technician"},
+		{name: "owner itself", username: "acme", roles: "technician"},
+		{name: "new role", username: "tech1", roles: "inspector"},
+		{name: "new role in strict mode", username: "tech1", roles: "inspector", strict: true, want: role.ErrRoleNotFound},
+		{name: "owner of another company", username: "globex", roles: "technician", notFound: true},
+		{name: "subuser of another company", username: "tech2", roles: "technician", notFound: true},
+		{name: "company role", username: "tech1", roles: "technician,company", want: role.ErrReservedRoleName},
+		{name: "role of the company", username: "tech1", roles: "company_20001", want: role.ErrReservedRoleName},
+		{name: "role of another company", username: "tech1", roles: "company_20002", want: role.ErrReservedRoleName},
+		{name: "platform admin role", username: "tech1", roles: role.RolePlatformAdmin, want: role.ErrReservedRoleName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newTestCompanies()
+			roles := newTestRoles()
+			uc := NewRoleUseCase(roles, users, nil)
+			uc.SetStrictAssign(tt.strict)
+
+			err := uc.AssignRolesToUser("acme", tt.username, tt.roles)
+			switch {
+			case tt.notFound:
+				if err == nil || !strings.Contains(err.Error(), "user not found") {
+					t.Fatalf("got %v, want user not found", err)
+				}
+			case !errors.Is(err, tt.want):
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+
+			// Nothing is granted when the request fails
+			usr, _ := users.GetByLogin(tt.username)
+			granted := len(roles.grants[usr.ID])
+			if ok := err == nil; ok != (granted == 1) {
+				t.Errorf("got %d roles granted to %s", granted, tt.username)
+			}
+		})
+	}
+}
+
+func TestEliminateRolesOfUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		roles    string
+		want     error
+		notFound bool // "user not found" error
+	}{
+		{name: "subuser of the company", username: "tech1", roles: "technician"},
+		{name: "subuser of another company", username: "tech2", roles: "technician", notFound: true},
+		{name: "company role", username: "tech1", roles: "technician,company", want: role.ErrReservedRoleName},
+		{name: "role of the company", username: "tech1", roles: "company_20001", want: role.ErrReservedRoleName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newTestCompanies()
+			roles := newTestRoles()
+			usr, _ := users.GetByLogin(tt.username)
+			roles.AssignRoleToUser(usr.ID, 4) // technician
+			uc := NewRoleUseCase(roles, users, nil)
+
+			err := uc.EliminateRolesOfUser("acme", tt.username, tt.roles)
+			switch {
+			case tt.notFound:
+				if err == nil || !strings.Contains(err.Error(), "user not found") {
+					t.Fatalf("got %v, want user not found", err)
+				}
+			case !errors.Is(err, tt.want):
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+
+			// The role is only removed when the request succeeds
+			granted := len(roles.grants[usr.ID])
+			if ok := err == nil; ok != (granted == 0) {
+				t.Errorf("got %d roles granted to %s", granted, tt.username)
+			}
+		})
+	}
+}
+
+func TestGetRolesByUsername(t *testing.T) {
+	uc := NewRoleUseCase(newTestRoles(), newTestCompanies(), nil)
+
+	if _, err := uc.GetRolesByUsername("acme", "tech1"); err != nil {
+		t.Fatalf("subuser of the company: %v", err)
+	}
+	if _, err := uc.GetRolesByUsername("acme", "tech2"); err == nil || !strings.Contains(err.Error(), "user not found") {
+		t.Fatalf("subuser of another company: got %v, want user not found", err)
+	}
+}
@@ -0,0 +1,234 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"app/internal/domain/federation"
+	"app/internal/domain/internal_company"
+	"app/internal/domain/provider"
+	"app/internal/domain/saml"
+	"app/internal/domain/session"
+	"app/internal/domain/user"
+	"app/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+type SAMLUseCase struct {
+	samlRepo    saml.Repository
+	userRepo    user.Repository
+	companyRepo internal_company.Repository
+	authUC      *AuthUseCase
+	flowTimeout time.Duration
+}
+
+func NewSAMLUseCase(samlRepo saml.Repository, userRepo user.Repository, companyRepo internal_company.Repository, authUC *AuthUseCase) *SAMLUseCase {
+	return &SAMLUseCase{
+		samlRepo:    samlRepo,
+		userRepo:    userRepo,
+		companyRepo: companyRepo,
+		authUC:      authUC,
+		flowTimeout: 10 * time.Minute,
+	}
+}
+
+// SetFlowTimeout sets the time to come back from the identity provider and open the session once a login is started
+func (uc *SAMLUseCase) SetFlowTimeout(d time.Duration) {
+	if d > 0 {
+		uc.flowTimeout = d
+	}
+}
+
+// GetConnection returns the SAML identity provider of the company of the owner
+func (uc *SAMLUseCase) GetConnection(ownerUsername string) (*saml.Connection, error) {
+	owner, err := uc.companyOwner(ownerUsername)
+	if err != nil {
+		return nil, err
+	}
+	return uc.samlRepo.GetConnection(owner.CompanyID)
+}
+
+// SaveConnection sets the SAML identity provider of the company of the owner. Its users are created
+// on their first login as subusers of the owner.
+func (uc *SAMLUseCase) SaveConnection(ownerUsername string, conn *saml.Connection) (*saml.Connection, error) {
+	owner, err := uc.companyOwner(ownerUsername)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.IdPEntityID = strings.TrimSpace(conn.IdPEntityID)
+	conn.IdPSSOURL = strings.TrimSpace(conn.IdPSSOURL)
+	conn.LoginSuffix = strings.TrimSpace(conn.LoginSuffix)
+	if err := conn.Validate(); err != nil {
+		return nil, err
+	}
+	conn.CompanyID = owner.CompanyID
+	conn.OwnerID = owner.ID
+	conn.UpdatedAt = time.Now()
+
+	if err := uc.samlRepo.SaveConnection(conn); err != nil {
+		return nil, fmt.Errorf("error saving saml connection: %w", err)
+	}
+	return conn, nil
+}
+
+// Metadata returns the metadata of the service provider of the company (to set it up in its identity provider)
+func (uc *SAMLUseCase) Metadata(companyID uint) ([]byte, error) {
+	idp, _, err := uc.samlProvider()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.companyRepo.GetByID(companyID); err != nil {
+		if uc.companyRepo.IsNotFoundError(err) {
+			return nil, saml.ErrConnectionNotFound
+		}
+		return nil, fmt.Errorf("error retrieving company: %w", err)
+	}
+	return idp.Metadata(companyID)
+}
+
+// Start starts a login with the identity provider of the company (SP-initiated SSO). The flow ID is only
+// known by the client that started the login: only that client can open the session (login CSRF).
+func (uc *SAMLUseCase) Start(companyID uint) (*FederatedLogin, error) {
+	idp, _, err := uc.samlProvider()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := uc.enabledConnection(companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	relayState, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	authURL, requestID, err := idp.AuthnRequestURL(conn, relayState)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	flow := &saml.Flow{
+		ID:         uuid.New().String(),
+		CompanyID:  companyID,
+		RequestID:  requestID,
+		RelayState: relayState,
+		ExpiresAt:  now.Add(uc.flowTimeout),
+	}
+	if err := uc.samlRepo.CreateFlow(flow); err != nil {
+		return nil, fmt.Errorf("error saving saml login: %w", err)
+	}
+
+	// The abandoned logins are removed along the way
+	if err := uc.samlRepo.DeleteExpiredFlows(now); err != nil {
+		logger.GetLogger().ServiceWarn("Error deleting expired saml logins", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	return &FederatedLogin{FlowID: flow.ID, AuthorizationURL: authURL}, nil
+}
+
+// Assert validates the response of the identity provider (assertion consumer service) to the login of the
+// relay state, and creates or updates its user. The session is opened later by Complete.
+func (uc *SAMLUseCase) Assert(companyID uint, relayState, samlResponse string) error {
+	idp, providerID, err := uc.samlProvider()
+	if err != nil {
+		return err
+	}
+	conn, err := uc.enabledConnection(companyID)
+	if err != nil {
+		return err
+	}
+
+	flow, err := uc.samlRepo.GetPendingFlow(relayState, companyID, time.Now())
+	if err != nil {
+		if errors.Is(err, saml.ErrFlowNotFound) {
+			return err
+		}
+		return fmt.Errorf("error retrieving saml login: %w", err)
+	}
+
+	identity, err := idp.ParseResponse(conn, samlResponse, flow.RequestID)
+	if err != nil {
+		if errors.Is(err, federation.ErrLoginRejected) {
+			logger.GetLogger().ServiceWarn("SAML login rejected", map[string]interface{}{
+				"companyId": companyID,
+				"error":     err.Error(),
+			})
+		}
+		return err
+	}
+
+	usr, err := uc.authUC.SaveFederatedUser(identity, idp, providerID)
+	if err != nil {
+		return err
+	}
+	if err := uc.samlRepo.CompleteFlow(flow.ID, usr.ID); err != nil {
+		if errors.Is(err, saml.ErrFlowNotFound) {
+			return err
+		}
+		return fmt.Errorf("error completing saml login: %w", err)
+	}
+	return nil
+}
+
+// Complete opens the session of the user of a login accepted by Assert, like Login (the user is returned
+// with an MFA token if the login needs a second factor). The flow can only be used once.
+func (uc *SAMLUseCase) Complete(companyID uint, flowID string, client session.ClientInfo) (*user.User, error) {
+	flow, err := uc.samlRepo.ConsumeFlow(flowID, companyID, time.Now())
+	if err != nil {
+		if errors.Is(err, saml.ErrFlowNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error retrieving saml login: %w", err)
+	}
+	if flow.UserID == nil {
+		return nil, saml.ErrFlowNotCompleted
+	}
+
+	usr, err := uc.userRepo.GetByID(*flow.UserID)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, saml.ErrFlowNotFound
+		}
+		return nil, fmt.Errorf("get user error: %w", err)
+	}
+	return uc.authUC.LoginFederatedUser(usr, client)
+}
+
+// samlProvider returns the registered SAML identity provider and its ID (ErrNotFederated if SAML is disabled)
+func (uc *SAMLUseCase) samlProvider() (provider.SAMLProvider, uint, error) {
+	idp, providerID, err := provider.LookupByName(provider.NameSAML)
+	if err != nil {
+		return nil, 0, federation.ErrNotFederated
+	}
+	samlIdP, ok := idp.(provider.SAMLProvider)
+	if !ok {
+		return nil, 0, federation.ErrNotFederated
+	}
+	return samlIdP, providerID, nil
+}
+
+// enabledConnection returns the identity provider of the company if its SAML login is enabled
+func (uc *SAMLUseCase) enabledConnection(companyID uint) (*saml.Connection, error) {
+	conn, err := uc.samlRepo.GetConnection(companyID)
+	if err != nil {
+		if errors.Is(err, saml.ErrConnectionNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error retrieving saml connection: %w", err)
+	}
+	if !conn.Enabled {
+		return nil, saml.ErrConnectionDisabled
+	}
+	return conn, nil
+}
+
+// companyOwner returns the owner of an internal company (the SAML logins are only available for them)
+func (uc *SAMLUseCase) companyOwner(ownerUsername string) (*user.User, error) {
+	return internalCompanyOwner(uc.userRepo, uc.companyRepo, ownerUsername, saml.ErrNotInternalCompany)
+}
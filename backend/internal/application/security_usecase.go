@@ -0,0 +1,41 @@
+package application
+
+import (
+	"time"
+
+	"app/internal/domain/security"
+	"app/pkg/logger"
+)
+
+// SecurityEventUseCase stores and logs security events
+type SecurityEventUseCase struct {
+	repo security.Repository
+}
+
+func NewSecurityEventUseCase(repo security.Repository) *SecurityEventUseCase {
+	return &SecurityEventUseCase{repo: repo}
+}
+
+// Emit logs the event and stores it. Storage errors are only logged,
+// a security event must never break the flow that emits it.
+func (uc *SecurityEventUseCase) Emit(event security.Event) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	fields := map[string]interface{}{
+		"type":      event.Type,
+		"username":  event.Username,
+		"sessionId": event.SessionID,
+		"ip":        event.IP,
+		"details":   event.Details,
+	}
+	logger.GetLogger().ServiceWarn("Security event", fields)
+
+	if err := uc.repo.Create(&event); err != nil {
+		logger.GetLogger().ServiceError("Error storing security event", map[string]interface{}{
+			"type":  event.Type,
+			"error": err.Error(),
+		})
+	}
+}
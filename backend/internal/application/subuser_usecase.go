@@ -2,15 +2,15 @@ package application
 
 import (
 	"app/internal/application/ports"
+	"app/internal/domain/provider"
 	"app/internal/domain/role"
 	"app/internal/domain/user"
+	"app/internal/domain/user_identity"
 	"fmt"
 	"net/http"
 	"time"
 )
 
-const PROVIDER_SECONDARY = 3
-
 type SubUserUseCase struct {
 	userRepo          user.Repository
 	roleRepo          role.RoleRepository
@@ -27,7 +27,10 @@ func NewSubUserUseCase(userRepo user.Repository, roleRepo role.RoleRepository, v
 	}
 }
 
-// CreateSubUser creates a subuser for a given main user's username
+// CreateSubUser creates a subuser for a given main user's username.
+// A login that exists in Verificaciones (e.g. a technician) is rejected: only the technician can link it to
+// the subuser (created with another login), proving it with its credentials (POST /auth/identities/link).
+// Without subPassword the subuser gets a random password that meets the password policy.
 func (uc *SubUserUseCase) CreateSubUser(mainUsername, subUsername, subPassword, roles, email string) (*user.User, error) {
 
 	// Check if user exists in verificaciones
@@ -36,7 +39,7 @@ func (uc *SubUserUseCase) CreateSubUser(mainUsername, subUsername, subPassword,
 		return nil, fmt.Errorf("error checking if user exists in verificaciones: %w", err)
 	}
 	if exists {
-		return nil, fmt.Errorf("user %s already exists in verificaciones", subUsername)
+		return nil, fmt.Errorf("%w: %s", user_identity.ErrProviderLogin, subUsername)
 	}
 
 	// Start a new transaction
@@ -60,7 +63,12 @@ func (uc *SubUserUseCase) CreateSubUser(mainUsername, subUsername, subPassword,
 		return nil, fmt.Errorf("error retrieving main user: %w", err)
 	}
 
-	// 2. Create subuser
+	// 2. Create subuser (local password, secondary provider)
+	providerID, err := provider.IDByName(provider.NameSecondary)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 	subUser := &user.User{
 		Login:       subUsername,
 		OwnerID:     &mainUser.ID,
@@ -69,13 +77,18 @@ func (uc *SubUserUseCase) CreateSubUser(mainUsername, subUsername, subPassword,
 		CompanyID:   mainUser.CompanyID,
 		CompanyName: mainUser.CompanyName,
 
-		ProviderID: uint(PROVIDER_SECONDARY),
+		ProviderID: providerID,
 		CreatedAt:  time.Now().Format(time.RFC3339),
 		LastAccess: time.Now().Format(time.RFC3339),
 	}
 
-	// Set password for subuser
-	if err := subUser.SetPassword(subPassword); err != nil {
+	// Set password for subuser (a random one that meets the password policy if it is empty)
+	if subPassword == "" {
+		_, err = subUser.SetRandomPassword()
+	} else {
+		err = subUser.SetPassword(subPassword)
+	}
+	if err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("error setting password for subuser: %w", err)
 	}
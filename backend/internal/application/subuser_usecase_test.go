@@ -0,0 +1,34 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"app/internal/application/ports"
+	"app/internal/domain/user_identity"
+)
+
+// fakeVerificacionesService — logins of the users of Verificaciones
+type fakeVerificacionesService struct {
+	ports.VerificacionesService
+	logins map[string]bool
+}
+
+func (s *fakeVerificacionesService) CheckIfUserExists(username string) (bool, error) {
+	return s.logins[username], nil
+}
+
+func TestCreateSubUserVerificacionesLogin(t *testing.T) {
+	users := newTestCompanies()
+	roles := newTestRoles()
+	uc := NewSubUserUseCase(users, roles, &fakeVerificacionesService{logins: map[string]bool{"bob": true}})
+
+	// The login of a technician of Verificaciones is never taken (nor linked) by the subuser of a company:
+	// the technician links it to its subuser with its own credentials
+	if _, err := uc.CreateSubUser("acme", "bob", "Correct-Horse-7", "", ""); !errors.Is(err, user_identity.ErrProviderLogin) {
+		t.Fatalf("got %v, want %v", err, user_identity.ErrProviderLogin)
+	}
+	if _, err := users.GetByLogin("bob"); err == nil {
+		t.Error("subuser created")
+	}
+}
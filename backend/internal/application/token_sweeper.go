@@ -0,0 +1,60 @@
+package application
+
+import (
+	"context"
+	"time"
+
+	"app/internal/infrastructure/token/paseto"
+	"app/pkg/logger"
+)
+
+// RevokedTokenSweeper periodically removes the revocation entries of the tokens that already expired
+type RevokedTokenSweeper struct {
+	revocations paseto.RevocationList
+	interval    time.Duration
+}
+
+func NewRevokedTokenSweeper(revocations paseto.RevocationList, interval time.Duration) *RevokedTokenSweeper {
+	if interval <= 0 {
+		interval = 10 * time.Minute
+	}
+	return &RevokedTokenSweeper{
+		revocations: revocations,
+		interval:    interval,
+	}
+}
+
+// Start runs the sweeper in a separate goroutine until ctx is cancelled
+func (s *RevokedTokenSweeper) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.Sweep()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Sweep()
+			}
+		}
+	}()
+}
+
+// Sweep removes the expired entries once
+func (s *RevokedTokenSweeper) Sweep() {
+	removed, err := s.revocations.DeleteExpired(time.Now())
+	if err != nil {
+		logger.GetLogger().ServiceError("Error removing expired revoked tokens", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if removed > 0 {
+		logger.GetLogger().ServiceInfo("Expired revoked tokens removed", map[string]interface{}{
+			"count": removed,
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"app/internal/domain/provider"
+	"app/internal/domain/security"
+	"app/internal/domain/user"
+	"app/internal/domain/user_identity"
+	"app/pkg/errorsLib"
+)
+
+// UserIdentityUseCase — identities of other providers linked to the users (account linking):
+// a user can sign in with its own login or with any of its linked identities
+type UserIdentityUseCase struct {
+	identityRepo user_identity.Repository
+	userRepo     user.Repository
+	authUC       *AuthUseCase
+}
+
+func NewUserIdentityUseCase(identityRepo user_identity.Repository, userRepo user.Repository, authUC *AuthUseCase) *UserIdentityUseCase {
+	return &UserIdentityUseCase{
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		authUC:       authUC,
+	}
+}
+
+// List returns the identities linked to the user
+func (uc *UserIdentityUseCase) List(username string) ([]*user_identity.UserIdentity, error) {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+	identities, err := uc.identityRepo.GetByUserID(usr.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving linked identities: %w", err)
+	}
+	return identities, nil
+}
+
+// Link links the login of another provider to the user. The user confirms its current password
+// and proves the identity with its credentials at the provider (both attempts are limited like
+// the logins, ip of the client may be empty). Only the providers that check passwords can be
+// linked: the local users, and the users of federated providers (OIDC, SAML), sign in in their own way.
+// With merge, a login that already has a user of its own at the provider (created on its first login,
+// e.g. a technician of Verificaciones that also has a local account) is merged: that user is removed
+// and its login linked to the user. Users that own subusers are never merged.
+func (uc *UserIdentityUseCase) Link(username, currentPassword, providerName, login, password, ip string, merge bool) (*user_identity.UserIdentity, error) {
+	usr, err := uc.activeUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.authUC.Reauthenticate(usr, currentPassword, ip); err != nil {
+		return nil, err
+	}
+
+	idp, providerID, err := provider.LookupByName(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if !isLinkable(idp) {
+		return nil, user_identity.ErrNotLinkable
+	}
+
+	// Credentials of the identity at the provider
+	login = strings.TrimSpace(login)
+	if err := uc.authUC.lockoutUC.Check(login, ip); err != nil {
+		return nil, err
+	}
+	identity, err := idp.Authenticate(login, password, nil)
+	if err != nil {
+		if errors.Is(err, provider.ErrUnknownUser) || errors.Is(err, provider.ErrInvalidCredentials) {
+			uc.authUC.lockoutUC.RecordFailure(login, ip)
+		}
+		return nil, err
+	}
+
+	// A login is either the login of a user or an identity linked to one user
+	merged, err := uc.checkSubject(usr, providerID, identity.Login, merge)
+	if err != nil {
+		return nil, err
+	}
+	exists, err := uc.identityRepo.Exists(providerID, identity.Login)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving linked identity: %w", err)
+	}
+	if exists {
+		return nil, user_identity.ErrIdentityLinked
+	}
+
+	link := &user_identity.UserIdentity{
+		UserID:       usr.ID,
+		ProviderID:   providerID,
+		ProviderName: idp.Name(),
+		Subject:      identity.Login,
+		CreatedAt:    time.Now(),
+	}
+	if merged == nil {
+		err = uc.identityRepo.Create(link)
+	} else {
+		// The tokens of the merged user die with it
+		if err := uc.authUC.revokeAllSessions(merged.ID, ""); err != nil {
+			return nil, err
+		}
+		err = uc.identityRepo.CreateMerging(link, merged.ID)
+	}
+	if err != nil {
+		if uc.identityRepo.IsAlreadyExistsError(err) {
+			return nil, user_identity.ErrIdentityLinked
+		}
+		return nil, fmt.Errorf("error saving linked identity: %w", err)
+	}
+
+	if merged != nil {
+		uc.authUC.securitySvc.Emit(security.Event{
+			Type:     security.EventAccountMerged,
+			UserID:   &usr.ID,
+			Username: usr.Login,
+			IP:       ip,
+			Details:  fmt.Sprintf("user %s (%d) merged into %s", merged.Login, merged.ID, usr.Login),
+		})
+	}
+
+	uc.authUC.securitySvc.Emit(security.Event{
+		Type:     security.EventIdentityLinked,
+		UserID:   &usr.ID,
+		Username: usr.Login,
+		IP:       ip,
+		Details:  fmt.Sprintf("%s identity %s linked", idp.Name(), identity.Login),
+	})
+	return link, nil
+}
+
+// Unlink removes an identity linked to the user, after confirming its current password
+// (the attempts are limited like the logins, ip of the client may be empty)
+func (uc *UserIdentityUseCase) Unlink(username, currentPassword string, id uint, ip string) error {
+	usr, err := uc.activeUser(username)
+	if err != nil {
+		return err
+	}
+	if err := uc.authUC.Reauthenticate(usr, currentPassword, ip); err != nil {
+		return err
+	}
+
+	if err := uc.identityRepo.Delete(id, usr.ID); err != nil {
+		if errors.Is(err, user_identity.ErrIdentityNotFound) {
+			return err
+		}
+		return fmt.Errorf("error deleting linked identity: %w", err)
+	}
+
+	uc.authUC.securitySvc.Emit(security.Event{
+		Type:     security.EventIdentityUnlinked,
+		UserID:   &usr.ID,
+		Username: usr.Login,
+		IP:       ip,
+		Details:  fmt.Sprintf("linked identity %d removed", id),
+	})
+	return nil
+}
+
+// checkSubject checks the login of the provider as an identity of the user. The login of the user itself
+// is only linked at another provider (same login at both). The login of another user is rejected, unless
+// it is merged: that user is returned when it is a user of the provider that owns no subusers.
+func (uc *UserIdentityUseCase) checkSubject(usr *user.User, providerID uint, subject string, merge bool) (*user.User, error) {
+	other, err := uc.userRepo.GetByLogin(subject)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if other.ID == usr.ID {
+		if usr.ProviderID == providerID {
+			return nil, user_identity.ErrPrimaryIdentity
+		}
+		return nil, nil
+	}
+
+	if !merge || other.ProviderID != providerID {
+		return nil, user_identity.ErrIdentityInUse
+	}
+	subUsers, err := uc.userRepo.GetByOwnerID(other.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving subusers: %w", err)
+	}
+	if len(subUsers) > 0 {
+		return nil, user_identity.ErrIdentityInUse
+	}
+	return other, nil
+}
+
+func (uc *UserIdentityUseCase) activeUser(username string) (*user.User, error) {
+	usr, err := uc.getUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if !usr.Active {
+		return nil, errorsLib.ErrForbidden
+	}
+	return usr, nil
+}
+
+func (uc *UserIdentityUseCase) getUser(username string) (*user.User, error) {
+	usr, err := uc.userRepo.GetByLogin(username)
+	if err != nil {
+		if uc.userRepo.IsNotFoundError(err) {
+			return nil, errorsLib.ErrNotFound
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	return usr, nil
+}
+
+// isLinkable reports whether the identities of the provider can be linked to a user: providers that check
+// the password of their users, other than the local users (their login is always a user of its own)
+func isLinkable(idp provider.IdentityProvider) bool {
+	if _, ok := idp.(provider.FederatedProvider); ok {
+		return false
+	}
+	if _, ok := idp.(provider.SAMLProvider); ok {
+		return false
+	}
+	return !idp.SupportsPasswordReset()
+}
@@ -3,6 +3,7 @@ package application
 import (
 	"app/internal/application/ports"
 	"app/internal/domain/internal_company"
+	"app/internal/domain/provider"
 	"app/internal/domain/user"
 	"app/pkg/errorsLib"
 	"fmt"
@@ -23,8 +24,23 @@ func NewUserUseCase(r user.Repository, internalCompanyRepo internal_company.Repo
 	return &UserUseCase{repo: r, internalCompanyRepo: internalCompanyRepo, verificacionesSvc: verificacionesSvc}
 }
 
-func (uc *UserUseCase) GetUserByID(id uint) (*user.User, error) {
-	return uc.repo.GetByID(id)
+// GetUserByID returns the company owner or one of its subusers (ownerUsername is the main username of the caller)
+func (uc *UserUseCase) GetUserByID(ownerUsername string, id uint) (*user.User, error) {
+	owner, err := uc.getUser(ownerUsername)
+	if err != nil {
+		return nil, err
+	}
+	usr, err := uc.repo.GetByID(id)
+	if err != nil {
+		if uc.repo.IsNotFoundError(err) {
+			return nil, errorsLib.ErrNotFound
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	if !usr.IsManagedBy(owner) {
+		return nil, errorsLib.ErrNotFound
+	}
+	return usr, nil
 }
 
 func (uc *UserUseCase) CheckIfUserIsCompany(login string) (bool, error) {
@@ -70,12 +86,20 @@ func (uc *UserUseCase) GetUserAndSubUsersByOwnerUsername(ownerUsername string) (
 	return mainUser, subUsers, nil
 }
 
-func (uc *UserUseCase) ActivateDeactivateUser(username string, active bool) error {
-
-	user, err := uc.repo.GetByLogin(username)
+// ActivateDeactivateUser activates or deactivates the company owner or one of its subusers
+// (ownerUsername is the main username of the caller)
+func (uc *UserUseCase) ActivateDeactivateUser(ownerUsername, username string, active bool) error {
+	owner, err := uc.getUser(ownerUsername)
+	if err != nil {
+		return err
+	}
+	user, err := uc.getUser(username)
 	if err != nil {
 		return err
 	}
+	if !user.IsManagedBy(owner) {
+		return errorsLib.ErrNotFound
+	}
 
 	switch active {
 	case true:
@@ -87,6 +111,17 @@ func (uc *UserUseCase) ActivateDeactivateUser(username string, active bool) erro
 	return err
 }
 
+func (uc *UserUseCase) getUser(username string) (*user.User, error) {
+	usr, err := uc.repo.GetByLogin(username)
+	if err != nil {
+		if uc.repo.IsNotFoundError(err) {
+			return nil, errorsLib.ErrNotFound
+		}
+		return nil, fmt.Errorf("error retrieving user: %w", err)
+	}
+	return usr, nil
+}
+
 func (uc *UserUseCase) activateUser(userID uint) error {
 	return uc.repo.UpdateActiveStatus(userID, true)
 }
@@ -96,6 +131,11 @@ func (uc *UserUseCase) deactivateUser(userID uint) error {
 }
 
 func (uc *UserUseCase) RegisterCompanyUser(username, password, companyName string) (*user.User, error) {
+	// Check the password before anything is created
+	if err := (&user.User{Login: username, CompanyName: companyName}).ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
 	tx := uc.repo.BeginTransaction()
 	defer tx.Rollback()
 
@@ -130,11 +170,15 @@ func (uc *UserUseCase) RegisterCompanyUser(username, password, companyName strin
 		return nil, fmt.Errorf("error creating company: %w", err)
 	}
 
-	// Create new user
+	// Create new user (local password, liftel provider)
+	providerID, err := provider.IDByName(provider.NameLiftel)
+	if err != nil {
+		return nil, err
+	}
 	newUser := &user.User{
 		Login:        username,
-		ProviderID:   1, // Assuming 1 is the ID for liftel
-		ProviderName: "Liftel",
+		ProviderID:   providerID,
+		ProviderName: provider.NameLiftel,
 		OwnerID:      nil,
 		CompanyID:    company.ID,
 		CompanyName:  company.Name,
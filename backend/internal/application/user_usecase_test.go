@@ -0,0 +1,86 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"app/internal/domain/user"
+	"app/pkg/errorsLib"
+)
+
+// newTestCompanies returns the users of two companies: the owner "acme" with its subuser "tech1"
+// and the owner "globex" with its subuser "tech2"
+func newTestCompanies() *memoryUserRepository {
+	repo := &memoryUserRepository{}
+	acme := repo.add(&user.User{Login: "acme", CompanyID: 20001, CompanyName: "Acme", Active: true})
+	repo.add(&user.User{Login: "tech1", CompanyID: 20001, CompanyName: "Acme", OwnerID: &acme.ID, Active: true})
+	globex := repo.add(&user.User{Login: "globex", CompanyID: 20002, CompanyName: "Globex", Active: true})
+	repo.add(&user.User{Login: "tech2", CompanyID: 20002, CompanyName: "Globex", OwnerID: &globex.ID, Active: true})
+	return repo
+}
+
+func TestGetUserByID(t *testing.T) {
+	repo := newTestCompanies()
+	uc := NewUserUseCase(repo, nil, nil)
+
+	tests := []struct {
+		name  string
+		owner string
+		id    uint
+		want  error
+	}{
+		{name: "owner itself", owner: "acme", id: 1},
+		{name: "subuser of the company", owner: "acme", id: 2},
+		{name: "owner of another company", owner: "acme", id: 3, want: errorsLib.ErrNotFound},
+		{name: "subuser of another company", owner: "acme", id: 4, want: errorsLib.ErrNotFound},
+		{name: "unknown user", owner: "acme", id: 99, want: errorsLib.ErrNotFound},
+		{name: "unknown caller", owner: "nobody", id: 1, want: errorsLib.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usr, err := uc.GetUserByID(tt.owner, tt.id)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && usr.ID != tt.id {
+				t.Errorf("got user %d, want %d", usr.ID, tt.id)
+			}
+			if tt.want != nil && usr != nil {
+				t.Error("user of another company returned")
+			}
+		})
+	}
+}
+
+func TestActivateDeactivateUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		owner    string
+		username string
+		want     error
+	}{
+		{name: "subuser of the company", owner: "acme", username: "tech1"},
+		{name: "owner itself", owner: "acme", username: "acme"},
+		{name: "owner of another company", owner: "acme", username: "globex", want: errorsLib.ErrNotFound},
+		{name: "subuser of another company", owner: "acme", username: "tech2", want: errorsLib.ErrNotFound},
+		{name: "unknown user", owner: "acme", username: "nobody", want: errorsLib.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestCompanies()
+			uc := NewUserUseCase(repo, nil, nil)
+
+			if err := uc.ActivateDeactivateUser(tt.owner, tt.username, false); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+
+			// Only the users of the caller's company change
+			for _, u := range repo.users {
+				deactivated := tt.want == nil && u.Login == tt.username
+				if u.Active == deactivated {
+					t.Errorf("user %s active %v", u.Login, u.Active)
+				}
+			}
+		})
+	}
+}
@@ -3,10 +3,14 @@ package composition
 // Application initialization
 
 func Run() {
-	config_init() // TODO Initialize configuration
-	email_init()  // TODO Initialize email
-	db_init()     // TODO Initialize database
-	http_init()   // TODO Initialize HTTP server
+	config_init()   // TODO Initialize configuration
+	email_init()    // TODO Initialize email
+	password_init() // Initialize password policy
+	db_init()       // TODO Initialize database
+	identity_init() // Initialize identity providers
+	token_init()    // Initialize token revocation
+	jobs_init()     // Initialize background jobs
+	http_init()     // TODO Initialize HTTP server
 
 	select {}
 }
@@ -1,10 +1,19 @@
 package composition
 
 import (
+	"app/internal/application"
+	"app/internal/domain/lockout"
+	"app/internal/domain/user"
 	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/identity"
+	"app/internal/infrastructure/repositories"
+	"app/internal/infrastructure/token/paseto"
 	"app/internal/infrastructure/transport/email"
 	http "app/internal/infrastructure/transport/http/server"
+	"app/internal/infrastructure/webhooks/verificaciones"
 	"app/pkg/config"
+	"context"
+	"log"
 
 	"github.com/spf13/viper"
 )
@@ -19,6 +28,23 @@ func config_init() {
 	config.INIT(YAML_PATH)
 }
 
+func password_init() {
+	policy := user.DefaultPasswordPolicy()
+	if viper.IsSet("password_policy") {
+		policy = user.PasswordPolicy{
+			MinLength:        viper.GetInt("password_policy.min_length"),
+			MaxLength:        viper.GetInt("password_policy.max_length"),
+			RequireUppercase: viper.GetBool("password_policy.require_uppercase"),
+			RequireLowercase: viper.GetBool("password_policy.require_lowercase"),
+			RequireDigit:     viper.GetBool("password_policy.require_digit"),
+			RequireSymbol:    viper.GetBool("password_policy.require_symbol"),
+			DisallowUserInfo: viper.GetBool("password_policy.disallow_user_info"),
+			Blacklist:        viper.GetStringSlice("password_policy.blacklist"),
+		}
+	}
+	user.SetPasswordPolicy(policy)
+}
+
 func db_init() {
 	cfg := db.Config{}
 	cfg.Set(
@@ -35,10 +61,113 @@ func db_init() {
 	db.Initialize(cfg)
 }
 
+func identity_init() {
+	// Identity providers configured for the companies: LDAP / Active Directory directories,
+	// external OpenID Connect providers and the service provider of the SAML logins
+	var cfg identity.Config
+	if err := viper.UnmarshalKey("ldap.directories", &cfg.LDAP); err != nil {
+		log.Fatal("Error reading LDAP directories: ", err)
+	}
+	if err := viper.UnmarshalKey("oidc_federation.providers", &cfg.OIDC); err != nil {
+		log.Fatal("Error reading OIDC providers: ", err)
+	}
+	if err := viper.UnmarshalKey("saml", &cfg.SAML); err != nil {
+		log.Fatal("Error reading SAML config: ", err)
+	}
+
+	// Identity providers of the users, bound to the providers table by name
+	if err := identity.Initialize(
+		repositories.NewProviderRepository(),
+		repositories.NewUserRepository(),
+		repositories.NewSAMLRepository(),
+		verificaciones.NewVerificacionesClient(),
+		cfg,
+	); err != nil {
+		log.Fatal("Error initializing identity providers: ", err)
+	}
+}
+
+func token_init() {
+	// Signing keys (v4.public) and revoked access tokens and sessions are stored in the database
+	if err := paseto.Paseto().SetKeyStore(repositories.NewSigningKeyRepository()); err != nil {
+		log.Fatal("Error loading signing keys: ", err)
+	}
+	paseto.Paseto().SetRevocationList(repositories.NewRevokedTokenRepository())
+}
+
+// Use cases shared by the routes of several handlers: built once, so all the routes use the same config
+func usecases_init() http.UseCases {
+	mfaUseCase := application.NewMFAUseCase(repositories.NewMFARepository(), repositories.NewUserRepository())
+	mfaUseCase.SetIssuer(viper.GetString("mfa.issuer"))
+	mfaUseCase.SetPendingTokenTTL(viper.GetDuration("mfa.pending_token_ttl"))
+
+	lockoutUseCase := application.NewLockoutUseCase(repositories.NewLoginAttemptRepository(),
+		repositories.NewUserRepository(),
+		repositories.NewSecurityEventRepository())
+	lockoutUseCase.SetPolicy(lockout.Policy{
+		Window:              viper.GetDuration("lockout.window"),
+		MaxUsernameFailures: viper.GetInt("lockout.max_username_failures"),
+		MaxIPFailures:       viper.GetInt("lockout.max_ip_failures"),
+		LockoutDuration:     viper.GetDuration("lockout.duration"),
+		DelayBase:           viper.GetDuration("lockout.delay_base"),
+		DelayMax:            viper.GetDuration("lockout.delay_max"),
+	})
+
+	passwordUseCase := application.NewPasswordUseCase(repositories.NewPasswordRepository(), repositories.NewUserRepository())
+	passwordUseCase.SetHistorySize(viper.GetInt("password_policy.history"))
+	passwordUseCase.SetChangeTokenTTL(viper.GetDuration("password_policy.change_token_ttl"))
+
+	authUseCase := application.NewAuthUseCase(repositories.NewUserRepository(),
+		repositories.NewRoleRepository(),
+		repositories.NewSessionRepository(),
+		repositories.NewSecurityEventRepository(),
+		repositories.NewUserIdentityRepository(),
+		mfaUseCase,
+		lockoutUseCase,
+		passwordUseCase)
+	authUseCase.SetPasswordChangedEmail(viper.GetString("mail.password_changed.subject"),
+		viper.GetString("mail.password_changed.body"))
+
+	return http.UseCases{
+		Auth:     authUseCase,
+		MFA:      mfaUseCase,
+		Lockout:  lockoutUseCase,
+		Password: passwordUseCase,
+	}
+}
+
 func http_init() {
-	http.MustLoad()
+	http.MustLoad(usecases_init())
 }
 
 func email_init() {
 	email.Mail()
 }
+
+func jobs_init() {
+	// Removes expired time-bound role grants
+	application.NewRoleGrantSweeper(
+		repositories.NewRoleRepository(),
+		viper.GetDuration("roles.grants_sweeper_interval"),
+	).Start(context.Background())
+
+	// Rotates the signing keys of the access tokens
+	application.NewSigningKeyRotator(
+		paseto.Paseto().KeyRing(),
+		viper.GetDuration("token.keys.rotation_interval"),
+		viper.GetDuration("token.keys.check_interval"),
+	).Start(context.Background())
+
+	// Removes the expired failed login attempt counters
+	application.NewLoginAttemptSweeper(
+		repositories.NewLoginAttemptRepository(),
+		viper.GetDuration("lockout.window"),
+		viper.GetDuration("lockout.sweeper_interval"),
+	).Start(context.Background())
+
+	// Removes the revocation entries of the already expired tokens
+	application.NewRevokedTokenSweeper(
+		repositories.NewRevokedTokenRepository(),
+		viper.GetDuration("token.revocation_sweeper_interval"),
+	).Start(context.Background())
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidClient      = errors.New("invalid client")
+	ErrNotInternalCompany = errors.New("OAuth clients are only available for internal companies")
+)
+
+// Client — OAuth client (a service or application of a company).
+// Confidential clients authenticate with their secret; public clients (SPA, mobile apps)
+// have no secret and can only use the authorization code grant with PKCE.
+type Client struct {
+	ID           uint      `json:"-"`
+	ClientID     string    `json:"clientId"`
+	SecretHash   string    `json:"-"`
+	Name         string    `json:"name"`
+	CompanyID    uint      `json:"companyId"`
+	Public       bool      `json:"public"`
+	RedirectURIs []string  `json:"redirectUris"`
+	Scopes       []string  `json:"scopes"` // Scopes the client is allowed to request
+	Active       bool      `json:"active"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// GenerateSecret creates a new random secret for the client and sets its hash.
+// The plain secret is returned only once, it is never stored.
+func (c *Client) GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	c.SecretHash = string(hashed)
+	return secret, nil
+}
+
+// CheckSecret checks the plain secret against the stored hash
+func (c *Client) CheckSecret(plain string) bool {
+	if c.SecretHash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(c.SecretHash), []byte(plain)) == nil
+}
+
+// HasRedirectURI checks if the redirect URI is registered (exact match)
+func (c *Client) HasRedirectURI(uri string) bool {
+	for _, u := range c.RedirectURIs {
+		if u == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsScopes checks if all the requested scopes are allowed for the client
+func (c *Client) AllowsScopes(requested []string) bool {
+	for _, r := range requested {
+		if !containsScope(c.Scopes, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,10 @@
+package client
+
+type Repository interface {
+	Create(c *Client) error
+	GetByClientID(clientID string) (*Client, error)
+	GetByCompanyID(companyID uint) ([]*Client, error)
+	Delete(clientID string) error
+
+	IsNotFoundError(err error) bool
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrInvalidRedirectURI = errors.New("invalid redirect uri")
+	ErrInvalidScope       = errors.New("invalid scope")
+)
+
+// ParseScope splits a space-delimited scope string (RFC 6749 section 3.3), removing duplicates
+func ParseScope(scope string) []string {
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if !containsScope(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// FormatScope joins the scopes into a space-delimited scope string
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// ValidateScopes checks that the scope names are not empty and don't contain spaces or quotes
+func ValidateScopes(scopes []string) error {
+	for _, s := range scopes {
+		if s == "" || strings.ContainsAny(s, " \t\n\"\\") {
+			return ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+// ValidateRedirectURI checks that the URI is absolute, has no fragment and uses https
+// (http is only allowed for localhost, for development and native apps)
+func ValidateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+		return ErrInvalidRedirectURI
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if host := u.Hostname(); host == "localhost" || host == "127.0.0.1" || host == "::1" {
+			return nil
+		}
+	}
+	return ErrInvalidRedirectURI
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package federation
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrFlowNotFound    = errors.New("federated login not found or expired")
+	ErrNotFederated    = errors.New("the provider does not support federated login")
+	ErrLoginRejected   = errors.New("federated login rejected by the provider")
+	ErrIdentityInUse   = errors.New("the login belongs to a user of another provider")
+	ErrMissingIdentity = errors.New("the provider did not return the login of the user")
+)
+
+// Flow — federated login (authorization code flow with an external provider) between its start
+// and the callback of the provider. Each flow can only be finished once.
+type Flow struct {
+	ID           string // returned to the client that started the login, required to finish it
+	ProviderID   uint
+	State        string // sent to the provider, returned in the callback
+	Nonce        string // bound to the ID token
+	CodeVerifier string // PKCE
+	ExpiresAt    time.Time
+}
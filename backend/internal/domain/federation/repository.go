@@ -0,0 +1,11 @@
+package federation
+
+import "time"
+
+type Repository interface {
+	CreateFlow(f *Flow) error
+	// ConsumeFlow returns the flow and deletes it, so it can't be finished twice.
+	// Returns ErrFlowNotFound if it doesn't exist, belongs to another provider or is expired.
+	ConsumeFlow(id string, providerID uint, now time.Time) (*Flow, error)
+	DeleteExpiredFlows(now time.Time) error
+}
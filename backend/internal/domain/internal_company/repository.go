@@ -4,6 +4,7 @@ import "gorm.io/gorm"
 
 type Repository interface {
 	GetAll() ([]*InternalCompany, error)
+	GetByID(id uint) (*InternalCompany, error)
 	GetByName(name string) (*InternalCompany, error)
 	Create(companyName string) (*InternalCompany, error)
 
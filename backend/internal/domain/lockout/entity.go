@@ -0,0 +1,96 @@
+package lockout
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAccountLocked   = errors.New("account temporarily locked after too many failed login attempts")
+	ErrTooManyAttempts = errors.New("too many failed login attempts, try again later")
+	ErrNotLocked       = errors.New("account is not locked")
+)
+
+// Subjects of the failed attempt counters
+const (
+	SubjectUsername = "username"
+	SubjectIP       = "ip"
+)
+
+// Counter — failed login attempts of a username or an IP address within the window
+type Counter struct {
+	Subject       string
+	Key           string
+	Failures      int
+	FirstFailedAt time.Time // start of the window
+	LastFailedAt  time.Time
+	LockedUntil   *time.Time
+}
+
+// IsLocked checks if the subject is locked at the given time
+func (c *Counter) IsLocked(now time.Time) bool {
+	return c.LockedUntil != nil && now.Before(*c.LockedUntil)
+}
+
+// Policy — limits of the failed login attempts
+type Policy struct {
+	Window              time.Duration // failures older than the window are forgotten
+	MaxUsernameFailures int           // failures of a username before it is locked
+	MaxIPFailures       int           // failures from an IP address before it is locked
+	LockoutDuration     time.Duration
+	DelayBase           time.Duration // delay after the first failure of a username, doubled after each failure
+	DelayMax            time.Duration
+}
+
+// DefaultPolicy returns the limits used when they are not configured
+func DefaultPolicy() Policy {
+	return Policy{
+		Window:              15 * time.Minute,
+		MaxUsernameFailures: 5,
+		MaxIPFailures:       50,
+		LockoutDuration:     15 * time.Minute,
+		DelayBase:           time.Second,
+		DelayMax:            30 * time.Second,
+	}
+}
+
+// Delay returns the time to wait before the next attempt after the given number of failures
+func (p Policy) Delay(failures int) time.Duration {
+	if failures <= 0 || p.DelayBase <= 0 {
+		return 0
+	}
+	delay := p.DelayBase
+	for i := 1; i < failures && delay < p.DelayMax; i++ {
+		delay *= 2
+	}
+	if delay > p.DelayMax {
+		delay = p.DelayMax
+	}
+	return delay
+}
+
+// ThrottledError — login attempt rejected without checking the password
+// (ErrAccountLocked or ErrTooManyAttempts) and the time to wait before the next one
+type ThrottledError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string { return e.Err.Error() }
+func (e *ThrottledError) Unwrap() error { return e.Err }
+
+// RetryAfterSeconds returns the value of the Retry-After header (rounded up)
+func (e *ThrottledError) RetryAfterSeconds() string {
+	seconds := int64((e.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return fmt.Sprint(seconds)
+}
+
+// NormalizeUsername returns the key of the counter of a username
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
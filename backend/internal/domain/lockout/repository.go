@@ -0,0 +1,16 @@
+package lockout
+
+import "time"
+
+type Repository interface {
+	Get(subject, key string) (*Counter, error)
+	// RecordFailure counts a failed attempt and returns the counter. The counter starts again
+	// when its window (started before windowStart) or its lock are over.
+	RecordFailure(subject, key string, now, windowStart time.Time) (*Counter, error)
+	Lock(subject, key string, until time.Time) error
+	Reset(subject, key string) error
+	// DeleteExpired removes the counters not updated since before and not locked anymore
+	DeleteExpired(before, now time.Time) (int64, error)
+
+	IsNotFoundError(err error) bool
+}
@@ -0,0 +1,102 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCode       = errors.New("invalid verification code")
+	ErrNotEnrolled       = errors.New("two-factor authentication is not enabled")
+	ErrAlreadyEnrolled   = errors.New("two-factor authentication is already enabled")
+	ErrRequiredByOwner   = errors.New("two-factor authentication is required by the company owner")
+	ErrTooManyAttempts   = errors.New("too many invalid verification codes, try again later")
+	ErrCodeAlreadyUsed   = errors.New("verification code already used")
+	ErrEnrollmentPending = errors.New("two-factor authentication has to be set up")
+)
+
+// Throttling of the verification: after MaxFailedAttempts consecutive invalid codes
+// the codes of the user are rejected until FailedAttemptsCooldown has passed since the last one
+const (
+	MaxFailedAttempts      = 5
+	FailedAttemptsCooldown = 5 * time.Minute
+)
+
+// Number of recovery codes generated at once
+const RecoveryCodesCount = 10
+
+// Enrollment — TOTP authenticator of a user. It is enabled once the user confirms it with a first code.
+type Enrollment struct {
+	UserID         uint
+	Secret         string // base32 secret (encrypted at rest)
+	ConfirmedAt    *time.Time
+	LastUsedStep   int64 // last accepted time step, the codes of older steps are rejected (replay protection)
+	FailedAttempts int
+	LastFailedAt   *time.Time
+	CreatedAt      time.Time
+}
+
+// IsConfirmed checks if the enrollment was confirmed (two-factor authentication enabled)
+func (e *Enrollment) IsConfirmed() bool {
+	return e.ConfirmedAt != nil
+}
+
+// IsThrottled checks if the verification is temporarily blocked by consecutive invalid codes
+func (e *Enrollment) IsThrottled(now time.Time) bool {
+	return e.FailedAttempts >= MaxFailedAttempts && e.LastFailedAt != nil &&
+		now.Before(e.LastFailedAt.Add(FailedAttemptsCooldown))
+}
+
+// RecoveryCode — single-use code that replaces a TOTP code when the device is lost. Only its hash is stored.
+type RecoveryCode struct {
+	ID        uint
+	UserID    uint
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+// Policy — two-factor authentication settings of a company owner for its subusers
+type Policy struct {
+	OwnerID            uint      `json:"-"`
+	RequireForSubusers bool      `json:"requireForSubusers"`
+	UpdatedAt          time.Time `json:"updatedAt"`
+}
+
+// recoveryAlphabet — lowercase letters and digits without the ambiguous ones (0/o, 1/l/i)
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCodes returns n random recovery codes formatted as xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	b := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := make([]byte, len(b))
+		for j, v := range b {
+			// 256 is not a multiple of the alphabet size: the bias is negligible for these codes
+			code[j] = recoveryAlphabet[int(v)%len(recoveryAlphabet)]
+		}
+		codes[i] = string(code[:5]) + "-" + string(code[5:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the hash of the recovery code that is stored in the database.
+// The code is normalized first, so it can be typed in any case and with or without the dash.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsRecoveryCode checks if the code has the format of a recovery code (not a TOTP code)
+func IsRecoveryCode(code string) bool {
+	return len(strings.NewReplacer("-", "", " ", "").Replace(code)) == 10
+}
@@ -0,0 +1,78 @@
+package mfa
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		if !IsRecoveryCode(code) {
+			t.Errorf("%q is not recognized as a recovery code", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicated recovery code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := HashRecoveryCode("abcde-fghjk")
+	for _, typed := range []string{"abcdefghjk", "ABCDE-FGHJK", " abcde fghjk "} {
+		if got := HashRecoveryCode(typed); got != want {
+			t.Errorf("%q: hash differs from the stored code", typed)
+		}
+	}
+	if HashRecoveryCode("abcde-fghjm") == want {
+		t.Error("different codes have the same hash")
+	}
+}
+
+func TestIsRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"abcde-fghjk", true},
+		{"abcdefghjk", true},
+		{"123456", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("IsRecoveryCode(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestEnrollmentIsThrottled(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-FailedAttemptsCooldown / 2)
+	old := now.Add(-FailedAttemptsCooldown - time.Second)
+
+	tests := []struct {
+		name     string
+		failures int
+		lastFail *time.Time
+		want     bool
+	}{
+		{"no failures", 0, nil, false},
+		{"below the limit", MaxFailedAttempts - 1, &recent, false},
+		{"at the limit", MaxFailedAttempts, &recent, true},
+		{"cooldown over", MaxFailedAttempts, &old, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Enrollment{FailedAttempts: tt.failures, LastFailedAt: tt.lastFail}
+			if got := e.IsThrottled(now); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
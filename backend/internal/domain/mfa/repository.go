@@ -0,0 +1,28 @@
+package mfa
+
+import "time"
+
+type Repository interface {
+	GetEnrollment(userID uint) (*Enrollment, error)
+	// SaveEnrollment creates the enrollment of the user or replaces its not confirmed enrollment
+	SaveEnrollment(e *Enrollment) error
+	// ConfirmEnrollment enables the enrollment and replaces the recovery codes of the user
+	ConfirmEnrollment(userID uint, step int64, confirmedAt time.Time, recoveryCodeHashes []string) error
+	// UseStep records the step of an accepted code and resets the failed attempts.
+	// Returns ErrCodeAlreadyUsed if the step (or a later one) was already used.
+	UseStep(userID uint, step int64) error
+	RecordFailedAttempt(userID uint, at time.Time) error
+	// DeleteEnrollment removes the enrollment and the recovery codes of the user
+	DeleteEnrollment(userID uint) error
+
+	// ReplaceRecoveryCodes invalidates the recovery codes of the user and stores the new ones
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error
+	// UseRecoveryCode marks the code as used. Returns ErrInvalidCode if it doesn't exist or was already used.
+	UseRecoveryCode(userID uint, codeHash string) error
+	CountRecoveryCodes(userID uint) (int64, error) // Not used codes
+
+	GetPolicy(ownerID uint) (*Policy, error)
+	SavePolicy(p *Policy) error
+
+	IsNotFoundError(err error) bool
+}
@@ -0,0 +1,109 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238). These are the defaults of the authenticator apps:
+// some of them ignore the parameters of the otpauth URI.
+const (
+	TOTPPeriod = 30 * time.Second
+	TOTPDigits = 6
+	// Accepted clock drift between the server and the device, in periods (before and after)
+	TOTPSkew = 1
+
+	secretSize = 20 // 160 bits, the size recommended by RFC 4226 for HMAC-SHA1
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random TOTP secret encoded in base32 (without padding)
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return b32.EncodeToString(b), nil
+}
+
+// TimeStep returns the TOTP time step of t
+func TimeStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// TOTPCode returns the code of the secret for the time step (HOTP of RFC 4226 with the step as counter)
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := b32.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod), nil
+}
+
+// VerifyTOTP checks the code against the steps around now and returns the matched step.
+// Steps up to lastUsedStep are rejected, so a code can't be used twice.
+func VerifyTOTP(secret, code string, now time.Time, lastUsedStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := TimeStep(now)
+	for step := current - TOTPSkew; step <= current+TOTPSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// OTPAuthURI returns the key URI of the secret (otpauth://totp/...). Authenticator apps import it
+// from a QR code, so it is also the payload of the QR code shown to the user.
+func OTPAuthURI(issuer, account, secret string) string {
+	label := url.PathEscape(account)
+	if issuer != "" {
+		label = url.PathEscape(issuer) + ":" + label
+	}
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	if issuer != "" {
+		params.Set("issuer", issuer)
+	}
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(TOTPDigits))
+	params.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
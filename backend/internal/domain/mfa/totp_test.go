@@ -0,0 +1,129 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secret of the test vectors of RFC 4226 and RFC 6238 (SHA-1): ASCII "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC4226(t *testing.T) {
+	// RFC 4226 appendix D: HOTP values of the counters 0 to 9
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, want := range expected {
+		got, err := TOTPCode(rfcSecret, int64(counter))
+		if err != nil {
+			t.Fatalf("counter %d: %v", counter, err)
+		}
+		if got != want {
+			t.Errorf("counter %d: got %s, want %s", counter, got, want)
+		}
+	}
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// RFC 6238 appendix B (SHA-1). The vectors have 8 digits: a 6-digit code is the last 6 of them.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		step := TimeStep(time.Unix(tt.unix, 0))
+		got, err := TOTPCode(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("time %d: %v", tt.unix, err)
+		}
+		if want := tt.want[len(tt.want)-TOTPDigits:]; got != want {
+			t.Errorf("time %d: got %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestTOTPCodeSecretCase(t *testing.T) {
+	upper, err := TOTPCode(rfcSecret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lower, err := TOTPCode(strings.ToLower(rfcSecret), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upper != lower {
+		t.Errorf("lowercase secret: got %s, want %s", lower, upper)
+	}
+
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("invalid secret: expected an error")
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TimeStep(now)
+	code := func(step int64) string {
+		c, err := TOTPCode(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name         string
+		code         string
+		lastUsedStep int64
+		wantStep     int64
+		wantOK       bool
+	}{
+		{"current step", code(current), 0, current, true},
+		{"surrounding spaces", " " + code(current) + " ", 0, current, true},
+		{"previous step (clock drift)", code(current - 1), 0, current - 1, true},
+		{"next step (clock drift)", code(current + 1), 0, current + 1, true},
+		{"step out of the skew", code(current - TOTPSkew - 1), 0, 0, false},
+		{"replayed code", code(current), current, 0, false},
+		{"code older than the last used one", code(current - 1), current, 0, false},
+		{"newer code after a used one", code(current + 1), current, current + 1, true},
+		{"wrong code", "000000", 0, 0, false},
+		{"wrong length", code(current)[1:], 0, 0, false},
+		{"empty", "", 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := VerifyTOTP(rfcSecret, tt.code, now, tt.lastUsedStep)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("got (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVerifyTOTPReplay(t *testing.T) {
+	now := time.Unix(2000000000, 0)
+	code, err := TOTPCode(rfcSecret, TimeStep(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := VerifyTOTP(rfcSecret, code, now, 0)
+	if !ok {
+		t.Fatal("first use of the code was rejected")
+	}
+	// The accepted step is stored as the last used one: the same code is rejected for the rest of its window
+	for _, at := range []time.Time{now, now.Add(TOTPPeriod)} {
+		if _, ok := VerifyTOTP(rfcSecret, code, at, step); ok {
+			t.Errorf("replayed code accepted at %s", at.Format(time.RFC3339))
+		}
+	}
+}
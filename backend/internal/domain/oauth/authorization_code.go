@@ -0,0 +1,64 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+// PKCE code challenge methods (RFC 7636). Only S256 is accepted.
+const CodeChallengeMethodS256 = "S256"
+
+var ErrCodeAlreadyUsed = errors.New("authorization code already used")
+
+// AuthorizationCode — code issued by the authorization endpoint. Only its hash is stored.
+type AuthorizationCode struct {
+	CodeHash            string
+	ClientID            string
+	UserID              uint
+	RedirectURI         string
+	Scope               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string // OpenID Connect nonce, copied into the ID token
+	SessionID           string // session opened when the code was exchanged
+	CreatedAt           time.Time
+	ExpiresAt           time.Time
+	UsedAt              *time.Time
+}
+
+// IsUsed checks if the code was already exchanged for tokens
+func (c *AuthorizationCode) IsUsed() bool {
+	return c.UsedAt != nil
+}
+
+// VerifyPKCE checks the code verifier against the code challenge of the authorization request
+func (c *AuthorizationCode) VerifyPKCE(verifier string) bool {
+	if c.CodeChallengeMethod != CodeChallengeMethodS256 || !ValidCodeVerifier(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(c.CodeChallenge)) == 1
+}
+
+// ValidCodeVerifier checks the format of a PKCE code verifier: 43-128 characters of [A-Za-z0-9-._~]
+func ValidCodeVerifier(verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, r := range verifier {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '_' || r == '~') {
+			return false
+		}
+	}
+	return true
+}
+
+// HashCode returns the hash of the authorization code that is stored in the database
+func HashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Example of RFC 7636 appendix B
+const (
+	rfcCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     AuthorizationCode
+		verifier string
+		want     bool
+	}{
+		{"RFC 7636 example", AuthorizationCode{CodeChallenge: rfcCodeChallenge, CodeChallengeMethod: CodeChallengeMethodS256}, rfcCodeVerifier, true},
+		{"another verifier", AuthorizationCode{CodeChallenge: rfcCodeChallenge, CodeChallengeMethod: CodeChallengeMethodS256}, strings.Repeat("a", 43), false},
+		{"verifier sent as the challenge", AuthorizationCode{CodeChallenge: rfcCodeChallenge, CodeChallengeMethod: CodeChallengeMethodS256}, rfcCodeChallenge, false},
+		{"empty verifier", AuthorizationCode{CodeChallenge: rfcCodeChallenge, CodeChallengeMethod: CodeChallengeMethodS256}, "", false},
+		// plain is not accepted, even when the verifier equals the challenge
+		{"plain method", AuthorizationCode{CodeChallenge: rfcCodeVerifier, CodeChallengeMethod: "plain"}, rfcCodeVerifier, false},
+		{"no challenge", AuthorizationCode{}, rfcCodeVerifier, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.code.VerifyPKCE(tt.verifier); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidCodeVerifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		want     bool
+	}{
+		{"RFC 7636 example", rfcCodeVerifier, true},
+		{"minimum length", strings.Repeat("a", 43), true},
+		{"maximum length", strings.Repeat("a", 128), true},
+		{"unreserved characters", "AZaz09-._~" + strings.Repeat("a", 33), true},
+		{"too short", strings.Repeat("a", 42), false},
+		{"too long", strings.Repeat("a", 129), false},
+		{"reserved character", strings.Repeat("a", 42) + "+", false},
+		{"space", strings.Repeat("a", 42) + " ", false},
+		{"non ASCII", strings.Repeat("a", 41) + "ñ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidCodeVerifier(tt.verifier); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashCode(t *testing.T) {
+	hash := HashCode("code")
+	if hash == "code" || hash != HashCode("code") {
+		t.Fatalf("unexpected hash %s", hash)
+	}
+	if HashCode("other") == hash {
+		t.Error("two codes with the same hash")
+	}
+}
+
+func TestIsUsed(t *testing.T) {
+	code := AuthorizationCode{}
+	if code.IsUsed() {
+		t.Error("new code is used")
+	}
+	now := time.Now()
+	code.UsedAt = &now
+	if !code.IsUsed() {
+		t.Error("exchanged code is not used")
+	}
+}
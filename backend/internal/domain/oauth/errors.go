@@ -0,0 +1,32 @@
+package oauth
+
+// Error — OAuth 2.0 error (RFC 6749 sections 4.1.2.1 and 5.2)
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// OAuth 2.0 error codes
+const (
+	ErrCodeInvalidRequest          = "invalid_request"
+	ErrCodeInvalidClient           = "invalid_client"
+	ErrCodeInvalidGrant            = "invalid_grant"
+	ErrCodeUnauthorizedClient      = "unauthorized_client"
+	ErrCodeUnsupportedGrantType    = "unsupported_grant_type"
+	ErrCodeUnsupportedResponseType = "unsupported_response_type"
+	ErrCodeInvalidScope            = "invalid_scope"
+	ErrCodeAccessDenied            = "access_denied"
+	ErrCodeServerError             = "server_error"
+)
+
+// NewError creates an OAuth 2.0 error
+func NewError(code, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
@@ -0,0 +1,15 @@
+package oauth
+
+import "time"
+
+type AuthorizationCodeRepository interface {
+	Create(code *AuthorizationCode) error
+	GetByHash(codeHash string) (*AuthorizationCode, error)
+	// MarkUsed marks the code as exchanged. Returns ErrCodeAlreadyUsed if the code was already exchanged.
+	MarkUsed(codeHash string) error
+	// SetSessionID records the session opened with the code (revoked if the code is presented again)
+	SetSessionID(codeHash, sessionID string) error
+	DeleteExpired(now time.Time) error
+
+	IsNotFoundError(err error) bool
+}
@@ -0,0 +1,47 @@
+package passkey
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrCeremonyNotFound    = errors.New("passkey ceremony not found or expired")
+	ErrInvalidCredential   = errors.New("invalid passkey")
+	ErrProviderNotAllowed  = errors.New("passkeys are only available for local users")
+	ErrClonedAuthenticator = errors.New("passkey signature counter mismatch, the authenticator may be cloned")
+)
+
+// Ceremony types
+const (
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+)
+
+// Credential — WebAuthn public key credential (passkey) registered by a user
+type Credential struct {
+	ID              uint       `json:"id"`
+	UserID          uint       `json:"-"`
+	Name            string     `json:"name"`
+	CredentialID    []byte     `json:"-"`
+	PublicKey       []byte     `json:"-"` // COSE encoded
+	AttestationType string     `json:"-"`
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `json:"-"`
+	Flags           uint8      `json:"-"` // authenticator data flags (UP, UV, BE, BS) of the registration
+	Transports      []string   `json:"transports"`
+	Attachment      string     `json:"attachment,omitempty"`
+	BackupState     bool       `json:"synced"` // the passkey is backed up / synced between devices
+	CreatedAt       time.Time  `json:"createdAt"`
+	LastUsedAt      *time.Time `json:"lastUsedAt"`
+}
+
+// Ceremony — state of a registration or login ceremony between its two steps (the challenge).
+// Each ceremony can only be finished once.
+type Ceremony struct {
+	ID        string
+	Type      string
+	UserID    *uint // nil for the login of discoverable credentials (the user is not known yet)
+	Data      []byte
+	ExpiresAt time.Time
+}
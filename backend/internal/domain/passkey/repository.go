@@ -0,0 +1,20 @@
+package passkey
+
+import "time"
+
+type Repository interface {
+	Create(c *Credential) error
+	GetByUserID(userID uint) ([]*Credential, error)
+	// UpdateUsage records a login with the credential (signature counter, flags and date)
+	UpdateUsage(id uint, signCount uint32, flags uint8, backupState bool, usedAt time.Time) error
+	// Delete removes a credential of the user
+	Delete(userID, id uint) error
+
+	CreateCeremony(c *Ceremony) error
+	// ConsumeCeremony returns the ceremony and deletes it, so it can't be finished twice.
+	// Returns ErrCeremonyNotFound if it doesn't exist, has another type or is expired.
+	ConsumeCeremony(id, ceremonyType string, now time.Time) (*Ceremony, error)
+	DeleteExpiredCeremonies(now time.Time) error
+
+	IsNotFoundError(err error) bool
+}
@@ -0,0 +1,58 @@
+package password
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrExpired       = errors.New("password expired, it has to be changed before signing in")
+	ErrInvalidMaxAge = errors.New("invalid max password age")
+	ErrNotLocalUser  = errors.New("the password of the user is not managed by this service")
+	ErrWrongPassword = errors.New("current password is incorrect")
+
+	ErrInvalidRecoveryToken = errors.New("invalid or expired recovery token")
+)
+
+// MaxAgeDaysLimit — longest max password age a company can set
+const MaxAgeDaysLimit = 3650
+
+// HistoryEntry — previous password (hash) of a user
+type HistoryEntry struct {
+	ID        uint
+	UserID    uint
+	Hash      string
+	CreatedAt time.Time // when it was replaced
+}
+
+// Policy — password expiry of a company (set by its owner for all its users)
+type Policy struct {
+	OwnerID    uint      `json:"-"`
+	MaxAgeDays int       `json:"maxAgeDays"` // 0: passwords don't expire
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// IsExpired checks if a password changed at changedAt is expired at now
+func (p *Policy) IsExpired(changedAt, now time.Time) bool {
+	if p == nil || p.MaxAgeDays <= 0 {
+		return false
+	}
+	return !now.Before(changedAt.AddDate(0, 0, p.MaxAgeDays))
+}
+
+// RecoveryToken — password recovery token sent by email (forgot password). Only the newest token
+// of a user is valid, it can be used once and not after a change of the password.
+type RecoveryToken struct {
+	Nonce     string // jti of the token
+	UserID    uint
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// IsValid checks if the token is not expired at now and the password was not changed after it was issued
+func (t *RecoveryToken) IsValid(passwordChangedAt *time.Time, now time.Time) bool {
+	if !now.Before(t.ExpiresAt) {
+		return false
+	}
+	return passwordChangedAt == nil || !passwordChangedAt.After(t.CreatedAt)
+}
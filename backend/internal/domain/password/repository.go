@@ -0,0 +1,24 @@
+package password
+
+import "time"
+
+type Repository interface {
+	// AddHistory records a replaced password and keeps only the newest keep entries of the user
+	AddHistory(entry *HistoryEntry, keep int) error
+	// GetHistory returns the newest limit previous passwords of the user
+	GetHistory(userID uint, limit int) ([]HistoryEntry, error)
+
+	GetPolicy(ownerID uint) (*Policy, error)
+	SavePolicy(p *Policy) error
+
+	// ReplaceRecoveryToken stores the token and removes the previous tokens of the user
+	ReplaceRecoveryToken(t *RecoveryToken) error
+	GetRecoveryToken(nonce string) (*RecoveryToken, error)
+	// ConsumeRecoveryToken removes the token and calls use in the same transaction: the token is kept
+	// if use fails. ErrInvalidRecoveryToken if it was already removed (only one request can use it).
+	ConsumeRecoveryToken(nonce string, use func() error) error
+	DeleteRecoveryTokens(userID uint) error
+	DeleteExpiredRecoveryTokens(now time.Time) error
+
+	IsNotFoundError(err error) bool
+}
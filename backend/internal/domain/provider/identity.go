@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"errors"
+
+	"app/internal/domain/saml"
+	"app/internal/domain/user"
+)
+
+// Names of the identity providers of the service (name column of the providers table)
+const (
+	NameLiftel         = "Liftel"
+	NameVerificaciones = "Verificaciones"
+	NameSecondary      = "Secondary"
+	NameSAML           = "SAML"
+)
+
+var (
+	// ErrUnknownUser — the provider doesn't know the login (another provider may know it)
+	ErrUnknownUser = errors.New("user does not exist")
+	// ErrInvalidCredentials — the provider knows the login but rejected the password
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrProvisioningNotSupported — the users of the provider are created by this service, not on their first login
+	ErrProvisioningNotSupported = errors.New("the provider does not provision users")
+	ErrProviderNotRegistered    = errors.New("identity provider not registered")
+)
+
+// Identity — user authenticated by an identity provider
+type Identity struct {
+	Login       string
+	CompanyID   uint // company of the user (0 if the provider does not tell it)
+	CompanyName string
+	Email       string
+	Name        string
+	Surname     string
+	Phone       string
+
+	// Roles granted by the provider (e.g. from the groups of a directory) among ManagedRoles.
+	// The ManagedRoles the identity doesn't have are removed from the user; roles that are not
+	// managed by the provider are left as they are. Both are empty for providers without roles.
+	Roles        []string
+	ManagedRoles []string
+}
+
+// ApplyProfile copies the non-empty profile fields of the identity to the profile
+// and reports whether any of them changed
+func (i *Identity) ApplyProfile(profile *user.Profile) bool {
+	changed := false
+	apply := func(field **string, value string) {
+		if value == "" || (*field != nil && **field == value) {
+			return
+		}
+		v := value
+		*field = &v
+		changed = true
+	}
+	apply(&profile.Email, i.Email)
+	apply(&profile.Name, i.Name)
+	apply(&profile.Surname, i.Surname)
+	apply(&profile.Phone, i.Phone)
+	return changed
+}
+
+// IdentityProvider checks the credentials of the users of a provider of the providers table
+type IdentityProvider interface {
+	// Name of the provider in the providers table
+	Name() string
+
+	// Authenticate checks the credentials of the login. usr is its local user (nil if it has none yet).
+	// Returns ErrUnknownUser if the provider doesn't know the login and ErrInvalidCredentials if the
+	// password is wrong (both count as failed login attempts); other errors are failures of the provider.
+	Authenticate(login, password string, usr *user.User) (*Identity, error)
+
+	// SupportsPasswordReset tells if the passwords of the users are managed by this service
+	// (forgot/reset/change password, expiry, passkeys)
+	SupportsPasswordReset() bool
+
+	// ProvisionUser returns the local user (not saved yet) of an identity authenticated for the first time,
+	// or ErrProvisioningNotSupported
+	ProvisionUser(identity *Identity) (*user.User, error)
+
+	// SyncProfile updates the local user with the identity on each login (the user is saved by the caller)
+	SyncProfile(usr *user.User, identity *Identity)
+}
+
+// FederatedProvider — identity provider whose users sign in on the provider itself and come back
+// with an authorization code (e.g. OpenID Connect). Password logins are rejected by Authenticate.
+type FederatedProvider interface {
+	IdentityProvider
+
+	// AuthCodeURL returns the URL of the provider where the user signs in
+	AuthCodeURL(state, nonce, codeVerifier string) (string, error)
+
+	// Exchange redeems the authorization code of the callback and returns the identity it proves
+	// (the nonce must match the one of AuthCodeURL)
+	Exchange(code, codeVerifier, nonce string) (*Identity, error)
+}
+
+// SAMLProvider — identity provider of the companies that sign in with their own SAML 2.0 identity provider
+// (SP-initiated SSO, one saml.Connection per company). Password logins are rejected by Authenticate.
+type SAMLProvider interface {
+	IdentityProvider
+
+	// Metadata returns the metadata of the service provider of the company (XML)
+	Metadata(companyID uint) ([]byte, error)
+
+	// AuthnRequestURL returns the URL of the identity provider where the user signs in
+	// (HTTP-Redirect binding) and the ID of the AuthnRequest
+	AuthnRequestURL(conn *saml.Connection, relayState string) (string, string, error)
+
+	// ParseResponse validates the response of the identity provider (base64, HTTP-POST binding) to
+	// the AuthnRequest: signature, issuer, audience, destination and validity. Returns the identity
+	// of the assertion with CompanyID set to the company of the connection.
+	ParseResponse(conn *saml.Connection, samlResponse, requestID string) (*Identity, error)
+}
@@ -0,0 +1,117 @@
+package provider
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Registration — identity provider registered for a provider of the providers table
+type Registration struct {
+	ID       uint
+	Provider IdentityProvider
+}
+
+var (
+	registryMu sync.RWMutex
+	registered []IdentityProvider  // in order of registration
+	boundIDs   = map[string]uint{} // lowercase name => ID in the providers table
+	byID       = map[uint]IdentityProvider{}
+)
+
+// Register adds an identity provider by its name. Logins of users that don't exist yet
+// are tried against the providers in order of registration.
+func Register(p IdentityProvider) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for i, r := range registered {
+		if strings.EqualFold(r.Name(), p.Name()) {
+			registered[i] = p
+			return
+		}
+	}
+	registered = append(registered, p)
+}
+
+// Bind links the registered identity providers to the providers table (by name, case-insensitive).
+// Every registered provider must have its row.
+func Bind(providers []Provider) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	ids := make(map[string]uint, len(providers))
+	for _, p := range providers {
+		ids[strings.ToLower(p.Name)] = p.ID
+	}
+
+	newBoundIDs := make(map[string]uint, len(registered))
+	newByID := make(map[uint]IdentityProvider, len(registered))
+	for _, r := range registered {
+		id, ok := ids[strings.ToLower(r.Name())]
+		if !ok {
+			return fmt.Errorf("provider %q not found in the providers table", r.Name())
+		}
+		newBoundIDs[strings.ToLower(r.Name())] = id
+		newByID[id] = r
+	}
+	boundIDs, byID = newBoundIDs, newByID
+	return nil
+}
+
+// Lookup returns the identity provider of the users of a provider
+func Lookup(providerID uint) (IdentityProvider, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	p, ok := byID[providerID]
+	if !ok {
+		return nil, fmt.Errorf("%w: provider %d", ErrProviderNotRegistered, providerID)
+	}
+	return p, nil
+}
+
+// IDByName returns the ID of a registered identity provider in the providers table
+func IDByName(name string) (uint, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	id, ok := boundIDs[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrProviderNotRegistered, name)
+	}
+	return id, nil
+}
+
+// LookupByName returns a registered identity provider and its ID in the providers table
+func LookupByName(name string) (IdentityProvider, uint, error) {
+	id, err := IDByName(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	p, err := Lookup(id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return p, id, nil
+}
+
+// Registered returns the bound identity providers in order of registration
+func Registered() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	registrations := make([]Registration, 0, len(registered))
+	for _, p := range registered {
+		if id, ok := boundIDs[strings.ToLower(p.Name())]; ok {
+			registrations = append(registrations, Registration{ID: id, Provider: p})
+		}
+	}
+	return registrations
+}
+
+// SupportsPasswordReset tells if the passwords of the users of a provider are managed by this service
+func SupportsPasswordReset(providerID uint) bool {
+	p, err := Lookup(providerID)
+	return err == nil && p.SupportsPasswordReset()
+}
@@ -3,4 +3,5 @@ package provider
 type Repository interface {
 	GetAll() ([]Provider, error)
 	GetByID(id uint) (*Provider, error)
+	Create(p *Provider) error
 }
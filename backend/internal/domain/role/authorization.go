@@ -0,0 +1,103 @@
+package role
+
+import "fmt"
+
+// Permission names used by the service
+const (
+	PermissionUsersRead      = "users:read"
+	PermissionUsersWrite     = "users:write"
+	PermissionRolesRead      = "roles:read"
+	PermissionRolesAssign    = "roles:assign"
+	PermissionSubusersCreate = "subusers:create"
+	PermissionClientsManage  = "clients:manage"
+	PermissionMFAManage      = "mfa:manage"
+	PermissionPasswordManage = "passwords:manage"
+	PermissionSAMLManage     = "saml:manage"
+	// Roles of the whole platform (create, rename, delete, inheritance): never granted to the companies
+	PermissionPlatformAdmin = "platform:admin"
+)
+
+// AuthorizationService resolves the effective permissions of a user from its roles
+type AuthorizationService struct {
+	roleRepo       RoleRepository
+	permissionRepo PermissionRepository
+}
+
+func NewAuthorizationService(roleRepo RoleRepository, permissionRepo PermissionRepository) *AuthorizationService {
+	return &AuthorizationService{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+	}
+}
+
+// GetUserPermissions returns the effective permissions of the user
+// (union of the permissions of all its roles, including inherited roles)
+func (s *AuthorizationService) GetUserPermissions(userID uint) ([]Permission, error) {
+	roles, err := s.roleRepo.GetUserRolesExpanded(userID)
+	if err != nil {
+		return nil, fmt.Errorf("get user roles error: %w", err)
+	}
+
+	roleNames := make([]string, len(roles))
+	for i, r := range roles {
+		roleNames[i] = r.Role
+	}
+
+	return s.GetPermissionsByRoleNames(roleNames)
+}
+
+// GetPermissionsByRoleNames returns the effective permissions of a list of roles (e.g. the roles of a token)
+func (s *AuthorizationService) GetPermissionsByRoleNames(roleNames []string) ([]Permission, error) {
+	if len(roleNames) == 0 {
+		return []Permission{}, nil
+	}
+
+	permissions, err := s.permissionRepo.GetPermissionsByRoleNames(roleNames)
+	if err != nil {
+		return nil, fmt.Errorf("get permissions error: %w", err)
+	}
+
+	// Remove duplicates (one permission can be granted by several roles)
+	seen := make(map[uint]bool)
+	result := make([]Permission, 0, len(permissions))
+	for _, p := range permissions {
+		if seen[p.ID] {
+			continue
+		}
+		seen[p.ID] = true
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// UserHasPermission checks if the user has the given permission
+func (s *AuthorizationService) UserHasPermission(userID uint, permission string) (bool, error) {
+	permissions, err := s.GetUserPermissions(userID)
+	if err != nil {
+		return false, err
+	}
+	return containsPermission(permissions, permission), nil
+}
+
+// RolesHavePermissions checks if the roles grant all the given permissions
+func (s *AuthorizationService) RolesHavePermissions(roleNames []string, required ...string) (bool, error) {
+	permissions, err := s.GetPermissionsByRoleNames(roleNames)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range required {
+		if !containsPermission(permissions, p) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func containsPermission(permissions []Permission, name string) bool {
+	for _, p := range permissions {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
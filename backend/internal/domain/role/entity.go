@@ -1,12 +1,29 @@
 package role
 
+import "time"
+
 type Role struct {
+	ID       uint   `json:"id"`
+	Role     string `json:"role"`
+	Desc     string `json:"desc"`
+	ParentID *uint  `json:"parentId,omitempty"` // role from which this role inherits
+	System   bool   `json:"system"`             // system roles can't be renamed or deleted
+}
+
+type RefRoleUser struct {
+	UserID     uint       `json:"userId"`
+	RoleID     uint       `json:"roleId"`
+	ValidFrom  *time.Time `json:"validFrom,omitempty"`  // nil => valid since assignment
+	ValidUntil *time.Time `json:"validUntil,omitempty"` // nil => never expires
+}
+
+type Permission struct {
 	ID   uint   `json:"id"`
-	Role string `json:"role"`
+	Name string `json:"name"`
 	Desc string `json:"desc"`
 }
 
-type RefRoleUser struct {
-	UserID uint `json:"userId"`
-	RoleID uint `json:"roleId"`
+type RefRolePermission struct {
+	RoleID       uint `json:"roleId"`
+	PermissionID uint `json:"permissionId"`
 }
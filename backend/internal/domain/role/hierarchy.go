@@ -0,0 +1,90 @@
+package role
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrRoleCycle      = errors.New("role hierarchy cycle detected")
+	ErrRoleSelfParent = errors.New("role cannot inherit from itself")
+)
+
+// ExpandRoles returns the given roles plus all the roles they inherit from (parents, grandparents, ...).
+// allRoles must contain every role of the system. Direct roles keep their order and inherited roles
+// are appended after them, without duplicates.
+func ExpandRoles(direct []Role, allRoles []Role) []Role {
+	byID := make(map[uint]Role, len(allRoles))
+	for _, r := range allRoles {
+		byID[r.ID] = r
+	}
+
+	seen := make(map[uint]bool)
+	expanded := make([]Role, 0, len(direct))
+
+	// Direct roles first
+	for _, r := range direct {
+		if seen[r.ID] {
+			continue
+		}
+		seen[r.ID] = true
+		expanded = append(expanded, r)
+	}
+
+	// Walk up the hierarchy of each direct role
+	for _, r := range direct {
+		current, ok := byID[r.ID]
+		if !ok {
+			current = r
+		}
+		for current.ParentID != nil {
+			parent, exists := byID[*current.ParentID]
+			if !exists || seen[parent.ID] {
+				// Unknown parent or already visited (also protects against cycles in DB)
+				break
+			}
+			seen[parent.ID] = true
+			expanded = append(expanded, parent)
+			current = parent
+		}
+	}
+
+	return expanded
+}
+
+// ValidateParent checks that roleID can inherit from parentID without creating a cycle.
+// allRoles must contain every role of the system.
+func ValidateParent(roleID, parentID uint, allRoles []Role) error {
+	if roleID == parentID {
+		return ErrRoleSelfParent
+	}
+
+	byID := make(map[uint]Role, len(allRoles))
+	for _, r := range allRoles {
+		byID[r.ID] = r
+	}
+
+	if _, ok := byID[parentID]; !ok {
+		return fmt.Errorf("parent role %d not found", parentID)
+	}
+
+	// Walk up from the new parent: if we reach roleID, the new relation closes a cycle
+	visited := make(map[uint]bool)
+	current := parentID
+	for {
+		if current == roleID {
+			return ErrRoleCycle
+		}
+		if visited[current] {
+			// Existing cycle that does not involve roleID
+			return ErrRoleCycle
+		}
+		visited[current] = true
+
+		r, ok := byID[current]
+		if !ok || r.ParentID == nil {
+			return nil
+		}
+		current = *r.ParentID
+	}
+}
@@ -1,13 +1,23 @@
 package role
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type RoleRepository interface {
 	GetAllRoles() ([]Role, error)
 	GetRoleByID(id uint) (*Role, error)
 	AssignRoleToUser(userID, roleID uint) error
+	AssignRoleToUserWithValidity(userID, roleID uint, validFrom, validUntil *time.Time) error
+	DeleteExpiredGrants(now time.Time) ([]RefRoleUser, error)
 	GetUserRoles(userID uint) ([]Role, error)
+	GetUserRolesExpanded(userID uint) ([]Role, error) // roles of the user + inherited roles
+	SetRoleParent(roleID uint, parentID *uint) error
 	CreateRole(role *Role) (uint, error)
+	UpdateRole(role *Role) error
+	DeleteRole(roleID uint) error
 	RemoveRoleFromUser(userID, roleID uint) error
 	GetRoleByName(name string) (*Role, error)
 	IsNotFoundError(err error) bool
@@ -15,3 +25,16 @@ type RoleRepository interface {
 	GetRoleByNameWithTransaction(tx *gorm.DB, roleName string) (*Role, error)
 	AssignRoleToUserWithTransaction(tx *gorm.DB, userID, roleID uint) error
 }
+
+type PermissionRepository interface {
+	GetAllPermissions() ([]Permission, error)
+	GetPermissionByName(name string) (*Permission, error)
+	CreatePermission(permission *Permission) (uint, error)
+
+	GetRolePermissions(roleID uint) ([]Permission, error)
+	GetPermissionsByRoleNames(roleNames []string) ([]Permission, error)
+	AssignPermissionToRole(roleID, permissionID uint) error
+	RemovePermissionFromRole(roleID, permissionID uint) error
+
+	IsNotFoundError(err error) bool
+}
@@ -0,0 +1,39 @@
+package role
+
+import (
+	"errors"
+	"regexp"
+)
+
+const (
+	// RoleCompany — role of the company owners
+	RoleCompany = "company"
+	// RolePlatformAdmin — role of the administrators of the platform (roles shared by all the companies)
+	RolePlatformAdmin = "platform_admin"
+	// RoleRecover — pseudo-role of the password recovery token
+	RoleRecover = "recover"
+	// RoleMFAPending — pseudo-role of the token of a login waiting for its second factor
+	RoleMFAPending = "mfa_pending"
+	// RolePasswordExpired — pseudo-role of the token that only allows to change an expired password
+	RolePasswordExpired = "password_expired"
+)
+
+var (
+	ErrSystemRole        = errors.New("system role can't be modified")
+	ErrReservedRoleName  = errors.New("role name is reserved")
+	ErrRoleAlreadyExists = errors.New("role already exists")
+	ErrRoleNotFound      = errors.New("role not found")
+
+	companyRoleRegexp = regexp.MustCompile(`^company_\d+$`)
+)
+
+// IsCompanyIDRole checks if the role name is an auto-generated company role (company_<id>)
+func IsCompanyIDRole(name string) bool {
+	return companyRoleRegexp.MatchString(name)
+}
+
+// IsReservedRoleName checks if the role name is managed by the service itself
+// and therefore can't be created, or renamed to, through the API
+func IsReservedRoleName(name string) bool {
+	return name == RoleCompany || name == RolePlatformAdmin || name == RoleRecover || name == RoleMFAPending || name == RolePasswordExpired || IsCompanyIDRole(name)
+}
@@ -0,0 +1,108 @@
+package saml
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"app/internal/domain/role"
+)
+
+var (
+	ErrConnectionNotFound = errors.New("the company has no SAML identity provider")
+	ErrConnectionDisabled = errors.New("SAML login is disabled for the company")
+	ErrInvalidConnection  = errors.New("invalid SAML identity provider")
+	ErrNotInternalCompany = errors.New("SAML login is only available for internal companies")
+	ErrFlowNotFound       = errors.New("SAML login not found or expired")
+	ErrFlowNotCompleted   = errors.New("SAML login not completed by the identity provider")
+)
+
+// Connection — SAML 2.0 identity provider of a company (internal_companies). Its users sign in on it
+// (SP-initiated SSO) and are created on their first login as subusers of the owner of the connection.
+type Connection struct {
+	CompanyID uint `json:"companyId"`
+	OwnerID   uint `json:"-"` // company owner that configured the connection
+	Enabled   bool `json:"enabled"`
+
+	IdPEntityID    string `json:"idpEntityId"`    // Issuer of the assertions
+	IdPSSOURL      string `json:"idpSsoUrl"`      // SingleSignOnService (HTTP-Redirect binding)
+	IdPCertificate string `json:"idpCertificate"` // signing certificate (PEM)
+
+	// Only logins ending with the suffix (e.g. "@acme.com") are accepted
+	LoginSuffix string        `json:"loginSuffix"`
+	Attributes  Attributes    `json:"attributes"`
+	RoleMapping []RoleMapping `json:"roleMapping"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Validate checks the settings of the identity provider and the role mapping.
+// The roles managed by the service itself can't be granted by the identity provider.
+func (c *Connection) Validate() error {
+	if strings.TrimSpace(c.IdPEntityID) == "" {
+		return fmt.Errorf("%w: idpEntityId is required", ErrInvalidConnection)
+	}
+	u, err := url.Parse(c.IdPSSOURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%w: idpSsoUrl must be an http(s) URL", ErrInvalidConnection)
+	}
+	if _, err := c.Certificate(); err != nil {
+		return err
+	}
+	for _, rm := range c.RoleMapping {
+		if strings.TrimSpace(rm.Value) == "" {
+			return fmt.Errorf("%w: role mapping without value", ErrInvalidConnection)
+		}
+		for _, name := range rm.Roles {
+			if role.IsReservedRoleName(name) {
+				return fmt.Errorf("%w: role %q can't be granted by the identity provider", ErrInvalidConnection, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Certificate returns the signing certificate of the identity provider
+func (c *Connection) Certificate() (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(c.IdPCertificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%w: idpCertificate must be a PEM certificate", ErrInvalidConnection)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConnection, err)
+	}
+	return cert, nil
+}
+
+// Attributes — attributes of the assertion mapped to the login, the profile and the roles of the user
+// (empty: not mapped; an empty Login maps the NameID)
+type Attributes struct {
+	Login   string `json:"login"`
+	Email   string `json:"email"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+	Phone   string `json:"phone"`
+	Roles   string `json:"roles"` // e.g. groups
+}
+
+// RoleMapping — roles granted when the roles attribute has the value (case-insensitive)
+type RoleMapping struct {
+	Value string   `json:"value"`
+	Roles []string `json:"roles"`
+}
+
+// Flow — SAML login between its start and the response of the identity provider (ACS), and until
+// the client that started it opens the session. Each flow can only be completed once.
+type Flow struct {
+	ID         string // returned to the client that started the login, required to open the session
+	CompanyID  uint
+	RequestID  string // ID of the AuthnRequest, the response must be InResponseTo it
+	RelayState string // sent to the identity provider, returned with the response
+	UserID     *uint  // user of the assertion, set once the response is accepted
+	ExpiresAt  time.Time
+}
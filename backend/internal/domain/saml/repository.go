@@ -0,0 +1,20 @@
+package saml
+
+import "time"
+
+type Repository interface {
+	// GetConnection returns the identity provider of the company (ErrConnectionNotFound if it has none)
+	GetConnection(companyID uint) (*Connection, error)
+	SaveConnection(c *Connection) error
+
+	CreateFlow(f *Flow) error
+	// GetPendingFlow returns the flow of the relay state not completed yet.
+	// Returns ErrFlowNotFound if it doesn't exist, belongs to another company, is completed or expired.
+	GetPendingFlow(relayState string, companyID uint, now time.Time) (*Flow, error)
+	// CompleteFlow sets the user of a pending flow (ErrFlowNotFound if it was completed meanwhile)
+	CompleteFlow(id string, userID uint) error
+	// ConsumeFlow returns the flow and deletes it, so it can't be used twice.
+	// Returns ErrFlowNotFound if it doesn't exist, belongs to another company or is expired.
+	ConsumeFlow(id string, companyID uint, now time.Time) (*Flow, error)
+	DeleteExpiredFlows(now time.Time) error
+}
@@ -0,0 +1,29 @@
+package security
+
+import "time"
+
+type EventType string
+
+// Security event types
+const (
+	EventRefreshTokenReuse EventType = "refresh_token_reuse"
+	EventAccountLocked     EventType = "account_locked"
+	EventIPLocked          EventType = "ip_locked"
+	EventAccountUnlocked   EventType = "account_unlocked"
+	EventIdentityLinked    EventType = "identity_linked"
+	EventIdentityUnlinked  EventType = "identity_unlinked"
+	EventAccountMerged     EventType = "account_merged"
+)
+
+// Event — security relevant event (stored for auditing and logged)
+type Event struct {
+	ID        uint      `json:"id"`
+	Type      EventType `json:"type"`
+	UserID    *uint     `json:"userId,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	SessionID string    `json:"sessionId,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
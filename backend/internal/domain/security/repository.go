@@ -0,0 +1,6 @@
+package security
+
+type Repository interface {
+	Create(e *Event) error
+	GetByUserID(userID uint, limit int) ([]Event, error)
+}
@@ -0,0 +1,53 @@
+package session
+
+import "time"
+
+// Session — one login of a user on one device. It's also the rotation family of its refresh tokens.
+type Session struct {
+	ID            string     `json:"id"`
+	UserID        uint       `json:"-"`
+	Device        string     `json:"device"`
+	UserAgent     string     `json:"userAgent"`
+	IP            string     `json:"ip"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	LastUsedAt    time.Time  `json:"lastUsedAt"`
+	ExpiresAt     time.Time  `json:"expiresAt"`
+	RevokedAt     *time.Time `json:"-"`
+	CompromisedAt *time.Time `json:"-"` // set when the reuse of a rotated refresh token is detected
+
+	// OAuth client that opened the session (empty for direct logins) and the scope granted to it
+	ClientID string `json:"clientId,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+
+	Current bool `json:"current"` // true if the session is the one of the request token
+}
+
+// RefreshToken — one refresh token of a session. The token is never stored, only its hash.
+// Each rotated token records its parent, so the whole family can be traced and revoked.
+type RefreshToken struct {
+	ID        uint
+	SessionID string
+	TokenHash string
+	ParentID  *uint
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RotatedAt *time.Time // set when the token is exchanged for a new one
+	RevokedAt *time.Time
+}
+
+// ClientInfo — information about the client that opens/uses a session
+type ClientInfo struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
+
+// IsActive checks if the session is not revoked and not expired
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && s.CompromisedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// IsRotated checks if the refresh token was already exchanged (presenting it again means reuse)
+func (t *RefreshToken) IsRotated() bool {
+	return t.RotatedAt != nil
+}
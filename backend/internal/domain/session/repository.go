@@ -0,0 +1,32 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRefreshTokenAlreadyRotated — the refresh token was rotated concurrently (or reused)
+var ErrRefreshTokenAlreadyRotated = errors.New("refresh token already rotated")
+
+type Repository interface {
+	// Create creates the session and its first refresh token
+	Create(s *Session, token *RefreshToken) error
+	GetByID(id string) (*Session, error)
+	GetActiveByUserID(userID uint) ([]*Session, error)
+
+	// Touch updates the usage/expiration dates of the session
+	Touch(id string, lastUsedAt, expiresAt time.Time) error
+
+	Revoke(id string) error
+	// RevokeAllByUserID revokes all the active sessions of the user except exceptID (if not empty)
+	RevokeAllByUserID(userID uint, exceptID string) error
+	// RevokeFamily revokes the session and all its refresh tokens and marks it as compromised
+	RevokeFamily(id string) error
+
+	GetRefreshTokenByHash(tokenHash string) (*RefreshToken, error)
+	// RotateRefreshToken marks the old token as rotated and creates the new one (child of the old one).
+	// Returns ErrRefreshTokenAlreadyRotated if the old token was already rotated.
+	RotateRefreshToken(oldID uint, newToken *RefreshToken) error
+
+	IsNotFoundError(err error) bool
+}
@@ -1,6 +1,8 @@
 package user
 
 import (
+	"time"
+
 	"app/internal/domain/role"
 
 	"golang.org/x/crypto/bcrypt"
@@ -20,28 +22,71 @@ type User struct {
 	CreatedAt    string  `json:"createdAt"`
 	LastAccess   string  `json:"lastAccess"`
 
-	Refresh    *string `json:"-"` // `json:"refresh"`
-	RefreshExp string  `json:"-"` // `json:"refreshExp"`
-	OwnerID    *uint   `json:"-"` // `json:"ownerId"`
+	// Temporary lock after too many failed login attempts (a locked user is still active)
+	Locked      bool       `json:"locked"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
+
+	// Last change of the password (nil: not changed since the user was created)
+	PasswordChangedAt *time.Time `json:"-"`
+
+	OwnerID *uint `json:"-"` // `json:"ownerId"`
 
 	Profile *Profile    `json:"profile"`
 	Roles   []role.Role `json:"-"` // `json:"roles"`
 
 	AccessToken   string `json:"-"`
+	RefreshToken  string `json:"-"` // refresh token of the current session (never stored in plain text)
+	SessionID     string `json:"-"`
 	OwnerUsername string `json:"-"`
+
+	// Set when the login needs a second factor: token to complete it (no session is opened before)
+	MFAToken              string `json:"-"`
+	MFAEnrollmentRequired bool   `json:"-"` // the user has to set up its authenticator first
+
+	// Set when the password is expired: token that only allows to change it (no session is opened)
+	PasswordChangeToken string `json:"-"`
+}
+
+// IsLocked checks if the user is locked at the given time
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// IsManagedBy checks if the user is the company owner itself or one of its subusers
+func (u *User) IsManagedBy(owner *User) bool {
+	return u.ID == owner.ID || (u.OwnerID != nil && *u.OwnerID == owner.ID)
 }
 
-// Setter for password (hashing)
+// Setter for password (hashing). The password must meet the password policy
+// (a *PasswordPolicyError is returned otherwise): set Login and CompanyName first.
 func (u *User) SetPassword(plain string) error {
+	if err := u.ValidatePassword(plain); err != nil {
+		return err
+	}
+
 	hashed, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 	hashedStr := string(hashed)
 	u.Password = &hashedStr
+	now := time.Now()
+	u.PasswordChangedAt = &now
 	return nil
 }
 
+// PasswordSetAt returns when the current password was set (the creation of the user if it was never changed)
+func (u *User) PasswordSetAt() (time.Time, bool) {
+	if u.PasswordChangedAt != nil {
+		return *u.PasswordChangedAt, true
+	}
+	createdAt, err := time.ParseInLocation("2006-01-02 15:04:05", u.CreatedAt, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return createdAt, true
+}
+
 // Check password
 func (u *User) CheckPassword(plain string) bool {
 	if u.Password == nil {
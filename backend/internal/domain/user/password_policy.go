@@ -0,0 +1,218 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"app/pkg/random"
+)
+
+// ErrPasswordPolicy — the password doesn't meet the policy (see PasswordPolicyError for the details)
+var ErrPasswordPolicy = errors.New("password does not meet the password policy")
+
+// Violation codes of the password policy
+const (
+	PasswordTooShort         = "too_short"
+	PasswordTooLong          = "too_long"
+	PasswordMissingUppercase = "missing_uppercase"
+	PasswordMissingLowercase = "missing_lowercase"
+	PasswordMissingDigit     = "missing_digit"
+	PasswordMissingSymbol    = "missing_symbol"
+	PasswordCommon           = "common_password"
+	PasswordContainsUsername = "contains_username"
+	PasswordContainsCompany  = "contains_company_name"
+	PasswordReused           = "reused" // one of the last passwords of the user (password history)
+)
+
+// Shorter usernames and company names are not searched in the password
+const userInfoMinLength = 3
+
+// Length of the random passwords (unless the policy requires longer ones)
+const generatedPasswordLength = 16
+
+// PasswordViolation — one rule of the policy the password doesn't meet
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError — all the rules of the policy the password doesn't meet
+type PasswordPolicyError struct {
+	Violations []PasswordViolation `json:"violations"`
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return ErrPasswordPolicy.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *PasswordPolicyError) Is(target error) bool { return target == ErrPasswordPolicy }
+
+// PasswordPolicy — rules of the passwords of the local users
+type PasswordPolicy struct {
+	MinLength        int // in characters
+	MaxLength        int // in bytes (bcrypt ignores what goes beyond 72 bytes)
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	DisallowUserInfo bool     // the password can't contain the username or the company name
+	Blacklist        []string // common passwords rejected (case-insensitive), in addition to the built-in list
+}
+
+// DefaultPasswordPolicy returns the policy used when it is not configured
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:        10,
+		MaxLength:        72,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		DisallowUserInfo: true,
+	}
+}
+
+// commonPasswords — the most common passwords, always rejected
+var commonPasswords = []string{
+	"123456", "123456789", "12345678", "1234567890", "12345", "1234567", "111111", "123123", "000000",
+	"password", "password1", "password123", "passw0rd", "p@ssw0rd", "qwerty", "qwerty123", "qwertyuiop",
+	"1q2w3e4r", "1q2w3e4r5t", "abc123", "abcd1234", "iloveyou", "admin", "admin123", "administrator",
+	"welcome", "welcome1", "welcome123", "letmein", "monkey", "dragon", "football", "sunshine", "princess",
+	"changeme", "secret", "master", "trustno1", "zaq12wsx", "contraseña", "contrasena", "liftel", "liftel123",
+}
+
+var (
+	passwordPolicyMu sync.RWMutex
+	passwordPolicy   = DefaultPasswordPolicy()
+	passwordBlocked  = blacklistSet(nil)
+)
+
+// SetPasswordPolicy sets the policy enforced by SetPassword
+func SetPasswordPolicy(policy PasswordPolicy) {
+	passwordPolicyMu.Lock()
+	defer passwordPolicyMu.Unlock()
+	passwordPolicy = policy
+	passwordBlocked = blacklistSet(policy.Blacklist)
+}
+
+// GetPasswordPolicy returns the policy enforced by SetPassword
+func GetPasswordPolicy() PasswordPolicy {
+	passwordPolicyMu.RLock()
+	defer passwordPolicyMu.RUnlock()
+	return passwordPolicy
+}
+
+// SetRandomPassword sets a random password that meets the policy (its length, the required characters,
+// the username and company name of the user...) and returns it
+func (u *User) SetRandomPassword() (string, error) {
+	policy := GetPasswordPolicy()
+	length := generatedPasswordLength
+	if policy.MinLength > length {
+		length = policy.MinLength
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		length = policy.MaxLength
+	}
+
+	plain, err := random.GenerateRandomPassword(length, u.ValidatePassword)
+	if err != nil {
+		return "", err
+	}
+	return plain, u.SetPassword(plain)
+}
+
+// ValidatePassword checks the password of the user (its username and company name must be set)
+// against the policy. Returns a *PasswordPolicyError with all the violations.
+func (u *User) ValidatePassword(plain string) error {
+	passwordPolicyMu.RLock()
+	policy, blocked := passwordPolicy, passwordBlocked
+	passwordPolicyMu.RUnlock()
+
+	var violations []PasswordViolation
+	add := func(code, message string) {
+		violations = append(violations, PasswordViolation{Code: code, Message: message})
+	}
+
+	if n := utf8.RuneCountInString(plain); n < policy.MinLength || n == 0 {
+		add(PasswordTooShort, "password is too short")
+	}
+	if policy.MaxLength > 0 && len(plain) > policy.MaxLength {
+		add(PasswordTooLong, "password is too long")
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range plain {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if policy.RequireUppercase && !upper {
+		add(PasswordMissingUppercase, "password must contain an uppercase letter")
+	}
+	if policy.RequireLowercase && !lower {
+		add(PasswordMissingLowercase, "password must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !digit {
+		add(PasswordMissingDigit, "password must contain a digit")
+	}
+	if policy.RequireSymbol && !symbol {
+		add(PasswordMissingSymbol, "password must contain a symbol")
+	}
+
+	lowered := strings.ToLower(plain)
+	if blocked[lowered] {
+		add(PasswordCommon, "password is too common")
+	}
+	if policy.DisallowUserInfo {
+		if containsUserInfo(lowered, u.Login) || containsUserInfo(lowered, localPart(u.Login)) {
+			add(PasswordContainsUsername, "password must not contain the username")
+		}
+		if containsUserInfo(lowered, u.CompanyName) {
+			add(PasswordContainsCompany, "password must not contain the company name")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func containsUserInfo(lowered, info string) bool {
+	info = strings.ToLower(strings.TrimSpace(info))
+	return utf8.RuneCountInString(info) >= userInfoMinLength && strings.Contains(lowered, info)
+}
+
+// localPart returns the part before the @ of a username that is an email address
+func localPart(login string) string {
+	if i := strings.Index(login, "@"); i > 0 {
+		return login[:i]
+	}
+	return ""
+}
+
+func blacklistSet(extra []string) map[string]bool {
+	set := make(map[string]bool, len(commonPasswords)+len(extra))
+	for _, p := range commonPasswords {
+		set[p] = true
+	}
+	for _, p := range extra {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			set[p] = true
+		}
+	}
+	return set
+}
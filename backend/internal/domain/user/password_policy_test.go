@@ -0,0 +1,51 @@
+package user
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSetRandomPassword(t *testing.T) {
+	t.Cleanup(func() { SetPasswordPolicy(DefaultPasswordPolicy()) })
+
+	strict := DefaultPasswordPolicy()
+	strict.RequireSymbol = true
+	long := strict
+	long.MinLength = 40
+	short := strict
+	short.MinLength, short.MaxLength = 8, 8
+
+	tests := []struct {
+		name   string
+		policy PasswordPolicy
+		length int
+	}{
+		{name: "default policy", policy: DefaultPasswordPolicy(), length: generatedPasswordLength},
+		{name: "symbol required", policy: strict, length: generatedPasswordLength},
+		{name: "long minimum length", policy: long, length: 40},
+		{name: "short maximum length", policy: short, length: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetPasswordPolicy(tt.policy)
+
+			// Short username and company name: they would often be found in the password
+			u := &User{Login: "abc", CompanyName: "xyz"}
+			for i := 0; i < 5; i++ {
+				plain, err := u.SetRandomPassword()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := u.ValidatePassword(plain); err != nil {
+					t.Fatalf("password %q: %v", plain, err)
+				}
+				if n := utf8.RuneCountInString(plain); n != tt.length {
+					t.Errorf("password %q: got length %d, want %d", plain, n, tt.length)
+				}
+				if !u.CheckPassword(plain) {
+					t.Fatalf("password %q not set", plain)
+				}
+			}
+		})
+	}
+}
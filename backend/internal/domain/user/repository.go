@@ -1,6 +1,8 @@
 package user
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -10,12 +12,13 @@ type Repository interface {
 
 	GetByID(id uint) (*User, error)
 	GetByLogin(login string) (*User, error)
+	GetByUUID(uuid string) (*User, error)
 	GetByOwnerID(ownerID uint) ([]*User, error)
-	GetByRefreshToken(refreshToken string) (*User, error)
 
-	UpdateRefreshToken(u *User) error
 	UpdateLastAccess(userId uint) error
 	UpdateActiveStatus(userID uint, active bool) error
+	UpdateLoggedStatus(userID uint, isLogged bool) error
+	UpdateLockedUntil(userID uint, lockedUntil *time.Time) error
 
 	DeleteUserByUsername(username string) error
 
@@ -67,8 +67,9 @@ func (s *UserService) EnsureUserRoles(usr *User) error {
 	// Create "company" role if it doesn't exist
 	if companyRoleID == 0 {
 		newRole := &role.Role{
-			Role: "company",
-			Desc: "General company role",
+			Role:   role.RoleCompany,
+			Desc:   "General company role",
+			System: true,
 		}
 		companyRoleID, err = s.roleRepo.CreateRole(newRole)
 		if err != nil {
@@ -79,8 +80,9 @@ func (s *UserService) EnsureUserRoles(usr *User) error {
 	// Create company_ID role if it doesn't exist
 	if companyIDRoleID == 0 {
 		newRole := &role.Role{
-			Role: companyIDRoleName,
-			Desc: fmt.Sprintf("Role for company %s", usr.CompanyName),
+			Role:   companyIDRoleName,
+			Desc:   fmt.Sprintf("Role for company %s", usr.CompanyName),
+			System: true,
 		}
 		companyIDRoleID, err = s.roleRepo.CreateRole(newRole)
 		if err != nil {
@@ -128,6 +130,20 @@ func (s *UserService) GetUserRoles(userID uint) ([]role.Role, error) {
 	return userRoles, nil
 }
 
+// GetUserRolesExpanded gets user roles from database including the roles inherited from parent roles
+func (s *UserService) GetUserRolesExpanded(userID uint) ([]role.Role, error) {
+	// Retry logic of GetUserRoles for freshly assigned roles
+	if _, err := s.GetUserRoles(userID); err != nil {
+		return nil, err
+	}
+
+	userRoles, err := s.roleRepo.GetUserRolesExpanded(userID)
+	if err != nil {
+		return nil, fmt.Errorf("get expanded user roles error: %w", err)
+	}
+	return userRoles, nil
+}
+
 // GetRoleNamesString converts roles to comma-separated string
 func (s *UserService) GetRoleNamesString(roles []role.Role) string {
 	roleNames := ""
@@ -0,0 +1,28 @@
+package user_identity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("linked identity not found")
+	ErrIdentityLinked   = errors.New("the identity is already linked to a user")
+	ErrIdentityInUse    = errors.New("the identity is the login of another user")
+	ErrPrimaryIdentity  = errors.New("the identity is the login of the user")
+	ErrNotLinkable      = errors.New("the identities of the provider can't be linked")
+	ErrProviderLogin    = errors.New("the login belongs to a user of an identity provider, only that user can link it")
+)
+
+// UserIdentity — login of a user at an identity provider other than its own (provider of the user),
+// so the same user can sign in with several login methods (e.g. a local user that is also a
+// technician of Verificaciones). A login of a provider belongs to one user at most.
+type UserIdentity struct {
+	ID           uint       `json:"id"`
+	UserID       uint       `json:"-"`
+	ProviderID   uint       `json:"providerId"`
+	ProviderName string     `json:"providerName"`
+	Subject      string     `json:"subject"` // login of the user at the provider
+	CreatedAt    time.Time  `json:"createdAt"`
+	LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+}
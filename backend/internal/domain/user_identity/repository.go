@@ -0,0 +1,25 @@
+package user_identity
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(i *UserIdentity) error
+	CreateWithTransaction(tx *gorm.DB, i *UserIdentity) error
+	// CreateMerging creates the identity and removes the user of its login (mergedUserID)
+	// in the same transaction: the login of that user becomes an identity of the user
+	CreateMerging(i *UserIdentity, mergedUserID uint) error
+	GetByUserID(userID uint) ([]*UserIdentity, error)
+	// GetBySubject returns the identities of any provider with the login (case-insensitive)
+	GetBySubject(subject string) ([]*UserIdentity, error)
+	// Exists checks if the login of the provider is linked to a user
+	Exists(providerID uint, subject string) (bool, error)
+	UpdateLastUsed(id uint, at time.Time) error
+	// Delete removes an identity of the user (ErrIdentityNotFound if the user has no such identity)
+	Delete(id, userID uint) error
+
+	IsAlreadyExistsError(err error) bool
+}
@@ -56,7 +56,29 @@ func Migrate(db *gorm.DB, creationDefaults bool) error {
 		&models.ProfileModel{},
 		&models.RoleModel{},
 		&models.RefRoleUserModel{},
+		&models.PermissionModel{},
+		&models.RefRolePermissionModel{},
+		&models.SessionModel{},
+		&models.RefreshTokenModel{},
+		&models.SecurityEventModel{},
+		&models.RevokedTokenModel{},
+		&models.SigningKeyModel{},
+		&models.ClientModel{},
+		&models.AuthorizationCodeModel{},
+		&models.MFAEnrollmentModel{},
+		&models.MFARecoveryCodeModel{},
+		&models.MFAPolicyModel{},
+		&models.PasskeyModel{},
+		&models.PasskeyCeremonyModel{},
+		&models.LoginAttemptModel{},
+		&models.PasswordHistoryModel{},
+		&models.PasswordPolicyModel{},
+		&models.PasswordRecoveryTokenModel{},
+		&models.FederationFlowModel{},
 		&models.InternalCompanyModel{},
+		&models.SAMLConnectionModel{},
+		&models.SAMLFlowModel{},
+		&models.UserIdentityModel{},
 	); err != nil {
 		return fmt.Errorf("autoMigrate error: %w", err)
 	}
@@ -65,6 +87,14 @@ func Migrate(db *gorm.DB, creationDefaults bool) error {
 		return err
 	}
 
+	if err := init_SystemRoles(db); err != nil {
+		return err
+	}
+
+	if err := migrate_LegacyRefreshTokens(db); err != nil {
+		return err
+	}
+
 	// 3. (Optional) initialize default data, if you want
 	if creationDefaults {
 		if err := init_default_data(db); err != nil {
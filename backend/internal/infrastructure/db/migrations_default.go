@@ -21,6 +21,14 @@ func init_default_data(db *gorm.DB) error {
 		return err
 	}
 
+	if err := init_PlatformAdmin(db); err != nil {
+		return err
+	}
+
+	if err := init_Permissions(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -57,9 +65,10 @@ func createProvider(db *gorm.DB, id uint, name, desc string) error {
 
 func createRole(db *gorm.DB, id uint, name, desc string) error {
 	role := models.RoleModel{
-		ID:   id,
-		Role: name,
-		Desc: desc,
+		ID:     id,
+		Role:   name,
+		Desc:   desc,
+		System: true,
 	}
 	if err := db.Create(&role).Error; err != nil {
 		return err
@@ -67,3 +76,45 @@ func createRole(db *gorm.DB, id uint, name, desc string) error {
 	log.Printf("Created default role: %s with ID: %d", role.Role, role.ID)
 	return nil
 }
+
+func setRoleParent(db *gorm.DB, roleName, parentName string) error {
+	var parent models.RoleModel
+	if err := db.Where("role = ?", parentName).First(&parent).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.RoleModel{}).Where("role = ?", roleName).Update("parent_id", parent.ID).Error; err != nil {
+		return err
+	}
+	log.Printf("Role %s inherits from role %s", roleName, parentName)
+	return nil
+}
+
+func createPermission(db *gorm.DB, id uint, name, desc string) error {
+	permission := models.PermissionModel{
+		ID:   id,
+		Name: name,
+		Desc: desc,
+	}
+	if err := db.Create(&permission).Error; err != nil {
+		return err
+	}
+	log.Printf("Created default permission: %s with ID: %d", permission.Name, permission.ID)
+	return nil
+}
+
+func assignPermissionToRole(db *gorm.DB, roleName string, permissionID uint) error {
+	var role models.RoleModel
+	if err := db.Where("role = ?", roleName).First(&role).Error; err != nil {
+		return err
+	}
+
+	ref := models.RefRolePermissionModel{
+		RoleID:       role.ID,
+		PermissionID: permissionID,
+	}
+	if err := db.Create(&ref).Error; err != nil {
+		return err
+	}
+	log.Printf("Assigned permission ID: %d to role: %s", permissionID, roleName)
+	return nil
+}
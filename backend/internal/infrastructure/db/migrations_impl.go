@@ -2,10 +2,14 @@ package db
 
 import (
 	"app/internal/infrastructure/db/models"
+	"app/internal/infrastructure/token/refresh"
 	"app/pkg/config"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/spf13/viper"
 	"gorm.io/gorm"
 )
@@ -45,6 +49,192 @@ func init_Roles(db *gorm.DB) error {
 		); err != nil {
 			return err
 		}
+
+		// Role hierarchy
+		for _, key := range []string{"company", "liftplay", "sat01"} {
+			prefix := "database.migrations.defaults.roles." + key
+			if parent := viper.GetString(prefix + ".parent"); parent != "" {
+				if err := setRoleParent(db, viper.GetString(prefix+".name"), parent); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Initialize the platform administrator role (also in existing databases) and grant it to the default user.
+// The role is reserved: it can't be assigned through the API.
+func init_PlatformAdmin(db *gorm.DB) error {
+	prefix := "database.migrations.defaults.roles.platform_admin"
+	name := viper.GetString(prefix + ".name")
+	if name == "" {
+		return nil
+	}
+
+	var adminRole models.RoleModel
+	err := db.Where("role = ?", name).First(&adminRole).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The default ID may be taken by a role created later in an existing database
+		id := uint(viper.GetInt(prefix + ".id"))
+		var taken int64
+		if err := db.Model(&models.RoleModel{}).Where("id = ?", id).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			id = 0
+		}
+		if err := createRole(db, id, name, viper.GetString(prefix+".desc")); err != nil {
+			return err
+		}
+		err = db.Where("role = ?", name).First(&adminRole).Error
+	}
+	if err != nil {
+		return err
+	}
+	// A role with the same name created through the API before the role was reserved must not become admin
+	if !adminRole.System {
+		return fmt.Errorf("role %s already exists and is not a system role: rename it before migrating", name)
+	}
+
+	// Default user
+	userID := uint(viper.GetInt("database.migrations.defaults.user.id"))
+	var count int64
+	if err := db.Model(&models.UserModel{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	if err := db.Model(&models.RefRoleUserModel{}).Where("user_id = ? AND role_id = ?", userID, adminRole.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if err := db.Create(&models.RefRoleUserModel{UserID: userID, RoleID: adminRole.ID}).Error; err != nil {
+		return err
+	}
+	log.Printf("Assigned %s role to user ID: %d", name, userID)
+	return nil
+}
+
+// Mark the default roles and the auto-generated company_<id> roles as system roles
+func init_SystemRoles(db *gorm.DB) error {
+	names := []string{
+		viper.GetString("database.migrations.defaults.roles.company.name"),
+		viper.GetString("database.migrations.defaults.roles.liftplay.name"),
+		viper.GetString("database.migrations.defaults.roles.sat01.name"),
+	}
+
+	if err := db.Model(&models.RoleModel{}).
+		Where("`system` = ?", false).
+		Where("role IN ? OR role REGEXP ?", names, "^company_[0-9]+$").
+		Update("system", true).Error; err != nil {
+		return fmt.Errorf("error marking system roles: %w", err)
+	}
+	return nil
+}
+
+// Move the single refresh token of users (replaced by the sessions table) to a session of its own,
+// so the users that are logged in keep their refresh token. The columns are only dropped when
+// database.migrations.drop_legacy_refresh is set: once no instance of the previous version is running.
+func migrate_LegacyRefreshTokens(db *gorm.DB) error {
+	for _, column := range []string{"refresh", "refreshExp"} {
+		if !db.Migrator().HasColumn(&models.UserModel{}, column) {
+			return nil
+		}
+	}
+
+	var legacy []struct {
+		ID         uint
+		Refresh    string
+		RefreshExp time.Time
+	}
+	if err := db.Table("users").
+		Select("id, refresh, refreshExp AS refresh_exp").
+		Where("refresh IS NOT NULL AND refresh <> '' AND refreshExp > ?", time.Now()).
+		Scan(&legacy).Error; err != nil {
+		return fmt.Errorf("error retrieving legacy refresh tokens: %w", err)
+	}
+
+	now := time.Now()
+	for _, l := range legacy {
+		hash := refresh.HashRefreshToken(l.Refresh)
+
+		var count int64
+		if err := db.Model(&models.RefreshTokenModel{}).Where("token_hash = ?", hash).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			sess := models.SessionModel{
+				ID:         uuid.New().String(),
+				UserID:     l.ID,
+				Device:     "legacy",
+				CreatedAt:  now,
+				LastUsedAt: now,
+				ExpiresAt:  l.RefreshExp,
+			}
+			if err := tx.Create(&sess).Error; err != nil {
+				return err
+			}
+			return tx.Create(&models.RefreshTokenModel{
+				SessionID: sess.ID,
+				TokenHash: hash,
+				CreatedAt: now,
+				ExpiresAt: l.RefreshExp,
+			}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("error migrating legacy refresh token of user %d: %w", l.ID, err)
+		}
+	}
+	if len(legacy) > 0 {
+		log.Printf("Migrated %d legacy refresh tokens to sessions", len(legacy))
+	}
+
+	if !viper.GetBool("database.migrations.drop_legacy_refresh") {
+		return nil
+	}
+	for _, column := range []string{"refresh", "refreshExp"} {
+		if err := db.Migrator().DropColumn(&models.UserModel{}, column); err != nil {
+			return fmt.Errorf("error dropping column users.%s: %w", column, err)
+		}
+		log.Printf("Dropped legacy column users.%s", column)
+	}
+	return nil
+}
+
+// Initialize default permissions and link them to the default roles.
+// Permissions are created by name, so new default permissions are also added to existing databases.
+func init_Permissions(db *gorm.DB) error {
+	for key := range viper.GetStringMap("database.migrations.defaults.permissions") {
+		prefix := "database.migrations.defaults.permissions." + key
+		id := uint(viper.GetInt(prefix + ".id"))
+		name := viper.GetString(prefix + ".name")
+
+		var count int64
+		if err := db.Model(&models.PermissionModel{}).Where("name = ?", name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		if err := createPermission(db, id, name, viper.GetString(prefix+".desc")); err != nil {
+			return err
+		}
+
+		// Roles that have this permission by default
+		for _, roleName := range viper.GetStringSlice(prefix + ".roles") {
+			if err := assignPermissionToRole(db, roleName, id); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"app/internal/domain/oauth"
+)
+
+// AuthorizationCodeModel — GORM-model for the oauth_authorization_codes table
+type AuthorizationCodeModel struct {
+	CodeHash            string     `gorm:"column:code_hash;primaryKey;size:64"`
+	ClientID            string     `gorm:"column:client_id;size:36;not null"`
+	UserID              uint       `gorm:"column:user_id;not null"`
+	RedirectURI         string     `gorm:"column:redirect_uri;size:1024;not null"`
+	Scope               string     `gorm:"column:scope;size:1024"`
+	CodeChallenge       string     `gorm:"column:code_challenge;size:128;not null"`
+	CodeChallengeMethod string     `gorm:"column:code_challenge_method;size:16;not null"`
+	Nonce               string     `gorm:"column:nonce;size:255"`
+	SessionID           string     `gorm:"column:session_id;size:36"`
+	CreatedAt           time.Time  `gorm:"column:created_at;type:DATETIME;not null"`
+	ExpiresAt           time.Time  `gorm:"column:expires_at;type:DATETIME;not null;index"`
+	UsedAt              *time.Time `gorm:"column:used_at;type:DATETIME;default:null"`
+}
+
+func (AuthorizationCodeModel) TableName() string { return "oauth_authorization_codes" }
+
+// ToDomain converts AuthorizationCodeModel to domain entity oauth.AuthorizationCode
+func (am *AuthorizationCodeModel) ToDomain() *oauth.AuthorizationCode {
+	return &oauth.AuthorizationCode{
+		CodeHash:            am.CodeHash,
+		ClientID:            am.ClientID,
+		UserID:              am.UserID,
+		RedirectURI:         am.RedirectURI,
+		Scope:               am.Scope,
+		CodeChallenge:       am.CodeChallenge,
+		CodeChallengeMethod: am.CodeChallengeMethod,
+		Nonce:               am.Nonce,
+		SessionID:           am.SessionID,
+		CreatedAt:           am.CreatedAt,
+		ExpiresAt:           am.ExpiresAt,
+		UsedAt:              am.UsedAt,
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"app/internal/domain/client"
+)
+
+// ClientModel — GORM-model for the oauth_clients table
+type ClientModel struct {
+	ID           uint      `gorm:"column:id;primaryKey"`
+	ClientID     string    `gorm:"column:client_id;size:36;not null;uniqueIndex"`
+	SecretHash   string    `gorm:"column:secret_hash;size:255"` // empty for public clients
+	Name         string    `gorm:"column:name;size:255;not null"`
+	CompanyID    uint      `gorm:"column:company_id;not null;index"`
+	Public       bool      `gorm:"column:public;not null;default:false"`
+	RedirectURIs string    `gorm:"column:redirect_uris;type:text"` // one per line
+	Scopes       string    `gorm:"column:scopes;size:1024"`        // space-delimited
+	Active       bool      `gorm:"column:active;not null;default:true"`
+	CreatedAt    time.Time `gorm:"column:created_at;type:DATETIME;not null"`
+}
+
+func (ClientModel) TableName() string { return "oauth_clients" }
+
+// ToDomain converts ClientModel to domain entity client.Client
+func (cm *ClientModel) ToDomain() *client.Client {
+	return &client.Client{
+		ID:           cm.ID,
+		ClientID:     cm.ClientID,
+		SecretHash:   cm.SecretHash,
+		Name:         cm.Name,
+		CompanyID:    cm.CompanyID,
+		Public:       cm.Public,
+		RedirectURIs: strings.Fields(cm.RedirectURIs),
+		Scopes:       client.ParseScope(cm.Scopes),
+		Active:       cm.Active,
+		CreatedAt:    cm.CreatedAt,
+	}
+}
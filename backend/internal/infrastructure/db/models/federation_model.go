@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"app/internal/domain/federation"
+)
+
+// FederationFlowModel — GORM-model for the federation_flows table (pending logins with external providers)
+type FederationFlowModel struct {
+	ID           string    `gorm:"column:id;type:char(36);primaryKey"`
+	ProviderID   uint      `gorm:"column:provider_id;not null"`
+	State        string    `gorm:"column:state;size:64;not null"`
+	Nonce        string    `gorm:"column:nonce;size:64;not null"`
+	CodeVerifier string    `gorm:"column:code_verifier;size:128;not null"`
+	ExpiresAt    time.Time `gorm:"column:expires_at;type:DATETIME;not null;index"`
+
+	Provider ProviderModel `gorm:"foreignKey:ProviderID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (FederationFlowModel) TableName() string { return "federation_flows" }
+
+// ToDomain converts FederationFlowModel to domain entity federation.Flow
+func (fm *FederationFlowModel) ToDomain() *federation.Flow {
+	return &federation.Flow{
+		ID:           fm.ID,
+		ProviderID:   fm.ProviderID,
+		State:        fm.State,
+		Nonce:        fm.Nonce,
+		CodeVerifier: fm.CodeVerifier,
+		ExpiresAt:    fm.ExpiresAt,
+	}
+}
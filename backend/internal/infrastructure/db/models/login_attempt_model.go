@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"app/internal/domain/lockout"
+)
+
+// LoginAttemptModel — GORM-model for the login_attempts table (failed login attempts per username and per IP address)
+type LoginAttemptModel struct {
+	Subject       string     `gorm:"column:subject;size:16;primaryKey"`
+	Key           string     `gorm:"column:subject_key;size:255;primaryKey"`
+	Failures      int        `gorm:"column:failures;not null;default:0"`
+	FirstFailedAt time.Time  `gorm:"column:first_failed_at;type:DATETIME;not null"`
+	LastFailedAt  time.Time  `gorm:"column:last_failed_at;type:DATETIME;not null;index"`
+	LockedUntil   *time.Time `gorm:"column:locked_until;type:DATETIME;default:null"`
+}
+
+func (LoginAttemptModel) TableName() string { return "login_attempts" }
+
+// ToDomain converts LoginAttemptModel to domain entity lockout.Counter
+func (m *LoginAttemptModel) ToDomain() *lockout.Counter {
+	return &lockout.Counter{
+		Subject:       m.Subject,
+		Key:           m.Key,
+		Failures:      m.Failures,
+		FirstFailedAt: m.FirstFailedAt,
+		LastFailedAt:  m.LastFailedAt,
+		LockedUntil:   m.LockedUntil,
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"app/internal/domain/mfa"
+)
+
+// MFAEnrollmentModel — GORM-model for the mfa_totp table (one TOTP authenticator per user).
+// The secret is sealed with the service secret, it is never stored in clear.
+type MFAEnrollmentModel struct {
+	UserID         uint       `gorm:"column:user_id;primaryKey"`
+	SealedSecret   []byte     `gorm:"column:sealed_secret;type:VARBINARY(128);not null"`
+	ConfirmedAt    *time.Time `gorm:"column:confirmed_at;type:DATETIME;default:null"`
+	LastUsedStep   int64      `gorm:"column:last_used_step;not null;default:0"`
+	FailedAttempts int        `gorm:"column:failed_attempts;not null;default:0"`
+	LastFailedAt   *time.Time `gorm:"column:last_failed_at;type:DATETIME;default:null"`
+	CreatedAt      time.Time  `gorm:"column:created_at;type:DATETIME;not null"`
+
+	User UserModel `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (MFAEnrollmentModel) TableName() string { return "mfa_totp" }
+
+// ToDomain converts MFAEnrollmentModel to domain entity mfa.Enrollment (without the secret, that has to be unsealed)
+func (em *MFAEnrollmentModel) ToDomain() *mfa.Enrollment {
+	return &mfa.Enrollment{
+		UserID:         em.UserID,
+		ConfirmedAt:    em.ConfirmedAt,
+		LastUsedStep:   em.LastUsedStep,
+		FailedAttempts: em.FailedAttempts,
+		LastFailedAt:   em.LastFailedAt,
+		CreatedAt:      em.CreatedAt,
+	}
+}
+
+// MFARecoveryCodeModel — GORM-model for the mfa_recovery_codes table
+type MFARecoveryCodeModel struct {
+	ID        uint       `gorm:"column:id;primaryKey"`
+	UserID    uint       `gorm:"column:user_id;not null;index"`
+	CodeHash  string     `gorm:"column:code_hash;type:char(64);not null"` // sha256 of the normalized code
+	CreatedAt time.Time  `gorm:"column:created_at;type:DATETIME;not null"`
+	UsedAt    *time.Time `gorm:"column:used_at;type:DATETIME;default:null"`
+
+	User UserModel `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (MFARecoveryCodeModel) TableName() string { return "mfa_recovery_codes" }
+
+// MFAPolicyModel — GORM-model for the mfa_policies table (settings of a company owner)
+type MFAPolicyModel struct {
+	OwnerID            uint      `gorm:"column:owner_id;primaryKey"`
+	RequireForSubusers bool      `gorm:"column:require_for_subusers;not null;default:false"`
+	UpdatedAt          time.Time `gorm:"column:updated_at;type:DATETIME;not null"`
+
+	Owner UserModel `gorm:"foreignKey:OwnerID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (MFAPolicyModel) TableName() string { return "mfa_policies" }
+
+// ToDomain converts MFAPolicyModel to domain entity mfa.Policy
+func (pm *MFAPolicyModel) ToDomain() *mfa.Policy {
+	return &mfa.Policy{
+		OwnerID:            pm.OwnerID,
+		RequireForSubusers: pm.RequireForSubusers,
+		UpdatedAt:          pm.UpdatedAt,
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"app/internal/domain/passkey"
+)
+
+// PasskeyModel — GORM-model for the passkeys table (WebAuthn credentials of the users)
+type PasskeyModel struct {
+	ID              uint       `gorm:"column:id;primaryKey"`
+	UserID          uint       `gorm:"column:user_id;not null;index"`
+	Name            string     `gorm:"column:name;size:255"`
+	CredentialID    []byte     `gorm:"column:credential_id;type:VARBINARY(1023);not null;uniqueIndex:idx_passkeys_credential_id,length:255"`
+	PublicKey       []byte     `gorm:"column:public_key;type:BLOB;not null"`
+	AttestationType string     `gorm:"column:attestation_type;size:32"`
+	AAGUID          []byte     `gorm:"column:aaguid;type:VARBINARY(16)"`
+	SignCount       uint32     `gorm:"column:sign_count;not null;default:0"`
+	Flags           uint8      `gorm:"column:flags;not null;default:0"`
+	Transports      string     `gorm:"column:transports;size:255"` // space-delimited
+	Attachment      string     `gorm:"column:attachment;size:32"`
+	BackupState     bool       `gorm:"column:backup_state;not null;default:false"`
+	CreatedAt       time.Time  `gorm:"column:created_at;type:DATETIME;not null"`
+	LastUsedAt      *time.Time `gorm:"column:last_used_at;type:DATETIME;default:null"`
+
+	User UserModel `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (PasskeyModel) TableName() string { return "passkeys" }
+
+// ToDomain converts PasskeyModel to domain entity passkey.Credential
+func (pm *PasskeyModel) ToDomain() *passkey.Credential {
+	return &passkey.Credential{
+		ID:              pm.ID,
+		UserID:          pm.UserID,
+		Name:            pm.Name,
+		CredentialID:    pm.CredentialID,
+		PublicKey:       pm.PublicKey,
+		AttestationType: pm.AttestationType,
+		AAGUID:          pm.AAGUID,
+		SignCount:       pm.SignCount,
+		Flags:           pm.Flags,
+		Transports:      strings.Fields(pm.Transports),
+		Attachment:      pm.Attachment,
+		BackupState:     pm.BackupState,
+		CreatedAt:       pm.CreatedAt,
+		LastUsedAt:      pm.LastUsedAt,
+	}
+}
+
+// PasskeyCeremonyModel — GORM-model for the passkey_ceremonies table (pending WebAuthn challenges)
+type PasskeyCeremonyModel struct {
+	ID        string    `gorm:"column:id;type:char(36);primaryKey"`
+	Type      string    `gorm:"column:type;size:16;not null"`
+	UserID    *uint     `gorm:"column:user_id;default:null"`
+	Data      []byte    `gorm:"column:data;type:BLOB;not null"` // webauthn session data (JSON)
+	ExpiresAt time.Time `gorm:"column:expires_at;type:DATETIME;not null;index"`
+}
+
+func (PasskeyCeremonyModel) TableName() string { return "passkey_ceremonies" }
+
+// ToDomain converts PasskeyCeremonyModel to domain entity passkey.Ceremony
+func (cm *PasskeyCeremonyModel) ToDomain() *passkey.Ceremony {
+	return &passkey.Ceremony{
+		ID:        cm.ID,
+		Type:      cm.Type,
+		UserID:    cm.UserID,
+		Data:      cm.Data,
+		ExpiresAt: cm.ExpiresAt,
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"app/internal/domain/password"
+)
+
+// PasswordHistoryModel — GORM-model for the password_history table (previous passwords of the users)
+type PasswordHistoryModel struct {
+	ID        uint      `gorm:"column:id;primaryKey"`
+	UserID    uint      `gorm:"column:user_id;not null;index"`
+	Hash      string    `gorm:"column:password_hash;size:255;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;type:DATETIME;not null"`
+
+	User UserModel `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (PasswordHistoryModel) TableName() string { return "password_history" }
+
+// ToDomain converts PasswordHistoryModel to domain entity password.HistoryEntry
+func (m *PasswordHistoryModel) ToDomain() password.HistoryEntry {
+	return password.HistoryEntry{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		Hash:      m.Hash,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// PasswordPolicyModel — GORM-model for the password_policies table (password expiry of the companies)
+type PasswordPolicyModel struct {
+	OwnerID    uint      `gorm:"column:owner_id;primaryKey"`
+	MaxAgeDays int       `gorm:"column:max_age_days;not null;default:0"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;type:DATETIME;not null"`
+
+	Owner UserModel `gorm:"foreignKey:OwnerID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (PasswordPolicyModel) TableName() string { return "password_policies" }
+
+// ToDomain converts PasswordPolicyModel to domain entity password.Policy
+func (m *PasswordPolicyModel) ToDomain() *password.Policy {
+	return &password.Policy{
+		OwnerID:    m.OwnerID,
+		MaxAgeDays: m.MaxAgeDays,
+		UpdatedAt:  m.UpdatedAt,
+	}
+}
+
+// PasswordRecoveryTokenModel — GORM-model for the password_recovery_tokens table (forgot password)
+type PasswordRecoveryTokenModel struct {
+	Nonce     string    `gorm:"column:nonce;type:char(36);primaryKey"`
+	UserID    uint      `gorm:"column:user_id;not null;index"`
+	CreatedAt time.Time `gorm:"column:created_at;type:DATETIME;not null"`
+	ExpiresAt time.Time `gorm:"column:expires_at;type:DATETIME;not null;index"`
+
+	User UserModel `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (PasswordRecoveryTokenModel) TableName() string { return "password_recovery_tokens" }
+
+// ToDomain converts PasswordRecoveryTokenModel to domain entity password.RecoveryToken
+func (m *PasswordRecoveryTokenModel) ToDomain() *password.RecoveryToken {
+	return &password.RecoveryToken{
+		Nonce:     m.Nonce,
+		UserID:    m.UserID,
+		CreatedAt: m.CreatedAt,
+		ExpiresAt: m.ExpiresAt,
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import "app/internal/domain/role"
+
+// PermissionModel - model for storing permissions
+type PermissionModel struct {
+	ID   uint   `gorm:"primaryKey;column:id"`
+	Name string `gorm:"column:name;size:255;not null;unique"`
+	Desc string `gorm:"column:desc;size:255"`
+}
+
+func (PermissionModel) TableName() string {
+	return "permissions"
+}
+
+// ToDomain - convert database model to domain structure
+func (pm *PermissionModel) ToDomain() *role.Permission {
+	return &role.Permission{
+		ID:   pm.ID,
+		Name: pm.Name,
+		Desc: pm.Desc,
+	}
+}
+
+// RefRolePermissionModel - many-to-many relationship between roles and permissions
+type RefRolePermissionModel struct {
+	RoleID       uint `gorm:"primaryKey;column:role_id"`
+	PermissionID uint `gorm:"primaryKey;column:permission_id"`
+
+	Role       RoleModel       `gorm:"foreignKey:RoleID;references:ID;constraint:OnDelete:CASCADE"`
+	Permission PermissionModel `gorm:"foreignKey:PermissionID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (RefRolePermissionModel) TableName() string {
+	return "ref_role_permission"
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RevokedTokenModel — GORM-model for the revoked_tokens table.
+// Identifier is the jti of a revoked access token or the sid of a revoked session.
+type RevokedTokenModel struct {
+	Identifier string    `gorm:"column:identifier;primaryKey;size:64"`
+	CreatedAt  time.Time `gorm:"column:created_at;type:DATETIME;not null"`
+	ExpiresAt  time.Time `gorm:"column:expires_at;type:DATETIME;not null;index"`
+}
+
+func (RevokedTokenModel) TableName() string { return "revoked_tokens" }
@@ -8,8 +8,18 @@ type RoleModel struct {
 	Role string `gorm:"column:role;size:255;not null;unique"`
 	Desc string `gorm:"column:desc;size:255"`
 
+	// System roles (seeded and company_<id>) can't be renamed or deleted
+	System bool `gorm:"column:system;not null;default:false"`
+
+	// Parent role (inheritance). If the parent is deleted, the role has no parent anymore
+	ParentID *uint      `gorm:"column:parent_id;default:null"`
+	Parent   *RoleModel `gorm:"foreignKey:ParentID;references:ID;constraint:OnDelete:SET NULL"`
+
 	// Many-to-many relationship through ref_user_role
 	Roles []RoleModel `gorm:"many2many:ref_user_role;foreignKey:ID;joinForeignKey:user_id;References:ID;joinReferences:role_id"`
+
+	// Many-to-many relationship through ref_role_permission
+	Permissions []PermissionModel `gorm:"many2many:ref_role_permission;foreignKey:ID;joinForeignKey:role_id;References:ID;joinReferences:permission_id"`
 }
 
 func (RoleModel) TableName() string {
@@ -19,8 +29,10 @@ func (RoleModel) TableName() string {
 // ToDomain - convert database model to domain structure
 func (rm *RoleModel) ToDomain() *role.Role {
 	return &role.Role{
-		ID:   rm.ID,
-		Role: rm.Role,
-		Desc: rm.Desc,
+		ID:       rm.ID,
+		Role:     rm.Role,
+		Desc:     rm.Desc,
+		ParentID: rm.ParentID,
+		System:   rm.System,
 	}
 }
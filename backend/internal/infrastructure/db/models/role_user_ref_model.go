@@ -1,10 +1,19 @@
 package models
 
+import (
+	"app/internal/domain/role"
+	"time"
+)
+
 // RefRoleUserModel
 type RefRoleUserModel struct {
 	UserID uint `gorm:"primaryKey;column:user_id"`
 	RoleID uint `gorm:"primaryKey;column:role_id"`
 
+	// Optional validity window of the grant (NULL => unlimited)
+	ValidFrom  *time.Time `gorm:"column:valid_from;type:DATETIME;default:null"`
+	ValidUntil *time.Time `gorm:"column:valid_until;type:DATETIME;default:null;index"`
+
 	User UserModel `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
 	Role RoleModel `gorm:"foreignKey:RoleID;references:ID;constraint:OnDelete:CASCADE"`
 }
@@ -12,3 +21,13 @@ type RefRoleUserModel struct {
 func (RefRoleUserModel) TableName() string {
 	return "ref_user_role"
 }
+
+// ToDomain - convert database model to domain structure
+func (ref *RefRoleUserModel) ToDomain() *role.RefRoleUser {
+	return &role.RefRoleUser{
+		UserID:     ref.UserID,
+		RoleID:     ref.RoleID,
+		ValidFrom:  ref.ValidFrom,
+		ValidUntil: ref.ValidUntil,
+	}
+}
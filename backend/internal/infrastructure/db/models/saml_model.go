@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"app/internal/domain/saml"
+)
+
+// SAMLConnectionModel — GORM-model for the saml_connections table (SAML identity provider of the companies)
+type SAMLConnectionModel struct {
+	CompanyID      uint               `gorm:"column:company_id;primaryKey;autoIncrement:false"`
+	OwnerID        uint               `gorm:"column:owner_id;not null;index"`
+	Enabled        bool               `gorm:"column:enabled;not null;default:false"`
+	IdPEntityID    string             `gorm:"column:idp_entity_id;size:1024;not null"`
+	IdPSSOURL      string             `gorm:"column:idp_sso_url;size:2048;not null"`
+	IdPCertificate string             `gorm:"column:idp_certificate;type:text;not null"` // PEM
+	LoginSuffix    string             `gorm:"column:login_suffix;size:255"`
+	AttrLogin      string             `gorm:"column:attr_login;size:255"`
+	AttrEmail      string             `gorm:"column:attr_email;size:255"`
+	AttrName       string             `gorm:"column:attr_name;size:255"`
+	AttrSurname    string             `gorm:"column:attr_surname;size:255"`
+	AttrPhone      string             `gorm:"column:attr_phone;size:255"`
+	AttrRoles      string             `gorm:"column:attr_roles;size:255"`
+	RoleMapping    []saml.RoleMapping `gorm:"column:role_mapping;type:text;serializer:json"`
+	UpdatedAt      time.Time          `gorm:"column:updated_at;type:DATETIME;not null"`
+
+	Company InternalCompanyModel `gorm:"foreignKey:CompanyID;references:ID;constraint:OnDelete:CASCADE"`
+	Owner   UserModel            `gorm:"foreignKey:OwnerID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (SAMLConnectionModel) TableName() string { return "saml_connections" }
+
+// ToDomain converts SAMLConnectionModel to domain entity saml.Connection
+func (m *SAMLConnectionModel) ToDomain() *saml.Connection {
+	return &saml.Connection{
+		CompanyID:      m.CompanyID,
+		OwnerID:        m.OwnerID,
+		Enabled:        m.Enabled,
+		IdPEntityID:    m.IdPEntityID,
+		IdPSSOURL:      m.IdPSSOURL,
+		IdPCertificate: m.IdPCertificate,
+		LoginSuffix:    m.LoginSuffix,
+		Attributes: saml.Attributes{
+			Login:   m.AttrLogin,
+			Email:   m.AttrEmail,
+			Name:    m.AttrName,
+			Surname: m.AttrSurname,
+			Phone:   m.AttrPhone,
+			Roles:   m.AttrRoles,
+		},
+		RoleMapping: m.RoleMapping,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+// SAMLFlowModel — GORM-model for the saml_flows table (pending SAML logins)
+type SAMLFlowModel struct {
+	ID         string    `gorm:"column:id;type:char(36);primaryKey"`
+	CompanyID  uint      `gorm:"column:company_id;not null"`
+	RequestID  string    `gorm:"column:request_id;size:64;not null"`
+	RelayState string    `gorm:"column:relay_state;size:64;not null;uniqueIndex"`
+	UserID     *uint     `gorm:"column:user_id;default:null"`
+	ExpiresAt  time.Time `gorm:"column:expires_at;type:DATETIME;not null;index"`
+
+	Company InternalCompanyModel `gorm:"foreignKey:CompanyID;references:ID;constraint:OnDelete:CASCADE"`
+	User    *UserModel           `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (SAMLFlowModel) TableName() string { return "saml_flows" }
+
+// ToDomain converts SAMLFlowModel to domain entity saml.Flow
+func (m *SAMLFlowModel) ToDomain() *saml.Flow {
+	return &saml.Flow{
+		ID:         m.ID,
+		CompanyID:  m.CompanyID,
+		RequestID:  m.RequestID,
+		RelayState: m.RelayState,
+		UserID:     m.UserID,
+		ExpiresAt:  m.ExpiresAt,
+	}
+}
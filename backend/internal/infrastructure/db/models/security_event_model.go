@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"app/internal/domain/security"
+)
+
+// SecurityEventModel — GORM-model for the security_events table
+type SecurityEventModel struct {
+	ID        uint      `gorm:"column:id;primaryKey"`
+	Type      string    `gorm:"column:type;size:64;not null;index"`
+	UserID    *uint     `gorm:"column:user_id;default:null;index"`
+	Username  string    `gorm:"column:username;size:255"`
+	SessionID string    `gorm:"column:session_id;size:36"`
+	IP        string    `gorm:"column:ip;size:64"`
+	UserAgent string    `gorm:"column:user_agent;size:512"`
+	Details   string    `gorm:"column:details;type:text"`
+	CreatedAt time.Time `gorm:"column:created_at;type:DATETIME;not null;index"`
+}
+
+func (SecurityEventModel) TableName() string { return "security_events" }
+
+// ToDomain converts SecurityEventModel to domain entity security.Event
+func (em *SecurityEventModel) ToDomain() *security.Event {
+	return &security.Event{
+		ID:        em.ID,
+		Type:      security.EventType(em.Type),
+		UserID:    em.UserID,
+		Username:  em.Username,
+		SessionID: em.SessionID,
+		IP:        em.IP,
+		UserAgent: em.UserAgent,
+		Details:   em.Details,
+		CreatedAt: em.CreatedAt,
+	}
+}
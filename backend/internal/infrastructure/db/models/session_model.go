@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"app/internal/domain/session"
+)
+
+// SessionModel — GORM-model for the sessions table (one row per logged device)
+type SessionModel struct {
+	ID            string     `gorm:"column:id;type:char(36);primaryKey"`
+	UserID        uint       `gorm:"column:user_id;not null;index"`
+	Device        string     `gorm:"column:device;size:255"`
+	UserAgent     string     `gorm:"column:user_agent;size:512"`
+	IP            string     `gorm:"column:ip;size:64"`
+	CreatedAt     time.Time  `gorm:"column:created_at;type:DATETIME;not null"`
+	LastUsedAt    time.Time  `gorm:"column:last_used_at;type:DATETIME;not null"`
+	ExpiresAt     time.Time  `gorm:"column:expires_at;type:DATETIME;not null;index"`
+	RevokedAt     *time.Time `gorm:"column:revoked_at;type:DATETIME;default:null"`
+	CompromisedAt *time.Time `gorm:"column:compromised_at;type:DATETIME;default:null"`
+	ClientID      string     `gorm:"column:client_id;size:36"`
+	Scope         string     `gorm:"column:scope;size:1024"`
+
+	User UserModel `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (SessionModel) TableName() string { return "sessions" }
+
+// ToDomain converts SessionModel to domain entity session.Session
+func (sm *SessionModel) ToDomain() *session.Session {
+	return &session.Session{
+		ID:            sm.ID,
+		UserID:        sm.UserID,
+		Device:        sm.Device,
+		UserAgent:     sm.UserAgent,
+		IP:            sm.IP,
+		CreatedAt:     sm.CreatedAt,
+		LastUsedAt:    sm.LastUsedAt,
+		ExpiresAt:     sm.ExpiresAt,
+		RevokedAt:     sm.RevokedAt,
+		CompromisedAt: sm.CompromisedAt,
+		ClientID:      sm.ClientID,
+		Scope:         sm.Scope,
+	}
+}
+
+// RefreshTokenModel — GORM-model for the refresh_tokens table (rotation family of a session)
+type RefreshTokenModel struct {
+	ID        uint       `gorm:"column:id;primaryKey"`
+	SessionID string     `gorm:"column:session_id;type:char(36);not null;index"`
+	TokenHash string     `gorm:"column:token_hash;type:char(64);not null;uniqueIndex"` // sha256 of the refresh token
+	ParentID  *uint      `gorm:"column:parent_id;default:null"`
+	CreatedAt time.Time  `gorm:"column:created_at;type:DATETIME;not null"`
+	ExpiresAt time.Time  `gorm:"column:expires_at;type:DATETIME;not null"`
+	RotatedAt *time.Time `gorm:"column:rotated_at;type:DATETIME;default:null"`
+	RevokedAt *time.Time `gorm:"column:revoked_at;type:DATETIME;default:null"`
+
+	Session SessionModel `gorm:"foreignKey:SessionID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (RefreshTokenModel) TableName() string { return "refresh_tokens" }
+
+// ToDomain converts RefreshTokenModel to domain entity session.RefreshToken
+func (tm *RefreshTokenModel) ToDomain() *session.RefreshToken {
+	return &session.RefreshToken{
+		ID:        tm.ID,
+		SessionID: tm.SessionID,
+		TokenHash: tm.TokenHash,
+		ParentID:  tm.ParentID,
+		CreatedAt: tm.CreatedAt,
+		ExpiresAt: tm.ExpiresAt,
+		RotatedAt: tm.RotatedAt,
+		RevokedAt: tm.RevokedAt,
+	}
+}
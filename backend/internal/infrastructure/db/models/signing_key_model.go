@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SigningKeyModel — GORM-model for the signing_keys table (key ring of the access tokens).
+// The private key is sealed with the service secret, it is never stored in clear.
+type SigningKeyModel struct {
+	ID               string     `gorm:"column:id;primaryKey;size:64"`
+	PublicKey        []byte     `gorm:"column:public_key;type:VARBINARY(32);not null"`
+	SealedPrivateKey []byte     `gorm:"column:sealed_private_key;type:VARBINARY(128);not null"`
+	CreatedAt        time.Time  `gorm:"column:created_at;type:DATETIME;not null"`
+	RetiredAt        *time.Time `gorm:"column:retired_at;type:DATETIME;default:null"`
+	ExpiresAt        *time.Time `gorm:"column:expires_at;type:DATETIME;default:null;index"`
+}
+
+func (SigningKeyModel) TableName() string { return "signing_keys" }
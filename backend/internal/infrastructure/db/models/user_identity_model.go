@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"app/internal/domain/user_identity"
+)
+
+// UserIdentityModel — GORM-model for the user_identities table (logins of the users at other providers)
+type UserIdentityModel struct {
+	ID         uint       `gorm:"column:id;primaryKey"`
+	UserID     uint       `gorm:"column:user_id;not null;index"`
+	ProviderID uint       `gorm:"column:provider_id;not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject    string     `gorm:"column:subject;size:255;not null;uniqueIndex:idx_user_identities_provider_subject;index"`
+	CreatedAt  time.Time  `gorm:"column:created_at;type:DATETIME;not null"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at;type:DATETIME;default:null"`
+
+	User     UserModel     `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE"`
+	Provider ProviderModel `gorm:"foreignKey:ProviderID;references:ID;constraint:OnDelete:CASCADE"`
+}
+
+func (UserIdentityModel) TableName() string { return "user_identities" }
+
+// ToDomain converts UserIdentityModel to domain entity user_identity.UserIdentity
+// (the name of the provider is set if the provider is preloaded)
+func (m *UserIdentityModel) ToDomain() *user_identity.UserIdentity {
+	return &user_identity.UserIdentity{
+		ID:           m.ID,
+		UserID:       m.UserID,
+		ProviderID:   m.ProviderID,
+		ProviderName: m.Provider.Name,
+		Subject:      m.Subject,
+		CreatedAt:    m.CreatedAt,
+		LastUsedAt:   m.LastUsedAt,
+	}
+}
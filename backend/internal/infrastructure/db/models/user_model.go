@@ -27,12 +27,16 @@ type UserModel struct {
 	ProviderID   uint   `gorm:"column:providerId;not null;default:1"`
 	ProviderName string `gorm:"column:providerName;size:255;not null;default:'Liftel'"`
 
-	Refresh    *string `gorm:"column:refresh;size:255,default:null"` // Refresh token
-	RefreshExp string  `gorm:"column:refreshExp;type:DATETIME"`      // Refresh token expiration date
-	Active     bool    `gorm:"column:active;default:true"`
-	IsLogged   bool    `gorm:"column:isLogged;default:false"`
-	LastAccess string  `gorm:"column:lastAccess;type:DATETIME"`
-	CreatedAt  string  `gorm:"column:createdAt;type:datetime"`
+	Active     bool   `gorm:"column:active;default:true"`
+	IsLogged   bool   `gorm:"column:isLogged;default:false"`
+	LastAccess string `gorm:"column:lastAccess;type:DATETIME"`
+	CreatedAt  string `gorm:"column:createdAt;type:datetime"`
+
+	// Temporary lock after too many failed login attempts (independent of Active)
+	LockedUntil *time.Time `gorm:"column:lockedUntil;type:DATETIME;default:null"`
+
+	// Last change of the password (null: not changed since the user was created)
+	PasswordChangedAt *time.Time `gorm:"column:passwordChangedAt;type:DATETIME;default:null"`
 
 	// GORM will load the Provider automatically
 	Provider ProviderModel `gorm:"foreignKey:ProviderID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
@@ -55,7 +59,6 @@ func (u *UserModel) BeforeCreate(tx *gorm.DB) (err error) {
 
 	u.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
 	u.LastAccess = time.Now().Format("2006-01-02 15:04:05")
-	u.RefreshExp = time.Now().Format("2006-01-02 15:04:05")
 
 	// Hash password if needed
 	if err := u.hashPasswordIfNeeded(); err != nil {
@@ -130,10 +133,12 @@ func (um *UserModel) ToDomain() *user.User {
 		IsLogged:     um.IsLogged,
 		CreatedAt:    um.CreatedAt,
 		LastAccess:   um.LastAccess,
-		Refresh:      um.Refresh,
-		RefreshExp:   um.RefreshExp,
 		OwnerID:      um.OwnerID,
+		LockedUntil:  um.LockedUntil,
+
+		PasswordChangedAt: um.PasswordChangedAt,
 	}
+	domainUser.Locked = domainUser.IsLocked(time.Now())
 
 	// Parse and format CreatedAt if it's not empty
 	if um.CreatedAt != "" {
@@ -155,16 +160,6 @@ func (um *UserModel) ToDomain() *user.User {
 		}
 	}
 
-	// Parse LastAccess if it's not empty
-	if um.RefreshExp != "" {
-		parsedTime, err := time.Parse(time.RFC3339, um.RefreshExp)
-		if err != nil {
-			log.Printf("Error parsing RefreshExp time: %v", err)
-		} else {
-			domainUser.RefreshExp = parsedTime.Format("2006-01-02 15:04:05")
-		}
-	}
-
 	// If UserModel has a profile (Preload("Profile") loaded it),
 	// then convert it to domain.Profile
 	if um.Profile != nil {
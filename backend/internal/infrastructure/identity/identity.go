@@ -0,0 +1,90 @@
+package identity
+
+import (
+	"fmt"
+	"strings"
+
+	"app/internal/application/ports"
+	"app/internal/domain/provider"
+	"app/internal/domain/saml"
+	"app/internal/domain/user"
+)
+
+// Config — identity providers configured for the companies
+type Config struct {
+	LDAP []LDAPConfig // LDAP / Active Directory directories
+	OIDC []OIDCConfig // external OpenID Connect providers
+	SAML SAMLConfig   // SAML 2.0 identity providers of the companies
+}
+
+const samlDesc = "SAML 2.0 identity providers of the companies"
+
+// Initialize registers the identity providers of the service and the ones configured for the companies,
+// and binds them to the providers table (the rows of the configured providers are created if missing).
+// Logins of users that don't exist yet are tried against them in this order.
+func Initialize(providerRepo provider.Repository, userRepo user.Repository, samlRepo saml.Repository, verSvc ports.VerificacionesService, cfg Config) error {
+	provider.Register(NewLocalProvider(provider.NameLiftel))
+	provider.Register(NewVerificacionesProvider(verSvc))
+	provider.Register(NewLocalProvider(provider.NameSecondary))
+
+	var configured []provider.IdentityProvider
+	for _, dir := range cfg.LDAP {
+		idp, err := NewLDAPProvider(dir, userRepo)
+		if err != nil {
+			return err
+		}
+		configured = append(configured, idp)
+	}
+	for _, op := range cfg.OIDC {
+		idp, err := NewOIDCProvider(op, userRepo)
+		if err != nil {
+			return err
+		}
+		configured = append(configured, idp)
+	}
+	if cfg.SAML.BaseURL != "" {
+		idp, err := NewSAMLProvider(cfg.SAML, samlRepo, userRepo)
+		if err != nil {
+			return err
+		}
+		configured = append(configured, idp)
+	}
+
+	providers, err := providerRepo.GetAll()
+	if err != nil {
+		return fmt.Errorf("error loading providers: %w", err)
+	}
+	descs := descriptions(cfg)
+	for _, idp := range configured {
+		if !hasProvider(providers, idp.Name()) {
+			p := provider.Provider{Name: idp.Name(), Desc: descs[idp.Name()]}
+			if err := providerRepo.Create(&p); err != nil {
+				return fmt.Errorf("error creating provider %q: %w", idp.Name(), err)
+			}
+			providers = append(providers, p)
+		}
+		provider.Register(idp)
+	}
+	return provider.Bind(providers)
+}
+
+// descriptions returns the description of each configured provider by name
+func descriptions(cfg Config) map[string]string {
+	descs := map[string]string{provider.NameSAML: samlDesc}
+	for _, dir := range cfg.LDAP {
+		descs[dir.Name] = dir.Desc
+	}
+	for _, op := range cfg.OIDC {
+		descs[op.Name] = op.Desc
+	}
+	return descs
+}
+
+func hasProvider(providers []provider.Provider, name string) bool {
+	for _, p := range providers {
+		if strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,287 @@
+package identity
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"app/internal/domain/provider"
+	"app/internal/domain/user"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConfig — directory (LDAP / Active Directory) whose users sign in with their directory credentials.
+// Its users are created on their first login as subusers of the company owner.
+type LDAPConfig struct {
+	Name string `mapstructure:"name"` // name of the provider (its row is created if missing)
+	Desc string `mapstructure:"desc"`
+
+	URL                string        `mapstructure:"url"` // ldap://host:389 or ldaps://host:636
+	StartTLS           bool          `mapstructure:"start_tls"`
+	InsecureSkipVerify bool          `mapstructure:"insecure_skip_verify"`
+	Timeout            time.Duration `mapstructure:"timeout"`
+
+	// Service account used to search the users (anonymous search if empty).
+	// Its password is read from the environment variable BindPasswordEnv.
+	BindDN          string `mapstructure:"bind_dn"`
+	BindPasswordEnv string `mapstructure:"bind_password_env"`
+
+	// Search of the user: {login} is replaced by the login and {username} by the login
+	// without LoginSuffix (both escaped), e.g. (&(objectClass=user)(sAMAccountName={username}))
+	BaseDN     string `mapstructure:"base_dn"`
+	UserFilter string `mapstructure:"user_filter"`
+	// Only logins ending with the suffix (e.g. "@acme.com") are looked up in the directory
+	LoginSuffix string `mapstructure:"login_suffix"`
+
+	// Login of the company owner the users belong to
+	Owner string `mapstructure:"owner"`
+
+	Attributes     LDAPAttributes  `mapstructure:"attributes"`
+	GroupAttribute string          `mapstructure:"group_attribute"` // e.g. memberOf
+	GroupRoles     []LDAPGroupRole `mapstructure:"group_roles"`
+}
+
+// LDAPAttributes — attributes of the directory entry copied to the profile of the user (empty: not copied)
+type LDAPAttributes struct {
+	Email   string `mapstructure:"email"`
+	Name    string `mapstructure:"name"`
+	Surname string `mapstructure:"surname"`
+	Phone   string `mapstructure:"phone"`
+}
+
+// LDAPGroupRole — roles granted to the members of a group (DN, case-insensitive)
+type LDAPGroupRole struct {
+	Group string   `mapstructure:"group"`
+	Roles []string `mapstructure:"roles"`
+}
+
+const defaultLDAPTimeout = 10 * time.Second
+
+var errAmbiguousLDAPUser = errors.New("login matches more than one directory entry")
+
+// ldapConn — operations of a directory connection used by the provider (*ldap.Conn)
+type ldapConn interface {
+	Bind(username, password string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// ldapProvider — users of a directory, created on their first login
+type ldapProvider struct {
+	cfg          LDAPConfig
+	bindPassword string
+	mappings     []roleMapping // groups => roles
+	managedRoles []string
+	userRepo     user.Repository
+	dial         func() (ldapConn, error)
+}
+
+// Ensure ldapProvider implements the domain interface
+var _ provider.IdentityProvider = (*ldapProvider)(nil)
+
+// NewLDAPProvider returns the identity provider of a directory. The owner of the users must be a company owner.
+func NewLDAPProvider(cfg LDAPConfig, userRepo user.Repository) (provider.IdentityProvider, error) {
+	if cfg.Name == "" || cfg.URL == "" || cfg.BaseDN == "" || cfg.Owner == "" {
+		return nil, errors.New("ldap directory requires name, url, base_dn and owner")
+	}
+	if !strings.Contains(cfg.UserFilter, "{login}") && !strings.Contains(cfg.UserFilter, "{username}") {
+		return nil, fmt.Errorf("ldap directory %q: user_filter must contain {login} or {username}", cfg.Name)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultLDAPTimeout
+	}
+
+	mappings := make([]roleMapping, len(cfg.GroupRoles))
+	for i, gr := range cfg.GroupRoles {
+		mappings[i] = roleMapping{value: gr.Group, roles: gr.Roles}
+	}
+	managed, err := managedRoles(cfg.Name, mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := companyOwner(userRepo, cfg.Name, cfg.Owner); err != nil {
+		return nil, err
+	}
+
+	p := &ldapProvider{
+		cfg:          cfg,
+		bindPassword: os.Getenv(cfg.BindPasswordEnv),
+		mappings:     mappings,
+		managedRoles: managed,
+		userRepo:     userRepo,
+	}
+	p.dial = p.dialDirectory
+	return p, nil
+}
+
+func (p *ldapProvider) Name() string { return p.cfg.Name }
+
+func (p *ldapProvider) Authenticate(login, password string, usr *user.User) (*provider.Identity, error) {
+	username, ok := p.username(login)
+	if !ok {
+		return nil, provider.ErrUnknownUser
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, fmt.Errorf("ldap connection error: %w", err)
+	}
+	defer conn.Close()
+
+	if p.cfg.BindDN != "" {
+		if err := conn.Bind(p.cfg.BindDN, p.bindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service bind error: %w", err)
+		}
+	}
+
+	entry, err := p.searchUser(conn, login, username)
+	if err != nil {
+		return nil, err
+	}
+
+	// A bind with an empty password is an unauthenticated bind, accepted for any DN
+	if password == "" {
+		return nil, provider.ErrInvalidCredentials
+	}
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, provider.ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("ldap user bind error: %w", err)
+	}
+
+	return p.identity(login, entry), nil
+}
+
+// SupportsPasswordReset — the passwords are managed by the directory (never stored here)
+func (p *ldapProvider) SupportsPasswordReset() bool { return false }
+
+// ProvisionUser creates the user as a subuser of the owner of the directory
+func (p *ldapProvider) ProvisionUser(identity *provider.Identity) (*user.User, error) {
+	owner, err := companyOwner(p.userRepo, p.cfg.Name, p.cfg.Owner)
+	if err != nil {
+		return nil, err
+	}
+	return subuserOf(owner, identity), nil
+}
+
+// SyncProfile — the company of the user is the one of its owner, nothing to update
+// (the profile and the roles of the identity are applied by the caller)
+func (p *ldapProvider) SyncProfile(usr *user.User, identity *provider.Identity) {}
+
+// username returns the login without the login suffix, or false if the login is not of the directory
+func (p *ldapProvider) username(login string) (string, bool) {
+	if p.cfg.LoginSuffix == "" {
+		return login, login != ""
+	}
+	if len(login) <= len(p.cfg.LoginSuffix) || !strings.HasSuffix(strings.ToLower(login), strings.ToLower(p.cfg.LoginSuffix)) {
+		return "", false
+	}
+	return login[:len(login)-len(p.cfg.LoginSuffix)], true
+}
+
+// searchUser returns the only entry of the user (ErrUnknownUser if it has none)
+func (p *ldapProvider) searchUser(conn ldapConn, login, username string) (*ldap.Entry, error) {
+	filter := strings.NewReplacer(
+		"{login}", ldap.EscapeFilter(login),
+		"{username}", ldap.EscapeFilter(username),
+	).Replace(p.cfg.UserFilter)
+
+	req := ldap.NewSearchRequest(
+		p.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(p.cfg.Timeout/time.Second), false,
+		filter, p.attributes(), nil,
+	)
+	res, err := conn.Search(req)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, errAmbiguousLDAPUser
+		}
+		return nil, fmt.Errorf("ldap search error: %w", err)
+	}
+
+	switch len(res.Entries) {
+	case 0:
+		return nil, provider.ErrUnknownUser
+	case 1:
+		return res.Entries[0], nil
+	default:
+		return nil, errAmbiguousLDAPUser
+	}
+}
+
+// attributes returns the attributes of the entry used by the provider
+func (p *ldapProvider) attributes() []string {
+	var attrs []string
+	for _, a := range []string{p.cfg.Attributes.Email, p.cfg.Attributes.Name, p.cfg.Attributes.Surname, p.cfg.Attributes.Phone, p.cfg.GroupAttribute} {
+		if a != "" {
+			attrs = append(attrs, a)
+		}
+	}
+	if len(attrs) == 0 {
+		// Only the DN is needed
+		return []string{"1.1"}
+	}
+	return attrs
+}
+
+// identity maps the entry of the user: attributes to the profile and groups to roles
+func (p *ldapProvider) identity(login string, entry *ldap.Entry) *provider.Identity {
+	identity := &provider.Identity{
+		Login:        login,
+		Email:        p.attribute(entry, p.cfg.Attributes.Email),
+		Name:         p.attribute(entry, p.cfg.Attributes.Name),
+		Surname:      p.attribute(entry, p.cfg.Attributes.Surname),
+		Phone:        p.attribute(entry, p.cfg.Attributes.Phone),
+		ManagedRoles: p.managedRoles,
+	}
+
+	if p.cfg.GroupAttribute == "" {
+		return identity
+	}
+	identity.Roles = grantedRoles(entry.GetEqualFoldAttributeValues(p.cfg.GroupAttribute), p.mappings)
+	return identity
+}
+
+func (p *ldapProvider) attribute(entry *ldap.Entry, name string) string {
+	if name == "" {
+		return ""
+	}
+	return entry.GetEqualFoldAttributeValue(name)
+}
+
+// dialDirectory connects to the directory (TLS with ldaps:// or StartTLS)
+func (p *ldapProvider) dialDirectory() (ldapConn, error) {
+	u, err := url.Parse(p.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: p.cfg.InsecureSkipVerify,
+	}
+
+	conn, err := ldap.DialURL(p.cfg.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: p.cfg.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(p.cfg.Timeout)
+
+	if p.cfg.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("start tls error: %w", err)
+		}
+	}
+	return conn, nil
+}
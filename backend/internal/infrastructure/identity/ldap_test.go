@@ -0,0 +1,250 @@
+package identity
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"app/internal/domain/provider"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	testBindDN       = "cn=service,dc=acme,dc=com"
+	testBindPassword = "service-secret"
+	testUserFilter   = "(&(objectClass=user)(sAMAccountName={username}))"
+)
+
+// fakeDirectory — directory that stands in for the LDAP server: the entries returned for each
+// filter and the passwords of the DNs. It records the binds and the searches it receives.
+type fakeDirectory struct {
+	results   map[string][]*ldap.Entry
+	passwords map[string]string
+	searchErr error
+
+	binds   []string
+	filters []string
+	closed  bool
+}
+
+func (d *fakeDirectory) Bind(username, password string) error {
+	d.binds = append(d.binds, username)
+	if want, ok := d.passwords[username]; !ok || want != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	return nil
+}
+
+func (d *fakeDirectory) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	d.filters = append(d.filters, req.Filter)
+	if d.searchErr != nil {
+		return nil, d.searchErr
+	}
+	return &ldap.SearchResult{Entries: d.results[req.Filter]}, nil
+}
+
+func (d *fakeDirectory) Close() error {
+	d.closed = true
+	return nil
+}
+
+func userFilter(username string) string {
+	return strings.ReplaceAll(testUserFilter, "{username}", username)
+}
+
+func newFakeDirectory() *fakeDirectory {
+	jdoe := "cn=John Doe,ou=users,dc=acme,dc=com"
+	return &fakeDirectory{
+		results: map[string][]*ldap.Entry{
+			userFilter("jdoe"): {ldap.NewEntry(jdoe, map[string][]string{
+				"mail":      {"jdoe@acme.com"},
+				"givenName": {"John"},
+				"sn":        {"Doe"},
+				"memberOf":  {"CN=Technicians,OU=Groups,DC=acme,DC=com", "cn=others,ou=groups,dc=acme,dc=com"},
+			})},
+			userFilter("twin"): {
+				ldap.NewEntry("cn=Twin 1,ou=users,dc=acme,dc=com", nil),
+				ldap.NewEntry("cn=Twin 2,ou=users,dc=acme,dc=com", nil),
+			},
+		},
+		passwords: map[string]string{
+			testBindDN: testBindPassword,
+			jdoe:       "user-secret",
+		},
+	}
+}
+
+func testLDAPConfig() LDAPConfig {
+	return LDAPConfig{
+		Name:            "AcmeAD",
+		URL:             "ldaps://ad.acme.com:636",
+		BindDN:          testBindDN,
+		BindPasswordEnv: "TEST_LDAP_BIND_PASSWORD",
+		BaseDN:          "dc=acme,dc=com",
+		UserFilter:      testUserFilter,
+		LoginSuffix:     "@acme.com",
+		Owner:           "acme",
+		Attributes:      LDAPAttributes{Email: "mail", Name: "givenName", Surname: "sn"},
+		GroupAttribute:  "memberOf",
+		GroupRoles: []LDAPGroupRole{
+			{Group: "cn=technicians,ou=groups,dc=acme,dc=com", Roles: []string{"technician", "liftplay"}},
+			{Group: "cn=admins,ou=groups,dc=acme,dc=com", Roles: []string{"admin"}},
+		},
+	}
+}
+
+// newTestLDAPProvider returns the provider of the directory connected to the fake directory
+func newTestLDAPProvider(t *testing.T, dir *fakeDirectory) *ldapProvider {
+	t.Helper()
+	t.Setenv("TEST_LDAP_BIND_PASSWORD", testBindPassword)
+	p, err := NewLDAPProvider(testLDAPConfig(), newTestUserRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lp := p.(*ldapProvider)
+	lp.dial = func() (ldapConn, error) {
+		if dir == nil {
+			return nil, errors.New("the directory must not be contacted")
+		}
+		return dir, nil
+	}
+	return lp
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	dir := newFakeDirectory()
+	p := newTestLDAPProvider(t, dir)
+
+	identity, err := p.Authenticate("jdoe@acme.com", "user-secret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Login != "jdoe@acme.com" || identity.Email != "jdoe@acme.com" || identity.Name != "John" || identity.Surname != "Doe" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	// Groups are compared case-insensitively
+	if !reflect.DeepEqual(identity.Roles, []string{"technician", "liftplay"}) {
+		t.Errorf("roles %v", identity.Roles)
+	}
+	if !reflect.DeepEqual(identity.ManagedRoles, []string{"technician", "liftplay", "admin"}) {
+		t.Errorf("managed roles %v", identity.ManagedRoles)
+	}
+
+	// The service account searches the user, then the user binds with its own password
+	if !reflect.DeepEqual(dir.binds, []string{testBindDN, "cn=John Doe,ou=users,dc=acme,dc=com"}) {
+		t.Errorf("binds %v", dir.binds)
+	}
+	if !dir.closed {
+		t.Error("connection not closed")
+	}
+}
+
+func TestLDAPAuthenticateRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		prepare  func(dir *fakeDirectory)
+		want     error
+	}{
+		{name: "wrong password", login: "jdoe@acme.com", password: "wrong", want: provider.ErrInvalidCredentials},
+		{name: "unknown user", login: "nobody@acme.com", password: "user-secret", want: provider.ErrUnknownUser},
+		{name: "login matching several entries", login: "twin@acme.com", password: "user-secret", want: errAmbiguousLDAPUser},
+		{name: "size limit exceeded", login: "jdoe@acme.com", password: "user-secret", prepare: func(dir *fakeDirectory) {
+			dir.searchErr = ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded"))
+		}, want: errAmbiguousLDAPUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newFakeDirectory()
+			if tt.prepare != nil {
+				tt.prepare(dir)
+			}
+			if _, err := newTestLDAPProvider(t, dir).Authenticate(tt.login, tt.password, nil); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLDAPAuthenticateEmptyPassword(t *testing.T) {
+	dir := newFakeDirectory()
+
+	// A bind with an empty password is an unauthenticated bind that the directory accepts for any DN
+	if _, err := newTestLDAPProvider(t, dir).Authenticate("jdoe@acme.com", "", nil); !errors.Is(err, provider.ErrInvalidCredentials) {
+		t.Fatalf("got %v, want %v", err, provider.ErrInvalidCredentials)
+	}
+	if !reflect.DeepEqual(dir.binds, []string{testBindDN}) {
+		t.Errorf("binds %v: the user must not be bound", dir.binds)
+	}
+}
+
+func TestLDAPAuthenticateOtherLogins(t *testing.T) {
+	// Logins without the suffix of the directory are not looked up in it
+	p := newTestLDAPProvider(t, nil)
+	for _, login := range []string{"jdoe@other.com", "jdoe", "@acme.com", ""} {
+		if _, err := p.Authenticate(login, "user-secret", nil); !errors.Is(err, provider.ErrUnknownUser) {
+			t.Errorf("%q: got %v, want %v", login, err, provider.ErrUnknownUser)
+		}
+	}
+}
+
+func TestLDAPAuthenticateEscapesFilter(t *testing.T) {
+	dir := newFakeDirectory()
+
+	_, err := newTestLDAPProvider(t, dir).Authenticate("*)(objectClass=*@acme.com", "user-secret", nil)
+	if !errors.Is(err, provider.ErrUnknownUser) {
+		t.Fatalf("got %v, want %v", err, provider.ErrUnknownUser)
+	}
+	if want := userFilter(`\2a\29\28objectClass=\2a`); len(dir.filters) != 1 || dir.filters[0] != want {
+		t.Errorf("filters %v, want %s", dir.filters, want)
+	}
+}
+
+func TestLDAPAuthenticateServiceBindError(t *testing.T) {
+	dir := newFakeDirectory()
+	dir.passwords[testBindDN] = "rotated"
+
+	// A wrong service account is an error of the service, not of the user
+	_, err := newTestLDAPProvider(t, dir).Authenticate("jdoe@acme.com", "user-secret", nil)
+	if err == nil || errors.Is(err, provider.ErrInvalidCredentials) || errors.Is(err, provider.ErrUnknownUser) {
+		t.Errorf("got %v, want a service error", err)
+	}
+}
+
+func TestLDAPProvisionUser(t *testing.T) {
+	usr, err := newTestLDAPProvider(t, nil).ProvisionUser(&provider.Identity{Login: "jdoe@acme.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usr.OwnerID == nil || *usr.OwnerID != 1 || usr.CompanyID != 20001 {
+		t.Errorf("unexpected user %+v", usr)
+	}
+}
+
+func TestNewLDAPProviderRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *LDAPConfig)
+	}{
+		{"without url", func(cfg *LDAPConfig) { cfg.URL = "" }},
+		{"without base DN", func(cfg *LDAPConfig) { cfg.BaseDN = "" }},
+		{"without owner", func(cfg *LDAPConfig) { cfg.Owner = "" }},
+		{"filter without login", func(cfg *LDAPConfig) { cfg.UserFilter = "(objectClass=user)" }},
+		{"reserved role", func(cfg *LDAPConfig) { cfg.GroupRoles[0].Roles = []string{"company"} }},
+		{"company ID role", func(cfg *LDAPConfig) { cfg.GroupRoles[0].Roles = []string{"company_20001"} }},
+		{"owner is a subuser", func(cfg *LDAPConfig) { cfg.Owner = "tech1" }},
+		{"unknown owner", func(cfg *LDAPConfig) { cfg.Owner = "nobody" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testLDAPConfig()
+			tt.modify(&cfg)
+			if _, err := NewLDAPProvider(cfg, newTestUserRepository()); err == nil {
+				t.Error("invalid directory accepted")
+			}
+		})
+	}
+}
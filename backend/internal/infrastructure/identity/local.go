@@ -0,0 +1,51 @@
+package identity
+
+import (
+	"app/internal/domain/provider"
+	"app/internal/domain/user"
+)
+
+// localProvider — users whose passwords are stored (hashed) by this service
+type localProvider struct {
+	name string
+}
+
+// Ensure localProvider implements the domain interface
+var _ provider.IdentityProvider = (*localProvider)(nil)
+
+// NewLocalProvider returns the identity provider of the users with local passwords
+// (companies registered in the service, subusers)
+func NewLocalProvider(name string) provider.IdentityProvider {
+	return &localProvider{name: name}
+}
+
+func (p *localProvider) Name() string { return p.name }
+
+func (p *localProvider) Authenticate(login, password string, usr *user.User) (*provider.Identity, error) {
+	// Local users are registered in the service: a login without user is not ours
+	if usr == nil {
+		return nil, provider.ErrUnknownUser
+	}
+	if !usr.CheckPassword(password) {
+		return nil, provider.ErrInvalidCredentials
+	}
+
+	identity := &provider.Identity{
+		Login:       usr.Login,
+		CompanyID:   usr.CompanyID,
+		CompanyName: usr.CompanyName,
+	}
+	if usr.Profile != nil && usr.Profile.Email != nil {
+		identity.Email = *usr.Profile.Email
+	}
+	return identity, nil
+}
+
+func (p *localProvider) SupportsPasswordReset() bool { return true }
+
+func (p *localProvider) ProvisionUser(identity *provider.Identity) (*user.User, error) {
+	return nil, provider.ErrProvisioningNotSupported
+}
+
+// SyncProfile — the local user is the source of the identity, nothing to update
+func (p *localProvider) SyncProfile(usr *user.User, identity *provider.Identity) {}
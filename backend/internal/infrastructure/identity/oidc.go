@@ -0,0 +1,282 @@
+package identity
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"app/internal/domain/federation"
+	"app/internal/domain/provider"
+	"app/internal/domain/user"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCConfig — external OpenID Connect provider ("Sign in with ...") of a company.
+// Its users are created on their first login as subusers of the company owner.
+type OIDCConfig struct {
+	Name string `mapstructure:"name"` // name of the provider in the URLs (/auth/oidc/{name}/...), its row is created if missing
+	Desc string `mapstructure:"desc"`
+
+	Issuer          string        `mapstructure:"issuer"` // discovery: {issuer}/.well-known/openid-configuration
+	ClientID        string        `mapstructure:"client_id"`
+	ClientSecretEnv string        `mapstructure:"client_secret_env"` // environment variable with the client secret
+	RedirectURL     string        `mapstructure:"redirect_url"`      // page of the frontend that receives the callback
+	Scopes          []string      `mapstructure:"scopes"`            // openid is always requested
+	Timeout         time.Duration `mapstructure:"timeout"`
+
+	// Login of the company owner the users belong to
+	Owner string `mapstructure:"owner"`
+	// Only logins ending with the suffix (e.g. "@acme.com") are accepted
+	LoginSuffix string `mapstructure:"login_suffix"`
+	// Reject ID tokens whose email_verified claim is not true
+	RequireVerifiedEmail bool `mapstructure:"require_verified_email"`
+
+	Claims      OIDCClaims        `mapstructure:"claims"`
+	RoleMapping []OIDCRoleMapping `mapstructure:"role_mapping"`
+}
+
+// OIDCClaims — claims of the ID token mapped to the login, the profile and the roles of the user
+type OIDCClaims struct {
+	Login   string `mapstructure:"login"` // default email
+	Email   string `mapstructure:"email"` // default email
+	Name    string `mapstructure:"name"`  // default given_name
+	Surname string `mapstructure:"surname"`
+	Phone   string `mapstructure:"phone"`
+	Roles   string `mapstructure:"roles"` // string or list of strings, e.g. groups
+}
+
+// OIDCRoleMapping — roles granted when the roles claim has the value (case-insensitive)
+type OIDCRoleMapping struct {
+	Value string   `mapstructure:"value"`
+	Roles []string `mapstructure:"roles"`
+}
+
+const defaultOIDCTimeout = 10 * time.Second
+
+var oidcNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// oidcProvider — users of an external OpenID Connect provider, created on their first login
+type oidcProvider struct {
+	cfg          OIDCConfig
+	clientSecret string
+	mappings     []roleMapping // values of the roles claim => roles
+	managedRoles []string
+	userRepo     user.Repository
+
+	// Requests to the provider use the HTTP client of the context (it is not cancelled)
+	ctx context.Context
+
+	mu         sync.Mutex
+	discovered *oidc.Provider // discovered on first use (the provider may be down at startup)
+}
+
+// Ensure oidcProvider implements the domain interface
+var _ provider.FederatedProvider = (*oidcProvider)(nil)
+
+// NewOIDCProvider returns the identity provider of an external OpenID Connect provider.
+// The owner of the users must be a company owner.
+func NewOIDCProvider(cfg OIDCConfig, userRepo user.Repository) (provider.FederatedProvider, error) {
+	if !oidcNameRegexp.MatchString(cfg.Name) {
+		return nil, fmt.Errorf("oidc provider %q: name must only contain letters, digits, _ and -", cfg.Name)
+	}
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" || cfg.Owner == "" {
+		return nil, fmt.Errorf("oidc provider %q requires issuer, client_id, redirect_url and owner", cfg.Name)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultOIDCTimeout
+	}
+	if cfg.Claims.Login == "" {
+		cfg.Claims.Login = "email"
+	}
+	if cfg.Claims.Email == "" {
+		cfg.Claims.Email = "email"
+	}
+	if cfg.Claims.Name == "" {
+		cfg.Claims.Name = "given_name"
+	}
+	if cfg.Claims.Surname == "" {
+		cfg.Claims.Surname = "family_name"
+	}
+	if !containsFold(cfg.Scopes, oidc.ScopeOpenID) {
+		cfg.Scopes = append([]string{oidc.ScopeOpenID}, cfg.Scopes...)
+	}
+
+	mappings := make([]roleMapping, len(cfg.RoleMapping))
+	for i, rm := range cfg.RoleMapping {
+		mappings[i] = roleMapping{value: rm.Value, roles: rm.Roles}
+	}
+	managed, err := managedRoles(cfg.Name, mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := companyOwner(userRepo, cfg.Name, cfg.Owner); err != nil {
+		return nil, err
+	}
+
+	return &oidcProvider{
+		cfg:          cfg,
+		clientSecret: os.Getenv(cfg.ClientSecretEnv),
+		mappings:     mappings,
+		managedRoles: managed,
+		userRepo:     userRepo,
+		ctx:          oidc.ClientContext(context.Background(), &http.Client{Timeout: cfg.Timeout}),
+	}, nil
+}
+
+func (p *oidcProvider) Name() string { return p.cfg.Name }
+
+// Authenticate — the users of the provider sign in on the provider itself, never with a password
+func (p *oidcProvider) Authenticate(login, password string, usr *user.User) (*provider.Identity, error) {
+	if usr == nil {
+		return nil, provider.ErrUnknownUser
+	}
+	return nil, provider.ErrInvalidCredentials
+}
+
+// SupportsPasswordReset — the users have no password in this service
+func (p *oidcProvider) SupportsPasswordReset() bool { return false }
+
+// ProvisionUser creates the user as a subuser of the owner of the provider
+func (p *oidcProvider) ProvisionUser(identity *provider.Identity) (*user.User, error) {
+	owner, err := companyOwner(p.userRepo, p.cfg.Name, p.cfg.Owner)
+	if err != nil {
+		return nil, err
+	}
+	return subuserOf(owner, identity), nil
+}
+
+// SyncProfile — the company of the user is the one of its owner, nothing to update
+// (the profile and the roles of the identity are applied by the caller)
+func (p *oidcProvider) SyncProfile(usr *user.User, identity *provider.Identity) {}
+
+// AuthCodeURL returns the authorization URL of the provider (authorization code flow with PKCE S256)
+func (p *oidcProvider) AuthCodeURL(state, nonce, codeVerifier string) (string, error) {
+	conf, _, err := p.client()
+	if err != nil {
+		return "", err
+	}
+	return conf.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(codeVerifier)), nil
+}
+
+// Exchange redeems the code at the token endpoint and maps the claims of the verified ID token
+func (p *oidcProvider) Exchange(code, codeVerifier, nonce string) (*provider.Identity, error) {
+	conf, verifier, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := conf.Exchange(p.ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.Response != nil && retrieveErr.Response.StatusCode < http.StatusInternalServerError {
+			return nil, fmt.Errorf("%w: token exchange failed (%s)", federation.ErrLoginRejected, retrieveErr.ErrorCode)
+		}
+		return nil, fmt.Errorf("oidc token exchange error: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("%w: no id token", federation.ErrLoginRejected)
+	}
+	idToken, err := verifier.Verify(p.ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", federation.ErrLoginRejected, err)
+	}
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", federation.ErrLoginRejected)
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", federation.ErrLoginRejected, err)
+	}
+	return p.identity(claims)
+}
+
+// identity maps the claims of the ID token: login, profile and roles
+func (p *oidcProvider) identity(claims map[string]interface{}) (*provider.Identity, error) {
+	login := claimString(claims, p.cfg.Claims.Login)
+	if login == "" {
+		return nil, federation.ErrMissingIdentity
+	}
+	if p.cfg.LoginSuffix != "" && !strings.HasSuffix(strings.ToLower(login), strings.ToLower(p.cfg.LoginSuffix)) {
+		return nil, fmt.Errorf("%w: login not allowed", federation.ErrLoginRejected)
+	}
+	if p.cfg.RequireVerifiedEmail {
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return nil, fmt.Errorf("%w: email not verified", federation.ErrLoginRejected)
+		}
+	}
+
+	return &provider.Identity{
+		Login:        login,
+		Email:        claimString(claims, p.cfg.Claims.Email),
+		Name:         claimString(claims, p.cfg.Claims.Name),
+		Surname:      claimString(claims, p.cfg.Claims.Surname),
+		Phone:        claimString(claims, p.cfg.Claims.Phone),
+		Roles:        grantedRoles(claimStrings(claims, p.cfg.Claims.Roles), p.mappings),
+		ManagedRoles: p.managedRoles,
+	}, nil
+}
+
+// client returns the OAuth2 client and the ID token verifier of the provider (discovered once)
+func (p *oidcProvider) client() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovered == nil {
+		discovered, err := oidc.NewProvider(p.ctx, p.cfg.Issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("oidc discovery error: %w", err)
+		}
+		p.discovered = discovered
+	}
+
+	conf := &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.clientSecret,
+		Endpoint:     p.discovered.Endpoint(),
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       p.cfg.Scopes,
+	}
+	return conf, p.discovered.Verifier(&oidc.Config{ClientID: p.cfg.ClientID}), nil
+}
+
+// claimString returns a string claim (empty if the claim is not set or not a string)
+func claimString(claims map[string]interface{}, name string) string {
+	if name == "" {
+		return ""
+	}
+	value, _ := claims[name].(string)
+	return strings.TrimSpace(value)
+}
+
+// claimStrings returns a claim that is a string or a list of strings
+func claimStrings(claims map[string]interface{}, name string) []string {
+	if name == "" {
+		return nil
+	}
+	switch value := claims[name].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"app/internal/domain/federation"
+	"app/internal/domain/provider"
+)
+
+const (
+	testOIDCClientID     = "liftel"
+	testOIDCClientSecret = "client-secret"
+	testOIDCRedirectURL  = "https://app.example.com/auth/oidc/acme/callback"
+)
+
+// mockIdP — OpenID Connect provider (discovery, JWKS and token endpoint) that issues the
+// authorization codes of the users that sign in on it. Each code can be redeemed once, with
+// the client secret and the PKCE verifier of the authorization request.
+type mockIdP struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+	kid string
+
+	mu    sync.Mutex
+	codes map[string]*mockCode
+}
+
+type mockCode struct {
+	challenge string
+	idToken   string
+	used      bool
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+	idp := &mockIdP{key: newRSAKey(t), kid: "idp-key-1", codes: map[string]*mockCode{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                idp.srv.URL,
+			"authorization_endpoint":                idp.srv.URL + "/authorize",
+			"token_endpoint":                        idp.srv.URL + "/token",
+			"jwks_uri":                              idp.srv.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": idp.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != testOIDCClientID || secret != testOIDCClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code, ok := idp.codes[r.PostForm.Get("code")]
+	if !ok || code.used || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	code.used = true
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != code.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	res := map[string]interface{}{"access_token": "idp-access-token", "token_type": "Bearer", "expires_in": 3600}
+	if code.idToken != "" {
+		res["id_token"] = code.idToken
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// signIn returns the code of the user that signed in for the authorization URL,
+// with an ID token of the claims (nonce and standard claims are added if missing)
+func (idp *mockIdP) signIn(t *testing.T, authURL string, claims map[string]interface{}, key *rsa.PrivateKey) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+
+	now := time.Now()
+	full := map[string]interface{}{
+		"iss":   idp.srv.URL,
+		"sub":   "248289761001",
+		"aud":   q.Get("client_id"),
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": q.Get("nonce"),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(full, name)
+			continue
+		}
+		full[name] = value
+	}
+
+	var idToken string
+	if key != nil {
+		idToken = signRS256(t, key, idp.kid, full)
+	}
+	code := base64.RawURLEncoding.EncodeToString([]byte(t.Name() + now.String()))
+	idp.mu.Lock()
+	idp.codes[code] = &mockCode{challenge: q.Get("code_challenge"), idToken: idToken}
+	idp.mu.Unlock()
+	return code
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestOIDCProvider(t *testing.T, idp *mockIdP) *oidcProvider {
+	t.Helper()
+	t.Setenv("TEST_OIDC_CLIENT_SECRET", testOIDCClientSecret)
+	p, err := NewOIDCProvider(OIDCConfig{
+		Name:                 "acme",
+		Issuer:               idp.srv.URL,
+		ClientID:             testOIDCClientID,
+		ClientSecretEnv:      "TEST_OIDC_CLIENT_SECRET",
+		RedirectURL:          testOIDCRedirectURL,
+		Scopes:               []string{"email", "profile"},
+		Owner:                "acme",
+		LoginSuffix:          "@acme.com",
+		RequireVerifiedEmail: true,
+		Claims:               OIDCClaims{Roles: "groups"},
+		RoleMapping:          []OIDCRoleMapping{{Value: "technicians", Roles: []string{"technician"}}},
+	}, newTestUserRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*oidcProvider)
+}
+
+// Verifier of the PKCE of the tests (RFC 7636 appendix B)
+const testPKCEVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+var testUserClaims = map[string]interface{}{
+	"email":          "jdoe@acme.com",
+	"email_verified": true,
+	"given_name":     "John",
+	"family_name":    "Doe",
+	"groups":         []string{"Technicians", "Everyone"},
+}
+
+func TestOIDCAuthCodeURL(t *testing.T) {
+	idp := newMockIdP(t)
+	p := newTestOIDCProvider(t, idp)
+
+	authURL, err := p.AuthCodeURL("state-1", "nonce-1", testPKCEVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testOIDCClientID,
+		"redirect_uri":          testOIDCRedirectURL,
+		"scope":                 "openid email profile",
+		"state":                 "state-1",
+		"nonce":                 "nonce-1",
+		"code_challenge_method": "S256",
+		"code_challenge":        "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+	}
+	for name, value := range want {
+		if got := q.Get(name); got != value {
+			t.Errorf("%s: got %q, want %q", name, got, value)
+		}
+	}
+	if !strings.HasPrefix(authURL, idp.srv.URL+"/authorize?") {
+		t.Errorf("authorization URL %s is not the one of the discovery", authURL)
+	}
+}
+
+func TestOIDCExchange(t *testing.T) {
+	idp := newMockIdP(t)
+	p := newTestOIDCProvider(t, idp)
+
+	authURL, err := p.AuthCodeURL("state-1", "nonce-1", testPKCEVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := idp.signIn(t, authURL, testUserClaims, idp.key)
+
+	identity, err := p.Exchange(code, testPKCEVerifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Login != "jdoe@acme.com" || identity.Email != "jdoe@acme.com" || identity.Name != "John" || identity.Surname != "Doe" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	if !reflect.DeepEqual(identity.Roles, []string{"technician"}) || !reflect.DeepEqual(identity.ManagedRoles, []string{"technician"}) {
+		t.Errorf("roles %v, managed roles %v", identity.Roles, identity.ManagedRoles)
+	}
+
+	// The code is redeemed once: presented again, the provider rejects it
+	if _, err := p.Exchange(code, testPKCEVerifier, "nonce-1"); !errors.Is(err, federation.ErrLoginRejected) {
+		t.Errorf("replayed code: got %v, want %v", err, federation.ErrLoginRejected)
+	}
+}
+
+func TestOIDCExchangeRejects(t *testing.T) {
+	other := newRSAKey(t)
+	tests := []struct {
+		name     string
+		claims   map[string]interface{} // changes to the claims of the user (nil removes the claim)
+		key      func(idp *mockIdP) *rsa.PrivateKey
+		verifier string
+		nonce    string
+		want     error
+	}{
+		{name: "PKCE verifier mismatch", verifier: strings.Repeat("a", 43), want: federation.ErrLoginRejected},
+		{name: "nonce mismatch", nonce: "nonce-2", want: federation.ErrLoginRejected},
+		{name: "ID token signed by another key", key: func(*mockIdP) *rsa.PrivateKey { return other }, want: federation.ErrLoginRejected},
+		{name: "without ID token", key: func(*mockIdP) *rsa.PrivateKey { return nil }, want: federation.ErrLoginRejected},
+		{name: "ID token of another client", claims: map[string]interface{}{"aud": "other-client"}, want: federation.ErrLoginRejected},
+		{name: "ID token of another issuer", claims: map[string]interface{}{"iss": "https://evil.example.com"}, want: federation.ErrLoginRejected},
+		{name: "expired ID token", claims: map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}, want: federation.ErrLoginRejected},
+		{name: "ID token without nonce", claims: map[string]interface{}{"nonce": nil}, want: federation.ErrLoginRejected},
+		{name: "email not verified", claims: map[string]interface{}{"email_verified": false}, want: federation.ErrLoginRejected},
+		{name: "login of another domain", claims: map[string]interface{}{"email": "jdoe@evil.com"}, want: federation.ErrLoginRejected},
+		{name: "without login", claims: map[string]interface{}{"email": nil}, want: federation.ErrMissingIdentity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newMockIdP(t)
+			p := newTestOIDCProvider(t, idp)
+
+			authURL, err := p.AuthCodeURL("state-1", "nonce-1", testPKCEVerifier)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims := map[string]interface{}{}
+			for name, value := range testUserClaims {
+				claims[name] = value
+			}
+			for name, value := range tt.claims {
+				claims[name] = value
+			}
+			key := idp.key
+			if tt.key != nil {
+				key = tt.key(idp)
+			}
+			code := idp.signIn(t, authURL, claims, key)
+
+			verifier, nonce := testPKCEVerifier, "nonce-1"
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			identity, err := p.Exchange(code, verifier, nonce)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if identity != nil {
+				t.Error("identity returned")
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticateWithPassword(t *testing.T) {
+	p := newTestOIDCProvider(t, newMockIdP(t))
+
+	// The users sign in on the provider, never with a password of this service
+	if _, err := p.Authenticate("jdoe@acme.com", "secret", nil); !errors.Is(err, provider.ErrUnknownUser) {
+		t.Errorf("got %v, want %v", err, provider.ErrUnknownUser)
+	}
+}
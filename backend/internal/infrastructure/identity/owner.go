@@ -0,0 +1,31 @@
+package identity
+
+import (
+	"fmt"
+
+	"app/internal/domain/provider"
+	"app/internal/domain/user"
+)
+
+// companyOwner returns the company owner the users of a provider belong to
+func companyOwner(userRepo user.Repository, providerName, login string) (*user.User, error) {
+	owner, err := userRepo.GetByLogin(login)
+	if err != nil {
+		return nil, fmt.Errorf("provider %q: error retrieving owner %q: %w", providerName, login, err)
+	}
+	if owner.OwnerID != nil {
+		return nil, fmt.Errorf("provider %q: owner %q is a subuser", providerName, login)
+	}
+	return owner, nil
+}
+
+// subuserOf returns the new user of an identity as a subuser of the company owner
+func subuserOf(owner *user.User, identity *provider.Identity) *user.User {
+	return &user.User{
+		Login:       identity.Login,
+		OwnerID:     &owner.ID,
+		CompanyID:   owner.CompanyID,
+		CompanyName: owner.CompanyName,
+		Password:    nil, // do not store password
+	}
+}
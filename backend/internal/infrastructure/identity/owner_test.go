@@ -0,0 +1,86 @@
+package identity
+
+import (
+	"errors"
+	"testing"
+
+	"app/internal/domain/provider"
+	"app/internal/domain/user"
+)
+
+var errRecordNotFound = errors.New("record not found")
+
+// memoryUserRepository — users by login and ID (stands in for the users table).
+// A method that is not implemented panics if it is called.
+type memoryUserRepository struct {
+	user.Repository
+	users []*user.User
+}
+
+func (r *memoryUserRepository) GetByLogin(login string) (*user.User, error) {
+	for _, u := range r.users {
+		if u.Login == login {
+			found := *u
+			return &found, nil
+		}
+	}
+	return nil, errRecordNotFound
+}
+
+func (r *memoryUserRepository) GetByID(id uint) (*user.User, error) {
+	for _, u := range r.users {
+		if u.ID == id {
+			found := *u
+			return &found, nil
+		}
+	}
+	return nil, errRecordNotFound
+}
+
+func (r *memoryUserRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, errRecordNotFound)
+}
+
+// newTestUserRepository returns the company owner "acme" (ID 1) and its subuser "tech1" (ID 2)
+func newTestUserRepository() *memoryUserRepository {
+	ownerID := uint(1)
+	return &memoryUserRepository{users: []*user.User{
+		{ID: 1, Login: "acme", CompanyID: 20001, CompanyName: "Acme", Active: true},
+		{ID: 2, Login: "tech1", CompanyID: 20001, CompanyName: "Acme", OwnerID: &ownerID, Active: true},
+	}}
+}
+
+func TestCompanyOwner(t *testing.T) {
+	repo := newTestUserRepository()
+
+	owner, err := companyOwner(repo, "directory", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner.ID != 1 {
+		t.Errorf("got owner %d", owner.ID)
+	}
+
+	if _, err := companyOwner(repo, "directory", "tech1"); err == nil {
+		t.Error("subuser accepted as owner")
+	}
+	if _, err := companyOwner(repo, "directory", "unknown"); err == nil {
+		t.Error("unknown owner accepted")
+	}
+}
+
+func TestSubuserOf(t *testing.T) {
+	owner, _ := newTestUserRepository().GetByLogin("acme")
+
+	usr := subuserOf(owner, &provider.Identity{Login: "jdoe@acme.com", CompanyID: 99999, CompanyName: "Other"})
+	if usr.Login != "jdoe@acme.com" || usr.OwnerID == nil || *usr.OwnerID != owner.ID {
+		t.Errorf("unexpected user %+v", usr)
+	}
+	// The company is always the one of the owner, never the one sent by the provider
+	if usr.CompanyID != 20001 || usr.CompanyName != "Acme" {
+		t.Errorf("company %d %q, want the one of the owner", usr.CompanyID, usr.CompanyName)
+	}
+	if usr.Password != nil {
+		t.Error("password stored for a user of a provider")
+	}
+}
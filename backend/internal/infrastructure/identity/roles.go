@@ -0,0 +1,60 @@
+package identity
+
+import (
+	"fmt"
+	"strings"
+
+	"app/internal/domain/role"
+)
+
+// roleMapping — local roles granted for a value of the provider (group of a directory, value of a claim)
+type roleMapping struct {
+	value string
+	roles []string
+}
+
+// managedRoles returns the roles granted by the mappings of a provider (without duplicates).
+// The roles managed by the service itself can't be granted by a provider.
+func managedRoles(providerName string, mappings []roleMapping) ([]string, error) {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range mappings {
+		for _, name := range m.roles {
+			if role.IsReservedRoleName(name) {
+				return nil, fmt.Errorf("provider %q: role %q can't be granted by the provider", providerName, name)
+			}
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// grantedRoles returns the roles of the mappings whose value is among values (case-insensitive)
+func grantedRoles(values []string, mappings []roleMapping) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range mappings {
+		if !containsFold(values, m.value) {
+			continue
+		}
+		for _, name := range m.roles {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,285 @@
+package identity
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"app/internal/domain/federation"
+	"app/internal/domain/provider"
+	"app/internal/domain/saml"
+	"app/internal/domain/user"
+
+	gosaml "github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// SAMLConfig — service provider of the SAML 2.0 logins of the companies (disabled if BaseURL is empty).
+// The identity provider of each company is configured by its owner (saml.Connection).
+type SAMLConfig struct {
+	BaseURL string `mapstructure:"base_url"` // public URL of the service, e.g. https://auth.example.com
+
+	// Key pair of the service provider (PEM), optional: published in the metadata, signs the
+	// AuthnRequests and decrypts encrypted assertions
+	KeyFile  string `mapstructure:"key_file"`
+	CertFile string `mapstructure:"cert_file"`
+}
+
+// samlProvider — users of the SAML identity providers of the companies, created on their first login
+type samlProvider struct {
+	baseURL  string
+	key      *rsa.PrivateKey
+	cert     *x509.Certificate
+	connRepo saml.Repository
+	userRepo user.Repository
+}
+
+// Ensure samlProvider implements the domain interface
+var _ provider.SAMLProvider = (*samlProvider)(nil)
+
+// NewSAMLProvider returns the identity provider of the SAML logins of the companies
+func NewSAMLProvider(cfg SAMLConfig, connRepo saml.Repository, userRepo user.Repository) (provider.SAMLProvider, error) {
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("saml: base_url must be an http(s) URL")
+	}
+
+	p := &samlProvider{
+		baseURL:  strings.TrimRight(cfg.BaseURL, "/"),
+		connRepo: connRepo,
+		userRepo: userRepo,
+	}
+	if cfg.KeyFile != "" || cfg.CertFile != "" {
+		if p.key, p.cert, err = loadKeyPair(cfg.CertFile, cfg.KeyFile); err != nil {
+			return nil, fmt.Errorf("saml: %w", err)
+		}
+	}
+	return p, nil
+}
+
+func (p *samlProvider) Name() string { return provider.NameSAML }
+
+// Authenticate — the users of the provider sign in on the identity provider of their company, never with a password
+func (p *samlProvider) Authenticate(login, password string, usr *user.User) (*provider.Identity, error) {
+	if usr == nil {
+		return nil, provider.ErrUnknownUser
+	}
+	return nil, provider.ErrInvalidCredentials
+}
+
+// SupportsPasswordReset — the users have no password in this service
+func (p *samlProvider) SupportsPasswordReset() bool { return false }
+
+// ProvisionUser creates the user as a subuser of the owner of the connection of its company
+func (p *samlProvider) ProvisionUser(identity *provider.Identity) (*user.User, error) {
+	conn, err := p.connRepo.GetConnection(identity.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := p.userRepo.GetByID(conn.OwnerID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving owner of the saml connection: %w", err)
+	}
+	if owner.OwnerID != nil || owner.CompanyID != conn.CompanyID {
+		return nil, fmt.Errorf("owner of the saml connection of company %d is not a company owner", conn.CompanyID)
+	}
+	return subuserOf(owner, identity), nil
+}
+
+// SyncProfile — the company of the user is the one of its owner, nothing to update
+// (the profile and the roles of the identity are applied by the caller)
+func (p *samlProvider) SyncProfile(usr *user.User, identity *provider.Identity) {}
+
+// Metadata returns the metadata of the service provider of the company
+func (p *samlProvider) Metadata(companyID uint) ([]byte, error) {
+	sp, err := p.serviceProvider(companyID, nil)
+	if err != nil {
+		return nil, err
+	}
+	md, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("saml metadata error: %w", err)
+	}
+	return append([]byte(xml.Header), md...), nil
+}
+
+// AuthnRequestURL returns the URL of the identity provider with the AuthnRequest (HTTP-Redirect binding)
+func (p *samlProvider) AuthnRequestURL(conn *saml.Connection, relayState string) (string, string, error) {
+	sp, err := p.serviceProvider(conn.CompanyID, conn)
+	if err != nil {
+		return "", "", err
+	}
+	req, err := sp.MakeAuthenticationRequest(conn.IdPSSOURL, gosaml.HTTPRedirectBinding, gosaml.HTTPPostBinding)
+	if err != nil {
+		return "", "", fmt.Errorf("saml authn request error: %w", err)
+	}
+	redirectURL, err := req.Redirect(relayState, sp)
+	if err != nil {
+		return "", "", fmt.Errorf("saml authn request error: %w", err)
+	}
+	return redirectURL.String(), req.ID, nil
+}
+
+// ParseResponse validates the response to the AuthnRequest and maps the attributes of its assertion
+func (p *samlProvider) ParseResponse(conn *saml.Connection, samlResponse, requestID string) (*provider.Identity, error) {
+	sp, err := p.serviceProvider(conn.CompanyID, conn)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid SAMLResponse encoding", federation.ErrLoginRejected)
+	}
+
+	assertion, err := sp.ParseXMLResponse(raw, []string{requestID})
+	if err != nil {
+		var invalid *gosaml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			return nil, fmt.Errorf("%w: %v", federation.ErrLoginRejected, invalid.PrivateErr)
+		}
+		return nil, fmt.Errorf("%w: %v", federation.ErrLoginRejected, err)
+	}
+	return p.identity(conn, assertion)
+}
+
+// identity maps the assertion: NameID or login attribute, profile and roles
+func (p *samlProvider) identity(conn *saml.Connection, assertion *gosaml.Assertion) (*provider.Identity, error) {
+	attrs := assertionAttributes(assertion)
+	first := func(name string) string {
+		if name == "" || len(attrs[name]) == 0 {
+			return ""
+		}
+		return strings.TrimSpace(attrs[name][0])
+	}
+
+	login := first(conn.Attributes.Login)
+	if conn.Attributes.Login == "" && assertion.Subject != nil && assertion.Subject.NameID != nil {
+		login = strings.TrimSpace(assertion.Subject.NameID.Value)
+	}
+	if login == "" {
+		return nil, federation.ErrMissingIdentity
+	}
+	if conn.LoginSuffix != "" && !strings.HasSuffix(strings.ToLower(login), strings.ToLower(conn.LoginSuffix)) {
+		return nil, fmt.Errorf("%w: login not allowed", federation.ErrLoginRejected)
+	}
+
+	mappings := make([]roleMapping, len(conn.RoleMapping))
+	for i, rm := range conn.RoleMapping {
+		mappings[i] = roleMapping{value: rm.Value, roles: rm.Roles}
+	}
+	managed, err := managedRoles(provider.NameSAML, mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	var roleValues []string
+	if conn.Attributes.Roles != "" {
+		roleValues = attrs[conn.Attributes.Roles]
+	}
+	return &provider.Identity{
+		Login:        login,
+		CompanyID:    conn.CompanyID,
+		Email:        first(conn.Attributes.Email),
+		Name:         first(conn.Attributes.Name),
+		Surname:      first(conn.Attributes.Surname),
+		Phone:        first(conn.Attributes.Phone),
+		Roles:        grantedRoles(roleValues, mappings),
+		ManagedRoles: managed,
+	}, nil
+}
+
+// serviceProvider returns the service provider of the company, with the identity provider of the connection if set
+func (p *samlProvider) serviceProvider(companyID uint, conn *saml.Connection) (*gosaml.ServiceProvider, error) {
+	base := fmt.Sprintf("%s/auth/saml/%d", p.baseURL, companyID)
+	metadataURL, err := url.Parse(base + "/metadata")
+	if err != nil {
+		return nil, err
+	}
+	acsURL, err := url.Parse(base + "/acs")
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &gosaml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		Key:               p.key,
+		Certificate:       p.cert,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		AuthnNameIDFormat: gosaml.UnspecifiedNameIDFormat,
+	}
+	if p.key != nil {
+		sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+	}
+
+	if conn != nil {
+		cert, err := conn.Certificate()
+		if err != nil {
+			return nil, err
+		}
+		sp.IDPMetadata = idpMetadata(conn, cert)
+	}
+	return sp, nil
+}
+
+// idpMetadata returns the metadata of the identity provider of the connection
+func idpMetadata(conn *saml.Connection, cert *x509.Certificate) *gosaml.EntityDescriptor {
+	return &gosaml.EntityDescriptor{
+		EntityID: conn.IdPEntityID,
+		IDPSSODescriptors: []gosaml.IDPSSODescriptor{{
+			SSODescriptor: gosaml.SSODescriptor{
+				RoleDescriptor: gosaml.RoleDescriptor{
+					ProtocolSupportEnumeration: "urn:oasis:names:tc:SAML:2.0:protocol",
+					KeyDescriptors: []gosaml.KeyDescriptor{{
+						Use: "signing",
+						KeyInfo: gosaml.KeyInfo{X509Data: gosaml.X509Data{
+							X509Certificates: []gosaml.X509Certificate{{Data: base64.StdEncoding.EncodeToString(cert.Raw)}},
+						}},
+					}},
+				},
+			},
+			SingleSignOnServices: []gosaml.Endpoint{{Binding: gosaml.HTTPRedirectBinding, Location: conn.IdPSSOURL}},
+		}},
+	}
+}
+
+// assertionAttributes returns the values of the attributes of the assertion by name and friendly name
+func assertionAttributes(assertion *gosaml.Assertion) map[string][]string {
+	attrs := map[string][]string{}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			values := make([]string, 0, len(attr.Values))
+			for _, v := range attr.Values {
+				values = append(values, v.Value)
+			}
+			attrs[attr.Name] = append(attrs[attr.Name], values...)
+			if attr.FriendlyName != "" && attr.FriendlyName != attr.Name {
+				attrs[attr.FriendlyName] = append(attrs[attr.FriendlyName], values...)
+			}
+		}
+	}
+	return attrs
+}
+
+// loadKeyPair loads the RSA key pair of the service provider
+func loadKeyPair(certFile, keyFile string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading key pair: %w", err)
+	}
+	key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("the key of the service provider must be an RSA key")
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing certificate: %w", err)
+	}
+	return key, cert, nil
+}
@@ -0,0 +1,330 @@
+package identity
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"app/internal/domain/federation"
+	"app/internal/domain/provider"
+	"app/internal/domain/saml"
+
+	"github.com/beevik/etree"
+	gosaml "github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	testSAMLBaseURL  = "https://auth.example.com"
+	testIdPEntityID  = "https://idp.acme.com/metadata"
+	testIdPSSOURL    = "https://idp.acme.com/sso"
+	testSAMLCompany  = 20001
+	testOtherCompany = 20002
+)
+
+// samlConnectionRepository — SAML connections of the companies (stands in for the saml tables)
+type samlConnectionRepository struct {
+	saml.Repository
+	connections map[uint]*saml.Connection
+}
+
+func (r *samlConnectionRepository) GetConnection(companyID uint) (*saml.Connection, error) {
+	c, ok := r.connections[companyID]
+	if !ok {
+		return nil, saml.ErrConnectionNotFound
+	}
+	return c, nil
+}
+
+// mockSAMLIdP — SAML identity provider of a company that answers the AuthnRequests of the
+// service provider with signed responses (crewjam/saml identity provider)
+type mockSAMLIdP struct {
+	idp  *gosaml.IdentityProvider
+	cert *x509.Certificate
+}
+
+func newMockSAMLIdP(t *testing.T, sp *samlProvider) *mockSAMLIdP {
+	t.Helper()
+	key := newRSAKey(t)
+	cert := newSelfSignedCert(t, key)
+
+	// The metadata of the service providers of the companies, as registered on the identity provider
+	metadata := map[string]*gosaml.EntityDescriptor{}
+	for _, companyID := range []uint{testSAMLCompany, testOtherCompany} {
+		raw, err := sp.Metadata(companyID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var md gosaml.EntityDescriptor
+		if err := xml.Unmarshal(raw, &md); err != nil {
+			t.Fatal(err)
+		}
+		metadata[md.EntityID] = &md
+	}
+
+	metadataURL, _ := url.Parse(testIdPEntityID)
+	ssoURL, _ := url.Parse(testIdPSSOURL)
+	return &mockSAMLIdP{
+		idp: &gosaml.IdentityProvider{
+			Key:                     key,
+			Certificate:             cert,
+			MetadataURL:             *metadataURL,
+			SSOURL:                  *ssoURL,
+			SignatureMethod:         dsig.RSASHA256SignatureMethod,
+			ServiceProviderProvider: serviceProviders(metadata),
+		},
+		cert: cert,
+	}
+}
+
+type serviceProviders map[string]*gosaml.EntityDescriptor
+
+func (s serviceProviders) GetServiceProvider(r *http.Request, serviceProviderID string) (*gosaml.EntityDescriptor, error) {
+	md, ok := s[serviceProviderID]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return md, nil
+}
+
+// signIn returns the response of the identity provider to the AuthnRequest of the redirect URL
+// once the user signed in. modify can change the request before the assertion is made.
+func (m *mockSAMLIdP) signIn(t *testing.T, redirectURL string, session *gosaml.Session, modify func(req *gosaml.IdpAuthnRequest)) *etree.Element {
+	t.Helper()
+	req, err := gosaml.NewIdpAuthnRequest(m.idp, httptest.NewRequest(http.MethodGet, redirectURL, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if modify != nil {
+		modify(req)
+	}
+	if err := (gosaml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.MakeResponse(); err != nil {
+		t.Fatal(err)
+	}
+	return req.ResponseEl
+}
+
+func (m *mockSAMLIdP) certificatePEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: m.cert.Raw}))
+}
+
+func newSelfSignedCert(t *testing.T, key *rsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.acme.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func encodeResponse(t *testing.T, el *etree.Element) string {
+	t.Helper()
+	doc := etree.NewDocument()
+	doc.SetRoot(el.Copy())
+	raw, err := doc.WriteToBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+// removeSignatures removes the signatures of the element and its children
+func removeSignatures(el *etree.Element) {
+	for _, child := range el.ChildElements() {
+		if child.Tag == "Signature" {
+			el.RemoveChild(child)
+			continue
+		}
+		removeSignatures(child)
+	}
+}
+
+func testSAMLConnection(idp *mockSAMLIdP, companyID uint) *saml.Connection {
+	return &saml.Connection{
+		CompanyID:      companyID,
+		OwnerID:        1,
+		Enabled:        true,
+		IdPEntityID:    testIdPEntityID,
+		IdPSSOURL:      testIdPSSOURL,
+		IdPCertificate: idp.certificatePEM(),
+		LoginSuffix:    "@acme.com",
+		Attributes: saml.Attributes{
+			Email:   "eduPersonPrincipalName",
+			Name:    "givenName",
+			Surname: "sn",
+			Roles:   "eduPersonAffiliation",
+		},
+		RoleMapping: []saml.RoleMapping{{Value: "technicians", Roles: []string{"technician"}}},
+	}
+}
+
+func testSAMLSession() *gosaml.Session {
+	return &gosaml.Session{
+		ID:            "session-1",
+		CreateTime:    time.Now(),
+		ExpireTime:    time.Now().Add(time.Hour),
+		Index:         "1",
+		NameID:        "jdoe@acme.com",
+		UserEmail:     "jdoe@acme.com",
+		UserGivenName: "John",
+		UserSurname:   "Doe",
+		Groups:        []string{"Technicians", "Everyone"},
+	}
+}
+
+func newTestSAMLProvider(t *testing.T, connections map[uint]*saml.Connection) *samlProvider {
+	t.Helper()
+	p, err := NewSAMLProvider(SAMLConfig{BaseURL: testSAMLBaseURL}, &samlConnectionRepository{connections: connections}, newTestUserRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*samlProvider)
+}
+
+func TestSAMLParseResponse(t *testing.T) {
+	p := newTestSAMLProvider(t, nil)
+	idp := newMockSAMLIdP(t, p)
+	conn := testSAMLConnection(idp, testSAMLCompany)
+
+	redirectURL, requestID, err := p.AuthnRequestURL(conn, "relay-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := idp.signIn(t, redirectURL, testSAMLSession(), nil)
+
+	identity, err := p.ParseResponse(conn, encodeResponse(t, response), requestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Login != "jdoe@acme.com" || identity.CompanyID != testSAMLCompany || identity.Email != "jdoe@acme.com" ||
+		identity.Name != "John" || identity.Surname != "Doe" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	if !reflect.DeepEqual(identity.Roles, []string{"technician"}) {
+		t.Errorf("roles %v", identity.Roles)
+	}
+}
+
+func TestSAMLParseResponseRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		session func(s *gosaml.Session)
+		modify  func(req *gosaml.IdpAuthnRequest)
+		tamper  func(t *testing.T, response *etree.Element) string // returns the SAMLResponse to parse
+		parse   func(conn *saml.Connection, requestID string) (*saml.Connection, string)
+		want    error
+	}{
+		{name: "tampered assertion", tamper: func(t *testing.T, response *etree.Element) string {
+			raw, _ := base64.StdEncoding.DecodeString(encodeResponse(t, response))
+			return base64.StdEncoding.EncodeToString(bytes.ReplaceAll(raw, []byte("jdoe@acme.com"), []byte("boss@acme.com")))
+		}, want: federation.ErrLoginRejected},
+		{name: "unsigned response and assertion", tamper: func(t *testing.T, response *etree.Element) string {
+			unsigned := response.Copy()
+			removeSignatures(unsigned)
+			return encodeResponse(t, unsigned)
+		}, want: federation.ErrLoginRejected},
+		{name: "signed by another identity provider", parse: func(conn *saml.Connection, requestID string) (*saml.Connection, string) {
+			other := *conn
+			other.IdPCertificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: newSelfSignedCert(t, newRSAKey(t)).Raw}))
+			return &other, requestID
+		}, want: federation.ErrLoginRejected},
+		{name: "response to another request", parse: func(conn *saml.Connection, requestID string) (*saml.Connection, string) {
+			return conn, "id-another-request"
+		}, want: federation.ErrLoginRejected},
+		{name: "assertion for the service provider of another company", parse: func(conn *saml.Connection, requestID string) (*saml.Connection, string) {
+			other := *conn
+			other.CompanyID = testOtherCompany
+			return &other, requestID
+		}, want: federation.ErrLoginRejected},
+		{name: "expired assertion", modify: func(req *gosaml.IdpAuthnRequest) {
+			req.Now = time.Now().Add(-time.Hour)
+		}, want: federation.ErrLoginRejected},
+		{name: "login of another domain", session: func(s *gosaml.Session) { s.NameID = "jdoe@evil.com" }, want: federation.ErrLoginRejected},
+		{name: "invalid encoding", tamper: func(t *testing.T, response *etree.Element) string { return "not base64!" }, want: federation.ErrLoginRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestSAMLProvider(t, nil)
+			idp := newMockSAMLIdP(t, p)
+			conn := testSAMLConnection(idp, testSAMLCompany)
+
+			redirectURL, requestID, err := p.AuthnRequestURL(conn, "relay-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			session := testSAMLSession()
+			if tt.session != nil {
+				tt.session(session)
+			}
+			response := idp.signIn(t, redirectURL, session, tt.modify)
+
+			samlResponse := encodeResponse(t, response)
+			if tt.tamper != nil {
+				samlResponse = tt.tamper(t, response)
+			}
+			if tt.parse != nil {
+				conn, requestID = tt.parse(conn, requestID)
+			}
+
+			identity, err := p.ParseResponse(conn, samlResponse, requestID)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if identity != nil {
+				t.Error("identity returned")
+			}
+		})
+	}
+}
+
+func TestSAMLProvisionUser(t *testing.T) {
+	p := newTestSAMLProvider(t, map[uint]*saml.Connection{
+		testSAMLCompany:  {CompanyID: testSAMLCompany, OwnerID: 1},
+		testOtherCompany: {CompanyID: testOtherCompany, OwnerID: 1}, // owner of another company
+	})
+
+	usr, err := p.ProvisionUser(&provider.Identity{Login: "jdoe@acme.com", CompanyID: testSAMLCompany})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usr.OwnerID == nil || *usr.OwnerID != 1 || usr.CompanyID != testSAMLCompany {
+		t.Errorf("unexpected user %+v", usr)
+	}
+
+	if _, err := p.ProvisionUser(&provider.Identity{Login: "jdoe@acme.com", CompanyID: testOtherCompany}); err == nil {
+		t.Error("user provisioned for the owner of another company")
+	}
+	if _, err := p.ProvisionUser(&provider.Identity{Login: "jdoe@acme.com", CompanyID: 30003}); !errors.Is(err, saml.ErrConnectionNotFound) {
+		t.Errorf("company without connection: got %v, want %v", err, saml.ErrConnectionNotFound)
+	}
+}
@@ -0,0 +1,78 @@
+package identity
+
+import (
+	"fmt"
+	"net/http"
+
+	"app/internal/application/ports"
+	"app/internal/domain/provider"
+	"app/internal/domain/user"
+)
+
+// verificacionesProvider — technicians of the Verificaciones service, created on their first login
+type verificacionesProvider struct {
+	verSvc ports.VerificacionesService
+}
+
+// Ensure verificacionesProvider implements the domain interface
+var _ provider.IdentityProvider = (*verificacionesProvider)(nil)
+
+func NewVerificacionesProvider(verSvc ports.VerificacionesService) provider.IdentityProvider {
+	return &verificacionesProvider{verSvc: verSvc}
+}
+
+func (p *verificacionesProvider) Name() string { return provider.NameVerificaciones }
+
+func (p *verificacionesProvider) Authenticate(login, password string, usr *user.User) (*provider.Identity, error) {
+	if usr == nil {
+		// Check if user already exists in verificaciones
+		exists, err := p.verSvc.CheckIfUserExists(login)
+		if err != nil {
+			return nil, fmt.Errorf("error checking if user exists in verificaciones: %w", err)
+		}
+		if !exists {
+			return nil, provider.ErrUnknownUser
+		}
+	}
+
+	resp, status, err := p.verSvc.Login(ports.LoginReq{
+		Username: login,
+		Password: password,
+	})
+	// Server errors of the external provider are not failed attempts
+	if status != http.StatusOK && status < http.StatusInternalServerError {
+		return nil, fmt.Errorf("login failed with status %d: %w", status, provider.ErrInvalidCredentials)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("external login error: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("login failed with status %d", status)
+	}
+
+	return &provider.Identity{
+		Login:       login,
+		CompanyID:   uint(resp.IdEmpresa),
+		CompanyName: resp.Empresa,
+	}, nil
+}
+
+// SupportsPasswordReset — the passwords are managed by Verificaciones (never stored here)
+func (p *verificacionesProvider) SupportsPasswordReset() bool { return false }
+
+func (p *verificacionesProvider) ProvisionUser(identity *provider.Identity) (*user.User, error) {
+	return &user.User{
+		Login:       identity.Login,
+		CompanyID:   identity.CompanyID,
+		CompanyName: identity.CompanyName,
+		Password:    nil, // do not store password
+	}, nil
+}
+
+// SyncProfile updates the company name. The company itself is not changed on login:
+// the roles of the user belong to its company (company_<id>).
+func (p *verificacionesProvider) SyncProfile(usr *user.User, identity *provider.Identity) {
+	if identity.CompanyName != "" {
+		usr.CompanyName = identity.CompanyName
+	}
+}
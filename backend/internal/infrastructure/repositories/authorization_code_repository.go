@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"app/internal/domain/oauth"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type authorizationCodeRepository struct {
+	db *gorm.DB
+}
+
+// Ensure authorizationCodeRepository implements the domain interface
+var _ oauth.AuthorizationCodeRepository = (*authorizationCodeRepository)(nil)
+
+func NewAuthorizationCodeRepository() oauth.AuthorizationCodeRepository {
+	return &authorizationCodeRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *authorizationCodeRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+func (r *authorizationCodeRepository) Create(code *oauth.AuthorizationCode) error {
+	return r.db.Create(&models.AuthorizationCodeModel{
+		CodeHash:            code.CodeHash,
+		ClientID:            code.ClientID,
+		UserID:              code.UserID,
+		RedirectURI:         code.RedirectURI,
+		Scope:               code.Scope,
+		CodeChallenge:       code.CodeChallenge,
+		CodeChallengeMethod: code.CodeChallengeMethod,
+		Nonce:               code.Nonce,
+		CreatedAt:           code.CreatedAt,
+		ExpiresAt:           code.ExpiresAt,
+	}).Error
+}
+
+func (r *authorizationCodeRepository) GetByHash(codeHash string) (*oauth.AuthorizationCode, error) {
+	var am models.AuthorizationCodeModel
+	if err := r.db.Where("code_hash = ?", codeHash).First(&am).Error; err != nil {
+		return nil, err
+	}
+	return am.ToDomain(), nil
+}
+
+func (r *authorizationCodeRepository) MarkUsed(codeHash string) error {
+	// Only one request can exchange the code: the update is conditional on used_at IS NULL
+	result := r.db.Model(&models.AuthorizationCodeModel{}).
+		Where("code_hash = ? AND used_at IS NULL", codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return oauth.ErrCodeAlreadyUsed
+	}
+	return nil
+}
+
+func (r *authorizationCodeRepository) SetSessionID(codeHash, sessionID string) error {
+	return r.db.Model(&models.AuthorizationCodeModel{}).
+		Where("code_hash = ?", codeHash).
+		Update("session_id", sessionID).Error
+}
+
+// DeleteExpired removes the expired codes. Used codes are kept until they expire,
+// so a second exchange attempt is still detected.
+func (r *authorizationCodeRepository) DeleteExpired(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&models.AuthorizationCodeModel{}).Error
+}
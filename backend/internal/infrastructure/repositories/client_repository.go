@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"app/internal/domain/client"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type clientRepository struct {
+	db *gorm.DB
+}
+
+// Ensure clientRepository implements the domain interface
+var _ client.Repository = (*clientRepository)(nil)
+
+func NewClientRepository() client.Repository {
+	return &clientRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *clientRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// Create creates the client (the client ID is generated if empty)
+func (r *clientRepository) Create(c *client.Client) error {
+	if c.ClientID == "" {
+		c.ClientID = uuid.New().String()
+	}
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+	cm := models.ClientModel{
+		ClientID:     c.ClientID,
+		SecretHash:   c.SecretHash,
+		Name:         c.Name,
+		CompanyID:    c.CompanyID,
+		Public:       c.Public,
+		RedirectURIs: strings.Join(c.RedirectURIs, "\n"),
+		Scopes:       client.FormatScope(c.Scopes),
+		Active:       c.Active,
+		CreatedAt:    c.CreatedAt,
+	}
+	if err := r.db.Create(&cm).Error; err != nil {
+		return err
+	}
+	c.ID = cm.ID
+	return nil
+}
+
+func (r *clientRepository) GetByClientID(clientID string) (*client.Client, error) {
+	var cm models.ClientModel
+	if err := r.db.Where("client_id = ?", clientID).First(&cm).Error; err != nil {
+		return nil, err
+	}
+	return cm.ToDomain(), nil
+}
+
+func (r *clientRepository) GetByCompanyID(companyID uint) ([]*client.Client, error) {
+	var clientModels []models.ClientModel
+	if err := r.db.Where("company_id = ?", companyID).Order("created_at").Find(&clientModels).Error; err != nil {
+		return nil, err
+	}
+
+	clients := make([]*client.Client, len(clientModels))
+	for i, cm := range clientModels {
+		clients[i] = cm.ToDomain()
+	}
+	return clients, nil
+}
+
+func (r *clientRepository) Delete(clientID string) error {
+	return r.db.Where("client_id = ?", clientID).Delete(&models.ClientModel{}).Error
+}
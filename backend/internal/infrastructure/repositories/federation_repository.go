@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"app/internal/domain/federation"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type federationRepository struct {
+	db *gorm.DB
+}
+
+// Ensure federationRepository implements the domain interface
+var _ federation.Repository = (*federationRepository)(nil)
+
+func NewFederationRepository() federation.Repository {
+	return &federationRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *federationRepository) CreateFlow(f *federation.Flow) error {
+	return r.db.Create(&models.FederationFlowModel{
+		ID:           f.ID,
+		ProviderID:   f.ProviderID,
+		State:        f.State,
+		Nonce:        f.Nonce,
+		CodeVerifier: f.CodeVerifier,
+		ExpiresAt:    f.ExpiresAt,
+	}).Error
+}
+
+func (r *federationRepository) ConsumeFlow(id string, providerID uint, now time.Time) (*federation.Flow, error) {
+	var fm models.FederationFlowModel
+	if err := r.db.Where("id = ? AND provider_id = ?", id, providerID).First(&fm).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, federation.ErrFlowNotFound
+		}
+		return nil, err
+	}
+
+	// Only one request can finish the flow: the one that deletes it
+	result := r.db.Where("id = ?", id).Delete(&models.FederationFlowModel{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || !now.Before(fm.ExpiresAt) {
+		return nil, federation.ErrFlowNotFound
+	}
+	return fm.ToDomain(), nil
+}
+
+func (r *federationRepository) DeleteExpiredFlows(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&models.FederationFlowModel{}).Error
+}
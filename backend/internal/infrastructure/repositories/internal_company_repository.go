@@ -37,6 +37,14 @@ func (r *internalCompanyRepository) GetAll() ([]*internal_company.InternalCompan
 	return companies, nil
 }
 
+func (r *internalCompanyRepository) GetByID(id uint) (*internal_company.InternalCompany, error) {
+	var companyModel models.InternalCompanyModel
+	if err := r.db.Where("id = ?", id).First(&companyModel).Error; err != nil {
+		return nil, err
+	}
+	return companyModel.ToDomain(), nil
+}
+
 func (r *internalCompanyRepository) GetByName(name string) (*internal_company.InternalCompany, error) {
 	var companyModel models.InternalCompanyModel
 	if err := r.db.Where("name = ?", name).First(&companyModel).Error; err != nil {
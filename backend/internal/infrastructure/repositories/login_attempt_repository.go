@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"app/internal/domain/lockout"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+// Ensure loginAttemptRepository implements the domain interface
+var _ lockout.Repository = (*loginAttemptRepository)(nil)
+
+func NewLoginAttemptRepository() lockout.Repository {
+	return &loginAttemptRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *loginAttemptRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+func (r *loginAttemptRepository) Get(subject, key string) (*lockout.Counter, error) {
+	var m models.LoginAttemptModel
+	if err := r.db.Where("subject = ? AND subject_key = ?", subject, key).First(&m).Error; err != nil {
+		return nil, err
+	}
+	return m.ToDomain(), nil
+}
+
+func (r *loginAttemptRepository) RecordFailure(subject, key string, now, windowStart time.Time) (*lockout.Counter, error) {
+	var m models.LoginAttemptModel
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Concurrent failures are counted by the database (insert or increment).
+		// MySQL applies the assignments in order: once failures is updated,
+		// "failures = 1" tells whether the counter has been started again.
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "subject"}, {Name: "subject_key"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "failures"}, Value: gorm.Expr(
+					"IF(first_failed_at < ? OR (locked_until IS NOT NULL AND locked_until <= ?), 1, failures + 1)", windowStart, now)},
+				{Column: clause.Column{Name: "first_failed_at"}, Value: gorm.Expr("IF(failures = 1, ?, first_failed_at)", now)},
+				{Column: clause.Column{Name: "locked_until"}, Value: gorm.Expr("IF(failures = 1, NULL, locked_until)")},
+				{Column: clause.Column{Name: "last_failed_at"}, Value: now},
+			},
+		}).Create(&models.LoginAttemptModel{
+			Subject:       subject,
+			Key:           key,
+			Failures:      1,
+			FirstFailedAt: now,
+			LastFailedAt:  now,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("subject = ? AND subject_key = ?", subject, key).First(&m).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.ToDomain(), nil
+}
+
+func (r *loginAttemptRepository) Lock(subject, key string, until time.Time) error {
+	return r.db.Model(&models.LoginAttemptModel{}).
+		Where("subject = ? AND subject_key = ?", subject, key).
+		Update("locked_until", until).Error
+}
+
+func (r *loginAttemptRepository) Reset(subject, key string) error {
+	return r.db.Where("subject = ? AND subject_key = ?", subject, key).Delete(&models.LoginAttemptModel{}).Error
+}
+
+func (r *loginAttemptRepository) DeleteExpired(before, now time.Time) (int64, error) {
+	result := r.db.Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until <= ?)", before, now).
+		Delete(&models.LoginAttemptModel{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"app/internal/domain/mfa"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"app/pkg/config"
+	"app/pkg/sealer"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type mfaRepository struct {
+	db     *gorm.DB
+	sealer *sealer.Sealer
+}
+
+// Ensure mfaRepository implements the domain interface
+var _ mfa.Repository = (*mfaRepository)(nil)
+
+// NewMFARepository — the TOTP secrets are sealed with a key derived from the service secret (PASETO_SK)
+func NewMFARepository() mfa.Repository {
+	return &mfaRepository{
+		db:     db.GetProvider().GetDB(),
+		sealer: sealer.New(config.ENV().PASETO_SK, "mfa-totp"),
+	}
+}
+
+func (r *mfaRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+func (r *mfaRepository) GetEnrollment(userID uint) (*mfa.Enrollment, error) {
+	var em models.MFAEnrollmentModel
+	if err := r.db.Where("user_id = ?", userID).First(&em).Error; err != nil {
+		return nil, err
+	}
+
+	secret, err := r.sealer.Open(em.SealedSecret)
+	if err != nil {
+		return nil, err
+	}
+	enrollment := em.ToDomain()
+	enrollment.Secret = string(secret)
+	return enrollment, nil
+}
+
+func (r *mfaRepository) SaveEnrollment(e *mfa.Enrollment) error {
+	sealed, err := r.sealer.Seal([]byte(e.Secret))
+	if err != nil {
+		return err
+	}
+
+	em := models.MFAEnrollmentModel{
+		UserID:       e.UserID,
+		SealedSecret: sealed,
+		ConfirmedAt:  e.ConfirmedAt,
+		LastUsedStep: e.LastUsedStep,
+		CreatedAt:    e.CreatedAt,
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"sealed_secret", "confirmed_at", "last_used_step", "failed_attempts", "last_failed_at", "created_at"}),
+	}).Create(&em).Error
+}
+
+func (r *mfaRepository) ConfirmEnrollment(userID uint, step int64, confirmedAt time.Time, recoveryCodeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Only one request can confirm the enrollment: the update is conditional on confirmed_at IS NULL
+		result := tx.Model(&models.MFAEnrollmentModel{}).
+			Where("user_id = ? AND confirmed_at IS NULL AND last_used_step < ?", userID, step).
+			Updates(map[string]interface{}{
+				"confirmed_at":    confirmedAt,
+				"last_used_step":  step,
+				"failed_attempts": 0,
+				"last_failed_at":  nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return mfa.ErrCodeAlreadyUsed
+		}
+
+		return replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+}
+
+func (r *mfaRepository) UseStep(userID uint, step int64) error {
+	// The update is conditional on the last used step, so two requests can't accept the same code
+	result := r.db.Model(&models.MFAEnrollmentModel{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Updates(map[string]interface{}{
+			"last_used_step":  step,
+			"failed_attempts": 0,
+			"last_failed_at":  nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return mfa.ErrCodeAlreadyUsed
+	}
+	return nil
+}
+
+func (r *mfaRepository) RecordFailedAttempt(userID uint, at time.Time) error {
+	return r.db.Model(&models.MFAEnrollmentModel{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"failed_attempts": gorm.Expr("failed_attempts + 1"),
+			"last_failed_at":  at,
+		}).Error
+}
+
+func (r *mfaRepository) DeleteEnrollment(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCodeModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.MFAEnrollmentModel{}).Error
+	})
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func (r *mfaRepository) UseRecoveryCode(userID uint, codeHash string) error {
+	// Only one request can use the code: the update is conditional on used_at IS NULL
+	result := r.db.Model(&models.MFARecoveryCodeModel{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return mfa.ErrInvalidCode
+	}
+	return nil
+}
+
+func (r *mfaRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.MFARecoveryCodeModel{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *mfaRepository) GetPolicy(ownerID uint) (*mfa.Policy, error) {
+	var pm models.MFAPolicyModel
+	if err := r.db.Where("owner_id = ?", ownerID).First(&pm).Error; err != nil {
+		return nil, err
+	}
+	return pm.ToDomain(), nil
+}
+
+func (r *mfaRepository) SavePolicy(p *mfa.Policy) error {
+	pm := models.MFAPolicyModel{
+		OwnerID:            p.OwnerID,
+		RequireForSubusers: p.RequireForSubusers,
+		UpdatedAt:          p.UpdatedAt,
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "owner_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"require_for_subusers", "updated_at"}),
+	}).Create(&pm).Error
+}
+
+// replaceRecoveryCodes removes all the recovery codes of the user (used ones included) and stores the new ones
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCodeModel{}).Error; err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	codes := make([]models.MFARecoveryCodeModel, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.MFARecoveryCodeModel{
+			UserID:    userID,
+			CodeHash:  hash,
+			CreatedAt: now,
+		}
+	}
+	return tx.Create(&codes).Error
+}
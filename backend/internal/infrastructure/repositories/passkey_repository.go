@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"app/internal/domain/passkey"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type passkeyRepository struct {
+	db *gorm.DB
+}
+
+// Ensure passkeyRepository implements the domain interface
+var _ passkey.Repository = (*passkeyRepository)(nil)
+
+func NewPasskeyRepository() passkey.Repository {
+	return &passkeyRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *passkeyRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+func (r *passkeyRepository) Create(c *passkey.Credential) error {
+	pm := models.PasskeyModel{
+		UserID:          c.UserID,
+		Name:            c.Name,
+		CredentialID:    c.CredentialID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		AAGUID:          c.AAGUID,
+		SignCount:       c.SignCount,
+		Flags:           c.Flags,
+		Transports:      strings.Join(c.Transports, " "),
+		Attachment:      c.Attachment,
+		BackupState:     c.BackupState,
+		CreatedAt:       c.CreatedAt,
+	}
+	if err := r.db.Create(&pm).Error; err != nil {
+		return err
+	}
+	c.ID = pm.ID
+	return nil
+}
+
+func (r *passkeyRepository) GetByUserID(userID uint) ([]*passkey.Credential, error) {
+	var passkeyModels []models.PasskeyModel
+	if err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&passkeyModels).Error; err != nil {
+		return nil, err
+	}
+
+	credentials := make([]*passkey.Credential, len(passkeyModels))
+	for i, pm := range passkeyModels {
+		credentials[i] = pm.ToDomain()
+	}
+	return credentials, nil
+}
+
+func (r *passkeyRepository) UpdateUsage(id uint, signCount uint32, flags uint8, backupState bool, usedAt time.Time) error {
+	return r.db.Model(&models.PasskeyModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"flags":        flags,
+		"backup_state": backupState,
+		"last_used_at": usedAt,
+	}).Error
+}
+
+func (r *passkeyRepository) Delete(userID, id uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.PasskeyModel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *passkeyRepository) CreateCeremony(c *passkey.Ceremony) error {
+	return r.db.Create(&models.PasskeyCeremonyModel{
+		ID:        c.ID,
+		Type:      c.Type,
+		UserID:    c.UserID,
+		Data:      c.Data,
+		ExpiresAt: c.ExpiresAt,
+	}).Error
+}
+
+func (r *passkeyRepository) ConsumeCeremony(id, ceremonyType string, now time.Time) (*passkey.Ceremony, error) {
+	var cm models.PasskeyCeremonyModel
+	if err := r.db.Where("id = ? AND type = ?", id, ceremonyType).First(&cm).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, passkey.ErrCeremonyNotFound
+		}
+		return nil, err
+	}
+
+	// Only one request can finish the ceremony: the one that deletes it
+	result := r.db.Where("id = ?", id).Delete(&models.PasskeyCeremonyModel{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || !now.Before(cm.ExpiresAt) {
+		return nil, passkey.ErrCeremonyNotFound
+	}
+	return cm.ToDomain(), nil
+}
+
+func (r *passkeyRepository) DeleteExpiredCeremonies(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&models.PasskeyCeremonyModel{}).Error
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"app/internal/domain/password"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type passwordRepository struct {
+	db *gorm.DB
+}
+
+// Ensure passwordRepository implements the domain interface
+var _ password.Repository = (*passwordRepository)(nil)
+
+func NewPasswordRepository() password.Repository {
+	return &passwordRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *passwordRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+func (r *passwordRepository) AddHistory(entry *password.HistoryEntry, keep int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		hm := models.PasswordHistoryModel{
+			UserID:    entry.UserID,
+			Hash:      entry.Hash,
+			CreatedAt: entry.CreatedAt,
+		}
+		if err := tx.Create(&hm).Error; err != nil {
+			return err
+		}
+		entry.ID = hm.ID
+
+		// Only the newest entries are kept
+		var ids []uint
+		if err := tx.Model(&models.PasswordHistoryModel{}).
+			Where("user_id = ?", entry.UserID).
+			Order("created_at DESC, id DESC").
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if keep < 0 {
+			keep = 0
+		}
+		if len(ids) <= keep {
+			return nil
+		}
+		return tx.Where("id IN ?", ids[keep:]).Delete(&models.PasswordHistoryModel{}).Error
+	})
+}
+
+func (r *passwordRepository) GetHistory(userID uint, limit int) ([]password.HistoryEntry, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	var hms []models.PasswordHistoryModel
+	if err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&hms).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]password.HistoryEntry, 0, len(hms))
+	for i := range hms {
+		entries = append(entries, hms[i].ToDomain())
+	}
+	return entries, nil
+}
+
+func (r *passwordRepository) GetPolicy(ownerID uint) (*password.Policy, error) {
+	var pm models.PasswordPolicyModel
+	if err := r.db.Where("owner_id = ?", ownerID).First(&pm).Error; err != nil {
+		return nil, err
+	}
+	return pm.ToDomain(), nil
+}
+
+func (r *passwordRepository) SavePolicy(p *password.Policy) error {
+	pm := models.PasswordPolicyModel{
+		OwnerID:    p.OwnerID,
+		MaxAgeDays: p.MaxAgeDays,
+		UpdatedAt:  p.UpdatedAt,
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "owner_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_age_days", "updated_at"}),
+	}).Create(&pm).Error
+}
+
+func (r *passwordRepository) ReplaceRecoveryToken(t *password.RecoveryToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", t.UserID).Delete(&models.PasswordRecoveryTokenModel{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.PasswordRecoveryTokenModel{
+			Nonce:     t.Nonce,
+			UserID:    t.UserID,
+			CreatedAt: t.CreatedAt,
+			ExpiresAt: t.ExpiresAt,
+		}).Error
+	})
+}
+
+func (r *passwordRepository) GetRecoveryToken(nonce string) (*password.RecoveryToken, error) {
+	var tm models.PasswordRecoveryTokenModel
+	if err := r.db.Where("nonce = ?", nonce).First(&tm).Error; err != nil {
+		return nil, err
+	}
+	return tm.ToDomain(), nil
+}
+
+func (r *passwordRepository) ConsumeRecoveryToken(nonce string, use func() error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Only one request can use the token: the one that deletes it (the others wait for the row lock)
+		result := tx.Where("nonce = ?", nonce).Delete(&models.PasswordRecoveryTokenModel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return password.ErrInvalidRecoveryToken
+		}
+		return use()
+	})
+}
+
+func (r *passwordRepository) DeleteRecoveryTokens(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.PasswordRecoveryTokenModel{}).Error
+}
+
+func (r *passwordRepository) DeleteExpiredRecoveryTokens(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&models.PasswordRecoveryTokenModel{}).Error
+}
@@ -0,0 +1,102 @@
+package repositories
+
+import (
+	"app/internal/domain/role"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type permissionRepository struct {
+	db *gorm.DB
+}
+
+// Ensure permissionRepository implements the domain interface
+var _ role.PermissionRepository = (*permissionRepository)(nil)
+
+func NewPermissionRepository() role.PermissionRepository {
+	return &permissionRepository{db: db.GetProvider().GetDB()}
+}
+
+// IsNotFoundError - check if error is a not found error
+func (r *permissionRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// GetAllPermissions - get all permissions
+func (r *permissionRepository) GetAllPermissions() ([]role.Permission, error) {
+	var permissionModels []models.PermissionModel
+	if err := r.db.Find(&permissionModels).Error; err != nil {
+		return nil, err
+	}
+	return permissionsToDomain(permissionModels), nil
+}
+
+// GetPermissionByName - get permission by name
+func (r *permissionRepository) GetPermissionByName(name string) (*role.Permission, error) {
+	var permissionModel models.PermissionModel
+	if err := r.db.Where("name = ?", name).First(&permissionModel).Error; err != nil {
+		return nil, err
+	}
+	return permissionModel.ToDomain(), nil
+}
+
+// CreatePermission - create new permission
+func (r *permissionRepository) CreatePermission(permission *role.Permission) (uint, error) {
+	permissionModel := models.PermissionModel{
+		Name: permission.Name,
+		Desc: permission.Desc,
+	}
+	if err := r.db.Create(&permissionModel).Error; err != nil {
+		return 0, err
+	}
+	return permissionModel.ID, nil
+}
+
+// GetRolePermissions - get permissions of a role
+func (r *permissionRepository) GetRolePermissions(roleID uint) ([]role.Permission, error) {
+	var permissionModels []models.PermissionModel
+	if err := r.db.Joins("JOIN ref_role_permission ON ref_role_permission.permission_id = permissions.id").
+		Where("ref_role_permission.role_id = ?", roleID).
+		Find(&permissionModels).Error; err != nil {
+		return nil, err
+	}
+	return permissionsToDomain(permissionModels), nil
+}
+
+// GetPermissionsByRoleNames - get permissions granted by any of the roles
+func (r *permissionRepository) GetPermissionsByRoleNames(roleNames []string) ([]role.Permission, error) {
+	var permissionModels []models.PermissionModel
+	if err := r.db.Distinct("permissions.*").
+		Joins("JOIN ref_role_permission ON ref_role_permission.permission_id = permissions.id").
+		Joins("JOIN roles ON roles.id = ref_role_permission.role_id").
+		Where("roles.role IN ?", roleNames).
+		Find(&permissionModels).Error; err != nil {
+		return nil, err
+	}
+	return permissionsToDomain(permissionModels), nil
+}
+
+// AssignPermissionToRole - assign permission to role
+func (r *permissionRepository) AssignPermissionToRole(roleID, permissionID uint) error {
+	ref := models.RefRolePermissionModel{
+		RoleID:       roleID,
+		PermissionID: permissionID,
+	}
+	return r.db.Create(&ref).Error
+}
+
+// RemovePermissionFromRole - remove permission from role
+func (r *permissionRepository) RemovePermissionFromRole(roleID, permissionID uint) error {
+	return r.db.Where("role_id = ? AND permission_id = ?", roleID, permissionID).Delete(&models.RefRolePermissionModel{}).Error
+}
+
+func permissionsToDomain(permissionModels []models.PermissionModel) []role.Permission {
+	permissions := make([]role.Permission, len(permissionModels))
+	for i, pm := range permissionModels {
+		permissions[i] = *pm.ToDomain()
+	}
+	return permissions
+}
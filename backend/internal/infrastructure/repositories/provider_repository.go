@@ -44,3 +44,13 @@ func (r *providerRepository) GetByID(id uint) (*provider.Provider, error) {
 	}
 	return pm.ToDomain(), nil
 }
+
+// Create inserts a provider (the ID is set by the database if empty)
+func (r *providerRepository) Create(p *provider.Provider) error {
+	pm := models.ProviderModel{ID: p.ID, Name: p.Name, Desc: p.Desc}
+	if err := r.db.Create(&pm).Error; err != nil {
+		return err
+	}
+	p.ID = pm.ID
+	return nil
+}
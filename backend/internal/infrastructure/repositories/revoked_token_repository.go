@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"app/internal/infrastructure/token/paseto"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type revokedTokenRepository struct {
+	db *gorm.DB
+}
+
+// Ensure revokedTokenRepository implements the revocation list of the token manager
+var _ paseto.RevocationList = (*revokedTokenRepository)(nil)
+
+func NewRevokedTokenRepository() paseto.RevocationList {
+	return &revokedTokenRepository{db: db.GetProvider().GetDB()}
+}
+
+// Revoke adds the identifier to the list. Revoking it again extends its expiration.
+func (r *revokedTokenRepository) Revoke(identifier string, expiresAt time.Time) error {
+	rm := models.RevokedTokenModel{
+		Identifier: identifier,
+		CreatedAt:  time.Now(),
+		ExpiresAt:  expiresAt,
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "identifier"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"expires_at": gorm.Expr("GREATEST(expires_at, ?)", expiresAt)}),
+	}).Create(&rm).Error
+}
+
+// IsRevoked checks if any of the identifiers is in the list and not expired
+func (r *revokedTokenRepository) IsRevoked(identifiers ...string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.RevokedTokenModel{}).
+		Where("identifier IN ? AND expires_at > ?", identifiers, time.Now()).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteExpired removes the entries whose tokens have already expired
+func (r *revokedTokenRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.RevokedTokenModel{})
+	return result.RowsAffected, result.Error
+}
@@ -4,8 +4,10 @@ import (
 	"app/internal/domain/role"
 	"app/internal/infrastructure/db"
 	"app/internal/infrastructure/db/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type roleRepository struct {
@@ -19,8 +21,10 @@ func NewRoleRepository() role.RoleRepository {
 // CreateRole - create new role
 func (r *roleRepository) CreateRole(role *role.Role) (uint, error) {
 	roleModel := models.RoleModel{
-		Role: role.Role,
-		Desc: role.Desc,
+		Role:     role.Role,
+		Desc:     role.Desc,
+		ParentID: role.ParentID,
+		System:   role.System,
 	}
 
 	if err := r.db.Create(&roleModel).Error; err != nil {
@@ -30,6 +34,31 @@ func (r *roleRepository) CreateRole(role *role.Role) (uint, error) {
 	return roleModel.ID, nil
 }
 
+// UpdateRole - update name and description of a role
+func (r *roleRepository) UpdateRole(role *role.Role) error {
+	return r.db.Model(&models.RoleModel{}).Where("id = ?", role.ID).Updates(map[string]interface{}{
+		"role": role.Role,
+		"desc": role.Desc,
+	}).Error
+}
+
+// DeleteRole - delete role (user and permission assignments are deleted in cascade)
+func (r *roleRepository) DeleteRole(roleID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", roleID).Delete(&models.RefRoleUserModel{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", roleID).Delete(&models.RefRolePermissionModel{}).Error; err != nil {
+			return err
+		}
+		// Children of the role lose their parent
+		if err := tx.Model(&models.RoleModel{}).Where("parent_id = ?", roleID).Update("parent_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.RoleModel{}, roleID).Error
+	})
+}
+
 // GetAllRoles - get all roles
 func (r *roleRepository) GetAllRoles() ([]role.Role, error) {
 	var roleModels []models.RoleModel
@@ -54,21 +83,58 @@ func (r *roleRepository) GetRoleByID(id uint) (*role.Role, error) {
 	return roleModel.ToDomain(), nil
 }
 
-// AssignRoleToUser - assign role to user
+// AssignRoleToUser - assign role to user (permanent grant)
 func (r *roleRepository) AssignRoleToUser(userID, roleID uint) error {
+	return r.AssignRoleToUserWithValidity(userID, roleID, nil, nil)
+}
+
+// AssignRoleToUserWithValidity - assign role to user for a time window (nil => unlimited).
+// If the user already has the grant, its window is replaced.
+func (r *roleRepository) AssignRoleToUserWithValidity(userID, roleID uint, validFrom, validUntil *time.Time) error {
 	ref := models.RefRoleUserModel{
-		UserID: userID,
-		RoleID: roleID,
+		UserID:     userID,
+		RoleID:     roleID,
+		ValidFrom:  validFrom,
+		ValidUntil: validUntil,
+	}
+	return r.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"valid_from", "valid_until"}),
+	}).Create(&ref).Error
+}
+
+// DeleteExpiredGrants - delete the grants whose window ended before now and return them
+func (r *roleRepository) DeleteExpiredGrants(now time.Time) ([]role.RefRoleUser, error) {
+	var expired []models.RefRoleUserModel
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("valid_until IS NOT NULL AND valid_until <= ?", now).Find(&expired).Error; err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+		return tx.Where("valid_until IS NOT NULL AND valid_until <= ?", now).Delete(&models.RefRoleUserModel{}).Error
+	})
+	if err != nil {
+		return nil, err
 	}
-	return r.db.Create(&ref).Error
+
+	grants := make([]role.RefRoleUser, len(expired))
+	for i, ref := range expired {
+		grants[i] = *ref.ToDomain()
+	}
+	return grants, nil
 }
 
-// GetUserRoles - get user roles
+// GetUserRoles - get user roles (only grants valid at this moment)
 func (r *roleRepository) GetUserRoles(userID uint) ([]role.Role, error) {
 	var roleModels []models.RoleModel
 
+	now := time.Now()
 	if err := r.db.Joins("JOIN ref_user_role ON ref_user_role.role_id = roles.id").
 		Where("ref_user_role.user_id = ?", userID).
+		Where("ref_user_role.valid_from IS NULL OR ref_user_role.valid_from <= ?", now).
+		Where("ref_user_role.valid_until IS NULL OR ref_user_role.valid_until > ?", now).
 		Find(&roleModels).Error; err != nil {
 		return nil, err
 	}
@@ -81,6 +147,26 @@ func (r *roleRepository) GetUserRoles(userID uint) ([]role.Role, error) {
 	return roles, nil
 }
 
+// GetUserRolesExpanded - get user roles including the roles inherited through the hierarchy
+func (r *roleRepository) GetUserRolesExpanded(userID uint) ([]role.Role, error) {
+	direct, err := r.GetUserRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	allRoles, err := r.GetAllRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	return role.ExpandRoles(direct, allRoles), nil
+}
+
+// SetRoleParent - set (or clear, if parentID is nil) the parent of a role
+func (r *roleRepository) SetRoleParent(roleID uint, parentID *uint) error {
+	return r.db.Model(&models.RoleModel{}).Where("id = ?", roleID).Update("parent_id", parentID).Error
+}
+
 // RemoveRoleFromUser - remove role from user
 func (r *roleRepository) RemoveRoleFromUser(userID, roleID uint) error {
 	return r.db.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.RefRoleUserModel{}).Error
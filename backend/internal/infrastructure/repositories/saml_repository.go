@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"app/internal/domain/saml"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type samlRepository struct {
+	db *gorm.DB
+}
+
+// Ensure samlRepository implements the domain interface
+var _ saml.Repository = (*samlRepository)(nil)
+
+func NewSAMLRepository() saml.Repository {
+	return &samlRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *samlRepository) GetConnection(companyID uint) (*saml.Connection, error) {
+	var cm models.SAMLConnectionModel
+	if err := r.db.Where("company_id = ?", companyID).First(&cm).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, saml.ErrConnectionNotFound
+		}
+		return nil, err
+	}
+	return cm.ToDomain(), nil
+}
+
+func (r *samlRepository) SaveConnection(c *saml.Connection) error {
+	cm := models.SAMLConnectionModel{
+		CompanyID:      c.CompanyID,
+		OwnerID:        c.OwnerID,
+		Enabled:        c.Enabled,
+		IdPEntityID:    c.IdPEntityID,
+		IdPSSOURL:      c.IdPSSOURL,
+		IdPCertificate: c.IdPCertificate,
+		LoginSuffix:    c.LoginSuffix,
+		AttrLogin:      c.Attributes.Login,
+		AttrEmail:      c.Attributes.Email,
+		AttrName:       c.Attributes.Name,
+		AttrSurname:    c.Attributes.Surname,
+		AttrPhone:      c.Attributes.Phone,
+		AttrRoles:      c.Attributes.Roles,
+		RoleMapping:    c.RoleMapping,
+		UpdatedAt:      c.UpdatedAt,
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "company_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"owner_id", "enabled", "idp_entity_id", "idp_sso_url", "idp_certificate",
+			"login_suffix", "attr_login", "attr_email", "attr_name", "attr_surname", "attr_phone", "attr_roles",
+			"role_mapping", "updated_at"}),
+	}).Create(&cm).Error
+}
+
+func (r *samlRepository) CreateFlow(f *saml.Flow) error {
+	return r.db.Create(&models.SAMLFlowModel{
+		ID:         f.ID,
+		CompanyID:  f.CompanyID,
+		RequestID:  f.RequestID,
+		RelayState: f.RelayState,
+		UserID:     f.UserID,
+		ExpiresAt:  f.ExpiresAt,
+	}).Error
+}
+
+func (r *samlRepository) GetPendingFlow(relayState string, companyID uint, now time.Time) (*saml.Flow, error) {
+	var fm models.SAMLFlowModel
+	err := r.db.Where("relay_state = ? AND company_id = ? AND user_id IS NULL AND expires_at > ?", relayState, companyID, now).
+		First(&fm).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, saml.ErrFlowNotFound
+		}
+		return nil, err
+	}
+	return fm.ToDomain(), nil
+}
+
+func (r *samlRepository) CompleteFlow(id string, userID uint) error {
+	// Only one response can complete the flow: the one that sets its user
+	result := r.db.Model(&models.SAMLFlowModel{}).
+		Where("id = ? AND user_id IS NULL", id).
+		Update("user_id", userID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return saml.ErrFlowNotFound
+	}
+	return nil
+}
+
+func (r *samlRepository) ConsumeFlow(id string, companyID uint, now time.Time) (*saml.Flow, error) {
+	var fm models.SAMLFlowModel
+	if err := r.db.Where("id = ? AND company_id = ?", id, companyID).First(&fm).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, saml.ErrFlowNotFound
+		}
+		return nil, err
+	}
+
+	// Only one request can use the flow: the one that deletes it
+	result := r.db.Where("id = ?", id).Delete(&models.SAMLFlowModel{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || !now.Before(fm.ExpiresAt) {
+		return nil, saml.ErrFlowNotFound
+	}
+	return fm.ToDomain(), nil
+}
+
+func (r *samlRepository) DeleteExpiredFlows(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&models.SAMLFlowModel{}).Error
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"app/internal/domain/security"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type securityEventRepository struct {
+	db *gorm.DB
+}
+
+// Ensure securityEventRepository implements the domain interface
+var _ security.Repository = (*securityEventRepository)(nil)
+
+func NewSecurityEventRepository() security.Repository {
+	return &securityEventRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *securityEventRepository) Create(e *security.Event) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	em := models.SecurityEventModel{
+		Type:      string(e.Type),
+		UserID:    e.UserID,
+		Username:  e.Username,
+		SessionID: e.SessionID,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+		Details:   e.Details,
+		CreatedAt: e.CreatedAt,
+	}
+	if err := r.db.Create(&em).Error; err != nil {
+		return err
+	}
+	e.ID = em.ID
+	return nil
+}
+
+// GetByUserID returns the last events of the user (newest first)
+func (r *securityEventRepository) GetByUserID(userID uint, limit int) ([]security.Event, error) {
+	var eventModels []models.SecurityEventModel
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&eventModels).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]security.Event, len(eventModels))
+	for i, em := range eventModels {
+		events[i] = *em.ToDomain()
+	}
+	return events, nil
+}
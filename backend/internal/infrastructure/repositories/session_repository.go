@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"app/internal/domain/session"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// Ensure sessionRepository implements the domain interface
+var _ session.Repository = (*sessionRepository)(nil)
+
+func NewSessionRepository() session.Repository {
+	return &sessionRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *sessionRepository) IsNotFoundError(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// Create creates a new session (the ID is generated if empty) and its first refresh token
+func (r *sessionRepository) Create(s *session.Session, token *session.RefreshToken) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	sm := models.SessionModel{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		Device:     s.Device,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		RevokedAt:  s.RevokedAt,
+		ClientID:   s.ClientID,
+		Scope:      s.Scope,
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&sm).Error; err != nil {
+			return err
+		}
+
+		token.SessionID = s.ID
+		tm := refreshTokenToModel(token)
+		if err := tx.Create(&tm).Error; err != nil {
+			return err
+		}
+		token.ID = tm.ID
+		return nil
+	})
+}
+
+func (r *sessionRepository) GetByID(id string) (*session.Session, error) {
+	var sm models.SessionModel
+	if err := r.db.Where("id = ?", id).First(&sm).Error; err != nil {
+		return nil, err
+	}
+	return sm.ToDomain(), nil
+}
+
+// GetActiveByUserID returns the not revoked and not expired sessions of the user
+func (r *sessionRepository) GetActiveByUserID(userID uint) ([]*session.Session, error) {
+	var sessionModels []models.SessionModel
+	if err := r.db.Where("user_id = ? AND revoked_at IS NULL AND compromised_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessionModels).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*session.Session, len(sessionModels))
+	for i, sm := range sessionModels {
+		sessions[i] = sm.ToDomain()
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) Touch(id string, lastUsedAt, expiresAt time.Time) error {
+	return r.db.Model(&models.SessionModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_used_at": lastUsedAt,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+func (r *sessionRepository) Revoke(id string) error {
+	return r.db.Model(&models.SessionModel{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepository) RevokeAllByUserID(userID uint, exceptID string) error {
+	query := r.db.Model(&models.SessionModel{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptID != "" {
+		query = query.Where("id <> ?", exceptID)
+	}
+	return query.Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepository) RevokeFamily(id string) error {
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshTokenModel{}).
+			Where("session_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.SessionModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"revoked_at":     gorm.Expr("COALESCE(revoked_at, ?)", now),
+			"compromised_at": now,
+		}).Error
+	})
+}
+
+func (r *sessionRepository) GetRefreshTokenByHash(tokenHash string) (*session.RefreshToken, error) {
+	var tm models.RefreshTokenModel
+	if err := r.db.Where("token_hash = ?", tokenHash).First(&tm).Error; err != nil {
+		return nil, err
+	}
+	return tm.ToDomain(), nil
+}
+
+func (r *sessionRepository) RotateRefreshToken(oldID uint, newToken *session.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Only one request can rotate the token: the update is conditional on rotated_at IS NULL
+		result := tx.Model(&models.RefreshTokenModel{}).
+			Where("id = ? AND rotated_at IS NULL", oldID).
+			Update("rotated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return session.ErrRefreshTokenAlreadyRotated
+		}
+
+		newToken.ParentID = &oldID
+		tm := refreshTokenToModel(newToken)
+		if err := tx.Create(&tm).Error; err != nil {
+			return err
+		}
+		newToken.ID = tm.ID
+		return nil
+	})
+}
+
+func refreshTokenToModel(t *session.RefreshToken) models.RefreshTokenModel {
+	return models.RefreshTokenModel{
+		SessionID: t.SessionID,
+		TokenHash: t.TokenHash,
+		ParentID:  t.ParentID,
+		CreatedAt: t.CreatedAt,
+		ExpiresAt: t.ExpiresAt,
+		RotatedAt: t.RotatedAt,
+		RevokedAt: t.RevokedAt,
+	}
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"app/internal/infrastructure/token/paseto"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type signingKeyRepository struct {
+	db *gorm.DB
+}
+
+// Ensure signingKeyRepository implements the key store of the token manager
+var _ paseto.KeyStore = (*signingKeyRepository)(nil)
+
+func NewSigningKeyRepository() paseto.KeyStore {
+	return &signingKeyRepository{db: db.GetProvider().GetDB()}
+}
+
+// GetAll returns the keys that are not expired
+func (r *signingKeyRepository) GetAll(now time.Time) ([]paseto.StoredSigningKey, error) {
+	var keyModels []models.SigningKeyModel
+	if err := r.db.Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("created_at DESC").
+		Find(&keyModels).Error; err != nil {
+		return nil, err
+	}
+
+	keys := make([]paseto.StoredSigningKey, len(keyModels))
+	for i, km := range keyModels {
+		keys[i] = paseto.StoredSigningKey{
+			ID:               km.ID,
+			PublicKey:        km.PublicKey,
+			SealedPrivateKey: km.SealedPrivateKey,
+			CreatedAt:        km.CreatedAt,
+			RetiredAt:        km.RetiredAt,
+			ExpiresAt:        km.ExpiresAt,
+		}
+	}
+	return keys, nil
+}
+
+func (r *signingKeyRepository) Create(key paseto.StoredSigningKey) error {
+	return r.db.Create(&models.SigningKeyModel{
+		ID:               key.ID,
+		PublicKey:        key.PublicKey,
+		SealedPrivateKey: key.SealedPrivateKey,
+		CreatedAt:        key.CreatedAt,
+		RetiredAt:        key.RetiredAt,
+		ExpiresAt:        key.ExpiresAt,
+	}).Error
+}
+
+// Retire stops the key from signing; it keeps verifying until expiresAt
+func (r *signingKeyRepository) Retire(id string, retiredAt, expiresAt time.Time) error {
+	return r.db.Model(&models.SigningKeyModel{}).
+		Where("id = ? AND retired_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"retired_at": retiredAt,
+			"expires_at": expiresAt,
+		}).Error
+}
+
+func (r *signingKeyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at IS NOT NULL AND expires_at <= ?", now).Delete(&models.SigningKeyModel{})
+	return result.RowsAffected, result.Error
+}
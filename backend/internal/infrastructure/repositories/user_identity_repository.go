@@ -0,0 +1,104 @@
+package repositories
+
+import (
+	"app/internal/domain/user_identity"
+	"app/internal/infrastructure/db"
+	"app/internal/infrastructure/db/models"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+// Ensure userIdentityRepository implements the domain interface
+var _ user_identity.Repository = (*userIdentityRepository)(nil)
+
+func NewUserIdentityRepository() user_identity.Repository {
+	return &userIdentityRepository{db: db.GetProvider().GetDB()}
+}
+
+func (r *userIdentityRepository) IsAlreadyExistsError(err error) bool {
+	return errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "Error 1062")
+}
+
+func (r *userIdentityRepository) Create(i *user_identity.UserIdentity) error {
+	return r.CreateWithTransaction(r.db, i)
+}
+
+func (r *userIdentityRepository) CreateWithTransaction(tx *gorm.DB, i *user_identity.UserIdentity) error {
+	im := models.UserIdentityModel{
+		UserID:     i.UserID,
+		ProviderID: i.ProviderID,
+		Subject:    i.Subject,
+		CreatedAt:  i.CreatedAt,
+	}
+	if err := tx.Create(&im).Error; err != nil {
+		return err
+	}
+	i.ID = im.ID
+	return nil
+}
+
+func (r *userIdentityRepository) CreateMerging(i *user_identity.UserIdentity, mergedUserID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Sessions, roles, profile... of the merged user are removed with it (ON DELETE CASCADE)
+		if err := tx.Where("id = ?", mergedUserID).Delete(&models.UserModel{}).Error; err != nil {
+			return err
+		}
+		return r.CreateWithTransaction(tx, i)
+	})
+}
+
+func (r *userIdentityRepository) GetByUserID(userID uint) ([]*user_identity.UserIdentity, error) {
+	var ims []models.UserIdentityModel
+	if err := r.db.Preload("Provider").Where("user_id = ?", userID).Order("id").Find(&ims).Error; err != nil {
+		return nil, err
+	}
+	return toUserIdentities(ims), nil
+}
+
+func (r *userIdentityRepository) GetBySubject(subject string) ([]*user_identity.UserIdentity, error) {
+	var ims []models.UserIdentityModel
+	if err := r.db.Preload("Provider").Where("subject = ?", subject).Order("id").Find(&ims).Error; err != nil {
+		return nil, err
+	}
+	return toUserIdentities(ims), nil
+}
+
+func (r *userIdentityRepository) Exists(providerID uint, subject string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.UserIdentityModel{}).
+		Where("provider_id = ? AND subject = ?", providerID, subject).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *userIdentityRepository) UpdateLastUsed(id uint, at time.Time) error {
+	return r.db.Model(&models.UserIdentityModel{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+func (r *userIdentityRepository) Delete(id, userID uint) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.UserIdentityModel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return user_identity.ErrIdentityNotFound
+	}
+	return nil
+}
+
+func toUserIdentities(ims []models.UserIdentityModel) []*user_identity.UserIdentity {
+	identities := make([]*user_identity.UserIdentity, len(ims))
+	for i := range ims {
+		identities[i] = ims[i].ToDomain()
+	}
+	return identities
+}
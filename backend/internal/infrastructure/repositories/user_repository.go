@@ -56,6 +56,17 @@ func (r *userRepository) GetByLogin(login string) (*user.User, error) {
 	return um.ToDomain(), nil
 }
 
+func (r *userRepository) GetByUUID(uuid string) (*user.User, error) {
+	var um models.UserModel
+	err := r.db.Preload("Profile").Preload("Roles").
+		Where("uuid = ?", uuid).
+		First(&um).Error
+	if err != nil {
+		return nil, err
+	}
+	return um.ToDomain(), nil
+}
+
 func (r *userRepository) Create(u *user.User) error {
 	um, err := db.FromDomainGeneric[user.User, models.UserModel](*u)
 	if err != nil {
@@ -170,21 +181,14 @@ func (r *userRepository) UpdateActiveStatus(userID uint, active bool) error {
 	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("active", active).Error
 }
 
-// GetByRefreshToken gets a user by refresh token
-func (r *userRepository) GetByRefreshToken(refreshToken string) (*user.User, error) {
-	var userModel models.UserModel
-	if err := r.db.Where("refresh = ?", refreshToken).First(&userModel).Error; err != nil {
-		return nil, err
-	}
-	return userModel.ToDomain(), nil
+// UpdateLockedUntil locks the user until the given time (nil unlocks it)
+func (r *userRepository) UpdateLockedUntil(userID uint, lockedUntil *time.Time) error {
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("lockedUntil", lockedUntil).Error
 }
 
-// UpdateRefreshToken updates the refresh token of a user
-func (r *userRepository) UpdateRefreshToken(u *user.User) error {
-	return r.db.Model(&models.UserModel{}).Where("id = ?", u.ID).Updates(map[string]interface{}{
-		"refresh":    u.Refresh,
-		"refreshExp": u.RefreshExp,
-	}).Error
+// UpdateLoggedStatus updates the isLogged flag of a user
+func (r *userRepository) UpdateLoggedStatus(userID uint, isLogged bool) error {
+	return r.db.Model(&models.UserModel{}).Where("id = ?", userID).Update("isLogged", isLogged).Error
 }
 
 func (r *userRepository) DeleteUserByUsername(username string) error {
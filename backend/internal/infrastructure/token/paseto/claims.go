@@ -9,17 +9,25 @@ import (
 
 // PasetoClaims — typed fields that you want to store in the token.
 type PasetoClaims struct {
+	TokenID     string `json:"jti,omitempty"`
 	Username    string `json:"username"`
 	CompanyID   int    `json:"companyId"`
 	CompanyName string `json:"companyName"`
 	Roles       string `json:"roles"`
 	// IsPrimary     bool   `json:"isPrimary"`
 	OwnerUsername string `json:"ownerUsername"`
+	SessionID     string `json:"sid,omitempty"`
+	ClientID      string `json:"cid,omitempty"`     // OAuth client the token was issued to
+	Scope         string `json:"scope,omitempty"`   // OAuth scope granted to the client
+	SubjectType   string `json:"subType,omitempty"` // SubjectTypeClient for client_credentials tokens
 
 	IssuedAt  time.Time `json:"iat"`
 	ExpiresAt time.Time `json:"exp"`
 }
 
+// SubjectTypeClient — the subject of the token is an OAuth client, not a user (client_credentials grant)
+const SubjectTypeClient = "client"
+
 func capitalizeKey(key string) string {
 	if len(key) == 0 {
 		return key
@@ -50,3 +58,38 @@ func (c *PasetoClaims) SetFromMap(data map[string]interface{}) {
 		}
 	}
 }
+
+// RoleList returns the roles of the claims as a slice
+func (c *PasetoClaims) RoleList() []string {
+	var roles []string
+	for _, r := range strings.Split(c.Roles, ",") {
+		r = strings.TrimSpace(r)
+		if r != "" {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// HasRole checks if the claims contain the given role
+func (c *PasetoClaims) HasRole(role string) bool {
+	for _, r := range c.RoleList() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// MainUsername returns the username of the company owner (the user itself if it is not a subuser)
+func (c *PasetoClaims) MainUsername() string {
+	if c.OwnerUsername == "" {
+		return c.Username
+	}
+	return c.OwnerUsername
+}
+
+// IsClientToken checks if the token was issued to an OAuth client on its own behalf (no user)
+func (c *PasetoClaims) IsClientToken() bool {
+	return c.SubjectType == SubjectTypeClient
+}
@@ -0,0 +1,38 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+)
+
+// OpenID Connect requires the ID token to be a JWT, so it is signed as a compact JWS (EdDSA)
+// with the same key ring as the access tokens. Clients verify it with the keys of /token/keys.
+
+// SignJWT signs the claims with the current key of the key ring (alg EdDSA, kid of the key)
+func (p *PasetoManager) SignJWT(claims map[string]interface{}) (string, error) {
+	if p.keyRing == nil {
+		return "", errors.New("key ring is not configured")
+	}
+	key := p.keyRing.Current()
+	if key == nil {
+		return "", errors.New("no signing key available")
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "EdDSA",
+		"typ": "JWT",
+		"kid": key.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	sig := ed25519.Sign(key.PrivateKey, []byte(signingInput))
+	return signingInput + "." + b64.EncodeToString(sig), nil
+}
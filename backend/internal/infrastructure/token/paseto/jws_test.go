@@ -0,0 +1,73 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSignJWT(t *testing.T) {
+	p := newTestManager(t)
+	key := p.KeyRing().Current()
+
+	jwt, err := p.SignJWT(map[string]interface{}{
+		"iss":   "https://issuer.example",
+		"sub":   "tech1",
+		"aud":   "client-1",
+		"nonce": "n-0S6_WzA2Mj",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("got %d parts, want a compact JWS", len(parts))
+	}
+
+	var header map[string]string
+	decodeJSON(t, parts[0], &header)
+	if header["alg"] != "EdDSA" || header["typ"] != "JWT" || header["kid"] != key.ID {
+		t.Errorf("unexpected header %v", header)
+	}
+
+	var claims map[string]interface{}
+	decodeJSON(t, parts[1], &claims)
+	if claims["sub"] != "tech1" || claims["nonce"] != "n-0S6_WzA2Mj" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+
+	// Clients verify the signature with the published key of the kid (RFC 8037)
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := parts[0] + "." + parts[1]
+	if !ed25519.Verify(key.PublicKey, []byte(signingInput), sig) {
+		t.Fatal("invalid signature")
+	}
+
+	// Any change of the header or the claims breaks the signature
+	tampered := b64.EncodeToString([]byte(`{"sub":"admin"}`))
+	if ed25519.Verify(key.PublicKey, []byte(parts[0]+"."+tampered), sig) {
+		t.Error("signature valid for other claims")
+	}
+}
+
+func TestSignJWTWithoutKeyRing(t *testing.T) {
+	if _, err := (&PasetoManager{}).SignJWT(map[string]interface{}{"sub": "user"}); err == nil {
+		t.Error("JWT signed without key ring")
+	}
+}
+
+func decodeJSON(t *testing.T, segment string, v interface{}) {
+	t.Helper()
+	data, err := b64.DecodeString(segment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
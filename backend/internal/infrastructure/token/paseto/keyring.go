@@ -0,0 +1,297 @@
+package paseto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"app/pkg/sealer"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// SigningKey — Ed25519 key of the key ring.
+// A retired key doesn't sign new tokens, but it still verifies the tokens it signed until ExpiresAt.
+type SigningKey struct {
+	ID         string
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+	CreatedAt  time.Time
+	RetiredAt  *time.Time
+	ExpiresAt  *time.Time
+}
+
+// StoredSigningKey — signing key as it is persisted: the private key is sealed with the service secret
+type StoredSigningKey struct {
+	ID               string
+	PublicKey        []byte
+	SealedPrivateKey []byte
+	CreatedAt        time.Time
+	RetiredAt        *time.Time
+	ExpiresAt        *time.Time
+}
+
+// KeyStore persists the key ring, so it is shared by all the instances of the service
+type KeyStore interface {
+	GetAll(now time.Time) ([]StoredSigningKey, error) // Not expired keys
+	Create(key StoredSigningKey) error
+	Retire(id string, retiredAt, expiresAt time.Time) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// PublicKey — verification key published for other services (JWK-like with the PASERK form)
+type PublicKey struct {
+	KeyID     string     `json:"kid"`
+	KeyType   string     `json:"kty"`
+	Curve     string     `json:"crv"`
+	Algorithm string     `json:"alg"`
+	Use       string     `json:"use"`
+	X         string     `json:"x"`
+	Paserk    string     `json:"paserk"`
+	Version   string     `json:"version"`
+	Purpose   string     `json:"purpose"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Minimum time between two reloads triggered by tokens signed with an unknown key
+const unknownKeyReloadInterval = 10 * time.Second
+
+// KeyRing keeps the signing keys in memory and rotates them
+type KeyRing struct {
+	store     KeyStore
+	sealer    *sealer.Sealer
+	retention time.Duration // How long a retired key verifies tokens (the longest token lifetime)
+
+	mu         sync.RWMutex
+	keys       map[string]*SigningKey
+	current    *SigningKey
+	lastReload time.Time
+}
+
+// NewKeyRing loads the keys from the store and creates the first key if there is none
+func NewKeyRing(store KeyStore, secret string, retention time.Duration) (*KeyRing, error) {
+	kr := &KeyRing{
+		store:     store,
+		sealer:    sealer.New(secret, "paseto-signing-key"),
+		retention: retention,
+		keys:      make(map[string]*SigningKey),
+	}
+
+	if err := kr.Reload(); err != nil {
+		return nil, err
+	}
+	if kr.Current() == nil {
+		if _, err := kr.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return kr, nil
+}
+
+// Reload replaces the keys in memory with the keys of the store (e.g. rotated by another instance)
+func (kr *KeyRing) Reload() error {
+	stored, err := kr.store.GetAll(time.Now())
+	if err != nil {
+		return fmt.Errorf("get signing keys error: %w", err)
+	}
+
+	keys := make(map[string]*SigningKey, len(stored))
+	var current *SigningKey
+	for _, sk := range stored {
+		key, err := kr.open(sk)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", sk.ID, err)
+		}
+		keys[key.ID] = key
+		if key.RetiredAt == nil && (current == nil || key.CreatedAt.After(current.CreatedAt)) {
+			current = key
+		}
+	}
+
+	kr.mu.Lock()
+	kr.keys = keys
+	kr.current = current
+	kr.lastReload = time.Now()
+	kr.mu.Unlock()
+	return nil
+}
+
+// Current returns the key that signs new tokens
+func (kr *KeyRing) Current() *SigningKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.current
+}
+
+// Lookup returns the verification key by its ID. An unknown ID triggers a (rate-limited) reload,
+// because the key may have been created by another instance after the last reload.
+func (kr *KeyRing) Lookup(kid string) (ed25519.PublicKey, bool) {
+	if key, ok := kr.lookup(kid); ok {
+		return key, true
+	}
+
+	kr.mu.RLock()
+	canReload := time.Since(kr.lastReload) > unknownKeyReloadInterval
+	kr.mu.RUnlock()
+	if !canReload || kr.Reload() != nil {
+		return nil, false
+	}
+	return kr.lookup(kid)
+}
+
+func (kr *KeyRing) lookup(kid string) (ed25519.PublicKey, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	key, ok := kr.keys[kid]
+	if !ok || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, false
+	}
+	return key.PublicKey, true
+}
+
+// Rotate creates a new signing key and retires the current one.
+// The retired key keeps verifying the tokens it signed until they expire.
+func (kr *KeyRing) Rotate() (*SigningKey, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate signing key error: %w", err)
+	}
+
+	now := time.Now()
+	key := &SigningKey{
+		ID:         keyID(publicKey),
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+		CreatedAt:  now,
+	}
+
+	sealed, err := kr.sealer.Seal(privateKey.Seed())
+	if err != nil {
+		return nil, err
+	}
+	if err := kr.store.Create(StoredSigningKey{
+		ID:               key.ID,
+		PublicKey:        publicKey,
+		SealedPrivateKey: sealed,
+		CreatedAt:        now,
+	}); err != nil {
+		return nil, fmt.Errorf("create signing key error: %w", err)
+	}
+
+	kr.mu.Lock()
+	previous := kr.current
+	kr.keys[key.ID] = key
+	kr.current = key
+	kr.mu.Unlock()
+
+	if previous != nil {
+		expiresAt := now.Add(kr.retention)
+		if err := kr.store.Retire(previous.ID, now, expiresAt); err != nil {
+			return nil, fmt.Errorf("retire signing key error: %w", err)
+		}
+		kr.mu.Lock()
+		previous.RetiredAt = &now
+		previous.ExpiresAt = &expiresAt
+		kr.mu.Unlock()
+	}
+	return key, nil
+}
+
+// RotateIfDue rotates the keys when the current key is older than interval
+func (kr *KeyRing) RotateIfDue(interval time.Duration) (bool, error) {
+	current := kr.Current()
+	if current != nil && time.Since(current.CreatedAt) < interval {
+		return false, nil
+	}
+	if _, err := kr.Rotate(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteExpired removes the keys that can't verify any token anymore
+func (kr *KeyRing) DeleteExpired() (int64, error) {
+	now := time.Now()
+
+	kr.mu.Lock()
+	for id, key := range kr.keys {
+		if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+			delete(kr.keys, id)
+		}
+	}
+	kr.mu.Unlock()
+
+	return kr.store.DeleteExpired(now)
+}
+
+// PublicKeys returns the verification keys, the current key first
+func (kr *KeyRing) PublicKeys() []PublicKey {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	now := time.Now()
+	keys := make([]PublicKey, 0, len(kr.keys))
+	for _, key := range kr.keys {
+		if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+			continue
+		}
+		keys = append(keys, PublicKey{
+			KeyID:     key.ID,
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			Algorithm: "EdDSA",
+			Use:       "sig",
+			X:         b64.EncodeToString(key.PublicKey),
+			Paserk:    paserkPublic(key.PublicKey),
+			Version:   "v4",
+			Purpose:   "public",
+			CreatedAt: key.CreatedAt,
+			ExpiresAt: key.ExpiresAt,
+		})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// open unseals the private key of a stored key
+func (kr *KeyRing) open(sk StoredSigningKey) (*SigningKey, error) {
+	seed, err := kr.sealer.Open(sk.SealedPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.New("invalid private key")
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	return &SigningKey{
+		ID:         sk.ID,
+		PrivateKey: privateKey,
+		PublicKey:  privateKey.Public().(ed25519.PublicKey),
+		CreatedAt:  sk.CreatedAt,
+		RetiredAt:  sk.RetiredAt,
+		ExpiresAt:  sk.ExpiresAt,
+	}, nil
+}
+
+// paserkPublic returns the PASERK form of the public key (k4.public.<key>)
+func paserkPublic(publicKey ed25519.PublicKey) string {
+	return "k4.public." + b64.EncodeToString(publicKey)
+}
+
+// keyID returns the PASERK ID of the public key (k4.pid.<hash>)
+func keyID(publicKey ed25519.PublicKey) string {
+	const header = "k4.pid."
+	h, _ := blake2b.New(33, nil)
+	h.Write([]byte(header + paserkPublic(publicKey)))
+	return header + b64.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,208 @@
+package paseto
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"app/pkg/sealer"
+)
+
+// memoryKeyStore — KeyStore kept in memory (stands in for the signing_keys table)
+type memoryKeyStore struct {
+	mu   sync.Mutex
+	keys []StoredSigningKey
+}
+
+func (s *memoryKeyStore) GetAll(now time.Time) ([]StoredSigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []StoredSigningKey
+	for _, k := range s.keys {
+		if k.ExpiresAt == nil || now.Before(*k.ExpiresAt) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (s *memoryKeyStore) Create(key StoredSigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *memoryKeyStore) Retire(id string, retiredAt, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.keys {
+		if s.keys[i].ID == id {
+			s.keys[i].RetiredAt, s.keys[i].ExpiresAt = &retiredAt, &expiresAt
+		}
+	}
+	return nil
+}
+
+func (s *memoryKeyStore) DeleteExpired(now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.keys[:0]
+	for _, k := range s.keys {
+		if k.ExpiresAt == nil || now.Before(*k.ExpiresAt) {
+			kept = append(kept, k)
+		}
+	}
+	deleted := int64(len(s.keys) - len(kept))
+	s.keys = kept
+	return deleted, nil
+}
+
+const testSecret = "test-secret"
+
+func newTestKeyRing(t *testing.T, store KeyStore, retention time.Duration) *KeyRing {
+	t.Helper()
+	kr, err := NewKeyRing(store, testSecret, retention)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kr
+}
+
+func TestNewKeyRingCreatesFirstKey(t *testing.T) {
+	store := &memoryKeyStore{}
+	kr := newTestKeyRing(t, store, time.Hour)
+
+	current := kr.Current()
+	if current == nil {
+		t.Fatal("no current key")
+	}
+	if len(store.keys) != 1 || store.keys[0].ID != current.ID {
+		t.Fatalf("stored keys: %+v", store.keys)
+	}
+	if !strings.HasPrefix(current.ID, "k4.pid.") {
+		t.Errorf("key ID %s is not a PASERK ID", current.ID)
+	}
+
+	// Another instance loads the same key instead of creating one
+	other := newTestKeyRing(t, store, time.Hour)
+	if other.Current().ID != current.ID || len(store.keys) != 1 {
+		t.Error("second instance did not load the stored key")
+	}
+}
+
+func TestKeyRingSealsPrivateKeys(t *testing.T) {
+	store := &memoryKeyStore{}
+	kr := newTestKeyRing(t, store, time.Hour)
+
+	sealed := store.keys[0].SealedPrivateKey
+	if strings.Contains(string(sealed), string(kr.Current().PrivateKey.Seed())) {
+		t.Fatal("private key stored in plain text")
+	}
+
+	// The keys are sealed with a key of their own, not the plain hash of the secret
+	if _, err := sealer.New(testSecret, "").Open(sealed); !errors.Is(err, sealer.ErrOpen) {
+		t.Errorf("signing key opened without its purpose: %v", err)
+	}
+
+	// Another secret can't load the keys
+	if _, err := NewKeyRing(store, "another-secret", time.Hour); err == nil {
+		t.Error("keys loaded with another secret")
+	}
+}
+
+func TestKeyRingRotate(t *testing.T) {
+	const retention = time.Hour
+	store := &memoryKeyStore{}
+	kr := newTestKeyRing(t, store, retention)
+	first := kr.Current()
+
+	token, err := signV4Public(first, []byte(`{"sub":"user"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := kr.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kr.Current().ID != second.ID || second.ID == first.ID {
+		t.Fatal("rotation did not change the current key")
+	}
+	if first.RetiredAt == nil || first.ExpiresAt == nil {
+		t.Fatal("previous key was not retired")
+	}
+	if d := first.ExpiresAt.Sub(*first.RetiredAt); d != retention {
+		t.Errorf("retired key kept for %s, want %s", d, retention)
+	}
+
+	// The retired key still verifies the tokens it signed
+	if _, err := verifyV4Public(token, kr.Lookup); err != nil {
+		t.Errorf("token of the retired key: %v", err)
+	}
+
+	// ...until it expires
+	expired := time.Now().Add(-time.Second)
+	first.ExpiresAt = &expired
+	if _, err := verifyV4Public(token, kr.Lookup); !errors.Is(err, errUnknownKey) {
+		t.Errorf("token of the expired key: got %v, want %v", err, errUnknownKey)
+	}
+}
+
+func TestKeyRingLookupReloadsUnknownKeys(t *testing.T) {
+	store := &memoryKeyStore{}
+	kr := newTestKeyRing(t, store, time.Hour)
+	other := newTestKeyRing(t, store, time.Hour)
+
+	// Another instance rotates the keys: the new key is loaded the first time one of its tokens is seen
+	rotated, err := other.Rotate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := signV4Public(rotated, []byte(`{"sub":"user"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kr.mu.Lock()
+	kr.lastReload = time.Now().Add(-2 * unknownKeyReloadInterval)
+	kr.mu.Unlock()
+	if _, err := verifyV4Public(token, kr.Lookup); err != nil {
+		t.Errorf("token of the key rotated by another instance: %v", err)
+	}
+}
+
+func TestKeyRingRotateIfDue(t *testing.T) {
+	kr := newTestKeyRing(t, &memoryKeyStore{}, time.Hour)
+
+	rotated, err := kr.RotateIfDue(time.Hour)
+	if err != nil || rotated {
+		t.Fatalf("new key rotated: %v %v", rotated, err)
+	}
+	rotated, err = kr.RotateIfDue(0)
+	if err != nil || !rotated {
+		t.Fatalf("old key not rotated: %v %v", rotated, err)
+	}
+}
+
+func TestKeyRingPublicKeys(t *testing.T) {
+	kr := newTestKeyRing(t, &memoryKeyStore{}, time.Hour)
+	if _, err := kr.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := kr.PublicKeys()
+	if len(keys) != 2 {
+		t.Fatalf("got %d keys, want 2", len(keys))
+	}
+	if keys[0].KeyID != kr.Current().ID {
+		t.Error("current key is not the first one")
+	}
+	for _, k := range keys {
+		if k.Algorithm != "EdDSA" || k.Curve != "Ed25519" || !strings.HasPrefix(k.Paserk, "k4.public.") {
+			t.Errorf("unexpected key %+v", k)
+		}
+	}
+}
@@ -1,15 +1,16 @@
 package paseto
 
 import (
+	"app/internal/domain/role"
 	"app/pkg/config"
-	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/o1egl/paseto"
+	"github.com/google/uuid"
 )
 
 // PasetoManager manages PASETO tokens
@@ -17,6 +18,8 @@ type PasetoManager struct {
 	baseKey               string
 	expirationTime        time.Duration
 	recoverExpirationTime time.Duration
+	revocations           RevocationList
+	keyRing               *KeyRing
 }
 
 var (
@@ -51,7 +54,7 @@ func Paseto() *PasetoManager {
 	return instance
 }
 
-// GenerateToken creates a new PASETO token and returns it along with the claims
+// GenerateToken creates a new PASETO v4.public token and returns it along with the claims
 func (p *PasetoManager) GenerateToken(claims PasetoClaims) (string, *PasetoClaims, error) {
 	if claims.Username == "" {
 		return "", nil, errors.New("missing username in claims")
@@ -63,37 +66,45 @@ func (p *PasetoManager) GenerateToken(claims PasetoClaims) (string, *PasetoClaim
 	}
 	claims.IssuedAt = time.Now()
 
-	// Prepare JSONToken from paseto
-	jsonToken := paseto.JSONToken{
-		Subject:    claims.Username,
-		IssuedAt:   claims.IssuedAt,
-		Expiration: claims.ExpiresAt,
+	// Unique identifier of the token, used to revoke it
+	if claims.TokenID == "" {
+		claims.TokenID = uuid.New().String()
 	}
 
-	// Set custom fields
-	jsonToken.Set("companyId", strconv.Itoa(claims.CompanyID))
-	jsonToken.Set("companyName", claims.CompanyName)
-	jsonToken.Set("roles", claims.Roles)
-	// jsonToken.Set("isPrimary", strconv.FormatBool(claims.IsPrimary))
-
+	payload := map[string]string{
+		"jti":         claims.TokenID,
+		"sub":         claims.Username,
+		"iat":         claims.IssuedAt.Format(time.RFC3339),
+		"exp":         claims.ExpiresAt.Format(time.RFC3339),
+		"companyId":   strconv.Itoa(claims.CompanyID),
+		"companyName": claims.CompanyName,
+		"roles":       claims.Roles,
+	}
 	if claims.OwnerUsername != "" {
-		jsonToken.Set("ownerUsername", claims.OwnerUsername)
+		payload["ownerUsername"] = claims.OwnerUsername
+	}
+	if claims.SessionID != "" {
+		payload["sid"] = claims.SessionID
+	}
+	if claims.ClientID != "" {
+		payload["cid"] = claims.ClientID
+	}
+	if claims.Scope != "" {
+		payload["scope"] = claims.Scope
+	}
+	if claims.SubjectType != "" {
+		payload["subType"] = claims.SubjectType
 	}
 
-	// Form the key (symmetricKey)
-	key := sha256.Sum256([]byte(p.baseKey))
-	symmetricKey := key[:]
-
-	// Encrypt
-	token, err := paseto.NewV2().Encrypt(symmetricKey, jsonToken, nil)
+	token, err := p.sign(payload)
 	if err != nil {
-		return "", nil, errors.New("error generating token")
+		return "", nil, err
 	}
-
 	return token, &claims, nil
 }
 
-// GenerateRecoverToken creates a new PASETO token for password recovery
+// GenerateRecoverToken creates a new PASETO token for password recovery.
+// Its nonce (jti) identifies the token in the server-side record that makes it single-use.
 func (p *PasetoManager) GenerateRecoverToken(claims PasetoClaims) (string, *PasetoClaims, error) {
 	if claims.Username == "" {
 		return "", nil, errors.New("missing username in claims")
@@ -102,29 +113,94 @@ func (p *PasetoManager) GenerateRecoverToken(claims PasetoClaims) (string, *Pase
 	// Set expiration and issued times if not set
 	claims.ExpiresAt = time.Now().Add(p.recoverExpirationTime)
 	claims.IssuedAt = time.Now()
-
-	// Prepare JSONToken from paseto
-	jsonToken := paseto.JSONToken{
-		Subject:    claims.Username,
-		IssuedAt:   claims.IssuedAt,
-		Expiration: claims.ExpiresAt,
+	claims.TokenID = uuid.New().String()
+	claims.Roles = role.RoleRecover
+
+	token, err := p.sign(map[string]string{
+		"jti":   claims.TokenID,
+		"sub":   claims.Username,
+		"iat":   claims.IssuedAt.Format(time.RFC3339),
+		"exp":   claims.ExpiresAt.Format(time.RFC3339),
+		"roles": claims.Roles,
+	})
+	if err != nil {
+		return "", nil, err
 	}
-	jsonToken.Set("roles", "recover")
+	return token, &claims, nil
+}
 
-	// Form the key (symmetricKey)
-	key := sha256.Sum256([]byte(p.baseKey))
-	symmetricKey := key[:]
+// GenerateMFAPendingToken creates a short-lived token of a login waiting for its second factor.
+// It only allows to complete that login and it is revoked (jti) once used.
+func (p *PasetoManager) GenerateMFAPendingToken(claims PasetoClaims, ttl time.Duration) (string, *PasetoClaims, error) {
+	if claims.Username == "" {
+		return "", nil, errors.New("missing username in claims")
+	}
 
-	// Encrypt
-	token, err := paseto.NewV2().Encrypt(symmetricKey, jsonToken, nil)
+	claims.IssuedAt = time.Now()
+	claims.ExpiresAt = claims.IssuedAt.Add(ttl)
+	claims.TokenID = uuid.New().String()
+	claims.Roles = role.RoleMFAPending
+
+	token, err := p.sign(map[string]string{
+		"jti":   claims.TokenID,
+		"sub":   claims.Username,
+		"iat":   claims.IssuedAt.Format(time.RFC3339),
+		"exp":   claims.ExpiresAt.Format(time.RFC3339),
+		"roles": claims.Roles,
+	})
 	if err != nil {
-		return "", nil, errors.New("error generating token")
+		return "", nil, err
+	}
+	return token, &claims, nil
+}
+
+// GeneratePasswordExpiredToken creates a short-lived token of a user whose password is expired.
+// It only allows to change the password and it is revoked (jti) once used.
+func (p *PasetoManager) GeneratePasswordExpiredToken(claims PasetoClaims, ttl time.Duration) (string, *PasetoClaims, error) {
+	if claims.Username == "" {
+		return "", nil, errors.New("missing username in claims")
 	}
 
+	claims.IssuedAt = time.Now()
+	claims.ExpiresAt = claims.IssuedAt.Add(ttl)
+	claims.TokenID = uuid.New().String()
+	claims.Roles = role.RolePasswordExpired
+
+	token, err := p.sign(map[string]string{
+		"jti":   claims.TokenID,
+		"sub":   claims.Username,
+		"iat":   claims.IssuedAt.Format(time.RFC3339),
+		"exp":   claims.ExpiresAt.Format(time.RFC3339),
+		"roles": claims.Roles,
+	})
+	if err != nil {
+		return "", nil, err
+	}
 	return token, &claims, nil
 }
 
-// ValidateToken validates a PASETO token and checks expiration
+// sign signs the payload with the current key of the key ring
+func (p *PasetoManager) sign(payload map[string]string) (string, error) {
+	if p.keyRing == nil {
+		return "", errors.New("key ring is not configured")
+	}
+	key := p.keyRing.Current()
+	if key == nil {
+		return "", errors.New("no signing key available")
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", errors.New("error generating token")
+	}
+	token, err := signV4Public(key, data)
+	if err != nil {
+		return "", errors.New("error generating token")
+	}
+	return token, nil
+}
+
+// ValidateToken validates a PASETO token and checks expiration and revocation
 func (p *PasetoManager) ValidateToken(tokenStr string) (*PasetoClaims, error) {
 	return p.validateTokenInternal(tokenStr, true)
 }
@@ -141,50 +217,100 @@ func (p *PasetoManager) validateTokenInternal(tokenStr string, checkExpiration b
 		tokenStr = tokenStr[len(BearerPrefix):]
 	}
 
-	var jsonToken paseto.JSONToken
-
-	// Get key
-	key := sha256.Sum256([]byte(p.baseKey))
-	symmetricKey := key[:]
+	payload, err := p.decode(tokenStr)
+	if err != nil {
+		return nil, err
+	}
 
-	err := paseto.NewV2().Decrypt(tokenStr, symmetricKey, &jsonToken, nil)
+	expiresAt, err := time.Parse(time.RFC3339, payload["exp"])
 	if err != nil {
-		return nil, errors.New("token decrypt error: " + err.Error())
+		return nil, errors.New("invalid expiration in token")
 	}
+	issuedAt, _ := time.Parse(time.RFC3339, payload["iat"])
 
 	// Check if the token has expired
-	if checkExpiration && time.Now().After(jsonToken.Expiration) {
+	if checkExpiration && time.Now().After(expiresAt) {
 		return nil, errors.New("token expired")
 	}
 
 	// Collect PasetoClaims
 	claims := &PasetoClaims{
-		Username:  jsonToken.Subject,
-		IssuedAt:  jsonToken.IssuedAt,
-		ExpiresAt: jsonToken.Expiration,
-	}
-
-	if ownerUsername := jsonToken.Get("ownerUsername"); ownerUsername != "" {
-		claims.OwnerUsername = ownerUsername
+		TokenID:       payload["jti"],
+		Username:      payload["sub"],
+		IssuedAt:      issuedAt,
+		ExpiresAt:     expiresAt,
+		OwnerUsername: payload["ownerUsername"],
+		CompanyName:   payload["companyName"],
+		SessionID:     payload["sid"],
+		Roles:         payload["roles"],
+		ClientID:      payload["cid"],
+		Scope:         payload["scope"],
+		SubjectType:   payload["subType"],
 	}
 
 	// Get other fields
-	if companyIdStr := jsonToken.Get("companyId"); companyIdStr != "" {
+	if companyIdStr := payload["companyId"]; companyIdStr != "" {
 		companyID, err := strconv.Atoi(companyIdStr)
 		if err == nil {
 			claims.CompanyID = companyID
 		}
 	}
-	claims.CompanyName = jsonToken.Get("companyName")
-	claims.Roles = jsonToken.Get("roles")
-	// if isPrimaryStr := jsonToken.Get("isPrimary"); isPrimaryStr != "" {
-	// 	claims.IsPrimary, _ = strconv.ParseBool(isPrimaryStr)
-	// }
 
 	// Check required fields
 	if claims.Username == "" {
 		return nil, errors.New("missing username in token")
 	}
 
+	// Check if the token (or its session) was revoked
+	if err := p.checkRevoked(claims); err != nil {
+		return nil, err
+	}
+
 	return claims, nil
 }
+
+// decode verifies the token and returns its claims.
+// Only v4.public tokens are accepted: v2.local tokens, encrypted with PASETO_SK, can be minted by every
+// service that holds the secret, so the clients get a v4.public token with their refresh token.
+func (p *PasetoManager) decode(tokenStr string) (map[string]string, error) {
+	if p.keyRing == nil {
+		return nil, errors.New("key ring is not configured")
+	}
+	data, err := verifyV4Public(tokenStr, p.keyRing.Lookup)
+	if err != nil {
+		return nil, errors.New("token verification error: " + err.Error())
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, errors.New("token verification error: " + errTokenFormat.Error())
+	}
+	return payload, nil
+}
+
+// SetKeyStore loads the key ring from the store (creating the first key if needed).
+// Must be called once at startup, before the tokens are generated or validated.
+func (p *PasetoManager) SetKeyStore(store KeyStore) error {
+	// A retired key must verify the tokens it signed until the longest of them expires
+	retention := p.expirationTime
+	if p.recoverExpirationTime > retention {
+		retention = p.recoverExpirationTime
+	}
+
+	keyRing, err := NewKeyRing(store, p.baseKey, retention)
+	if err != nil {
+		return err
+	}
+	p.keyRing = keyRing
+	return nil
+}
+
+// ExpirationTime returns the lifetime of the access tokens
+func (p *PasetoManager) ExpirationTime() time.Duration {
+	return p.expirationTime
+}
+
+// KeyRing returns the signing keys of the manager (nil until SetKeyStore is called)
+func (p *PasetoManager) KeyRing() *KeyRing {
+	return p.keyRing
+}
@@ -0,0 +1,207 @@
+package paseto
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"app/internal/domain/role"
+)
+
+// memoryRevocationList — RevocationList kept in memory (stands in for the revoked_tokens table)
+type memoryRevocationList struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+func (l *memoryRevocationList) Revoke(identifier string, expiresAt time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.revoked == nil {
+		l.revoked = make(map[string]time.Time)
+	}
+	l.revoked[identifier] = expiresAt
+	return nil
+}
+
+func (l *memoryRevocationList) IsRevoked(identifiers ...string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range identifiers {
+		if _, ok := l.revoked[id]; ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (l *memoryRevocationList) DeleteExpired(now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newTestManager(t *testing.T) *PasetoManager {
+	t.Helper()
+	p := &PasetoManager{
+		baseKey:               testSecret,
+		expirationTime:        time.Hour,
+		recoverExpirationTime: 5 * time.Minute,
+	}
+	if err := p.SetKeyStore(&memoryKeyStore{}); err != nil {
+		t.Fatal(err)
+	}
+	p.SetRevocationList(&memoryRevocationList{})
+	return p
+}
+
+func TestGenerateAndValidateToken(t *testing.T) {
+	p := newTestManager(t)
+
+	token, _, err := p.GenerateToken(PasetoClaims{
+		Username:      "tech1",
+		CompanyID:     20001,
+		CompanyName:   "Acme",
+		Roles:         "company,liftplay",
+		OwnerUsername: "acme",
+		SessionID:     "session-1",
+		ClientID:      "client-1",
+		Scope:         "openid profile",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, v4PublicHeader) {
+		t.Fatalf("token %s is not v4.public", token)
+	}
+
+	// The header of the request is accepted as is
+	claims, err := p.ValidateToken("Bearer " + token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Username != "tech1" || claims.CompanyID != 20001 || claims.CompanyName != "Acme" ||
+		claims.Roles != "company,liftplay" || claims.OwnerUsername != "acme" || claims.SessionID != "session-1" ||
+		claims.ClientID != "client-1" || claims.Scope != "openid profile" || claims.TokenID == "" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if d := claims.ExpiresAt.Sub(claims.IssuedAt); d < time.Hour-time.Second || d > time.Hour+time.Second {
+		t.Errorf("token lifetime %s, want 1h", d)
+	}
+}
+
+func TestValidateTokenRejects(t *testing.T) {
+	p := newTestManager(t)
+
+	expired, _, err := p.GenerateToken(PasetoClaims{Username: "user", ExpiresAt: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, _, err := p.GenerateToken(PasetoClaims{Username: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Token of another service that holds the same secret but not the key ring
+	foreign, _, err := newTestManager(t).GenerateToken(PasetoClaims{Username: "user", Roles: "company"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", expired},
+		{"signed by another key ring", foreign},
+		{"truncated", valid[:len(valid)-5]},
+		{"legacy v2.local", "v2.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"},
+		{"empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := p.ValidateToken(tt.token); err == nil {
+				t.Error("token accepted")
+			}
+		})
+	}
+
+	// The expiration is only skipped on purpose
+	if _, err := p.ValidateTokenWithoutExpirationCheck(expired); err != nil {
+		t.Errorf("expired token without expiration check: %v", err)
+	}
+}
+
+func TestValidateTokenRevoked(t *testing.T) {
+	p := newTestManager(t)
+
+	token, claims, err := p.GenerateToken(PasetoClaims{Username: "user", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := p.GenerateToken(PasetoClaims{Username: "user", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.RevokeToken(claims); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ValidateToken(token); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("revoked token: got %v, want %v", err, ErrTokenRevoked)
+	}
+	if _, err := p.ValidateToken(other); err != nil {
+		t.Errorf("another token of the session: %v", err)
+	}
+
+	if err := p.RevokeSession("session-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ValidateToken(other); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("token of a revoked session: got %v, want %v", err, ErrTokenRevoked)
+	}
+}
+
+func TestSpecialTokens(t *testing.T) {
+	p := newTestManager(t)
+
+	tests := []struct {
+		name     string
+		generate func(PasetoClaims) (string, *PasetoClaims, error)
+		role     string
+		lifetime time.Duration
+	}{
+		{"recover", p.GenerateRecoverToken, role.RoleRecover, p.recoverExpirationTime},
+		{"mfa pending", func(c PasetoClaims) (string, *PasetoClaims, error) {
+			return p.GenerateMFAPendingToken(c, 2*time.Minute)
+		}, role.RoleMFAPending, 2 * time.Minute},
+		{"password expired", func(c PasetoClaims) (string, *PasetoClaims, error) {
+			return p.GeneratePasswordExpiredToken(c, 3*time.Minute)
+		}, role.RolePasswordExpired, 3 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _, err := tt.generate(PasetoClaims{Username: "user", Roles: "company"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := p.ValidateToken(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The roles of the user are never carried by these tokens
+			if claims.Roles != tt.role {
+				t.Errorf("roles %q, want %q", claims.Roles, tt.role)
+			}
+			// The times of the token have a precision of one second
+			if d := claims.ExpiresAt.Sub(claims.IssuedAt); d < tt.lifetime-time.Second || d > tt.lifetime+time.Second {
+				t.Errorf("lifetime %s, want %s", d, tt.lifetime)
+			}
+		})
+	}
+}
+
+func TestGenerateTokenRequiresUsername(t *testing.T) {
+	if _, _, err := newTestManager(t).GenerateToken(PasetoClaims{}); err == nil {
+		t.Error("token without username generated")
+	}
+}
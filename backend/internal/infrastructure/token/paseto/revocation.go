@@ -0,0 +1,73 @@
+package paseto
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// RevocationList stores the identifiers of the revoked access tokens (jti) and sessions (sid).
+// An entry only has to be kept until the tokens it revokes would have expired anyway.
+type RevocationList interface {
+	Revoke(identifier string, expiresAt time.Time) error
+	IsRevoked(identifiers ...string) (bool, error)
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// SetRevocationList sets the revocation list consulted by ValidateToken.
+// Must be called once at startup, before the tokens are validated.
+func (p *PasetoManager) SetRevocationList(list RevocationList) {
+	p.revocations = list
+}
+
+// RevokeToken revokes one access token until its expiration
+func (p *PasetoManager) RevokeToken(claims *PasetoClaims) error {
+	if claims.TokenID == "" {
+		return errors.New("missing token id in claims")
+	}
+	if p.revocations == nil {
+		return errors.New("revocation list is not configured")
+	}
+	return p.revocations.Revoke(claims.TokenID, claims.ExpiresAt)
+}
+
+// RevokeSession revokes all the access tokens issued for a session.
+// Tokens are issued with the configured expiration time, so the entry is kept that long.
+func (p *PasetoManager) RevokeSession(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	if p.revocations == nil {
+		return errors.New("revocation list is not configured")
+	}
+	return p.revocations.Revoke(sessionID, time.Now().Add(p.expirationTime))
+}
+
+// checkRevoked returns ErrTokenRevoked if the token or its session was revoked
+func (p *PasetoManager) checkRevoked(claims *PasetoClaims) error {
+	if p.revocations == nil {
+		return nil
+	}
+
+	identifiers := make([]string, 0, 2)
+	if claims.TokenID != "" {
+		identifiers = append(identifiers, claims.TokenID)
+	}
+	if claims.SessionID != "" {
+		identifiers = append(identifiers, claims.SessionID)
+	}
+	if len(identifiers) == 0 {
+		return nil
+	}
+
+	revoked, err := p.revocations.IsRevoked(identifiers...)
+	if err != nil {
+		// Fail closed: a token can't be accepted if we can't check it
+		return errors.New("token revocation check error: " + err.Error())
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package paseto
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// Test vectors of the PASETO specification (docs/03-Implementation-Guide/Test-Vectors, v4.json)
+const (
+	vectorSecretKey = "b4cbfb43df4ce210727d953e4a713307fa19bb7d9f85041438d9e11b942a3774" +
+		"1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"
+	vectorPublicKey = "1eb9dbbbbc047c03fd70604e0071f0987e16b28b757225c11f00415d0e20b1a2"
+	vectorPayload   = `{"data":"this is a signed message","exp":"2022-01-01T00:00:00+00:00"}`
+	vectorKeyID     = "zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"
+
+	// 4-S-1: no footer
+	vector4S1 = "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+		"bg_XBBzds8lTZShVlwwKSgeKpLT3yukTw6JUz3W4h_ExsQV-P0V54zemZDcAxFaSeef1QlXEFtkqxT1ciiQEDA"
+	// 4-S-2: footer {"kid":"zVhMiPBP9fRf2snEcT7gFTioeA9COcNy9DfgL1W60haN"}
+	vector4S2 = "v4.public.eyJkYXRhIjoidGhpcyBpcyBhIHNpZ25lZCBtZXNzYWdlIiwiZXhwIjoiMjAyMi0wMS0wMVQwMDowMDowMCswMDowMCJ9" +
+		"v3Jt8mx_TdM2ceTGoqwrh4yDFn0XsHvvV_D0DtwQxVrJEBMl0F2caAdgnpKlt4p7xBnx1HcO-SPo8FPp214HDw" +
+		".eyJraWQiOiJ6VmhNaVBCUDlmUmYyc25FY1Q3Z0ZUaW9lQTlDT2NOeTlEZmdMMVc2MGhhTiJ9"
+)
+
+func vectorKey(t *testing.T) *SigningKey {
+	t.Helper()
+	sk, err := hex.DecodeString(vectorSecretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := hex.DecodeString(vectorPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SigningKey{ID: vectorKeyID, PrivateKey: sk, PublicKey: pk}
+}
+
+func lookupKey(key *SigningKey) func(kid string) (ed25519.PublicKey, bool) {
+	return func(kid string) (ed25519.PublicKey, bool) {
+		if kid != key.ID {
+			return nil, false
+		}
+		return key.PublicKey, true
+	}
+}
+
+func TestPAE(t *testing.T) {
+	// Examples of the Pre-Authentication Encoding in the PASETO specification
+	tests := []struct {
+		name   string
+		pieces [][]byte
+		want   string
+	}{
+		{"no pieces", nil, "0000000000000000"},
+		{"empty piece", [][]byte{{}}, "0100000000000000" + "0000000000000000"},
+		{"one piece", [][]byte{[]byte("test")}, "0100000000000000" + "0400000000000000" + hex.EncodeToString([]byte("test"))},
+		{"two pieces", [][]byte{[]byte("ab"), []byte("c")},
+			"0200000000000000" + "0200000000000000" + "6162" + "0100000000000000" + "63"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hex.EncodeToString(pae(tt.pieces...)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLE64ClearsMostSignificantBit(t *testing.T) {
+	if got := hex.EncodeToString(le64(1<<63 | 1)); got != "0100000000000000" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestSignV4PublicVector(t *testing.T) {
+	key := vectorKey(t)
+
+	token, err := signV4Public(key, []byte(vectorPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ed25519 signatures are deterministic: the token is the one of the specification
+	if token != vector4S2 {
+		t.Errorf("got %s, want test vector 4-S-2", token)
+	}
+}
+
+func TestVerifyV4PublicVector(t *testing.T) {
+	payload, err := verifyV4Public(vector4S2, lookupKey(vectorKey(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != vectorPayload {
+		t.Errorf("got payload %s", payload)
+	}
+
+	// The signature of 4-S-1 is valid, but the tokens without the key ID in the footer are not accepted
+	if _, err := verifyV4Public(vector4S1, lookupKey(vectorKey(t))); !errors.Is(err, errTokenFormat) {
+		t.Errorf("4-S-1: got %v, want %v", err, errTokenFormat)
+	}
+}
+
+func TestVerifyV4PublicRejects(t *testing.T) {
+	key := vectorKey(t)
+
+	otherPublic, otherPrivate, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same key ID, another key: the footer is only used to choose the key
+	forged, err := signV4Public(&SigningKey{ID: vectorKeyID, PrivateKey: otherPrivate, PublicKey: otherPublic}, []byte(vectorPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknownKey, err := signV4Public(&SigningKey{ID: "other", PrivateKey: otherPrivate, PublicKey: otherPublic}, []byte(vectorPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, footer, _ := strings.Cut(strings.TrimPrefix(vector4S2, v4PublicHeader), ".")
+	raw, err := b64.DecodeString(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flip := func(i int) string {
+		b := bytes.Clone(raw)
+		b[i] ^= 0x01
+		return v4PublicHeader + b64.EncodeToString(b) + "." + footer
+	}
+	otherFooter := b64.EncodeToString([]byte(`{"kid":"` + vectorKeyID + `","x":1}`))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"tampered payload", flip(0), errTokenSignature},
+		{"tampered signature", flip(len(raw) - 1), errTokenSignature},
+		{"tampered footer", v4PublicHeader + body + "." + otherFooter, errTokenSignature},
+		{"signed by another key", forged, errTokenSignature},
+		{"unknown key", unknownKey, errUnknownKey},
+		{"local token", strings.Replace(vector4S2, "v4.public.", "v4.local.", 1), errTokenFormat},
+		{"v2 token", strings.Replace(vector4S2, "v4.public.", "v2.public.", 1), errTokenFormat},
+		{"missing footer", v4PublicHeader + body, errTokenFormat},
+		{"extra part", vector4S2 + ".e30", errTokenFormat},
+		{"invalid base64", v4PublicHeader + "!!!." + footer, errTokenFormat},
+		{"body shorter than a signature", v4PublicHeader + b64.EncodeToString(raw[:10]) + "." + footer, errTokenFormat},
+		{"footer without key ID", v4PublicHeader + body + "." + b64.EncodeToString([]byte(`{}`)), errTokenFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := verifyV4Public(tt.token, lookupKey(key)); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"app/internal/infrastructure/token"
 	"app/pkg/config"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"net/http"
 	"time"
 )
@@ -19,22 +21,36 @@ var (
 
 // Generate refresh-token and save it in the database
 func GenerateRefreshToken() (string, string, error) {
+	refreshToken, expiresAt, err := NewRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	return refreshToken, expiresAt.Format("2006-01-02 15:04:05"), nil
+}
+
+// NewRefreshToken generates a refresh-token and its expiration time
+func NewRefreshToken() (string, time.Time, error) {
 	// Generate refreshToken
 	b := make([]byte, 32)
 	_, err := rand.Read(b)
 	if err != nil {
-		return "", "", errTokenGeneration
+		return "", time.Time{}, errTokenGeneration
 	}
 
 	// Codificar en base64
 	refreshToken := base64.URLEncoding.EncodeToString(b)
 	timeExp, err := time.ParseDuration(config.ENV().REFRESH_EXPIRATION_TIME)
 	if err != nil {
-		return "", "", errTokenExpirationTime
+		return "", time.Time{}, errTokenExpirationTime
 	}
-	refreshTokenExp := time.Now().Add(timeExp).Format("2006-01-02 15:04:05")
 
-	return refreshToken, refreshTokenExp, nil
+	return refreshToken, time.Now().Add(timeExp), nil
+}
+
+// HashRefreshToken returns the hash of the refresh-token that is stored in the database
+func HashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
 }
 
 // ValidateRefreshToken comprueba la validez del refreshToken
@@ -1,13 +1,17 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	"app/internal/application"
-	"app/internal/infrastructure/token/paseto"
+	"app/internal/domain/lockout"
+	"app/internal/domain/session"
+	"app/internal/domain/user"
+	"app/internal/infrastructure/transport/http/server/middleware"
 	"app/pkg/errorsLib"
 )
 
@@ -22,6 +26,7 @@ func NewAuthHandler(uc *application.AuthUseCase) *AuthHandler {
 type loginRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	Device   string `json:"device"` // optional name of the device (e.g. "Chrome on Windows")
 }
 
 // POST /login
@@ -32,9 +37,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authUC.Login(req.Login, req.Password)
+	user, err := h.authUC.Login(req.Login, req.Password, clientInfo(c, req.Device))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.JSON(loginStatusCode(c, err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -43,16 +48,104 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Second factor required: no tokens until the code is verified (POST /mfa/verify)
+	if user.MFAToken != "" {
+		respondMFARequired(c, user)
+		return
+	}
+
+	// Expired password: no tokens until it is changed (POST /change-password)
+	if user.PasswordChangeToken != "" {
+		respondPasswordExpired(c, user, nil)
+		return
+	}
+
 	c.Header("Authorization", "Bearer "+user.AccessToken)
-	c.Header("Refresh", *user.Refresh)
+	c.Header("Refresh", user.RefreshToken)
 
 	c.JSON(http.StatusOK, user)
 }
 
+type mfaLoginRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"` // code of the authenticator app or recovery code
+	Device   string `json:"device"`
+}
+
+// POST /mfa/verify — second step of the login: verify the code and open the session
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req mfaLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	user, err := h.authUC.CompleteMFALogin(req.MFAToken, req.Code, clientInfo(c, req.Device))
+	if err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	if user.PasswordChangeToken != "" {
+		respondPasswordExpired(c, user, nil)
+		return
+	}
+
+	c.Header("Authorization", "Bearer "+user.AccessToken)
+	c.Header("Refresh", user.RefreshToken)
+
+	c.JSON(http.StatusOK, user)
+}
+
+type mfaTokenRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+}
+
+// POST /mfa/pending/enroll — set up the authenticator of a user that can't log in without it
+func (h *AuthHandler) BeginPendingMFAEnrollment(c *gin.Context) {
+	var req mfaTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	setup, err := h.authUC.BeginMFAEnrollment(req.MFAToken)
+	if err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, setup)
+}
+
+// POST /mfa/pending/confirm — confirm the authenticator with its first code and open the session
+func (h *AuthHandler) ConfirmPendingMFAEnrollment(c *gin.Context) {
+	var req mfaLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	user, recoveryCodes, err := h.authUC.CompleteMFAEnrollment(req.MFAToken, req.Code, clientInfo(c, req.Device))
+	if err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	if user.PasswordChangeToken != "" {
+		respondPasswordExpired(c, user, recoveryCodes)
+		return
+	}
+
+	c.Header("Authorization", "Bearer "+user.AccessToken)
+	c.Header("Refresh", user.RefreshToken)
+	c.Header("Cache-Control", "no-store")
+
+	c.JSON(http.StatusOK, gin.H{"user": user, "recoveryCodes": recoveryCodes})
+}
+
 func (h *AuthHandler) RefreshPairTokens(c *gin.Context) {
 	refreshTokenReq := c.Query("refresh")
 
-	accessToken, refreshToken, err := h.authUC.RefreshPairTokens(refreshTokenReq)
+	accessToken, refreshToken, err := h.authUC.RefreshPairTokens(refreshTokenReq, clientInfo(c, ""), "")
 	if err != nil {
 		if err.Error() == errorsLib.ErrAccessDenied.Error() {
 			c.JSON(http.StatusLocked, gin.H{"error": err.Error()})
@@ -100,6 +193,7 @@ type resetPasswordRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// POST /reset-password?token= — set a new password with the recovery token of the forgot password email (single use)
 func (h *AuthHandler) ResetPasswordWithTokenRecover(c *gin.Context) {
 
 	var req resetPasswordRequest
@@ -108,22 +202,185 @@ func (h *AuthHandler) ResetPasswordWithTokenRecover(c *gin.Context) {
 		return
 	}
 
-	claims, err := paseto.Paseto().ValidateToken(c.Query("token"))
+	err := h.authUC.ResetPassword(c.Query("token"), req.Password)
+	if err != nil {
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
+		c.JSON(passwordStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password reset successfully"})
+}
+
+// GET /reset-password/validate?token= — check the recovery token before showing the reset password form
+func (h *AuthHandler) ValidateRecoveryToken(c *gin.Context) {
+	info, err := h.authUC.ValidateRecoveryToken(c.Query("token"))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.JSON(passwordStatusCode(err), gin.H{"error": err.Error()})
 		return
 	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, info)
+}
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
 
-	if claims.Roles != "recover" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+// POST /change-password — change the password of the token user (the current password is required).
+// The other sessions are closed. Also accepts the password change token of a login with an expired
+// password: then no session is kept and the user logs in again with the new password.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req changePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 		return
 	}
 
-	err = h.authUC.ResetPassword(claims.Username, req.Password)
+	err := h.authUC.ChangePassword(claims, req.CurrentPassword, req.NewPassword, c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
+		var throttled *lockout.ThrottledError
+		if errors.As(err, &throttled) {
+			c.JSON(loginStatusCode(c, err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(passwordStatusCode(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "password reset successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "password changed successfully"})
+}
+
+// GET /sessions — list the active sessions of the token user
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	sessions, err := h.authUC.ListSessions(claims.Username, claims.SessionID)
+	if err != nil {
+		c.JSON(errorsLib.HTTPStatusCode(err.Error()), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// POST /sessions/revoke?id= — revoke one session of the token user
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	sessionID := c.Query("id")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session id is required"})
+		return
+	}
+
+	if err := h.authUC.RevokeSession(claims.Username, sessionID); err != nil {
+		c.JSON(errorsLib.HTTPStatusCode(err.Error()), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "session revoked successfully"})
+}
+
+// POST /sessions/revoke-all?keepCurrent=true — revoke all the sessions of the token user
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	exceptSessionID := ""
+	if c.Query("keepCurrent") == "true" {
+		exceptSessionID = claims.SessionID
+	}
+
+	if err := h.authUC.RevokeAllSessions(claims.Username, exceptSessionID); err != nil {
+		c.JSON(errorsLib.HTTPStatusCode(err.Error()), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "sessions revoked successfully"})
+}
+
+// POST /logout — close the session of the token (current device)
+func (h *AuthHandler) Logout(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	if err := h.authUC.Logout(claims); err != nil {
+		c.JSON(errorsLib.HTTPStatusCode(err.Error()), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+// POST /logout-all — close all the sessions of the token user (every device)
+func (h *AuthHandler) LogoutEverywhere(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	if err := h.authUC.LogoutEverywhere(claims); err != nil {
+		c.JSON(errorsLib.HTTPStatusCode(err.Error()), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out from all devices successfully"})
+}
+
+// clientInfo collects the information of the client device from the request.
+// The IP address counts the failed logins of the lockout: X-Forwarded-For is only honoured when the
+// request comes from one of the proxies of server.http.trusted_proxies.
+func clientInfo(c *gin.Context, device string) session.ClientInfo {
+	return session.ClientInfo{
+		Device:    device,
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+// loginStatusCode returns the HTTP status code of a failed password login.
+// Attempts rejected by the brute-force protection set the Retry-After header.
+func loginStatusCode(c *gin.Context, err error) int {
+	var throttled *lockout.ThrottledError
+	if !errors.As(err, &throttled) {
+		return http.StatusUnauthorized
+	}
+
+	c.Header("Retry-After", throttled.RetryAfterSeconds())
+	if errors.Is(err, lockout.ErrAccountLocked) {
+		return http.StatusLocked
+	}
+	return http.StatusTooManyRequests
+}
+
+// respondMFARequired responds with the MFA token of a login that needs a second factor
+func respondMFARequired(c *gin.Context, usr *user.User) {
+	c.JSON(http.StatusOK, gin.H{
+		"mfaRequired":        true,
+		"mfaToken":           usr.MFAToken,
+		"enrollmentRequired": usr.MFAEnrollmentRequired,
+	})
+}
+
+// respondPasswordExpired responds with the password change token of a login whose password is expired
+// (and the recovery codes of an enrollment confirmed during that login, shown only once)
+func respondPasswordExpired(c *gin.Context, usr *user.User, recoveryCodes []string) {
+	body := gin.H{
+		"passwordExpired":     true,
+		"passwordChangeToken": usr.PasswordChangeToken,
+	}
+	if recoveryCodes != nil {
+		body["recoveryCodes"] = recoveryCodes
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, body)
+}
+
+// respondPasswordPolicyError responds 400 with the violations when err is a password policy error
+func respondPasswordPolicyError(c *gin.Context, err error) bool {
+	var policyErr *user.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": user.ErrPasswordPolicy.Error(), "violations": policyErr.Violations})
+	return true
 }
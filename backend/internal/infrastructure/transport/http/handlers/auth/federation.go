@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"app/internal/application"
+	"app/internal/domain/federation"
+	"app/pkg/errorsLib"
+)
+
+// FederationHandler - HTTP handler for the logins with external providers ("Sign in with ...")
+type FederationHandler struct {
+	federationUC *application.FederationUseCase
+}
+
+func NewFederationHandler(uc *application.FederationUseCase) *FederationHandler {
+	return &FederationHandler{federationUC: uc}
+}
+
+// POST /oidc/:provider/start — URL of the provider where the user signs in, and the flow ID
+// to send back with the callback (keep it in the browser until then)
+func (h *FederationHandler) Start(c *gin.Context) {
+	login, err := h.federationUC.Start(c.Param("provider"))
+	if err != nil {
+		c.JSON(federationStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, login)
+}
+
+type federationCallbackRequest struct {
+	FlowID string `json:"flowId" binding:"required"`
+	State  string `json:"state" binding:"required"` // query parameters of the redirect of the provider
+	Code   string `json:"code" binding:"required"`
+	Device string `json:"device"`
+}
+
+// POST /oidc/:provider/callback — finish the login with the code of the provider.
+// Opens the session like POST /login.
+func (h *FederationHandler) Callback(c *gin.Context) {
+	var req federationCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	user, err := h.federationUC.Finish(c.Param("provider"), req.FlowID, req.State, req.Code, clientInfo(c, req.Device))
+	if err != nil {
+		c.JSON(federationStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Second factor required: no tokens until the code is verified (POST /mfa/verify)
+	if user.MFAToken != "" {
+		respondMFARequired(c, user)
+		return
+	}
+
+	c.Header("Authorization", "Bearer "+user.AccessToken)
+	c.Header("Refresh", user.RefreshToken)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// federationStatusCode returns the HTTP status code of the errors of the federated logins
+func federationStatusCode(err error) int {
+	switch {
+	case errors.Is(err, federation.ErrNotFederated):
+		return http.StatusNotFound
+	case errors.Is(err, federation.ErrFlowNotFound), errors.Is(err, federation.ErrLoginRejected),
+		errors.Is(err, federation.ErrMissingIdentity):
+		return http.StatusUnauthorized
+	case errors.Is(err, federation.ErrIdentityInUse):
+		return http.StatusConflict
+	default:
+		return errorsLib.HTTPStatusCode(err.Error())
+	}
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"app/internal/application"
+	"app/internal/domain/lockout"
+	"app/internal/domain/password"
+	"app/internal/domain/provider"
+	"app/internal/domain/user_identity"
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"app/pkg/errorsLib"
+)
+
+// IdentityHandler - HTTP handler for the identities of other providers linked to the users
+type IdentityHandler struct {
+	identityUC *application.UserIdentityUseCase
+}
+
+func NewIdentityHandler(uc *application.UserIdentityUseCase) *IdentityHandler {
+	return &IdentityHandler{identityUC: uc}
+}
+
+// GET /identities — identities linked to the token user
+func (h *IdentityHandler) List(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	identities, err := h.identityUC.List(claims.Username)
+	if err != nil {
+		c.JSON(identityStatusCode(c, err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, identities)
+}
+
+type linkIdentityRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	Provider        string `json:"provider" binding:"required"`
+	Login           string `json:"login" binding:"required"`
+	Password        string `json:"password" binding:"required"`
+	Merge           bool   `json:"merge"` // the login has a user of its own: remove it and link its login
+}
+
+// POST /identities/link — link the login of another provider to the token user
+// (current password of the user and credentials of the login at the provider).
+// "merge": true merges the user of the login into the token user (see UserIdentityUseCase.Link)
+func (h *IdentityHandler) Link(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req linkIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	identity, err := h.identityUC.Link(claims.Username, req.CurrentPassword, req.Provider, req.Login, req.Password, c.ClientIP(), req.Merge)
+	if err != nil {
+		c.JSON(identityStatusCode(c, err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, identity)
+}
+
+type unlinkIdentityRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+}
+
+// POST /identities/unlink?id= — remove an identity linked to the token user (current password of the user)
+func (h *IdentityHandler) Unlink(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req unlinkIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	if err := h.identityUC.Unlink(claims.Username, req.CurrentPassword, uint(id), c.ClientIP()); err != nil {
+		c.JSON(identityStatusCode(c, err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "identity unlinked successfully"})
+}
+
+// identityStatusCode returns the HTTP status code of the errors of the linked identities
+// (the Retry-After header is set for throttled attempts)
+func identityStatusCode(c *gin.Context, err error) int {
+	var throttled *lockout.ThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		return loginStatusCode(c, err)
+	case errors.Is(err, user_identity.ErrIdentityNotFound), errors.Is(err, provider.ErrProviderNotRegistered):
+		return http.StatusNotFound
+	case errors.Is(err, user_identity.ErrIdentityLinked), errors.Is(err, user_identity.ErrIdentityInUse),
+		errors.Is(err, user_identity.ErrPrimaryIdentity):
+		return http.StatusConflict
+	case errors.Is(err, user_identity.ErrNotLinkable):
+		return http.StatusBadRequest
+	case errors.Is(err, password.ErrWrongPassword), errors.Is(err, provider.ErrInvalidCredentials),
+		errors.Is(err, provider.ErrUnknownUser):
+		return http.StatusUnauthorized
+	default:
+		return errorsLib.HTTPStatusCode(err.Error())
+	}
+}
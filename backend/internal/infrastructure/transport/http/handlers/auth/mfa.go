@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"app/internal/application"
+	"app/internal/domain/mfa"
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"app/pkg/errorsLib"
+)
+
+// MFAHandler - HTTP handler for the two-factor authentication settings of the token user
+type MFAHandler struct {
+	mfaUC *application.MFAUseCase
+}
+
+func NewMFAHandler(uc *application.MFAUseCase) *MFAHandler {
+	return &MFAHandler{mfaUC: uc}
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// GET /mfa/status — two-factor authentication state of the token user
+func (h *MFAHandler) Status(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	status, err := h.mfaUC.Status(claims.Username)
+	if err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// POST /mfa/enroll — generate the secret of a new authenticator (enabled once confirmed)
+func (h *MFAHandler) BeginEnrollment(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	setup, err := h.mfaUC.BeginEnrollment(claims.Username)
+	if err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, setup)
+}
+
+// POST /mfa/enroll/confirm — enable the authenticator with its first code, returns the recovery codes
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	recoveryCodes, err := h.mfaUC.ConfirmEnrollment(claims.Username, req.Code)
+	if err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": recoveryCodes})
+}
+
+// POST /mfa/disable — remove the authenticator (a code or a recovery code is required)
+func (h *MFAHandler) Disable(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req struct {
+		Code string `json:"code"` // not needed for a not confirmed enrollment
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	if err := h.mfaUC.Disable(claims.Username, req.Code); err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled successfully"})
+}
+
+// POST /mfa/recovery-codes — replace the recovery codes (a code of the authenticator is required)
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req mfaCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	recoveryCodes, err := h.mfaUC.RegenerateRecoveryCodes(claims.Username, req.Code)
+	if err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": recoveryCodes})
+}
+
+// GET /mfa/policy — two-factor authentication policy of the company
+func (h *MFAHandler) GetPolicy(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	policy, err := h.mfaUC.GetPolicy(claims.MainUsername())
+	if err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+type mfaPolicyRequest struct {
+	RequireForSubusers *bool `json:"requireForSubusers" binding:"required"`
+}
+
+// POST /mfa/policy — require (or not) two-factor authentication for all the subusers of the company
+func (h *MFAHandler) SetPolicy(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req mfaPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	policy, err := h.mfaUC.SetRequiredForSubusers(claims.MainUsername(), *req.RequireForSubusers)
+	if err != nil {
+		c.JSON(mfaStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// mfaStatusCode returns the HTTP status code of the errors of the two-factor authentication
+func mfaStatusCode(err error) int {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return http.StatusUnauthorized
+	case errors.Is(err, mfa.ErrTooManyAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, mfa.ErrAlreadyEnrolled), errors.Is(err, mfa.ErrNotEnrolled):
+		return http.StatusConflict
+	case errors.Is(err, mfa.ErrRequiredByOwner), errors.Is(err, mfa.ErrEnrollmentPending):
+		return http.StatusForbidden
+	default:
+		return errorsLib.HTTPStatusCode(err.Error())
+	}
+}
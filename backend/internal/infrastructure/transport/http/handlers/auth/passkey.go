@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"app/internal/application"
+	"app/internal/domain/passkey"
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"app/pkg/errorsLib"
+)
+
+// PasskeyHandler - HTTP handler for the passkeys (WebAuthn) of the local users
+type PasskeyHandler struct {
+	passkeyUC *application.PasskeyUseCase
+}
+
+func NewPasskeyHandler(uc *application.PasskeyUseCase) *PasskeyHandler {
+	return &PasskeyHandler{passkeyUC: uc}
+}
+
+// GET /passkeys — passkeys of the token user
+func (h *PasskeyHandler) List(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	credentials, err := h.passkeyUC.List(claims.Username)
+	if err != nil {
+		c.JSON(passkeyStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, credentials)
+}
+
+// POST /passkeys/delete?id= — remove a passkey of the token user
+func (h *PasskeyHandler) Delete(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	id, err := strconv.ParseUint(c.Query("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.passkeyUC.Delete(claims.Username, uint(id)); err != nil {
+		c.JSON(passkeyStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "passkey deleted successfully"})
+}
+
+// POST /passkeys/register/begin — options for navigator.credentials.create()
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	ceremony, err := h.passkeyUC.BeginRegistration(claims.Username)
+	if err != nil {
+		c.JSON(passkeyStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, ceremony)
+}
+
+// POST /passkeys/register/finish?ceremonyId=&name= — body: the credential created by the authenticator
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	credential, err := h.passkeyUC.FinishRegistration(claims.Username, c.Query("ceremonyId"), c.Query("name"), c.Request.Body)
+	if err != nil {
+		c.JSON(passkeyStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, credential)
+}
+
+// POST /passkeys/login/begin — options for navigator.credentials.get()
+func (h *PasskeyHandler) BeginLogin(c *gin.Context) {
+	ceremony, err := h.passkeyUC.BeginLogin()
+	if err != nil {
+		c.JSON(passkeyStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, ceremony)
+}
+
+// POST /passkeys/login/finish?ceremonyId=&device= — body: the assertion of the authenticator.
+// Opens the session like POST /login.
+func (h *PasskeyHandler) FinishLogin(c *gin.Context) {
+	user, err := h.passkeyUC.FinishLogin(c.Query("ceremonyId"), c.Request.Body, clientInfo(c, c.Query("device")))
+	if err != nil {
+		c.JSON(passkeyStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Authorization", "Bearer "+user.AccessToken)
+	c.Header("Refresh", user.RefreshToken)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// passkeyStatusCode returns the HTTP status code of the errors of the passkeys
+func passkeyStatusCode(err error) int {
+	switch {
+	case errors.Is(err, passkey.ErrInvalidCredential), errors.Is(err, passkey.ErrClonedAuthenticator),
+		errors.Is(err, passkey.ErrCeremonyNotFound):
+		return http.StatusUnauthorized
+	case errors.Is(err, passkey.ErrProviderNotAllowed):
+		return http.StatusForbidden
+	default:
+		return errorsLib.HTTPStatusCode(err.Error())
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"app/internal/application"
+	"app/internal/domain/password"
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"app/pkg/errorsLib"
+)
+
+// PasswordHandler - HTTP handler for the password expiry of the company
+type PasswordHandler struct {
+	passwordUC *application.PasswordUseCase
+}
+
+func NewPasswordHandler(uc *application.PasswordUseCase) *PasswordHandler {
+	return &PasswordHandler{passwordUC: uc}
+}
+
+// GET /password/policy — password expiry of the company
+func (h *PasswordHandler) GetPolicy(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	policy, err := h.passwordUC.GetPolicy(claims.MainUsername())
+	if err != nil {
+		c.JSON(passwordStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+type passwordPolicyRequest struct {
+	MaxAgeDays *int `json:"maxAgeDays" binding:"required"`
+}
+
+// POST /password/policy — max age (in days) of the passwords of the company users (0: they don't expire)
+func (h *PasswordHandler) SetPolicy(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req passwordPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	policy, err := h.passwordUC.SetMaxAge(claims.MainUsername(), *req.MaxAgeDays)
+	if err != nil {
+		c.JSON(passwordStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// passwordStatusCode returns the HTTP status code of the errors of the password changes and expiry
+func passwordStatusCode(err error) int {
+	switch {
+	case errors.Is(err, password.ErrWrongPassword), errors.Is(err, password.ErrInvalidRecoveryToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, password.ErrInvalidMaxAge):
+		return http.StatusBadRequest
+	case errors.Is(err, password.ErrNotLocalUser), errors.Is(err, password.ErrExpired):
+		return http.StatusForbidden
+	default:
+		return errorsLib.HTTPStatusCode(err.Error())
+	}
+}
@@ -1,18 +1,50 @@
 package auth
 
 import (
+	"log"
+
 	"app/internal/application"
+	"app/internal/domain/role"
 	"app/internal/infrastructure/repositories"
-	"app/internal/infrastructure/webhooks/verificaciones"
+	"app/internal/infrastructure/transport/http/server/middleware"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 )
 
-func Routes(router *gin.Engine) {
-	handler := NewAuthHandler(
-		application.NewAuthUseCase(repositories.NewUserRepository(),
-			verificaciones.NewVerificacionesClient(),
-			repositories.NewRoleRepository()))
+func Routes(router *gin.Engine, authUseCase *application.AuthUseCase, mfaUseCase *application.MFAUseCase,
+	passwordUseCase *application.PasswordUseCase) {
+	passkeyUseCase, err := application.NewPasskeyUseCase(repositories.NewPasskeyRepository(), repositories.NewUserRepository(), authUseCase,
+		application.PasskeyConfig{
+			RPID:            viper.GetString("webauthn.rp_id"),
+			RPDisplayName:   viper.GetString("webauthn.rp_display_name"),
+			RPOrigins:       viper.GetStringSlice("webauthn.rp_origins"),
+			CeremonyTimeout: viper.GetDuration("webauthn.ceremony_timeout"),
+		})
+	if err != nil {
+		log.Fatalf("Invalid webauthn config: %v", err)
+	}
+
+	federationUseCase := application.NewFederationUseCase(repositories.NewFederationRepository(), authUseCase)
+	federationUseCase.SetFlowTimeout(viper.GetDuration("oidc_federation.flow_timeout"))
+
+	samlUseCase := application.NewSAMLUseCase(repositories.NewSAMLRepository(),
+		repositories.NewUserRepository(),
+		repositories.NewInternalCompanyRepository(),
+		authUseCase)
+	samlUseCase.SetFlowTimeout(viper.GetDuration("saml.flow_timeout"))
+
+	identityUseCase := application.NewUserIdentityUseCase(repositories.NewUserIdentityRepository(),
+		repositories.NewUserRepository(),
+		authUseCase)
+
+	handler := NewAuthHandler(authUseCase)
+	mfaHandler := NewMFAHandler(mfaUseCase)
+	passkeyHandler := NewPasskeyHandler(passkeyUseCase)
+	passwordHandler := NewPasswordHandler(passwordUseCase)
+	federationHandler := NewFederationHandler(federationUseCase)
+	samlHandler := NewSAMLHandler(samlUseCase, viper.GetString("saml.login_redirect_url"))
+	identityHandler := NewIdentityHandler(identityUseCase)
 
 	// Routes
 	group := router.Group("/auth")
@@ -21,12 +53,85 @@ func Routes(router *gin.Engine) {
 		// group.GET("/all", handler.GetAllProviders)
 
 		// Get user by ID
-		group.POST("/login", handler.Login)
+		group.POST("/login", middleware.RateLimit("login"), handler.Login)
 		group.POST("/refresh", handler.RefreshPairTokens)
 
+		// Logout (current device) && logout everywhere
+		logout := group.Group("", middleware.Protected()...)
+		logout.POST("/logout", handler.Logout)
+		logout.POST("/logout-all", handler.LogoutEverywhere)
+
 		// Forgot password && reset password
-		group.POST("/forgot-password", handler.ForgotPassword)
+		group.POST("/forgot-password", middleware.RateLimit("forgot_password"), handler.ForgotPassword)
 		group.POST("/reset-password", handler.ResetPasswordWithTokenRecover)
+		group.GET("/reset-password/validate", handler.ValidateRecoveryToken)
+
+		// Change password of the token user (or of a login with an expired password)
+		group.POST("/change-password", middleware.AuthenticatePasswordChange(), middleware.RateLimit("login"), handler.ChangePassword)
+
+		// Password expiry of the company (permission passwords:manage)
+		passwordPolicy := group.Group("/password/policy", middleware.ProtectedWithPermissions(role.PermissionPasswordManage)...)
+		passwordPolicy.GET("", passwordHandler.GetPolicy)
+		passwordPolicy.POST("", passwordHandler.SetPolicy)
+
+		// Sessions of the token user (one per device)
+		sessions := group.Group("/sessions", middleware.Protected()...)
+		sessions.GET("", handler.ListSessions)                  // List my sessions
+		sessions.POST("/revoke", handler.RevokeSession)         // Revoke one of my sessions
+		sessions.POST("/revoke-all", handler.RevokeAllSessions) // Revoke all my sessions
+
+		// Second step of a login with two-factor authentication (MFA token of the login)
+		mfa := group.Group("/mfa")
+		mfa.POST("/verify", middleware.RateLimit("login"), handler.VerifyMFA) // Verify the code and open the session
+		mfa.POST("/pending/enroll", handler.BeginPendingMFAEnrollment)        // Set up the authenticator (required by the owner)
+		mfa.POST("/pending/confirm", handler.ConfirmPendingMFAEnrollment)     // Confirm it and open the session
+
+		// Two-factor authentication of the token user
+		mfaSettings := mfa.Group("", middleware.Protected()...)
+		mfaSettings.GET("/status", mfaHandler.Status)
+		mfaSettings.POST("/enroll", mfaHandler.BeginEnrollment)
+		mfaSettings.POST("/enroll/confirm", mfaHandler.ConfirmEnrollment)
+		mfaSettings.POST("/disable", mfaHandler.Disable)
+		mfaSettings.POST("/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+
+		// Two-factor authentication policy of the company (permission mfa:manage)
+		mfaPolicy := mfa.Group("/policy", middleware.ProtectedWithPermissions(role.PermissionMFAManage)...)
+		mfaPolicy.GET("", mfaHandler.GetPolicy)
+		mfaPolicy.POST("", mfaHandler.SetPolicy)
+
+		// Login with a passkey (alternative to the password of the local users)
+		passkeys := group.Group("/passkeys")
+		passkeys.POST("/login/begin", passkeyHandler.BeginLogin)
+		passkeys.POST("/login/finish", middleware.RateLimit("login"), passkeyHandler.FinishLogin)
+
+		// Passkeys of the token user
+		passkeySettings := passkeys.Group("", middleware.Protected()...)
+		passkeySettings.GET("", passkeyHandler.List)
+		passkeySettings.POST("/delete", passkeyHandler.Delete)
+		passkeySettings.POST("/register/begin", passkeyHandler.BeginRegistration)
+		passkeySettings.POST("/register/finish", passkeyHandler.FinishRegistration)
+
+		// Login with an external OpenID Connect provider of a company ("Sign in with ...")
+		oidcLogin := group.Group("/oidc/:provider")
+		oidcLogin.POST("/start", federationHandler.Start)
+		oidcLogin.POST("/callback", middleware.RateLimit("login"), federationHandler.Callback)
+
+		// Login with the SAML 2.0 identity provider of a company (SP-initiated SSO)
+		samlLogin := group.Group("/saml/:companyId")
+		samlLogin.GET("/metadata", samlHandler.Metadata)
+		samlLogin.POST("/start", samlHandler.Start)
+		samlLogin.POST("/acs", middleware.RateLimit("login"), samlHandler.ACS)
+		samlLogin.POST("/complete", middleware.RateLimit("login"), samlHandler.Complete)
+
+		// SAML identity provider of the company (permission saml:manage)
+		samlConnection := group.Group("/saml/connection", middleware.ProtectedWithPermissions(role.PermissionSAMLManage)...)
+		samlConnection.GET("", samlHandler.GetConnection)
+		samlConnection.POST("", samlHandler.SaveConnection)
 
+		// Logins of other providers linked to the token user (it can sign in with any of them)
+		identities := group.Group("/identities", middleware.Protected()...)
+		identities.GET("", identityHandler.List)
+		identities.POST("/link", middleware.RateLimit("login"), identityHandler.Link)
+		identities.POST("/unlink", middleware.RateLimit("login"), identityHandler.Unlink)
 	}
 }
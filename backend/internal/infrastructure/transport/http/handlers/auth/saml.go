@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"app/internal/application"
+	"app/internal/domain/federation"
+	"app/internal/domain/saml"
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"app/pkg/errorsLib"
+	"app/pkg/logger"
+)
+
+// SAMLHandler - HTTP handler for the SAML 2.0 logins of the companies
+type SAMLHandler struct {
+	samlUC *application.SAMLUseCase
+	// Page of the frontend where the browser is sent back after the response of the identity provider
+	loginRedirectURL string
+}
+
+func NewSAMLHandler(uc *application.SAMLUseCase, loginRedirectURL string) *SAMLHandler {
+	return &SAMLHandler{samlUC: uc, loginRedirectURL: loginRedirectURL}
+}
+
+// GET /saml/:companyId/metadata — metadata of the service provider of the company (XML)
+func (h *SAMLHandler) Metadata(c *gin.Context) {
+	companyID, ok := samlCompanyID(c)
+	if !ok {
+		return
+	}
+	metadata, err := h.samlUC.Metadata(companyID)
+	if err != nil {
+		c.JSON(samlStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// POST /saml/:companyId/start — URL of the identity provider of the company where the user signs in,
+// and the flow ID to open the session once it comes back (keep it in the browser until then)
+func (h *SAMLHandler) Start(c *gin.Context) {
+	companyID, ok := samlCompanyID(c)
+	if !ok {
+		return
+	}
+	login, err := h.samlUC.Start(companyID)
+	if err != nil {
+		c.JSON(samlStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, login)
+}
+
+// POST /saml/:companyId/acs — assertion consumer service (HTTP-POST binding): the browser posts the response
+// of the identity provider and is sent back to the login page of the frontend (?error= if it was rejected)
+func (h *SAMLHandler) ACS(c *gin.Context) {
+	companyID, ok := samlCompanyID(c)
+	if !ok {
+		return
+	}
+
+	err := h.samlUC.Assert(companyID, c.PostForm("RelayState"), c.PostForm("SAMLResponse"))
+	if h.loginRedirectURL == "" {
+		if err != nil {
+			c.JSON(samlStatusCode(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	redirectURL, parseErr := url.Parse(h.loginRedirectURL)
+	if parseErr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid login redirect url"})
+		return
+	}
+	query := redirectURL.Query()
+	query.Set("companyId", strconv.FormatUint(uint64(companyID), 10))
+	if err != nil {
+		code := samlErrorCode(err)
+		if code == "server_error" {
+			logger.GetLogger().ServiceError("SAML assertion consumer service error", map[string]interface{}{
+				"companyId": companyID,
+				"error":     err.Error(),
+			})
+		}
+		query.Set("error", code)
+	}
+	redirectURL.RawQuery = query.Encode()
+	c.Redirect(http.StatusSeeOther, redirectURL.String())
+}
+
+type samlCompleteRequest struct {
+	FlowID string `json:"flowId" binding:"required"`
+	Device string `json:"device"`
+}
+
+// POST /saml/:companyId/complete — open the session of a login accepted by the assertion consumer service.
+// Opens the session like POST /login.
+func (h *SAMLHandler) Complete(c *gin.Context) {
+	companyID, ok := samlCompanyID(c)
+	if !ok {
+		return
+	}
+	var req samlCompleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	user, err := h.samlUC.Complete(companyID, req.FlowID, clientInfo(c, req.Device))
+	if err != nil {
+		c.JSON(samlStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Second factor required: no tokens until the code is verified (POST /mfa/verify)
+	if user.MFAToken != "" {
+		respondMFARequired(c, user)
+		return
+	}
+
+	c.Header("Authorization", "Bearer "+user.AccessToken)
+	c.Header("Refresh", user.RefreshToken)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GET /saml/connection — SAML identity provider of the company
+func (h *SAMLHandler) GetConnection(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	conn, err := h.samlUC.GetConnection(claims.MainUsername())
+	if err != nil {
+		c.JSON(samlStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, conn)
+}
+
+type samlConnectionRequest struct {
+	Enabled        bool               `json:"enabled"`
+	IdPEntityID    string             `json:"idpEntityId" binding:"required"`
+	IdPSSOURL      string             `json:"idpSsoUrl" binding:"required"`
+	IdPCertificate string             `json:"idpCertificate" binding:"required"`
+	LoginSuffix    string             `json:"loginSuffix"`
+	Attributes     saml.Attributes    `json:"attributes"`
+	RoleMapping    []saml.RoleMapping `json:"roleMapping"`
+}
+
+// POST /saml/connection — set the SAML identity provider of the company
+func (h *SAMLHandler) SaveConnection(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req samlConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		return
+	}
+
+	conn, err := h.samlUC.SaveConnection(claims.MainUsername(), &saml.Connection{
+		Enabled:        req.Enabled,
+		IdPEntityID:    req.IdPEntityID,
+		IdPSSOURL:      req.IdPSSOURL,
+		IdPCertificate: req.IdPCertificate,
+		LoginSuffix:    req.LoginSuffix,
+		Attributes:     req.Attributes,
+		RoleMapping:    req.RoleMapping,
+	})
+	if err != nil {
+		c.JSON(samlStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, conn)
+}
+
+// samlCompanyID returns the company of the URL (responds 404 if it is not a valid ID)
+func samlCompanyID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("companyId"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": saml.ErrConnectionNotFound.Error()})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// samlStatusCode returns the HTTP status code of the errors of the SAML logins and connections
+func samlStatusCode(err error) int {
+	switch {
+	case errors.Is(err, federation.ErrNotFederated), errors.Is(err, saml.ErrConnectionNotFound),
+		errors.Is(err, saml.ErrConnectionDisabled):
+		return http.StatusNotFound
+	case errors.Is(err, saml.ErrInvalidConnection):
+		return http.StatusBadRequest
+	case errors.Is(err, saml.ErrNotInternalCompany):
+		return http.StatusForbidden
+	case errors.Is(err, saml.ErrFlowNotFound), errors.Is(err, saml.ErrFlowNotCompleted),
+		errors.Is(err, federation.ErrLoginRejected), errors.Is(err, federation.ErrMissingIdentity):
+		return http.StatusUnauthorized
+	case errors.Is(err, federation.ErrIdentityInUse):
+		return http.StatusConflict
+	default:
+		return errorsLib.HTTPStatusCode(err.Error())
+	}
+}
+
+// samlErrorCode returns the error code sent to the login page of the frontend when a response is rejected
+func samlErrorCode(err error) string {
+	switch {
+	case errors.Is(err, federation.ErrNotFederated), errors.Is(err, saml.ErrConnectionNotFound),
+		errors.Is(err, saml.ErrConnectionDisabled):
+		return "not_available"
+	case errors.Is(err, saml.ErrFlowNotFound):
+		return "login_expired"
+	case errors.Is(err, federation.ErrLoginRejected), errors.Is(err, federation.ErrMissingIdentity):
+		return "login_rejected"
+	case errors.Is(err, federation.ErrIdentityInUse):
+		return "identity_in_use"
+	default:
+		return "server_error"
+	}
+}
@@ -0,0 +1,92 @@
+package clients
+
+import (
+	"app/internal/application"
+	"app/internal/domain/client"
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"app/pkg/errorsLib"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClientHandler - HTTP handler for the OAuth clients of the company
+type ClientHandler struct {
+	clientUC *application.ClientUseCase
+}
+
+// NewClientHandler - constructor for client handler
+func NewClientHandler(clientUC *application.ClientUseCase) *ClientHandler {
+	return &ClientHandler{clientUC: clientUC}
+}
+
+// GetClients - handler for getting the clients of the company of the token owner
+func (h *ClientHandler) GetClients(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	clients, err := h.clientUC.GetClientsByCompany(claims.MainUsername())
+	if err != nil {
+		c.JSON(clientStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, clients)
+}
+
+// CreateClient - handler for registering a client of the token company.
+// The secret is only returned in this response.
+func (h *ClientHandler) CreateClient(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req struct {
+		Name         string   `json:"name" binding:"required"`
+		RedirectURIs []string `json:"redirectUris"`
+		Scopes       []string `json:"scopes"`
+		Public       bool     `json:"public"` // public clients (SPA, mobile) have no secret
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	newClient, secret, err := h.clientUC.CreateClient(claims.MainUsername(), req.Name, req.RedirectURIs, req.Scopes, req.Public)
+	if err != nil {
+		if errors.Is(err, client.ErrInvalidRedirectURI) || errors.Is(err, client.ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(clientStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"client": newClient}
+	if !newClient.Public {
+		response["clientSecret"] = secret
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// DeleteClient - handler for deleting a client of the token company (?clientId=)
+func (h *ClientHandler) DeleteClient(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	clientID := c.Query("clientId")
+	if clientID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "clientId is required"})
+		return
+	}
+
+	if err := h.clientUC.DeleteClient(claims.MainUsername(), clientID); err != nil {
+		c.JSON(clientStatusCode(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "client deleted successfully"})
+}
+
+// clientStatusCode returns the HTTP status code of the errors of the client management
+func clientStatusCode(err error) int {
+	if errors.Is(err, client.ErrNotInternalCompany) {
+		return http.StatusForbidden
+	}
+	return errorsLib.HTTPStatusCode(err.Error())
+}
@@ -0,0 +1,26 @@
+package clients
+
+import (
+	"app/internal/application"
+	"app/internal/domain/role"
+	"app/internal/infrastructure/repositories"
+	"app/internal/infrastructure/transport/http/server/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Routes(router *gin.Engine) {
+
+	handler := NewClientHandler(application.NewClientUseCase(
+		repositories.NewClientRepository(),
+		repositories.NewUserRepository(),
+		repositories.NewInternalCompanyRepository()))
+
+	// Routes (permission clients:manage), only for the owners of internal companies and scoped to their company
+	group := router.Group("/clients", middleware.ProtectedWithPermissions(role.PermissionClientsManage)...)
+	{
+		group.GET("/all", handler.GetClients)       // Get clients of my company
+		group.POST("/create", handler.CreateClient) // Register client (returns its secret once)
+		group.POST("/delete", handler.DeleteClient) // Delete client
+	}
+}
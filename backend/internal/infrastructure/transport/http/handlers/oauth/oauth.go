@@ -0,0 +1,350 @@
+package oauth
+
+import (
+	"app/internal/application"
+	"app/internal/domain/client"
+	"app/internal/domain/lockout"
+	"app/internal/domain/mfa"
+	"app/internal/domain/oauth"
+	"app/internal/domain/password"
+	"app/internal/domain/session"
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"app/pkg/errorsLib"
+	"app/pkg/logger"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthHandler - HTTP handler for the OAuth 2.0 authorization server and its OpenID Connect layer
+type OAuthHandler struct {
+	oauthUC *application.OAuthUseCase
+	oidcUC  *application.OIDCUseCase
+	issuer  string // configured issuer, never derived from the request (Host and X-Forwarded-* are client input)
+}
+
+// NewOAuthHandler - constructor for OAuth handler
+func NewOAuthHandler(oauthUC *application.OAuthUseCase, oidcUC *application.OIDCUseCase, issuer string) *OAuthHandler {
+	return &OAuthHandler{
+		oauthUC: oauthUC,
+		oidcUC:  oidcUC,
+		issuer:  strings.TrimSuffix(issuer, "/"),
+	}
+}
+
+// authorizeForm — parameters of the authorization request (query of GET, form of POST)
+type authorizeForm struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"`
+}
+
+func (f authorizeForm) toRequest() application.AuthorizeRequest {
+	return application.AuthorizeRequest{
+		ResponseType:        f.ResponseType,
+		ClientID:            f.ClientID,
+		RedirectURI:         f.RedirectURI,
+		Scope:               f.Scope,
+		State:               f.State,
+		CodeChallenge:       f.CodeChallenge,
+		CodeChallengeMethod: f.CodeChallengeMethod,
+		Nonce:               f.Nonce,
+	}
+}
+
+// GET /authorize — show the login/consent page
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var form authorizeForm
+	if err := c.ShouldBindQuery(&form); err != nil {
+		h.renderPage(c, http.StatusBadRequest, authorizePageData{Error: "invalid request"})
+		return
+	}
+
+	cl, scopes, ok := h.validateAuthorize(c, form)
+	if !ok {
+		return
+	}
+
+	h.renderPage(c, http.StatusOK, authorizePageData{
+		ClientName: cl.Name,
+		Scopes:     scopes,
+		Request:    form,
+	})
+}
+
+// POST /authorize — approve (with the user credentials) or deny the request
+func (h *OAuthHandler) AuthorizeDecision(c *gin.Context) {
+	var form authorizeForm
+	if err := c.ShouldBind(&form); err != nil {
+		h.renderPage(c, http.StatusBadRequest, authorizePageData{Error: "invalid request"})
+		return
+	}
+
+	cl, scopes, ok := h.validateAuthorize(c, form)
+	if !ok {
+		return
+	}
+
+	if c.PostForm("decision") != "approve" {
+		redirectWithParams(c, form.RedirectURI, url.Values{
+			"error": {oauth.ErrCodeAccessDenied},
+			"state": {form.State},
+		})
+		return
+	}
+
+	// Second step of a login with two-factor authentication
+	if mfaToken := c.PostForm("mfa_token"); mfaToken != "" {
+		h.authorizeMFA(c, cl, scopes, form, mfaToken)
+		return
+	}
+
+	username := c.PostForm("username")
+	code, mfaToken, err := h.oauthUC.Authorize(cl, form.toRequest(), scopes, username, c.PostForm("password"), c.ClientIP())
+	if err != nil {
+		logger.GetLogger().ServiceWarn("OAuth authorization failed", map[string]interface{}{
+			"clientId": cl.ClientID,
+			"username": username,
+			"error":    err.Error(),
+		})
+		status, message := http.StatusUnauthorized, "Invalid username or password"
+		var throttled *lockout.ThrottledError
+		switch {
+		case errors.Is(err, mfa.ErrEnrollmentPending):
+			message = "Two-factor authentication has to be set up before signing in to applications"
+		case errors.Is(err, password.ErrExpired):
+			status, message = http.StatusForbidden, "Your password has expired, change it by signing in to your account before signing in to applications"
+		case errors.As(err, &throttled):
+			status, message = http.StatusTooManyRequests, "Too many failed attempts, try again later"
+			c.Header("Retry-After", throttled.RetryAfterSeconds())
+		}
+		h.renderPage(c, status, authorizePageData{
+			Error:      message,
+			ClientName: cl.Name,
+			Scopes:     scopes,
+			Request:    form,
+			Username:   username,
+		})
+		return
+	}
+
+	// The user has to enter the code of its authenticator app
+	if mfaToken != "" {
+		h.renderPage(c, http.StatusOK, authorizePageData{
+			ClientName: cl.Name,
+			Scopes:     scopes,
+			Request:    form,
+			MFAToken:   mfaToken,
+		})
+		return
+	}
+
+	redirectWithParams(c, form.RedirectURI, url.Values{
+		"code":  {code},
+		"state": {form.State},
+	})
+}
+
+// authorizeMFA verifies the code of the authenticator app (or a recovery code) and redirects with the authorization code
+func (h *OAuthHandler) authorizeMFA(c *gin.Context, cl *client.Client, scopes []string, form authorizeForm, mfaToken string) {
+	code, err := h.oauthUC.AuthorizeMFA(cl, form.toRequest(), scopes, mfaToken, c.PostForm("otp"))
+	if err != nil {
+		logger.GetLogger().ServiceWarn("OAuth authorization failed (second factor)", map[string]interface{}{
+			"clientId": cl.ClientID,
+			"error":    err.Error(),
+		})
+		data := authorizePageData{
+			Error:      "Invalid authentication code",
+			ClientName: cl.Name,
+			Scopes:     scopes,
+			Request:    form,
+			MFAToken:   mfaToken,
+		}
+		switch {
+		case errors.Is(err, mfa.ErrTooManyAttempts):
+			data.Error = "Too many invalid codes, try again later"
+		case errors.Is(err, errorsLib.ErrAccessDenied), errors.Is(err, errorsLib.ErrForbidden):
+			// The MFA token expired (or the user was disabled): back to the login form
+			data.Error = "The sign in has expired, enter your username and password again"
+			data.MFAToken = ""
+		case errors.Is(err, password.ErrExpired):
+			data.Error = "Your password has expired, change it by signing in to your account before signing in to applications"
+			data.MFAToken = ""
+		}
+		h.renderPage(c, http.StatusUnauthorized, data)
+		return
+	}
+
+	redirectWithParams(c, form.RedirectURI, url.Values{
+		"code":  {code},
+		"state": {form.State},
+	})
+}
+
+// POST /token — token endpoint (authorization_code, client_credentials and refresh_token grants)
+func (h *OAuthHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	// Client authentication: HTTP Basic (RFC 6749 section 2.3.1) or form fields
+	clientID, clientSecret, basic := c.Request.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID = c.PostForm("client_id")
+		clientSecret = c.PostForm("client_secret")
+	}
+
+	cl, err := h.oauthUC.AuthenticateClient(clientID, clientSecret)
+	if err != nil {
+		if basic {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		tokenError(c, err)
+		return
+	}
+
+	info := session.ClientInfo{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
+
+	var response *application.TokenResponse
+	switch c.PostForm("grant_type") {
+	case application.GrantTypeAuthorizationCode:
+		response, err = h.oauthUC.ExchangeAuthorizationCode(cl, c.PostForm("code"), c.PostForm("redirect_uri"), c.PostForm("code_verifier"), h.issuer, info)
+	case application.GrantTypeClientCredentials:
+		response, err = h.oauthUC.ClientCredentials(cl, c.PostForm("scope"))
+	case application.GrantTypeRefreshToken:
+		response, err = h.oauthUC.RefreshToken(cl, c.PostForm("refresh_token"), info)
+	case "":
+		err = oauth.NewError(oauth.ErrCodeInvalidRequest, "grant_type is required")
+	default:
+		err = oauth.NewError(oauth.ErrCodeUnsupportedGrantType, "")
+	}
+	if err != nil {
+		tokenError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GET /.well-known/openid-configuration — OpenID Provider metadata
+func (h *OAuthHandler) Discovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, h.oidcUC.Discovery(h.issuer))
+}
+
+// GET|POST /userinfo — claims of the token user (access token with the openid scope)
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	userInfo, err := h.oidcUC.UserInfo(claims)
+	if err != nil {
+		if errors.Is(err, application.ErrInsufficientScope) {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope"})
+			return
+		}
+		if errors.Is(err, errorsLib.ErrNotFound) || errors.Is(err, errorsLib.ErrAccessDenied) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, userInfo)
+}
+
+// validateAuthorize validates the authorization request. Client and redirect URI errors are shown
+// on the page; the other errors are redirected to the client. Returns false if the response was written.
+func (h *OAuthHandler) validateAuthorize(c *gin.Context, form authorizeForm) (*client.Client, []string, bool) {
+	cl, err := h.oauthUC.ResolveClient(form.ClientID, form.RedirectURI)
+	if err != nil {
+		h.renderPage(c, http.StatusBadRequest, authorizePageData{Error: pageError(err)})
+		return nil, nil, false
+	}
+
+	scopes, err := h.oauthUC.ValidateAuthorizeRequest(cl, form.toRequest())
+	if err != nil {
+		params := url.Values{"state": {form.State}}
+		var oauthErr *oauth.Error
+		if errors.As(err, &oauthErr) {
+			params.Set("error", oauthErr.Code)
+			params.Set("error_description", oauthErr.Description)
+		} else {
+			params.Set("error", oauth.ErrCodeServerError)
+		}
+		redirectWithParams(c, form.RedirectURI, params)
+		return nil, nil, false
+	}
+	return cl, scopes, true
+}
+
+func (h *OAuthHandler) renderPage(c *gin.Context, status int, data authorizePageData) {
+	// The page contains a login form: it can't be framed or cached
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "frame-ancestors 'none'")
+	c.Header("Cache-Control", "no-store")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := authorizePage.Execute(c.Writer, data); err != nil {
+		logger.GetLogger().ServiceError("Error rendering the authorization page", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// redirectWithParams redirects to the (already validated) redirect URI adding the params to its query
+func redirectWithParams(c *gin.Context, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		c.String(http.StatusBadRequest, "invalid redirect_uri")
+		return
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		if len(values) > 0 && values[0] != "" {
+			query.Set(key, values[0])
+		}
+	}
+	u.RawQuery = query.Encode()
+	c.Redirect(http.StatusFound, u.String())
+}
+
+// tokenError writes an error of the token endpoint (RFC 6749 section 5.2)
+func tokenError(c *gin.Context, err error) {
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		logger.GetLogger().ServiceError("OAuth token endpoint error", map[string]interface{}{
+			"error": err.Error(),
+		})
+		c.JSON(http.StatusInternalServerError, oauth.NewError(oauth.ErrCodeServerError, ""))
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == oauth.ErrCodeInvalidClient {
+		status = http.StatusUnauthorized
+	}
+	c.JSON(status, oauthErr)
+}
+
+func pageError(err error) string {
+	var oauthErr *oauth.Error
+	if errors.As(err, &oauthErr) && oauthErr.Description != "" {
+		return oauthErr.Description
+	}
+	return "the authorization request is not valid"
+}
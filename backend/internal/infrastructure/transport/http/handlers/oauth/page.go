@@ -0,0 +1,69 @@
+package oauth
+
+import "html/template"
+
+// authorizePageData — data of the login/consent page
+type authorizePageData struct {
+	Error      string
+	ClientName string
+	Scopes     []string
+	Request    authorizeForm
+	Username   string
+	MFAToken   string // set when the user has to enter the code of its authenticator app
+}
+
+// authorizePage — minimal login/consent page of the authorization endpoint.
+// The parameters of the authorization request travel in hidden fields and are validated again on submit.
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>Sign in</title>
+	<style>
+		body { font-family: sans-serif; background: #f4f5f7; display: flex; justify-content: center; padding-top: 10vh; }
+		main { background: #fff; padding: 2rem; border-radius: 8px; width: 22rem; box-shadow: 0 1px 4px rgba(0,0,0,.15); }
+		label { display: block; margin-top: 1rem; }
+		input[type=text], input[type=password] { width: 100%; padding: .5rem; box-sizing: border-box; }
+		.error { color: #b00020; }
+		.actions { display: flex; gap: 1rem; margin-top: 1.5rem; }
+		button { flex: 1; padding: .6rem; }
+	</style>
+</head>
+<body>
+<main>
+{{if .ClientName}}
+	<h1>Sign in</h1>
+	<p><strong>{{.ClientName}}</strong> wants to access your account{{if .Scopes}} with the following permissions:{{end}}</p>
+	{{if .Scopes}}<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+	{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+	<form method="post" action="authorize">
+		<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+		<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+		<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+		<input type="hidden" name="scope" value="{{.Request.Scope}}">
+		<input type="hidden" name="state" value="{{.Request.State}}">
+		<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+		<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+		<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+		{{if .MFAToken}}
+		<input type="hidden" name="mfa_token" value="{{.MFAToken}}">
+		<label>Authentication code <input type="text" name="otp" inputmode="numeric" autocomplete="one-time-code" autofocus required></label>
+		<p>Enter the code of your authenticator app or one of your recovery codes.</p>
+		{{else}}
+		<label>Username <input type="text" name="username" value="{{.Username}}" autocomplete="username" required></label>
+		<label>Password <input type="password" name="password" autocomplete="current-password"></label>
+		{{end}}
+		<div class="actions">
+			<button type="submit" name="decision" value="deny" formnovalidate>Deny</button>
+			<button type="submit" name="decision" value="approve">Allow</button>
+		</div>
+	</form>
+{{else}}
+	<h1>Authorization error</h1>
+	<p class="error">{{.Error}}</p>
+{{end}}
+</main>
+</body>
+</html>
+`))
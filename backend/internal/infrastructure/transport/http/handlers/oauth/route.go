@@ -0,0 +1,49 @@
+package oauth
+
+import (
+	"log"
+
+	"app/internal/application"
+	"app/internal/infrastructure/repositories"
+	"app/internal/infrastructure/transport/http/server/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func Routes(router *gin.Engine, authUseCase *application.AuthUseCase) {
+
+	oauthUseCase := application.NewOAuthUseCase(
+		repositories.NewClientRepository(),
+		repositories.NewAuthorizationCodeRepository(),
+		repositories.NewUserRepository(),
+		authUseCase)
+	oauthUseCase.SetCodeTTL(viper.GetDuration("oauth.authorization_code_ttl"))
+
+	// The issuer is in the discovery document and in the ID tokens: it is never derived from the request
+	issuer := viper.GetString("oidc.issuer")
+	if issuer == "" {
+		log.Fatal("oidc.issuer is not configured")
+	}
+
+	handler := NewOAuthHandler(
+		oauthUseCase,
+		application.NewOIDCUseCase(repositories.NewUserRepository()),
+		issuer)
+
+	// OpenID Connect discovery
+	router.GET("/.well-known/openid-configuration", handler.Discovery)
+
+	// Routes
+	group := router.Group("/oauth")
+	{
+		group.GET("/authorize", handler.Authorize)                                         // Login/consent page
+		group.POST("/authorize", middleware.RateLimit("login"), handler.AuthorizeDecision) // Approve or deny (issues the authorization code)
+		group.POST("/token", handler.Token)                                                // Token endpoint
+
+		// OpenID Connect userinfo (access token with the openid scope)
+		userinfo := group.Group("", middleware.AuthenticateDelegated())
+		userinfo.GET("/userinfo", handler.UserInfo)
+		userinfo.POST("/userinfo", handler.UserInfo)
+	}
+}
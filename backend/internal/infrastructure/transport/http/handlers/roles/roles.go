@@ -2,10 +2,14 @@ package roles
 
 import (
 	"app/internal/application"
+	"app/internal/domain/role"
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,9 +24,11 @@ func NewRoleHandler(roleUseCase *application.RoleUseCase) *RoleHandler {
 	return &RoleHandler{RoleUseCase: roleUseCase}
 }
 
-// GetAllRoles - handler for getting all roles
+// GetAllRoles - handler for getting all roles (visible to the company of the token)
 func (h *RoleHandler) GetAllRoles(c *gin.Context) {
-	roles, err := h.RoleUseCase.GetAllRoles()
+	claims := middleware.MustGetClaims(c)
+
+	roles, err := h.RoleUseCase.GetAllRoles(uint(claims.CompanyID))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -30,40 +36,51 @@ func (h *RoleHandler) GetAllRoles(c *gin.Context) {
 	c.JSON(http.StatusOK, roles)
 }
 
-// GetRoleByID - handler for getting role by ID
+// GetRoleByID - handler for getting role by ID (?id=)
 func (h *RoleHandler) GetRoleByID(c *gin.Context) {
-	roleID, err := strconv.Atoi(c.Param("id"))
+	claims := middleware.MustGetClaims(c)
+
+	roleID, err := strconv.Atoi(c.Query("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
 
-	role, err := h.RoleUseCase.GetRoleByID(uint(roleID))
+	role, err := h.RoleUseCase.GetRoleByID(uint(claims.CompanyID), uint(roleID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, role)
 }
 
-// GetRolesByUsername - handler for getting roles by username
+// GetRolesByUsername - handler for getting roles of the company owner or one of its subusers by username
 func (h *RoleHandler) GetRolesByUsername(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
 	username := c.Query("username")
 
-	roles, err := h.RoleUseCase.GetRolesByUsername(username)
+	roles, err := h.RoleUseCase.GetRolesByUsername(claims.MainUsername(), username)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if strings.Contains(err.Error(), "user not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, roles)
 }
 
-// AssignRolesToUser - handler for assigning roles to user
+// AssignRolesToUser - handler for assigning roles to the company owner or one of its subusers
 func (h *RoleHandler) AssignRolesToUser(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
 	// Parse request body
 	var req struct {
-		Username string `json:"username" binding:"required"`
-		Roles    string `json:"roles" binding:"required"`
+		Username   string     `json:"username" binding:"required"`
+		Roles      string     `json:"roles" binding:"required"`
+		ValidFrom  *time.Time `json:"validFrom"`  // optional, RFC3339
+		ValidUntil *time.Time `json:"validUntil"` // optional, RFC3339
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -72,13 +89,17 @@ func (h *RoleHandler) AssignRolesToUser(c *gin.Context) {
 	}
 
 	// Call usecase
-	if err := h.RoleUseCase.AssignRolesToUser(req.Username, req.Roles); err != nil {
+	if err := h.RoleUseCase.AssignRolesToUserWithValidity(claims.MainUsername(), req.Username, req.Roles, req.ValidFrom, req.ValidUntil); err != nil {
 		// Check if it's a "user not found" error
 		if strings.Contains(err.Error(), "user not found") {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if strings.Contains(err.Error(), "invalid validity window") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -88,8 +109,10 @@ func (h *RoleHandler) AssignRolesToUser(c *gin.Context) {
 	})
 }
 
-// EliminateRolesOfUser - handler for removing roles from user
+// EliminateRolesOfUser - handler for removing roles from the company owner or one of its subusers
 func (h *RoleHandler) RemoveRolesOfUser(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
 	// Parse request body
 	var req struct {
 		Username string `json:"username" binding:"required"`
@@ -102,13 +125,13 @@ func (h *RoleHandler) RemoveRolesOfUser(c *gin.Context) {
 	}
 
 	// Call usecase
-	if err := h.RoleUseCase.EliminateRolesOfUser(req.Username, req.Roles); err != nil {
+	if err := h.RoleUseCase.EliminateRolesOfUser(claims.MainUsername(), req.Username, req.Roles); err != nil {
 		// Check if it's a "user not found" error
 		if strings.Contains(err.Error(), "user not found") {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -117,3 +140,150 @@ func (h *RoleHandler) RemoveRolesOfUser(c *gin.Context) {
 		"message": fmt.Sprintf("Roles removed from user %s successfully", req.Username),
 	})
 }
+
+// GetAllPermissions - handler for getting all permissions
+func (h *RoleHandler) GetAllPermissions(c *gin.Context) {
+	permissions, err := h.RoleUseCase.GetAllPermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+// GetOwnPermissions - handler for getting the effective permissions of the authenticated user
+func (h *RoleHandler) GetOwnPermissions(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	permissions, err := h.RoleUseCase.GetPermissionsByUsername(claims.Username)
+	if err != nil {
+		if strings.Contains(err.Error(), "user not found") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, permissions)
+}
+
+// SetRoleParent - handler for setting the parent role (inheritance) of a role
+func (h *RoleHandler) SetRoleParent(c *gin.Context) {
+	var req struct {
+		Role   string `json:"role" binding:"required"`
+		Parent string `json:"parent"` // empty => remove parent
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.RoleUseCase.SetRoleParent(req.Role, req.Parent); err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Parent of role %s updated successfully", req.Role),
+	})
+}
+
+type createRoleRequest struct {
+	Role   string `json:"role" binding:"required"`
+	Desc   string `json:"desc"`
+	Parent string `json:"parent"`
+}
+
+// CreateRole - handler for creating a role
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req createRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	r, err := h.RoleUseCase.CreateRole(req.Role, req.Desc, req.Parent)
+	if err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+type updateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+	Desc string `json:"desc"`
+}
+
+// UpdateRole - handler for updating the description of a role
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	var req updateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	r, err := h.RoleUseCase.UpdateRole(req.Role, req.Desc)
+	if err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+type renameRoleRequest struct {
+	Role    string `json:"role" binding:"required"`
+	NewName string `json:"newName" binding:"required"`
+}
+
+// RenameRole - handler for renaming a role
+func (h *RoleHandler) RenameRole(c *gin.Context) {
+	var req renameRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	r, err := h.RoleUseCase.RenameRole(req.Role, req.NewName)
+	if err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// DeleteRole - handler for deleting a role
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err := h.RoleUseCase.DeleteRole(req.Role); err != nil {
+		c.JSON(roleErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Role %s deleted successfully", req.Role),
+	})
+}
+
+// roleErrorStatus - map role domain errors to HTTP status codes
+func roleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, role.ErrRoleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, role.ErrSystemRole), errors.Is(err, role.ErrReservedRoleName):
+		return http.StatusForbidden
+	case errors.Is(err, role.ErrRoleAlreadyExists), errors.Is(err, role.ErrRoleCycle), errors.Is(err, role.ErrRoleSelfParent):
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "invalid role name"):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
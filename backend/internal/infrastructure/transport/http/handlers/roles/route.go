@@ -2,23 +2,50 @@ package roles
 
 import (
 	"app/internal/application"
+	"app/internal/domain/role"
 	"app/internal/infrastructure/repositories"
+	"app/internal/infrastructure/transport/http/server/middleware"
 
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
 )
 
 func Routes(router *gin.Engine) {
 
-	handler := NewRoleHandler(application.NewRoleUseCase(repositories.NewRoleRepository(), repositories.NewUserRepository()))
+	roleUseCase := application.NewRoleUseCase(
+		repositories.NewRoleRepository(),
+		repositories.NewUserRepository(),
+		repositories.NewPermissionRepository())
+	roleUseCase.SetStrictAssign(viper.GetBool("roles.strict_assign"))
+
+	handler := NewRoleHandler(roleUseCase)
 
 	// // Routes
 	group := router.Group("/roles")
 	{
-		group.GET("/all", handler.GetAllRoles)                // Get all roles
-		group.GET("/by-id", handler.GetRoleByID)              // Get role by ID
-		group.GET("/by-username", handler.GetRolesByUsername) // Get roles by username
+		// Read routes (permission roles:read)
+		read := group.Group("", middleware.ProtectedWithPermissions(role.PermissionRolesRead)...)
+		read.GET("/all", handler.GetAllRoles)                // Get all roles
+		read.GET("/by-id", handler.GetRoleByID)              // Get role by ID
+		read.GET("/by-username", handler.GetRolesByUsername) // Get roles of a user of the company
+
+		read.GET("/permissions/all", handler.GetAllPermissions) // Get all permissions
+
+		// Authenticated routes
+		authenticated := group.Group("", middleware.Protected()...)
+		authenticated.GET("/permissions/me", handler.GetOwnPermissions) // Get effective permissions of the token user
+
+		// Protected routes (permission roles:assign)
+		protected := group.Group("", middleware.ProtectedWithPermissions(role.PermissionRolesAssign)...)
+		protected.POST("/assign", handler.AssignRolesToUser) // Assign roles to user
+		protected.POST("/remove", handler.RemoveRolesOfUser) // Remove roles from user
 
-		group.POST("/assign", handler.AssignRolesToUser) // Assign roles to user
-		group.POST("/remove", handler.RemoveRolesOfUser) // Remove roles from user
+		// Role management routes, shared by all the companies (permission platform:admin)
+		manage := group.Group("", middleware.ProtectedWithPermissions(role.PermissionPlatformAdmin)...)
+		manage.POST("/create", handler.CreateRole)    // Create role
+		manage.POST("/update", handler.UpdateRole)    // Update role description
+		manage.POST("/rename", handler.RenameRole)    // Rename role (not system roles)
+		manage.POST("/delete", handler.DeleteRole)    // Delete role (not system roles)
+		manage.POST("/parent", handler.SetRoleParent) // Set parent role (inheritance)
 	}
 }
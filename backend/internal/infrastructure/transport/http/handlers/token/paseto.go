@@ -1,32 +1,68 @@
 package token
 
 import (
+	"app/internal/application"
+	"app/internal/domain/client"
 	"app/internal/infrastructure/token/paseto"
-	"app/pkg/errorsLib"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
 type PasetoHandler struct {
+	introspectionUC *application.IntrospectionUseCase
+	clientUC        *application.ClientUseCase
 }
 
-func NewPasetoHandler() *PasetoHandler {
-	return &PasetoHandler{}
+func NewPasetoHandler(introspectionUC *application.IntrospectionUseCase, clientUC *application.ClientUseCase) *PasetoHandler {
+	return &PasetoHandler{
+		introspectionUC: introspectionUC,
+		clientUC:        clientUC,
+	}
 }
 
-func (h *PasetoHandler) DecodePasetoToken(ctx *gin.Context) {
+// POST /token/introspect — RFC 7662 token introspection.
+// The caller authenticates with its client credentials (HTTP Basic or client_id/client_secret form fields),
+// the token is sent in the "token" form field.
+func (h *PasetoHandler) IntrospectToken(ctx *gin.Context) {
+	clientID, clientSecret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		clientID = ctx.PostForm("client_id")
+		clientSecret = ctx.PostForm("client_secret")
+	}
 
-	claims, err := paseto.Paseto().ValidateToken(ctx.GetHeader("Authorization"))
+	caller, err := h.clientUC.AuthenticateClient(clientID, clientSecret)
 	if err != nil {
-		ctx.JSON(errorsLib.HTTPStatusCode(err.Error()), gin.H{"error": err.Error()})
+		if errors.Is(err, client.ErrInvalidClient) {
+			ctx.Header("WWW-Authenticate", `Basic realm="token"`)
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if ctx.Query("code") != "123456" {
-		ctx.JSON(errorsLib.HTTPStatusCode(errorsLib.ErrAccessDenied.Error()), gin.H{"error": errorsLib.ErrAccessDenied.Error()})
+	token := ctx.PostForm("token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request"})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, h.introspectionUC.Introspect(caller, token))
+}
+
+// GET /token/keys — public verification keys of the access tokens (v4.public).
+// Other services can validate the tokens offline: the footer of a token contains the kid of its key.
+func (h *PasetoHandler) GetPublicKeys(ctx *gin.Context) {
+	keyRing := paseto.Paseto().KeyRing()
+	if keyRing == nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "key ring is not configured"})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, claims)
+	// Keys change rarely, but a rotated key must be picked up quickly by the clients
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, gin.H{"keys": keyRing.PublicKeys()})
 }
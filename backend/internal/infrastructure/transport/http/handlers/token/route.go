@@ -1,17 +1,27 @@
 package token
 
 import (
+	"app/internal/application"
+	"app/internal/infrastructure/repositories"
+
 	"github.com/gin-gonic/gin"
 )
 
 func Routes(router *gin.Engine) {
 
-	handler := NewPasetoHandler()
+	handler := NewPasetoHandler(
+		application.NewIntrospectionUseCase(repositories.NewUserRepository(), repositories.NewClientRepository()),
+		application.NewClientUseCase(repositories.NewClientRepository(),
+			repositories.NewUserRepository(),
+			repositories.NewInternalCompanyRepository()))
 
 	// // Routes
 	group := router.Group("/token")
 	{
-		// Get all providers
-		group.GET("/decode", handler.DecodePasetoToken)
+		// Token introspection (RFC 7662), client credentials required
+		group.POST("/introspect", handler.IntrospectToken)
+
+		// Public verification keys
+		group.GET("/keys", handler.GetPublicKeys)
 	}
 }
@@ -0,0 +1,47 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"app/internal/application"
+	"app/internal/domain/lockout"
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"app/pkg/errorsLib"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LockoutHandler - HTTP handler for the users locked after too many failed login attempts
+type LockoutHandler struct {
+	lockoutUC *application.LockoutUseCase
+}
+
+func NewLockoutHandler(uc *application.LockoutUseCase) *LockoutHandler {
+	return &LockoutHandler{lockoutUC: uc}
+}
+
+type unlockUserRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// POST /users/unlock — unlock the company owner or one of its subusers before the lock expires
+func (h *LockoutHandler) UnlockUser(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
+
+	var req unlockUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.lockoutUC.Unlock(claims.MainUsername(), req.Username); err != nil {
+		if errors.Is(err, lockout.ErrNotLocked) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(errorsLib.HTTPStatusCode(err.Error()), gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked"})
+}
@@ -3,7 +3,7 @@ package profile
 import (
 	"app/internal/application"
 	"app/internal/domain/user"
-	"app/internal/infrastructure/token/paseto"
+	"app/internal/infrastructure/transport/http/server/middleware"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -26,11 +26,7 @@ type ProfileRequest struct {
 }
 
 func (h *ProfileHandler) UpdateOwnProfile(c *gin.Context) {
-	claims, err := paseto.Paseto().ValidateToken(c.GetHeader("Authorization"))
-	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		return
-	}
+	claims := middleware.MustGetClaims(c)
 	h.updateProfile(c, claims.Username)
 }
 
@@ -43,17 +39,8 @@ func (h *ProfileHandler) UpdateUserProfile(c *gin.Context) {
 // UpdateUserProfile updates the profile of a specific user by user ID
 func (h *ProfileHandler) updateProfile(c *gin.Context, username string) {
 
-	claims, err := paseto.Paseto().ValidateToken(c.GetHeader("Authorization"))
-	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		return
-	}
-	var ownerUsername string
-	if claims.OwnerUsername == "" {
-		ownerUsername = claims.Username
-	} else {
-		ownerUsername = claims.OwnerUsername
-	}
+	claims := middleware.MustGetClaims(c)
+	ownerUsername := claims.MainUsername()
 
 	var profile ProfileRequest
 	if err := c.ShouldBindJSON(&profile); err != nil {
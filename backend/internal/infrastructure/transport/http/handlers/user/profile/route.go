@@ -3,6 +3,7 @@ package profile
 import (
 	"app/internal/application"
 	"app/internal/infrastructure/repositories"
+	"app/internal/infrastructure/transport/http/server/middleware"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,7 +12,7 @@ func Routes(router *gin.Engine) {
 	handler := NewProfileHandler(application.NewProfileUseCase(repositories.NewUserRepository()))
 
 	// // Routes
-	group := router.Group("/users")
+	group := router.Group("/users", middleware.Protected()...)
 	{
 		// // Get all providers
 		// group.GET("/all", handler.GetAllProviders)
@@ -2,13 +2,15 @@ package user
 
 import (
 	"app/internal/application"
+	"app/internal/domain/role"
 	"app/internal/infrastructure/repositories"
+	"app/internal/infrastructure/transport/http/server/middleware"
 	"app/internal/infrastructure/webhooks/verificaciones"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Routes(router *gin.Engine) {
+func Routes(router *gin.Engine, lockoutUseCase *application.LockoutUseCase) {
 	handler := NewUserHandler(
 		application.NewUserUseCase(
 			repositories.NewUserRepository(),
@@ -20,21 +22,36 @@ func Routes(router *gin.Engine) {
 			repositories.NewUserRepository(),
 			repositories.NewRoleRepository(),
 			verificaciones.NewVerificacionesClient()))
+
+	lockoutHandler := NewLockoutHandler(lockoutUseCase)
+
 	// // Routes
 	group := router.Group("/users")
 	{
 		// // Get all providers
 		// group.GET("/all", handler.GetAllProviders)
-		group.POST("/register", handler.RegisterCompanyUser)        // Register company user
-		group.POST("/subuser", subUserHandler.CreateSubUser)        // Create subuser
-		group.POST("/subuser/delete", subUserHandler.DeleteSubuser) // Delete subuser
+		group.POST("/register", middleware.RateLimit("register"), handler.RegisterCompanyUser) // Register company user
+
+		group.GET("/by-login", handler.GetUserByLogin)         // Get user by login
+		group.GET("/is-company", handler.CheckIfUserIsCompany) // Check if user is company
+		group.GET("/is-logged", handler.CheckIfUserIsLogged)   // Check if user is logged
+
+		// Authenticated routes
+		authenticated := group.Group("", middleware.Protected()...)
+		authenticated.GET("/all", handler.GetUserAndSubUsersByOwnerUsername) // Get user and subusers by owner username
+
+		// User read routes (permission users:read)
+		read := group.Group("", middleware.ProtectedWithPermissions(role.PermissionUsersRead)...)
+		read.GET("/by-id", handler.GetUserByID) // Get user by ID
 
-		group.GET("/all", handler.GetUserAndSubUsersByOwnerUsername) // Get user and subusers by owner username
-		group.GET("/by-id", handler.GetUserByID)                     // Get user by ID
-		group.GET("/by-login", handler.GetUserByLogin)               // Get user by login
-		group.GET("/is-company", handler.CheckIfUserIsCompany)       // Check if user is company
-		group.GET("/is-logged", handler.CheckIfUserIsLogged)         // Check if user is logged
+		// Subuser management routes (permission subusers:create)
+		subusers := group.Group("", middleware.ProtectedWithPermissions(role.PermissionSubusersCreate)...)
+		subusers.POST("/subuser", subUserHandler.CreateSubUser)        // Create subuser
+		subusers.POST("/subuser/delete", subUserHandler.DeleteSubuser) // Delete subuser
 
-		group.POST("/activation", handler.ActivateDeactivateUser) // Activate/deactivate user
+		// User management routes (permission users:write)
+		write := group.Group("", middleware.ProtectedWithPermissions(role.PermissionUsersWrite)...)
+		write.POST("/activation", handler.ActivateDeactivateUser) // Activate/deactivate user
+		write.POST("/unlock", lockoutHandler.UnlockUser)          // Unlock user locked by failed login attempts
 	}
 }
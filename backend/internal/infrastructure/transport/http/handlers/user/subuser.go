@@ -1,14 +1,15 @@
 package user
 
 import (
+	"errors"
 	"net/http"
 	"net/url"
 	"strings"
 
 	"app/internal/application"
-	"app/internal/infrastructure/token/paseto"
+	"app/internal/domain/user_identity"
+	"app/internal/infrastructure/transport/http/server/middleware"
 	"app/pkg/config"
-	"app/pkg/random"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,11 +33,7 @@ type CreateSubUserRequest struct {
 
 func (h *SubUserHandler) CreateSubUser(c *gin.Context) {
 
-	claims, err := paseto.Paseto().ValidateToken(c.GetHeader("Authorization"))
-	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		return
-	}
+	claims := middleware.MustGetClaims(c)
 
 	var req CreateSubUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -44,17 +41,20 @@ func (h *SubUserHandler) CreateSubUser(c *gin.Context) {
 		return
 	}
 
-	// If middleware password is not correct, generate random password
+	// If middleware password is not correct, generate random password (empty: see CreateSubUser)
 	if config.ENV().MIDDLEWARE_PASSWORD == c.GetHeader("X-Middleware-Password") {
 		req.Email = ""
 	} else {
-		req.Password, _ = random.GenerateRandomPassword()
+		req.Password = ""
 	}
 
 	// Create subuser
 	subUser, err := h.subUserUseCase.CreateSubUser(claims.Username, req.Username, req.Password, req.Roles, req.Email)
 	if err != nil {
-		if strings.Contains(err.Error(), "user already exists") {
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
+		if strings.Contains(err.Error(), "user already exists") || errors.Is(err, user_identity.ErrProviderLogin) {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -67,11 +67,7 @@ func (h *SubUserHandler) CreateSubUser(c *gin.Context) {
 
 func (h *SubUserHandler) DeleteSubuser(c *gin.Context) {
 
-	claims, err := paseto.Paseto().ValidateToken(c.GetHeader("Authorization"))
-	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		return
-	}
+	claims := middleware.MustGetClaims(c)
 
 	username := c.Query("username")
 	if username == "" {
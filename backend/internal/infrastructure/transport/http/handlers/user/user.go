@@ -2,8 +2,10 @@ package user
 
 import (
 	"app/internal/application"
-	"app/internal/infrastructure/token/paseto"
+	"app/internal/domain/user"
+	"app/internal/infrastructure/transport/http/server/middleware"
 	"app/pkg/errorsLib"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -19,7 +21,9 @@ func NewUserHandler(userUC *application.UserUseCase) *UserHandler {
 	return &UserHandler{userUC: userUC}
 }
 
+// GET /users/by-id?id= — the company owner or one of its subusers
 func (h *UserHandler) GetUserByID(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
 
 	id, err := strconv.Atoi(c.Query("id"))
 	if err != nil {
@@ -27,10 +31,13 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 		return
 	}
 	// Call use-case
-	user, err := h.userUC.GetUserByID(uint(id))
+	user, err := h.userUC.GetUserByID(claims.MainUsername(), uint(id))
 	if err != nil {
-		// For example, gorm.ErrRecordNotFound => 404
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		if errors.Is(err, errorsLib.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user by ID"})
+		}
 		return
 	}
 
@@ -84,21 +91,9 @@ func (h *UserHandler) GetUserByLogin(c *gin.Context) {
 
 func (h *UserHandler) GetUserAndSubUsersByOwnerUsername(c *gin.Context) {
 
-	claims, err := paseto.Paseto().ValidateToken(c.GetHeader("Authorization"))
-	if err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		return
-	}
-
-	var mainUserUsername string
+	claims := middleware.MustGetClaims(c)
 
-	if claims.OwnerUsername == "" {
-		mainUserUsername = claims.Username
-	} else {
-		mainUserUsername = claims.OwnerUsername
-	}
-
-	mainUser, subUsers, err := h.userUC.GetUserAndSubUsersByOwnerUsername(mainUserUsername)
+	mainUser, subUsers, err := h.userUC.GetUserAndSubUsersByOwnerUsername(claims.MainUsername())
 	if err != nil {
 		c.JSON(errorsLib.HTTPStatusCode(err.Error()), gin.H{"error": err.Error()})
 		return
@@ -115,7 +110,9 @@ type activateDeactivateUserRequest struct {
 // TODO 2025/03/12 16:57:23 migration error: autoMigrate error: Error 1061 (42000): Duplicate key name 'idx_users_uuid'
 // exit status 1
 
+// POST /users/activation — activate/deactivate the company owner or one of its subusers
 func (h *UserHandler) ActivateDeactivateUser(c *gin.Context) {
+	claims := middleware.MustGetClaims(c)
 
 	var req activateDeactivateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -123,9 +120,9 @@ func (h *UserHandler) ActivateDeactivateUser(c *gin.Context) {
 		return
 	}
 
-	err := h.userUC.ActivateDeactivateUser(req.Username, req.Active)
+	err := h.userUC.ActivateDeactivateUser(claims.MainUsername(), req.Username, req.Active)
 	if err != nil {
-		if h.userUC.GetRepo().IsNotFoundError(err) {
+		if errors.Is(err, errorsLib.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate/deactivate user"})
@@ -155,6 +152,9 @@ func (h *UserHandler) RegisterCompanyUser(c *gin.Context) {
 
 	user, err := h.userUC.RegisterCompanyUser(req.Username, req.Password, req.CompanyName)
 	if err != nil {
+		if respondPasswordPolicyError(c, err) {
+			return
+		}
 		if strings.Contains(err.Error(), "user already exists") {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		} else {
@@ -165,3 +165,13 @@ func (h *UserHandler) RegisterCompanyUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"user": user})
 }
+
+// respondPasswordPolicyError responds 400 with the violations when err is a password policy error
+func respondPasswordPolicyError(c *gin.Context, err error) bool {
+	var policyErr *user.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": user.ErrPasswordPolicy.Error(), "violations": policyErr.Violations})
+	return true
+}
@@ -17,16 +17,16 @@ var (
 
 // GetInstance — lazy initialization (singleton)
 // On first call, it calls HTTP() and returns *gin.Engine
-func GetInstance() *gin.Engine {
+func GetInstance(uc UseCases) *gin.Engine {
 	once.Do(func() {
-		HTTP()
+		HTTP(uc)
 	})
 	return instance
 }
 
 // MustLoad — starts HTTP-server in a separate goroutine
-func MustLoad() {
-	router := GetInstance() // call GetInstance() => creates/gets instance
+func MustLoad(uc UseCases) {
+	router := GetInstance(uc) // call GetInstance() => creates/gets instance
 	port := viper.GetInt("server.http.port")
 
 	go func() {
@@ -40,13 +40,14 @@ func MustLoad() {
 
 // HTTP — initialization of the *gin.Engine object (CORS, middleware, routes)
 // Called once (through once.Do).
-func HTTP() {
+func HTTP(uc UseCases) {
 	setMode()
 	instance = gin.Default()
+	setTrustedProxies(instance)
 	setCors(instance)
 	instance.Use(TimeoutMiddleware(viper.GetString("server.http.timeout")))
 	instance.Use(RouteLogger())
-	InitRoutes(instance)
+	InitRoutes(instance, uc)
 }
 
 func setMode() {
@@ -56,3 +57,12 @@ func setMode() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 }
+
+// setTrustedProxies — only the proxies of the config can set the address of the client (X-Forwarded-For,
+// X-Real-IP): it's what the rate limits, the lockout and the sessions count the clients by.
+// Without proxies in the config the address of the connection is used.
+func setTrustedProxies(engine *gin.Engine) {
+	if err := engine.SetTrustedProxies(viper.GetStringSlice("server.http.trusted_proxies")); err != nil {
+		log.Fatalf("Invalid server.http.trusted_proxies: %v", err)
+	}
+}
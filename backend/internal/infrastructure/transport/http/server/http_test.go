@@ -0,0 +1,53 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"app/internal/infrastructure/transport/http/server/middleware"
+	"app/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+func TestRateLimitForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	viper.Set("rate_limit.enabled", true)
+	viper.Set("rate_limit.routes.login", map[string]interface{}{"requests": 1, "period": "1h", "burst": 2, "keys": []string{"ip"}})
+	t.Cleanup(viper.Reset)
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		want           []int // status of the requests, each one with another X-Forwarded-For
+	}{
+		{name: "no trusted proxies", want: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+		{name: "proxy not trusted", trustedProxies: []string{"10.0.0.0/8"}, want: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+		{name: "trusted proxy", trustedProxies: []string{"192.0.2.0/24"}, want: []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("server.http.trusted_proxies", tt.trustedProxies)
+			middleware.SetRateLimitStore(ratelimit.NewMemoryStore())
+
+			router := gin.New()
+			setTrustedProxies(router)
+			router.POST("/auth/login", middleware.RateLimit("login"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			forwardedFor := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4"}
+			for i, ip := range forwardedFor {
+				req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+				req.RemoteAddr = "192.0.2.10:4321"
+				req.Header.Set("X-Forwarded-For", ip)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				if w.Code != tt.want[i] {
+					t.Errorf("request %d from %s: got %d, want %d", i+1, ip, w.Code, tt.want[i])
+				}
+			}
+		})
+	}
+}
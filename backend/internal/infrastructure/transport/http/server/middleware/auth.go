@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"app/internal/domain/role"
+	"app/internal/infrastructure/token/paseto"
+	"app/pkg/errorsLib"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ClaimsKey — key under which the validated PasetoClaims are stored in the gin.Context
+const ClaimsKey = "paseto_claims"
+
+// Authenticate validates the bearer token once and stores the typed claims in the gin.Context.
+// Requests without a valid token are aborted with 401. The access tokens issued to OAuth clients
+// are not accepted: their scopes only grant the OAuth endpoints (see AuthenticateDelegated).
+func Authenticate() gin.HandlerFunc {
+	return authenticate(false)
+}
+
+// AuthenticateDelegated is Authenticate for the endpoints of the OAuth clients (userinfo):
+// it also accepts the access tokens issued to them on behalf of the user
+func AuthenticateDelegated() gin.HandlerFunc {
+	return authenticate(true)
+}
+
+func authenticate(delegated bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if strings.TrimSpace(header) == "" {
+			abortUnauthorized(c, "missing authorization token")
+			return
+		}
+
+		claims, err := paseto.Paseto().ValidateToken(header)
+		if err != nil {
+			abortUnauthorized(c, err.Error())
+			return
+		}
+
+		// The recover token only allows to reset the password
+		if claims.HasRole(role.RoleRecover) {
+			abortUnauthorized(c, "invalid token")
+			return
+		}
+
+		// The MFA pending token only allows to complete the login
+		if claims.HasRole(role.RoleMFAPending) {
+			abortUnauthorized(c, "invalid token")
+			return
+		}
+
+		// The password expired token only allows to change the password
+		if claims.HasRole(role.RolePasswordExpired) {
+			abortUnauthorized(c, "invalid token")
+			return
+		}
+
+		// Client credentials tokens have no user, they are only for other services
+		if claims.IsClientToken() {
+			abortUnauthorized(c, "invalid token")
+			return
+		}
+
+		// A token issued to an OAuth client only grants the scopes consented by the user
+		if claims.ClientID != "" && !delegated {
+			abortUnauthorized(c, "invalid token")
+			return
+		}
+
+		c.Set(ClaimsKey, claims)
+		c.Next()
+	}
+}
+
+// AuthenticatePasswordChange is Authenticate for the change password route: it also accepts
+// the password expired token of a login, which only allows to change the password
+func AuthenticatePasswordChange() gin.HandlerFunc {
+	authenticate := Authenticate()
+	return func(c *gin.Context) {
+		claims, err := paseto.Paseto().ValidateToken(c.GetHeader("Authorization"))
+		if err == nil && claims.HasRole(role.RolePasswordExpired) {
+			c.Set(ClaimsKey, claims)
+			c.Next()
+			return
+		}
+		authenticate(c)
+	}
+}
+
+// RequireRoles checks that the authenticated user has at least one of the given roles.
+// A role may contain "%d", which is replaced by the company ID of the token
+// (for example "company_%d" => "company_20001").
+// Must be used after Authenticate. Requests without the roles are aborted with 403.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			abortUnauthorized(c, errorsLib.ErrAccessDenied.Error())
+			return
+		}
+
+		for _, name := range roles {
+			if strings.Contains(name, "%d") {
+				name = fmt.Sprintf(name, claims.CompanyID)
+			}
+			if claims.HasRole(name) {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errorsLib.ErrForbidden.Error()})
+	}
+}
+
+// Protected is a shortcut for Authenticate + RequireRoles
+func Protected(roles ...string) []gin.HandlerFunc {
+	if len(roles) == 0 {
+		return []gin.HandlerFunc{Authenticate()}
+	}
+	return []gin.HandlerFunc{Authenticate(), RequireRoles(roles...)}
+}
+
+// GetClaims returns the claims stored by Authenticate
+func GetClaims(c *gin.Context) (*paseto.PasetoClaims, bool) {
+	value, exists := c.Get(ClaimsKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(*paseto.PasetoClaims)
+	return claims, ok
+}
+
+// MustGetClaims returns the claims stored by Authenticate.
+// Panics if the route is not protected by Authenticate (programming error).
+func MustGetClaims(c *gin.Context) *paseto.PasetoClaims {
+	claims, ok := GetClaims(c)
+	if !ok {
+		panic("middleware: claims not found in context, route is not protected by Authenticate")
+	}
+	return claims
+}
+
+func abortUnauthorized(c *gin.Context, message string) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
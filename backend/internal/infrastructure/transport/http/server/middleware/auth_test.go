@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"app/internal/infrastructure/token/paseto"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testClaims() paseto.PasetoClaims {
+	return paseto.PasetoClaims{
+		Username:      "tech1",
+		CompanyID:     20001,
+		CompanyName:   "Acme",
+		Roles:         "company,technician",
+		OwnerUsername: "acme",
+		SessionID:     "session-1",
+	}
+}
+
+// newToken returns a token of the given claims, signed with the key ring of the tests
+func newToken(t *testing.T, generate func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error)) string {
+	t.Helper()
+	token, _, err := generate(paseto.Paseto())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// serve runs a request with the authorization header through the middlewares and returns its status
+func serve(authorization string, handlers ...gin.HandlerFunc) int {
+	router := gin.New()
+	router.GET("/", append(handlers, func(c *gin.Context) {
+		MustGetClaims(c)
+		c.Status(http.StatusOK)
+	})...)
+	return serveRouter(router, authorization)
+}
+
+// serveRouter runs a request with the authorization header through the router and returns its status
+func serveRouter(router *gin.Engine, authorization string) int {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestAuthenticate(t *testing.T) {
+	userToken := func(modify func(c *paseto.PasetoClaims)) func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error) {
+		return func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error) {
+			claims := testClaims()
+			if modify != nil {
+				modify(&claims)
+			}
+			return p.GenerateToken(claims)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		generate  func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error)
+		header    string // used without generate
+		want      int
+		delegated int // status of AuthenticateDelegated (want if 0)
+		change    int // status of AuthenticatePasswordChange (want if 0)
+	}{
+		{name: "access token", generate: userToken(nil), want: http.StatusOK},
+		{name: "without token", want: http.StatusUnauthorized},
+		{name: "malformed token", header: "Bearer v4.public.invalid", want: http.StatusUnauthorized},
+		{name: "expired token", generate: userToken(func(c *paseto.PasetoClaims) {
+			c.ExpiresAt = time.Now().Add(-time.Minute)
+		}), want: http.StatusUnauthorized},
+		{name: "recover token", generate: func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error) {
+			return p.GenerateRecoverToken(testClaims())
+		}, want: http.StatusUnauthorized},
+		{name: "MFA pending token", generate: func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error) {
+			return p.GenerateMFAPendingToken(testClaims(), time.Minute)
+		}, want: http.StatusUnauthorized},
+		{name: "password expired token", generate: func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error) {
+			return p.GeneratePasswordExpiredToken(testClaims(), time.Minute)
+		}, want: http.StatusUnauthorized, change: http.StatusOK},
+		{name: "client credentials token", generate: userToken(func(c *paseto.PasetoClaims) {
+			c.Username, c.SubjectType, c.ClientID, c.Roles = "client-1", paseto.SubjectTypeClient, "client-1", ""
+		}), want: http.StatusUnauthorized},
+		{name: "token issued to an OAuth client", generate: userToken(func(c *paseto.PasetoClaims) {
+			c.ClientID, c.Scope = "client-1", "openid profile"
+		}), want: http.StatusUnauthorized, delegated: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if tt.generate != nil {
+				header = "Bearer " + newToken(t, tt.generate)
+			}
+
+			if got := serve(header, Authenticate()); got != tt.want {
+				t.Errorf("Authenticate: got %d, want %d", got, tt.want)
+			}
+
+			delegated := tt.delegated
+			if delegated == 0 {
+				delegated = tt.want
+			}
+			if got := serve(header, AuthenticateDelegated()); got != delegated {
+				t.Errorf("AuthenticateDelegated: got %d, want %d", got, delegated)
+			}
+
+			change := tt.change
+			if change == 0 {
+				change = tt.want
+			}
+			if got := serve(header, AuthenticatePasswordChange()); got != change {
+				t.Errorf("AuthenticatePasswordChange: got %d, want %d", got, change)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRevokedToken(t *testing.T) {
+	token, claims, err := paseto.Paseto().GenerateToken(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := serve("Bearer "+token, Authenticate()); got != http.StatusOK {
+		t.Fatalf("got %d before the revocation", got)
+	}
+
+	if err := paseto.Paseto().RevokeToken(claims); err != nil {
+		t.Fatal(err)
+	}
+	if got := serve("Bearer "+token, Authenticate()); got != http.StatusUnauthorized {
+		t.Errorf("revoked token: got %d, want %d", got, http.StatusUnauthorized)
+	}
+}
+
+func TestRequireRoles(t *testing.T) {
+	token := "Bearer " + newToken(t, func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error) {
+		return p.GenerateToken(testClaims())
+	})
+
+	tests := []struct {
+		name  string
+		roles []string
+		want  int
+	}{
+		{name: "one of the roles", roles: []string{"admin", "technician"}, want: http.StatusOK},
+		{name: "role of the company", roles: []string{"company_%d"}, want: http.StatusForbidden},
+		{name: "none of the roles", roles: []string{"admin"}, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(token, Protected(tt.roles...)...); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	companyToken := "Bearer " + newToken(t, func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error) {
+		claims := testClaims()
+		claims.Roles = "company_20001"
+		return p.GenerateToken(claims)
+	})
+	if got := serve(companyToken, Protected("company_%d")...); got != http.StatusOK {
+		t.Errorf("role of the company of the token: got %d, want %d", got, http.StatusOK)
+	}
+	if got := serve(companyToken, Protected("company_20002")...); got != http.StatusForbidden {
+		t.Errorf("role of another company: got %d, want %d", got, http.StatusForbidden)
+	}
+}
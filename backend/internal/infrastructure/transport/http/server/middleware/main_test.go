@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"app/internal/infrastructure/token/paseto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestMain prepares what the middlewares take from the running service: the environment
+// (config.ENV() loads the .env of the working directory) and the key ring and the revocation
+// list of the access tokens (kept in memory).
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	gin.SetMode(gin.TestMode)
+
+	dir, err := os.MkdirTemp("", "middleware-test")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	env := "DB_USER=test\nDB_PASSWORD=test\n" +
+		"VERIFICACIONES_USERNAME=test\nVERIFICACIONES_PASSWORD=test\n" +
+		"DEFAULT_USER_LOGIN=test\nDEFAULT_USER_PASSWORD=test\n" +
+		"PASETO_SK=test-secret\nPASETO_EXPIRATION_TIME=1h\nPASETO_RECOVER_EXPIRATION_TIME=5m\nREFRESH_EXPIRATION_TIME=24h\n" +
+		"MAIL_SMTP_HOST=localhost\nMAIL_SMTP_PORT=25\nMAIL_SMTP_USERNAME=test\nMAIL_SMTP_PASSWORD=test\nMAIL_SMTP_TLS=false\n" +
+		"MIDDLEWARE_PASSWORD=test\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(env), 0o600); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer os.Chdir(wd)
+
+	if err := paseto.Paseto().SetKeyStore(&memoryKeyStore{}); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	paseto.Paseto().SetRevocationList(&memoryRevocationList{})
+
+	return m.Run()
+}
+
+// memoryKeyStore — signing keys of the access tokens (stands in for the signing_keys table)
+type memoryKeyStore struct {
+	mu   sync.Mutex
+	keys []paseto.StoredSigningKey
+}
+
+func (s *memoryKeyStore) GetAll(now time.Time) ([]paseto.StoredSigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]paseto.StoredSigningKey(nil), s.keys...), nil
+}
+
+func (s *memoryKeyStore) Create(key paseto.StoredSigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func (s *memoryKeyStore) Retire(id string, retiredAt, expiresAt time.Time) error { return nil }
+
+func (s *memoryKeyStore) DeleteExpired(now time.Time) (int64, error) { return 0, nil }
+
+// memoryRevocationList — revoked access tokens and sessions (stands in for the revoked_tokens table)
+type memoryRevocationList struct {
+	mu      sync.Mutex
+	revoked map[string]bool
+}
+
+func (l *memoryRevocationList) Revoke(identifier string, expiresAt time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.revoked == nil {
+		l.revoked = make(map[string]bool)
+	}
+	l.revoked[identifier] = true
+	return nil
+}
+
+func (l *memoryRevocationList) IsRevoked(identifiers ...string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range identifiers {
+		if l.revoked[id] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (l *memoryRevocationList) DeleteExpired(now time.Time) (int64, error) { return 0, nil }
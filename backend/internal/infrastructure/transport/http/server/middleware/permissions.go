@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"app/internal/domain/role"
+	"app/internal/infrastructure/repositories"
+	"app/pkg/errorsLib"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermissions checks that the roles of the authenticated user grant all the given permissions.
+// Must be used after Authenticate. Requests without the permissions are aborted with 403.
+func RequirePermissions(permissions ...string) gin.HandlerFunc {
+	return requirePermissions(role.NewAuthorizationService(repositories.NewRoleRepository(), repositories.NewPermissionRepository()), permissions...)
+}
+
+func requirePermissions(authSvc *role.AuthorizationService, permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			abortUnauthorized(c, errorsLib.ErrAccessDenied.Error())
+			return
+		}
+
+		allowed, err := authSvc.RolesHavePermissions(claims.RoleList(), permissions...)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !allowed {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": errorsLib.ErrForbidden.Error()})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ProtectedWithPermissions is a shortcut for Authenticate + RequirePermissions
+func ProtectedWithPermissions(permissions ...string) []gin.HandlerFunc {
+	return []gin.HandlerFunc{Authenticate(), RequirePermissions(permissions...)}
+}
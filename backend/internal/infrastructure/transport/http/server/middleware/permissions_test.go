@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"app/internal/domain/role"
+	"app/internal/infrastructure/token/paseto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryPermissionRepository — permissions granted to each role (stands in for the permissions tables).
+// A method that is not implemented panics if it is called.
+type memoryPermissionRepository struct {
+	role.PermissionRepository
+	grants map[string][]string // role => permissions
+	err    error
+}
+
+func (r *memoryPermissionRepository) GetPermissionsByRoleNames(roleNames []string) ([]role.Permission, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	ids := map[string]uint{}
+	var permissions []role.Permission
+	for _, name := range roleNames {
+		for _, p := range r.grants[name] {
+			if _, ok := ids[p]; !ok {
+				ids[p] = uint(len(ids) + 1)
+			}
+			permissions = append(permissions, role.Permission{ID: ids[p], Name: p})
+		}
+	}
+	return permissions, nil
+}
+
+func newTestAuthorizationService(err error) *role.AuthorizationService {
+	return role.NewAuthorizationService(nil, &memoryPermissionRepository{
+		grants: map[string][]string{
+			role.RoleCompany:       {role.PermissionUsersRead, role.PermissionUsersWrite, role.PermissionRolesAssign},
+			"technician":           {role.PermissionUsersRead},
+			role.RolePlatformAdmin: {role.PermissionPlatformAdmin},
+		},
+		err: err,
+	})
+}
+
+func TestRequirePermissions(t *testing.T) {
+	tokenWithRoles := func(roles string) string {
+		return "Bearer " + newToken(t, func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error) {
+			claims := testClaims()
+			claims.Roles = roles
+			return p.GenerateToken(claims)
+		})
+	}
+
+	tests := []struct {
+		name        string
+		roles       string
+		permissions []string
+		storeErr    error
+		want        int
+	}{
+		{name: "permission of a role", roles: "technician", permissions: []string{role.PermissionUsersRead}, want: http.StatusOK},
+		{name: "all the permissions", roles: "company,technician", permissions: []string{role.PermissionUsersRead, role.PermissionRolesAssign}, want: http.StatusOK},
+		{name: "only some of the permissions", roles: "technician", permissions: []string{role.PermissionUsersRead, role.PermissionUsersWrite}, want: http.StatusForbidden},
+		{name: "permission of another role", roles: "company", permissions: []string{role.PermissionPlatformAdmin}, want: http.StatusForbidden},
+		{name: "role without permissions", roles: "sat01", permissions: []string{role.PermissionUsersRead}, want: http.StatusForbidden},
+		{name: "permissions not available", roles: "company", permissions: []string{role.PermissionUsersRead}, storeErr: errors.New("connection refused"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require := requirePermissions(newTestAuthorizationService(tt.storeErr), tt.permissions...)
+			if got := serve(tokenWithRoles(tt.roles), Authenticate(), require); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequirePermissionsWithoutAuthenticate(t *testing.T) {
+	token := "Bearer " + newToken(t, func(p *paseto.PasetoManager) (string, *paseto.PasetoClaims, error) {
+		return p.GenerateToken(testClaims())
+	})
+
+	// The claims are only taken from Authenticate, never from the header itself
+	router := gin.New()
+	router.GET("/", requirePermissions(newTestAuthorizationService(nil), role.PermissionUsersRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	if got := serveRouter(router, token); got != http.StatusUnauthorized {
+		t.Errorf("got %d, want %d", got, http.StatusUnauthorized)
+	}
+}
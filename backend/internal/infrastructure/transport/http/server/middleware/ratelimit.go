@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"app/internal/infrastructure/token/paseto"
+	"app/pkg/logger"
+	"app/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+)
+
+// Maximum size of the body read to get a rate limit key
+const rateLimitMaxBody = 64 << 10
+
+var (
+	rateLimitMu    sync.RWMutex
+	rateLimitStore ratelimit.Store = ratelimit.NewMemoryStore()
+)
+
+// SetRateLimitStore replaces the store of the rate limit buckets
+// (in memory by default: a shared store is needed to limit several instances together)
+func SetRateLimitStore(store ratelimit.Store) {
+	rateLimitMu.Lock()
+	defer rateLimitMu.Unlock()
+	rateLimitStore = store
+}
+
+func getRateLimitStore() ratelimit.Store {
+	rateLimitMu.RLock()
+	defer rateLimitMu.RUnlock()
+	return rateLimitStore
+}
+
+// rateLimitKey — what the requests are counted by: each value has its own bucket
+type rateLimitKey struct {
+	name  string
+	value func(c *gin.Context) string
+}
+
+// RateLimit limits the requests of a route (group) with the token bucket "rate_limit.routes.<name>"
+// of the config: "requests" per "period", up to "burst" at once, counted per each of its "keys":
+//   - ip: address of the client (X-Forwarded-For only counts behind the trusted proxies of the config)
+//   - subject: user of the access token (skipped without a valid token)
+//   - body:<field>: field of the JSON (or form) body, e.g. body:login (skipped if empty)
+//
+// Requests over the limit of any key are aborted with 429 and the Retry-After header.
+// Without the limit in the config (or with rate_limit.enabled false) the requests are not limited.
+func RateLimit(name string) gin.HandlerFunc {
+	prefix := "rate_limit.routes." + name
+	limit := ratelimit.Every(viper.GetInt(prefix+".requests"), viper.GetDuration(prefix+".period"), viper.GetInt(prefix+".burst"))
+	if !viper.GetBool("rate_limit.enabled") || limit.IsZero() {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	keyNames := viper.GetStringSlice(prefix + ".keys")
+	if len(keyNames) == 0 {
+		keyNames = []string{"ip"}
+	}
+	keys := make([]rateLimitKey, len(keyNames))
+	for i, keyName := range keyNames {
+		key, ok := parseRateLimitKey(keyName)
+		if !ok {
+			log.Fatalf("Invalid rate limit key %q of %s", keyName, prefix)
+		}
+		keys[i] = key
+	}
+
+	return func(c *gin.Context) {
+		now := time.Now()
+		for _, key := range keys {
+			value := key.value(c)
+			if value == "" {
+				continue
+			}
+
+			result, err := getRateLimitStore().Take(c.Request.Context(), name+":"+key.name+":"+value, limit, now)
+			if err != nil {
+				// The limits must not take the service down: the request goes on
+				logger.GetLogger().ServiceError("Rate limit store error", map[string]interface{}{
+					"route": name,
+					"error": err.Error(),
+				})
+				continue
+			}
+			if !result.Allowed {
+				c.Header("Retry-After", strconv.FormatInt(int64((result.RetryAfter+time.Second-1)/time.Second), 10))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+func parseRateLimitKey(name string) (rateLimitKey, bool) {
+	switch {
+	case name == "ip":
+		return rateLimitKey{name: name, value: func(c *gin.Context) string { return c.ClientIP() }}, true
+	case name == "subject":
+		return rateLimitKey{name: name, value: tokenSubject}, true
+	case strings.HasPrefix(name, "body:") && len(name) > len("body:"):
+		field := strings.TrimPrefix(name, "body:")
+		return rateLimitKey{name: name, value: func(c *gin.Context) string { return bodyField(c, field) }}, true
+	}
+	return rateLimitKey{}, false
+}
+
+// tokenSubject returns the user of the access token (already validated by Authenticate or validated here)
+func tokenSubject(c *gin.Context) string {
+	if claims, ok := GetClaims(c); ok {
+		return claims.Username
+	}
+
+	header := c.GetHeader("Authorization")
+	if strings.TrimSpace(header) == "" {
+		return ""
+	}
+	claims, err := paseto.Paseto().ValidateToken(header)
+	if err != nil || claims.IsClientToken() {
+		return ""
+	}
+	return claims.Username
+}
+
+// bodyField returns a string field of the body (normalized). The body is restored for the handler.
+func bodyField(c *gin.Context, field string) string {
+	var value string
+	if strings.HasPrefix(c.ContentType(), "application/json") {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, rateLimitMaxBody))
+		if err != nil {
+			return ""
+		}
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+
+		var fields map[string]interface{}
+		if json.Unmarshal(body, &fields) != nil {
+			return ""
+		}
+		value, _ = fields[field].(string)
+	} else {
+		value = c.PostForm(field)
+	}
+	return strings.ToLower(strings.TrimSpace(value))
+}
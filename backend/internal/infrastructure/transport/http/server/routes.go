@@ -1,7 +1,10 @@
 package http
 
 import (
+	"app/internal/application"
 	"app/internal/infrastructure/transport/http/handlers/auth"
+	"app/internal/infrastructure/transport/http/handlers/clients"
+	"app/internal/infrastructure/transport/http/handlers/oauth"
 	"app/internal/infrastructure/transport/http/handlers/provider"
 	"app/internal/infrastructure/transport/http/handlers/roles"
 	"app/internal/infrastructure/transport/http/handlers/token"
@@ -11,14 +14,24 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func InitRoutes(router *gin.Engine) {
+// UseCases — use cases shared by the routes of several handlers, built once by the composition
+type UseCases struct {
+	Auth     *application.AuthUseCase
+	MFA      *application.MFAUseCase
+	Lockout  *application.LockoutUseCase
+	Password *application.PasswordUseCase
+}
+
+func InitRoutes(router *gin.Engine, uc UseCases) {
 
 	provider.Routes(router)
-	user.Routes(router)
+	user.Routes(router, uc.Lockout)
 	profile.Routes(router)
-	auth.Routes(router)
+	auth.Routes(router, uc.Auth, uc.MFA, uc.Password)
 	token.Routes(router)
 	roles.Routes(router)
+	clients.Routes(router)
+	oauth.Routes(router, uc.Auth)
 
 	printRoutes(router)
 }
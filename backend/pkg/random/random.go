@@ -1,12 +1,31 @@
 package random
 
-import "github.com/sethvargo/go-password/password"
+import (
+	"github.com/sethvargo/go-password/password"
+)
 
-func GenerateRandomPassword() (string, error) {
-	password, err := password.Generate(15, 5, 5, false, false)
-	if err != nil {
-		return "", err
+// Attempts to generate a password accepted by the caller before giving up
+const maxPasswordAttempts = 100
+
+// GenerateRandomPassword generates a password of the given length with letters and at least one digit
+// and one symbol. accept checks it against the rules of the caller (e.g. the password policy):
+// the password is generated again until it is accepted. Returns the last error of accept otherwise.
+func GenerateRandomPassword(length int, accept func(password string) error) (string, error) {
+	// A quarter of digits and a quarter of symbols, the rest are letters
+	special := length / 4
+	if special < 1 {
+		special = 1
 	}
 
-	return password, nil
+	var err error
+	for i := 0; i < maxPasswordAttempts; i++ {
+		var plain string
+		if plain, err = password.Generate(length, special, special, false, true); err != nil {
+			return "", err
+		}
+		if err = accept(plain); err == nil {
+			return plain, nil
+		}
+	}
+	return "", err
 }
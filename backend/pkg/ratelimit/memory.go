@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryCleanupInterval — minimum time between two removals of the full buckets
+const memoryCleanupInterval = time.Minute
+
+// MemoryStore keeps the buckets in memory. The limits are per instance of the service.
+type MemoryStore struct {
+	mu          sync.Mutex
+	buckets     map[string]*memoryBucket
+	lastCleanup time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	limit Limit
+}
+
+// Ensure MemoryStore implements Store
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cleanup(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{bucket: bucket{tokens: float64(limit.Burst), last: now}}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	return b.take(limit, now), nil
+}
+
+// cleanup removes the buckets that are full again: they are the same as a new bucket
+func (s *MemoryStore) cleanup(now time.Time) {
+	if now.Sub(s.lastCleanup) < memoryCleanupInterval {
+		return
+	}
+	s.lastCleanup = now
+
+	for key, b := range s.buckets {
+		if b.full(b.limit, now) {
+			delete(s.buckets, key)
+		}
+	}
+}
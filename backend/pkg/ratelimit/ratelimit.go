@@ -0,0 +1,73 @@
+// Package ratelimit implements token-bucket rate limits.
+// The buckets are kept by a Store: MemoryStore keeps them in the process,
+// a shared store is needed when the limits must hold across several instances.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit — token bucket: Burst requests at once, refilled at Rate requests per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every returns the limit of n requests per period, up to burst at once
+func Every(n int, period time.Duration, burst int) Limit {
+	if n <= 0 || period <= 0 {
+		return Limit{}
+	}
+	if burst <= 0 {
+		burst = n
+	}
+	return Limit{Rate: float64(n) / period.Seconds(), Burst: burst}
+}
+
+// IsZero reports whether the limit is not set (no limit)
+func (l Limit) IsZero() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// Result — outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Remaining  int           // tokens left in the bucket
+	RetryAfter time.Duration // time until the next token when not allowed
+}
+
+// Store keeps the buckets of the keys
+type Store interface {
+	// Take takes one token from the bucket of the key (created full if it doesn't exist)
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+// bucket — state of a token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take refills the bucket up to now and takes one token if there is one
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * limit.Rate
+	}
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+		return Result{Allowed: false, RetryAfter: wait}
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: int(b.tokens)}
+}
+
+// full reports whether the bucket is full again at now (it can be forgotten)
+func (b *bucket) full(limit Limit, now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= float64(limit.Burst)
+}
@@ -0,0 +1,64 @@
+package sealer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+var ErrOpen = errors.New("can't open the sealed data (was the secret changed?)")
+
+// Sealer encrypts small secrets stored in the database (AES-256-GCM, key derived from a service secret)
+type Sealer struct {
+	key []byte
+}
+
+// New derives the key from the secret. purpose separates the keys of different kinds of data.
+func New(secret, purpose string) *Sealer {
+	var key [32]byte
+	if purpose == "" {
+		key = sha256.Sum256([]byte(secret))
+	} else {
+		key = sha256.Sum256([]byte(purpose + ":" + secret))
+	}
+	return &Sealer{key: key[:]}
+}
+
+// Seal encrypts data (nonce || ciphertext)
+func (s *Sealer) Seal(data []byte) ([]byte, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// Open decrypts data sealed by Seal
+func (s *Sealer) Open(data []byte) ([]byte, error) {
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrOpen
+	}
+	data, err = gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrOpen
+	}
+	return data, nil
+}
+
+func (s *Sealer) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}